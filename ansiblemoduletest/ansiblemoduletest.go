@@ -0,0 +1,176 @@
+// Package ansiblemoduletest provides helpers for testing modules built with ansiblemodule
+package ansiblemoduletest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+// exitPanic is raised by the harness exit function to stop the module body
+type exitPanic struct {
+	code int
+}
+
+// Result is the outcome of running a module with RunModule
+type Result struct {
+	Exited   bool                         // The module called ExitJson or FailJson
+	ExitCode int                          // Exit code passed to the exit function
+	Stdout   string                       // Raw module output
+	Output   map[string]interface{}       // Parsed JSON result, nil if the module printed none
+	Err      error                        // Error returned by NewModule
+	Module   *ansiblemodule.AnsibleModule // Module instance, nil if NewModule failed
+}
+
+// RunModule creates a module from argSpec with input as its arguments and runs
+// body with it, capturing the JSON result. Stdin, stdout and the exit function
+// are replaced while the module runs, so tests using it must not run in parallel.
+func RunModule(t testing.TB, argSpec ansiblemodule.ArgSpecMap, input map[string]interface{},
+	body func(m *ansiblemodule.AnsibleModule)) *Result {
+	t.Helper()
+
+	inputData, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("Failed to serialize module input: %v", err)
+	}
+
+	// Feed the arguments on stdin
+	stdinFile, err := os.CreateTemp("", "ansiblemoduletest-stdin-")
+	if err != nil {
+		t.Fatalf("Failed to create stdin file: %v", err)
+	}
+	defer os.Remove(stdinFile.Name())
+	defer stdinFile.Close()
+	if _, err := stdinFile.Write(inputData); err != nil {
+		t.Fatalf("Failed to write stdin file: %v", err)
+	}
+	if _, err := stdinFile.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to rewind stdin file: %v", err)
+	}
+
+	// Capture stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create stdout pipe: %v", err)
+	}
+	output := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		output <- buf.String()
+	}()
+
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	oldExitFunc, oldHandleSignals := ansiblemodule.DefaultExitFunc, ansiblemodule.HandleSignals
+	oldModuleArgs, hadModuleArgs := os.LookupEnv("ANSIBLE_MODULE_ARGS")
+	os.Stdin, os.Stdout = stdinFile, w
+	ansiblemodule.DefaultExitFunc = func(code int) { panic(exitPanic{code: code}) }
+	ansiblemodule.HandleSignals = false
+	os.Unsetenv("ANSIBLE_MODULE_ARGS")
+
+	result := &Result{}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				exit, ok := r.(exitPanic)
+				if !ok {
+					panic(r)
+				}
+				result.Exited = true
+				result.ExitCode = exit.code
+			}
+		}()
+
+		result.Module, result.Err = ansiblemodule.NewModule(argSpec, nil, nil, nil, nil, true)
+		if result.Err == nil && body != nil {
+			body(result.Module)
+		}
+	}()
+
+	w.Close()
+	os.Stdin, os.Stdout = oldStdin, oldStdout
+	ansiblemodule.DefaultExitFunc, ansiblemodule.HandleSignals = oldExitFunc, oldHandleSignals
+	if hadModuleArgs {
+		os.Setenv("ANSIBLE_MODULE_ARGS", oldModuleArgs)
+	}
+	if result.Module != nil {
+		result.Module.Cleanup()
+	}
+
+	result.Stdout = <-output
+	if trimmed := strings.TrimSpace(result.Stdout); trimmed != "" {
+		if err := json.Unmarshal([]byte(trimmed), &result.Output); err != nil {
+			t.Fatalf("Failed to parse module output %q: %v", result.Stdout, err)
+		}
+	}
+	return result
+}
+
+// Failed reports whether the module result is a failure
+func (r *Result) Failed() bool {
+	return r.Output != nil && r.Output["failed"] == true
+}
+
+// Changed reports whether the module result has changed set
+func (r *Result) Changed() bool {
+	return r.Output != nil && r.Output["changed"] == true
+}
+
+// Msg returns the msg field of the result
+func (r *Result) Msg() string {
+	msg, _ := r.Output["msg"].(string)
+	return msg
+}
+
+// AssertExited fails the test if the module returned without reporting a result
+func (r *Result) AssertExited(t testing.TB) {
+	t.Helper()
+	if !r.Exited {
+		t.Fatalf("Expected module to exit, got error %v and output %q", r.Err, r.Stdout)
+	}
+}
+
+// AssertSucceeded fails the test if the module failed
+func (r *Result) AssertSucceeded(t testing.TB) {
+	t.Helper()
+	r.AssertExited(t)
+	if r.Failed() {
+		t.Fatalf("Expected module to succeed, got failure: %s", r.Msg())
+	}
+}
+
+// AssertChanged fails the test unless the module succeeded with changed set to expected
+func (r *Result) AssertChanged(t testing.TB, expected bool) {
+	t.Helper()
+	r.AssertSucceeded(t)
+	if r.Changed() != expected {
+		t.Fatalf("Expected changed to be %v, got %v", expected, r.Output["changed"])
+	}
+}
+
+// AssertFailedContains fails the test unless the module failed with msg containing substr
+func (r *Result) AssertFailedContains(t testing.TB, substr string) {
+	t.Helper()
+	r.AssertExited(t)
+	if !r.Failed() {
+		t.Fatalf("Expected module to fail with %q, got %s", substr, r.describe())
+	}
+	if !strings.Contains(r.Msg(), substr) {
+		t.Fatalf("Expected failure message to contain %q, got %q", substr, r.Msg())
+	}
+}
+
+// describe summarizes the result for assertion messages
+func (r *Result) describe() string {
+	if r.Output == nil {
+		return fmt.Sprintf("no output (error: %v)", r.Err)
+	}
+	data, _ := json.Marshal(r.Output)
+	return string(data)
+}
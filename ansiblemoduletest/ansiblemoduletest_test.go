@@ -0,0 +1,57 @@
+package ansiblemoduletest
+
+import (
+	"testing"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+var testArgSpec = ansiblemodule.ArgSpecMap{
+	"name":  {Type: "str", Required: true},
+	"state": {Type: "str", Default: "present", Choices: []string{"present", "absent"}},
+}
+
+func TestRunModuleChanged(t *testing.T) {
+	result := RunModule(t, testArgSpec, map[string]interface{}{"name": "demo"}, func(m *ansiblemodule.AnsibleModule) {
+		name, _ := m.GetParamString("name")
+		m.ExitJson(map[string]interface{}{"changed": true, "name": name})
+	})
+
+	result.AssertChanged(t, true)
+	if result.Output["name"] != "demo" {
+		t.Errorf("Expected name demo, got %v", result.Output["name"])
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", result.ExitCode)
+	}
+}
+
+func TestRunModuleFailed(t *testing.T) {
+	result := RunModule(t, testArgSpec, map[string]interface{}{"name": "demo"}, func(m *ansiblemodule.AnsibleModule) {
+		m.FailJson("package demo not found", nil)
+	})
+	result.AssertFailedContains(t, "not found")
+}
+
+func TestRunModuleValidation(t *testing.T) {
+	called := false
+	result := RunModule(t, testArgSpec, map[string]interface{}{"name": "demo", "state": "broken"}, func(m *ansiblemodule.AnsibleModule) {
+		called = true
+	})
+
+	if called {
+		t.Error("Expected module body not to run when validation fails")
+	}
+	result.AssertFailedContains(t, "state")
+}
+
+func TestRunModuleNoExit(t *testing.T) {
+	result := RunModule(t, testArgSpec, map[string]interface{}{"name": "demo"}, func(m *ansiblemodule.AnsibleModule) {})
+
+	if result.Exited || result.Output != nil {
+		t.Errorf("Expected no result from module that did not exit, got %v", result.Output)
+	}
+	if result.Module == nil {
+		t.Error("Expected module instance to be returned")
+	}
+}
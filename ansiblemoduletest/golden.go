@@ -0,0 +1,197 @@
+package ansiblemoduletest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// UpdateGoldenEnv names the environment variable that makes AssertGolden rewrite golden files
+const UpdateGoldenEnv = "ANSIBLEMODULETEST_UPDATE_GOLDEN"
+
+// VolatileKeys are result keys removed by NormalizeResult at any depth
+var VolatileKeys = []string{"timings", "start", "end", "delta", "results_file"}
+
+// NormalizeResult returns a copy of result without volatile keys and with every
+// occurrence of a replacements key in string values replaced by its value.
+// The system temp directory is always replaced with "<TMP>".
+func NormalizeResult(result map[string]interface{}, replacements map[string]string) map[string]interface{} {
+	all := map[string]string{filepath.Clean(os.TempDir()): "<TMP>"}
+	for k, v := range replacements {
+		if k != "" {
+			all[k] = v
+		}
+	}
+
+	// Replace longer strings first so nested paths are replaced whole
+	olds := make([]string, 0, len(all))
+	for k := range all {
+		olds = append(olds, k)
+	}
+	sort.Slice(olds, func(i, j int) bool { return len(olds[i]) > len(olds[j]) })
+	pairs := make([]string, 0, 2*len(olds))
+	for _, old := range olds {
+		pairs = append(pairs, old, all[old])
+	}
+
+	normalized, _ := normalizeValue(result, strings.NewReplacer(pairs...)).(map[string]interface{})
+	return normalized
+}
+
+// normalizeValue applies NormalizeResult to a nested value
+func normalizeValue(value interface{}, replacer *strings.Replacer) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if isVolatileKey(key) {
+				continue
+			}
+			out[key] = normalizeValue(item, replacer)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = normalizeValue(item, replacer)
+		}
+		return out
+	case []string:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = replacer.Replace(item)
+		}
+		return out
+	case string:
+		return replacer.Replace(v)
+	default:
+		return v
+	}
+}
+
+// isVolatileKey reports whether a key is listed in VolatileKeys
+func isVolatileKey(key string) bool {
+	for _, volatile := range VolatileKeys {
+		if key == volatile {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalized returns the module result normalized with its TmpDir replaced by "<TMPDIR>"
+func (r *Result) Normalized() map[string]interface{} {
+	replacements := map[string]string{}
+	if r.Module != nil && r.Module.TmpDir != "" {
+		replacements[r.Module.TmpDir] = "<TMPDIR>"
+	}
+	return NormalizeResult(r.Output, replacements)
+}
+
+// AssertGolden compares the normalized module result with a golden file
+func (r *Result) AssertGolden(t testing.TB, goldenPath string) {
+	t.Helper()
+	AssertGolden(t, r.Normalized(), goldenPath)
+}
+
+// AssertGolden compares result, as indented JSON with sorted keys, with the
+// content of goldenPath. When UpdateGoldenEnv is set the file is rewritten instead.
+func AssertGolden(t testing.TB, result map[string]interface{}, goldenPath string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		t.Fatalf("Failed to serialize result: %v", err)
+	}
+	actual := buf.Bytes()
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Fatalf("Failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(goldenPath, actual, 0644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (set %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if string(expected) != string(actual) {
+		t.Errorf("Result does not match %s (set %s=1 to update):\n%s",
+			goldenPath, UpdateGoldenEnv, lineDiff(string(expected), string(actual)))
+	}
+}
+
+// lineDiff returns the differing lines of two texts, with unchanged lines
+// around each change for context
+func lineDiff(expected, actual string) string {
+	a := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	// Longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, "+ "+b[j])
+			j++
+		default:
+			lines = append(lines, "- "+a[i])
+			i++
+		}
+	}
+
+	// Keep three lines of context around changes
+	const context = 3
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "  ") {
+			for k := max(0, i-context); k <= min(len(lines)-1, i+context); k++ {
+				keep[k] = true
+			}
+		}
+	}
+	var out strings.Builder
+	skipped := false
+	for i, line := range lines {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			out.WriteString("  ...\n")
+			skipped = false
+		}
+		fmt.Fprintln(&out, line)
+	}
+	return out.String()
+}
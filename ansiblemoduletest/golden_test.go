@@ -0,0 +1,68 @@
+package ansiblemoduletest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+func TestNormalizeResult(t *testing.T) {
+	result := map[string]interface{}{
+		"changed": true,
+		"path":    "/work/run-42/file.txt",
+		"timings": []interface{}{map[string]interface{}{"name": "phase"}},
+		"items": []interface{}{
+			map[string]interface{}{"start": "2024-01-01", "dest": "/work/run-42/a"},
+		},
+	}
+
+	normalized := NormalizeResult(result, map[string]string{"/work/run-42": "<WORK>"})
+	if _, ok := normalized["timings"]; ok {
+		t.Error("Expected timings to be removed")
+	}
+	if normalized["path"] != "<WORK>/file.txt" {
+		t.Errorf("Expected path to be replaced, got %v", normalized["path"])
+	}
+	item := normalized["items"].([]interface{})[0].(map[string]interface{})
+	if _, ok := item["start"]; ok || item["dest"] != "<WORK>/a" {
+		t.Errorf("Expected nested values to be normalized, got %v", item)
+	}
+	if result["timings"] == nil {
+		t.Error("Expected original result to be left untouched")
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	result := RunModule(t, testArgSpec, map[string]interface{}{"name": "demo"}, func(m *ansiblemodule.AnsibleModule) {
+		tmpDir, err := m.GetTmpDir()
+		if err != nil {
+			t.Fatalf("Failed to create temp dir: %v", err)
+		}
+		m.ExitJson(map[string]interface{}{"changed": true, "dest": tmpDir + "/demo.txt"})
+	})
+	result.AssertGolden(t, filepath.Join("testdata", "demo.golden.json"))
+}
+
+func TestAssertGoldenUpdate(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "result.golden.json")
+	t.Setenv(UpdateGoldenEnv, "1")
+	AssertGolden(t, map[string]interface{}{"b": 1, "a": "x"}, golden)
+
+	content, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Expected golden file to be written: %v", err)
+	}
+	if string(content) != "{\n  \"a\": \"x\",\n  \"b\": 1\n}\n" {
+		t.Errorf("Unexpected golden content %q", content)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	diff := lineDiff("a\nb\nc\n", "a\nx\nc\n")
+	if !strings.Contains(diff, "- b") || !strings.Contains(diff, "+ x") || !strings.Contains(diff, "  a") {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+}
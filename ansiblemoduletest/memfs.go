@@ -0,0 +1,541 @@
+package ansiblemoduletest
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+// maxSymlinkDepth limits symlink resolution like the kernel does
+const maxSymlinkDepth = 40
+
+// MemFS is an in-memory FileSystem for testing file helpers hermetically.
+// Paths use forward slashes and relative paths are resolved against "/".
+type MemFS struct {
+	mu      sync.Mutex
+	nodes   map[string]*memNode
+	tempSeq int
+}
+
+// memNode is a file, directory or symlink in a MemFS
+type memNode struct {
+	mode    fs.FileMode
+	data    []byte
+	target  string
+	modTime time.Time
+}
+
+// NewMemFS creates an empty file system containing only the root directory
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: map[string]*memNode{
+		"/": {mode: fs.ModeDir | 0755, modTime: time.Now()},
+	}}
+}
+
+// WriteFile creates or replaces a file, creating parent directories as needed
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := m.MkdirAll(path.Dir(cleanPath(name)), 0755); err != nil {
+		return err
+	}
+	file, err := m.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Paths returns all paths in the file system in sorted order
+func (m *MemFS) Paths() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	paths := make([]string, 0, len(m.nodes))
+	for name := range m.nodes {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// cleanPath returns the absolute slash-separated form of name
+func cleanPath(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return path.Clean(name)
+}
+
+// resolve returns the real path of name, following symlinks in parent
+// directories and, if followLast is set, in the final element
+func (m *MemFS) resolve(op, name string, followLast bool) (string, error) {
+	name = cleanPath(name)
+	resolved := "/"
+	remaining := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	depth := 0
+
+	for len(remaining) > 0 {
+		part := remaining[0]
+		remaining = remaining[1:]
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		node, ok := m.nodes[next]
+		if ok && node.mode&fs.ModeSymlink != 0 && (len(remaining) > 0 || followLast) {
+			depth++
+			if depth > maxSymlinkDepth {
+				return "", &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("too many levels of symbolic links")}
+			}
+			target := node.target
+			if !strings.HasPrefix(target, "/") {
+				target = path.Join(resolved, target)
+			}
+			remaining = append(strings.Split(strings.TrimPrefix(path.Clean(target), "/"), "/"), remaining...)
+			resolved = "/"
+			continue
+		}
+		if len(remaining) > 0 && (!ok || !node.mode.IsDir()) {
+			if !ok {
+				return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+			return "", &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("not a directory")}
+		}
+		resolved = next
+	}
+	return resolved, nil
+}
+
+// lookup returns the node at name
+func (m *MemFS) lookup(op, name string, followLast bool) (string, *memNode, error) {
+	resolved, err := m.resolve(op, name, followLast)
+	if err != nil {
+		return "", nil, err
+	}
+	node, ok := m.nodes[resolved]
+	if !ok {
+		return resolved, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return resolved, node, nil
+}
+
+// checkParent verifies that the parent of a resolved path is a directory
+func (m *MemFS) checkParent(op, name, resolved string) error {
+	parent, ok := m.nodes[path.Dir(resolved)]
+	if !ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !parent.mode.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("not a directory")}
+	}
+	return nil
+}
+
+// Open opens a file for reading
+func (m *MemFS) Open(name string) (ansiblemodule.File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens a file with the given flags
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (ansiblemodule.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resolved, node, err := m.lookup("open", name, true)
+	if err != nil && resolved == "" {
+		return nil, err
+	}
+	if node == nil {
+		if flag&os.O_CREATE == 0 {
+			return nil, err
+		}
+		if err := m.checkParent("open", name, resolved); err != nil {
+			return nil, err
+		}
+		node = &memNode{mode: perm.Perm(), modTime: time.Now()}
+		m.nodes[resolved] = node
+	} else {
+		if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+		if node.mode.IsDir() && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("is a directory")}
+		}
+		if flag&os.O_TRUNC != 0 {
+			node.data = nil
+			node.modTime = time.Now()
+		}
+	}
+
+	file := &memFile{fs: m, name: name, node: node, flag: flag}
+	if flag&os.O_APPEND != 0 {
+		file.offset = len(node.data)
+	}
+	return file, nil
+}
+
+// CreateTemp creates a new temporary file in dir
+func (m *MemFS) CreateTemp(dir, pattern string) (ansiblemodule.File, error) {
+	for {
+		m.mu.Lock()
+		m.tempSeq++
+		seq := m.tempSeq
+		m.mu.Unlock()
+
+		name := pattern + fmt.Sprint(seq)
+		if prefix, suffix, found := strings.Cut(pattern, "*"); found {
+			name = prefix + fmt.Sprint(seq) + suffix
+		}
+		file, err := m.OpenFile(path.Join(cleanPath(dir), name), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil || !os.IsExist(err) {
+			return file, err
+		}
+	}
+}
+
+// MkdirTemp creates a new temporary directory in dir, or /tmp if dir is empty
+func (m *MemFS) MkdirTemp(dir, pattern string) (string, error) {
+	if dir == "" {
+		dir = "/tmp"
+	}
+	if err := m.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	for {
+		m.mu.Lock()
+		m.tempSeq++
+		seq := m.tempSeq
+		name := pattern + fmt.Sprint(seq)
+		if prefix, suffix, found := strings.Cut(pattern, "*"); found {
+			name = prefix + fmt.Sprint(seq) + suffix
+		}
+		resolved, node, err := m.lookup("mkdirtemp", path.Join(cleanPath(dir), name), false)
+		if resolved == "" {
+			m.mu.Unlock()
+			return "", err
+		}
+		if node == nil {
+			m.nodes[resolved] = &memNode{mode: fs.ModeDir | 0700, modTime: time.Now()}
+			m.mu.Unlock()
+			return resolved, nil
+		}
+		m.mu.Unlock()
+	}
+}
+
+// ReadFile reads the whole file
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, node, err := m.lookup("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fmt.Errorf("is a directory")}
+	}
+	return append([]byte(nil), node.data...), nil
+}
+
+// ReadDir returns the entries of a directory sorted by name
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resolved, node, err := m.lookup("readdirent", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdirent", Path: name, Err: fmt.Errorf("not a directory")}
+	}
+	var entries []fs.DirEntry
+	for other, child := range m.nodes {
+		if other != resolved && path.Dir(other) == resolved {
+			entries = append(entries, fs.FileInfoToDirEntry(nodeInfo(other, child)))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Stat returns file information, following symlinks
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	return m.stat("stat", name, true)
+}
+
+// Lstat returns file information without following symlinks
+func (m *MemFS) Lstat(name string) (fs.FileInfo, error) {
+	return m.stat("lstat", name, false)
+}
+
+// stat builds the file information for a node
+func (m *MemFS) stat(op, name string, follow bool) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resolved, node, err := m.lookup(op, name, follow)
+	if err != nil {
+		return nil, err
+	}
+	return nodeInfo(resolved, node), nil
+}
+
+// nodeInfo returns the file information of the node at a resolved path
+func nodeInfo(resolved string, node *memNode) *memFileInfo {
+	size := int64(len(node.data))
+	if node.mode&fs.ModeSymlink != 0 {
+		size = int64(len(node.target))
+	}
+	return &memFileInfo{name: path.Base(resolved), size: size, mode: node.mode, modTime: node.modTime}
+}
+
+// Readlink returns the target of a symlink
+func (m *MemFS) Readlink(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, node, err := m.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if node.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fmt.Errorf("invalid argument")}
+	}
+	return node.target, nil
+}
+
+// Symlink creates newname as a symlink to oldname
+func (m *MemFS) Symlink(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resolved, node, err := m.lookup("symlink", newname, false)
+	if node != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+	if resolved == "" {
+		return err
+	}
+	if err := m.checkParent("symlink", newname, resolved); err != nil {
+		return err
+	}
+	m.nodes[resolved] = &memNode{mode: fs.ModeSymlink | 0777, target: oldname, modTime: time.Now()}
+	return nil
+}
+
+// MkdirAll creates a directory and any missing parents
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := "/"
+	for _, part := range strings.Split(strings.TrimPrefix(cleanPath(name), "/"), "/") {
+		if part == "" {
+			continue
+		}
+		resolved, node, err := m.lookup("mkdir", path.Join(current, part), true)
+		if resolved == "" {
+			return err
+		}
+		if node == nil {
+			node = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+			m.nodes[resolved] = node
+		} else if !node.mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fmt.Errorf("not a directory")}
+		}
+		current = resolved
+	}
+	return nil
+}
+
+// Chmod changes the permission bits of a file
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, node, err := m.lookup("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	node.mode = node.mode&fs.ModeType | mode.Perm()
+	return nil
+}
+
+// Rename moves a file or directory, replacing a file at the destination
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldResolved, node, err := m.lookup("rename", oldpath, false)
+	if err != nil {
+		return err
+	}
+	newResolved, existing, err := m.lookup("rename", newpath, false)
+	if newResolved == "" {
+		return err
+	}
+	if err := m.checkParent("rename", newpath, newResolved); err != nil {
+		return err
+	}
+	if existing != nil && existing.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fmt.Errorf("file exists")}
+	}
+
+	// Move the node and everything below it
+	for name, child := range m.nodes {
+		if strings.HasPrefix(name, oldResolved+"/") {
+			delete(m.nodes, name)
+			m.nodes[newResolved+strings.TrimPrefix(name, oldResolved)] = child
+		}
+	}
+	delete(m.nodes, oldResolved)
+	m.nodes[newResolved] = node
+	return nil
+}
+
+// Remove removes a file or empty directory
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resolved, node, err := m.lookup("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if node.mode.IsDir() {
+		for other := range m.nodes {
+			if strings.HasPrefix(other, resolved+"/") {
+				return &fs.PathError{Op: "remove", Path: name, Err: fmt.Errorf("directory not empty")}
+			}
+		}
+	}
+	delete(m.nodes, resolved)
+	return nil
+}
+
+// RemoveAll removes a path and everything below it, succeeding if it does not exist
+func (m *MemFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resolved, node, err := m.lookup("removeall", name, false)
+	if resolved == "" {
+		return err
+	}
+	if node == nil {
+		return nil
+	}
+	for other := range m.nodes {
+		if strings.HasPrefix(other, resolved+"/") {
+			delete(m.nodes, other)
+		}
+	}
+	if resolved != "/" {
+		delete(m.nodes, resolved)
+	}
+	return nil
+}
+
+// memFile is an open MemFS file
+type memFile struct {
+	fs     *MemFS
+	name   string
+	node   *memNode
+	flag   int
+	offset int
+	closed bool
+}
+
+// Name returns the name the file was opened with
+func (f *memFile) Name() string {
+	return f.name
+}
+
+// Read reads from the current offset
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fmt.Errorf("bad file descriptor")}
+	}
+	if f.offset >= len(f.node.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += n
+	return n, nil
+}
+
+// Write writes at the current offset
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fmt.Errorf("bad file descriptor")}
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = len(f.node.data)
+	}
+	end := f.offset + len(p)
+	if end > len(f.node.data) {
+		f.node.data = append(f.node.data, make([]byte, end-len(f.node.data))...)
+	}
+	copy(f.node.data[f.offset:], p)
+	f.offset = end
+	f.node.modTime = time.Now()
+	return len(p), nil
+}
+
+// Close closes the file
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// memFileInfo describes a MemFS node
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *memFileInfo) Name() string       { return i.name }
+func (i *memFileInfo) Size() int64        { return i.size }
+func (i *memFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *memFileInfo) ModTime() time.Time { return i.modTime }
+func (i *memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,177 @@
+package ansiblemoduletest
+
+import (
+	"os"
+	"testing"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+func TestMemFSFileHelpers(t *testing.T) {
+	memFS := NewMemFS()
+	module := &ansiblemodule.AnsibleModule{FileSystem: memFS}
+	defer module.Cleanup()
+
+	if err := memFS.WriteFile("/etc/app/app.conf", []byte("key=value\n"), 0644); err != nil {
+		t.Fatalf("Failed to seed file: %v", err)
+	}
+
+	// Test WriteTextFile
+	changed, err := module.WriteTextFile("/etc/app/app.conf", "key=value\n", 0644)
+	if err != nil || changed {
+		t.Errorf("Expected unchanged write, got changed=%v err=%v", changed, err)
+	}
+	changed, err = module.WriteTextFile("/etc/app/app.conf", "key=other\n", 0600)
+	if err != nil || !changed {
+		t.Fatalf("Expected changed write, got changed=%v err=%v", changed, err)
+	}
+	content, err := module.ReadTextFile("/etc/app/app.conf")
+	if err != nil || content != "key=other\n" {
+		t.Errorf("Unexpected content %q (%v)", content, err)
+	}
+	if info, _ := memFS.Stat("/etc/app/app.conf"); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %o", info.Mode().Perm())
+	}
+
+	// Test CopyFile and CompareFiles
+	changed, err = module.CopyFile("/etc/app/app.conf", "/etc/app/copy.conf", 0640)
+	if err != nil || !changed {
+		t.Fatalf("Expected copy to change, got changed=%v err=%v", changed, err)
+	}
+	if same, err := module.CompareFiles("/etc/app/app.conf", "/etc/app/copy.conf"); err != nil || !same {
+		t.Errorf("Expected copied files to match, got %v (%v)", same, err)
+	}
+
+	// Test directories and symlinks
+	if changed, err := module.CreateDirectory("/srv/data", 0750); err != nil || !changed {
+		t.Errorf("Expected directory to be created, got changed=%v err=%v", changed, err)
+	}
+	if !module.IsDir("/srv/data") {
+		t.Error("Expected /srv/data to be a directory")
+	}
+	if changed, err := module.CreateSymlink("/etc/app/app.conf", "/srv/data/link"); err != nil || !changed {
+		t.Errorf("Expected symlink to be created, got changed=%v err=%v", changed, err)
+	}
+	if !module.IsSymlink("/srv/data/link") || !module.IsFile("/srv/data/link") {
+		t.Error("Expected link to be a symlink to a file")
+	}
+	if changed, _ := module.CreateSymlink("/etc/app/app.conf", "/srv/data/link"); changed {
+		t.Error("Expected existing symlink to be unchanged")
+	}
+
+	stat, err := module.FileStat("/srv/data/link")
+	if err != nil || stat["islnk"] != true || stat["lnk_target"] != "/etc/app/app.conf" {
+		t.Errorf("Unexpected stat result %v (%v)", stat, err)
+	}
+
+	// Test directory listings and the helpers built on them
+	entries, err := memFS.ReadDir("/etc/app")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "app.conf" || entries[1].Name() != "copy.conf" {
+		t.Errorf("Unexpected entries %v (%v)", entries, err)
+	}
+	if _, err := memFS.ReadDir("/etc/app/app.conf"); err == nil {
+		t.Error("Expected listing a file to fail")
+	}
+	memFS.WriteFile("/etc/ansible/facts.d/app.fact", []byte(`{"role": "web"}`), 0644)
+	facts, err := module.ReadLocalFacts("/etc/ansible/facts.d")
+	if err != nil || len(facts) != 1 || facts["app"].(map[string]interface{})["role"] != "web" {
+		t.Errorf("Unexpected local facts %v (%v)", facts, err)
+	}
+	if err := module.ShredFile("/etc/app/copy.conf"); err != nil || module.FileExists("/etc/app/copy.conf") {
+		t.Errorf("Expected ShredFile to remove the file: %v", err)
+	}
+
+	// Nothing was written to the real file system
+	if _, err := os.Stat("/etc/app/copy.conf"); !os.IsNotExist(err) {
+		t.Error("Expected in-memory file not to exist on disk")
+	}
+}
+
+func TestMemFSErrors(t *testing.T) {
+	memFS := NewMemFS()
+
+	if _, err := memFS.Open("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if err := memFS.WriteFile("/a/file", []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := memFS.Remove("/a"); err == nil {
+		t.Error("Expected removing a non-empty directory to fail")
+	}
+	if err := memFS.Symlink("/a", "/loop"); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if data, err := memFS.ReadFile("/loop/file"); err != nil || string(data) != "x" {
+		t.Errorf("Expected read through directory symlink, got %q (%v)", data, err)
+	}
+	if err := memFS.Rename("/a", "/b"); err != nil {
+		t.Fatalf("Failed to rename directory: %v", err)
+	}
+	if _, err := memFS.Stat("/b/file"); err != nil {
+		t.Errorf("Expected file to move with its directory: %v", err)
+	}
+}
+
+func TestMemFSTmpDir(t *testing.T) {
+	memFS := NewMemFS()
+	module := &ansiblemodule.AnsibleModule{FileSystem: memFS}
+
+	dir, err := module.GetTmpDir()
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	if info, err := memFS.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("Expected %s to be a directory in memory, got %v", dir, err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to exist on disk", dir)
+	}
+
+	workspace, err := module.Workspace("build")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	path, err := workspace.WriteFile("conf/app.conf", []byte("key=value\n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+	if content, err := memFS.ReadFile(path); err != nil || string(content) != "key=value\n" {
+		t.Errorf("Unexpected staged content %q (%v)", content, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to exist on disk", path)
+	}
+
+	module.Cleanup()
+	if _, err := memFS.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected cleanup to remove %s, got %v", dir, err)
+	}
+	if _, err := memFS.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected cleanup to remove %s, got %v", path, err)
+	}
+}
+
+func TestMemFSReplaceDirectory(t *testing.T) {
+	memFS := NewMemFS()
+	module := &ansiblemodule.AnsibleModule{FileSystem: memFS}
+	memFS.WriteFile("/srv/site/index.html", []byte("old"), 0644)
+	memFS.WriteFile("/srv/site/old.html", []byte("gone"), 0644)
+	memFS.WriteFile("/srv/.site.new/index.html", []byte("new"), 0644)
+
+	changed, err := module.ReplaceDirectory("/srv/.site.new", "/srv/site")
+	if err != nil || !changed {
+		t.Fatalf("Expected the tree to be replaced, got %v (%v)", changed, err)
+	}
+	if content, _ := memFS.ReadFile("/srv/site/index.html"); string(content) != "new" || module.FileExists("/srv/site/old.html") {
+		t.Error("Expected the target to hold exactly the new tree")
+	}
+	if entries, _ := memFS.ReadDir("/srv"); len(entries) != 1 {
+		t.Errorf("Expected only the target to remain, got %v", entries)
+	}
+
+	memFS.WriteFile("/srv/.site.new/index.html", []byte("new"), 0644)
+	if changed, err := module.ReplaceDirectory("/srv/.site.new", "/srv/site"); err != nil || changed {
+		t.Errorf("Expected an identical tree to be unchanged, got %v (%v)", changed, err)
+	}
+}
@@ -0,0 +1,129 @@
+package ansiblemoduletest
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+// FakeRunner is a CommandRunner returning canned results, for use as AnsibleModule.Runner
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses []fakeResponse
+	paths     map[string]string
+	calls     [][]string
+}
+
+// fakeResponse is a canned result for a command line
+type fakeResponse struct {
+	argv   []string
+	result ansiblemodule.CommandResult
+	err    error
+}
+
+// NewFakeRunner creates a runner that knows no commands
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{paths: make(map[string]string)}
+}
+
+// On registers the result for a command line. The command is matched by base
+// name, so "systemctl" matches "/usr/bin/systemctl". A trailing "*" argument
+// matches any remaining arguments. The command becomes available to LookPath.
+func (f *FakeRunner) On(argv []string, stdout, stderr string, rc int) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses = append(f.responses, fakeResponse{
+		argv:   argv,
+		result: ansiblemodule.CommandResult{Stdout: stdout, Stderr: stderr, Rc: rc},
+	})
+	if _, ok := f.paths[argv[0]]; !ok && !filepath.IsAbs(argv[0]) {
+		f.paths[argv[0]] = "/usr/bin/" + argv[0]
+	}
+	return f
+}
+
+// OnError registers a command that cannot be run at all
+func (f *FakeRunner) OnError(argv []string, err error) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses = append(f.responses, fakeResponse{argv: argv, err: err})
+	return f
+}
+
+// SetPath sets the path LookPath returns for name, or removes it if path is empty
+func (f *FakeRunner) SetPath(name, path string) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if path == "" {
+		delete(f.paths, name)
+	} else {
+		f.paths[name] = path
+	}
+	return f
+}
+
+// Calls returns the command lines run so far
+func (f *FakeRunner) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([][]string(nil), f.calls...)
+}
+
+// Run returns the first registered result matching the command line
+func (f *FakeRunner) Run(ctx context.Context, cmd string, args []string, env []string, data string) (ansiblemodule.CommandResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	argv := append([]string{cmd}, args...)
+	f.calls = append(f.calls, argv)
+
+	for _, response := range f.responses {
+		if matchArgv(response.argv, argv) {
+			result := response.result
+			result.Cmd = cmd
+			return result, response.err
+		}
+	}
+	return ansiblemodule.CommandResult{Cmd: cmd, Rc: 127},
+		fmt.Errorf("unexpected command: %s", strings.Join(argv, " "))
+}
+
+// LookPath returns the path registered for name
+func (f *FakeRunner) LookPath(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if path, ok := f.paths[name]; ok {
+		return path, nil
+	}
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+// matchArgv compares a registered command line with an executed one
+func matchArgv(pattern, argv []string) bool {
+	for i, arg := range pattern {
+		if arg == "*" && i == len(pattern)-1 {
+			return len(argv) >= i
+		}
+		if i >= len(argv) {
+			return false
+		}
+		if i == 0 {
+			if filepath.Base(arg) != filepath.Base(argv[0]) {
+				return false
+			}
+		} else if arg != argv[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(argv)
+}
@@ -0,0 +1,48 @@
+package ansiblemoduletest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+func TestFakeRunner(t *testing.T) {
+	runner := NewFakeRunner().
+		On([]string{"systemctl", "is-active", "nginx"}, "active\n", "", 0).
+		On([]string{"systemctl", "is-enabled", "*"}, "disabled\n", "", 1).
+		OnError([]string{"broken"}, errors.New("permission denied"))
+	module := &ansiblemodule.AnsibleModule{Runner: runner}
+
+	path, err := module.GetBinPath("systemctl", true)
+	if err != nil || path != "/usr/bin/systemctl" {
+		t.Fatalf("Expected fake systemctl path, got %q (%v)", path, err)
+	}
+	if _, err := module.GetBinPath("apt-get", true); err == nil {
+		t.Error("Expected unknown command to be missing")
+	}
+
+	result, err := module.RunCommand(path, []string{"is-active", "nginx"}, nil, "")
+	if err != nil || result.Stdout != "active\n" || result.Rc != 0 {
+		t.Errorf("Unexpected result: %+v (%v)", result, err)
+	}
+
+	result, err = module.RunCommand("systemctl", []string{"is-enabled", "sshd"}, nil, "")
+	if err == nil || result.Rc != 1 || result.Stdout != "disabled\n" {
+		t.Errorf("Expected rc 1 with output, got %+v (%v)", result, err)
+	}
+
+	if _, err := module.RunCommand("broken", nil, nil, ""); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected registered error, got %v", err)
+	}
+
+	result, err = module.RunCommand("systemctl", []string{"restart", "nginx"}, nil, "")
+	if err == nil || result.Rc != 127 {
+		t.Errorf("Expected unexpected command to fail with rc 127, got %+v (%v)", result, err)
+	}
+
+	if calls := runner.Calls(); len(calls) != 4 || calls[0][0] != "/usr/bin/systemctl" {
+		t.Errorf("Unexpected calls: %v", calls)
+	}
+}
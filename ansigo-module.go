@@ -3,20 +3,21 @@ package ansiblemodule
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"maps"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 )
 
@@ -32,7 +33,9 @@ type ArgumentSpec struct {
 	Options      ArgSpecMap  `json:"options,omitempty"`
 	AppliesTo    []string    `json:"applies_to,omitempty"`
 	RemoveInFile string      `json:"removed_in_version,omitempty"`
-	SubOptions   ArgSpecMap  `json:"suboptions,omitempty"` // For nested list elements
+	SubOptions   ArgSpecMap  `json:"suboptions,omitempty"`    // For nested list elements
+	Description  string      `json:"description,omitempty"`   // Documentation only, paragraphs separated by blank lines
+	VersionAdded string      `json:"version_added,omitempty"` // Documentation only
 }
 
 // ArgSpecMap is a map of argument names to their specifications
@@ -43,23 +46,69 @@ type ModuleParams map[string]interface{}
 
 // AnsibleModule is the core structure for Ansible modules written in Go
 type AnsibleModule struct {
-	Params            ModuleParams
-	ArgSpec           ArgSpecMap
-	CheckMode         bool
-	Debug             bool
-	Warnings          []string
-	DeprecationMsgs   []string
-	NoLog             []string
-	TmpDir            string
-	FromFile          string
-	MutuallyExclusive [][]string
-	RequiredTogether  [][]string
-	RequiredOne       [][]string
-	RequiredIf        []RequiredIfSpec
-	Aliases           map[string]string
-	RequiredBy        map[string][]string // Parameters required by other parameters
-	TestMode          bool                // Flag to indicate if we're in test mode
-	ExitFunc          func(int)           // Custom exit function for testing
+	Params                 ModuleParams
+	ArgSpec                ArgSpecMap
+	CheckMode              bool
+	Debug                  bool
+	Warnings               []string
+	DeprecationMsgs        []string
+	NoLog                  []string
+	TmpDir                 string
+	FromFile               string
+	MutuallyExclusive      [][]string
+	RequiredTogether       [][]string
+	RequiredOne            [][]string
+	RequiredIf             []RequiredIfSpec
+	Aliases                map[string]string
+	RequiredBy             map[string][]string // Parameters required by other parameters
+	TestMode               bool                // Flag to indicate if we're in test mode
+	ExitFunc               func(int)           // Custom exit function for testing
+	HTTPClient             *http.Client        // Client used by FetchURL, created on first use
+	Locale                 string              // Locale applied to the module and command children
+	NoTargetSyslog         bool                // Disable logging to the journal and syslog
+	Logger                 Logger              // Destination for Log messages, defaults to journal/syslog
+	Verbosity              int                 // Verbosity level requested by the controller (-v count)
+	Runner                 CommandRunner       // Executes commands, defaults to ExecRunner
+	FileSystem             FileSystem          // Used by the file helpers, defaults to OSFileSystem
+	JunctionFallback       bool                // Link directories with junctions when Windows denies symlinks
+	CompareOptions         CompareOptions      // Differences ignored by CompareFiles and WriteTextFile
+	Audit                  bool                // Record state-changing operations under the audit result key
+	OutputLimit            int                 // Keep only the last OutputLimit bytes of command output, 0 keeps everything
+	Output                 io.Writer           // Destination of the JSON result, defaults to stdout
+	IndentResult           bool                // Indent the JSON result, for reading it while debugging
+	StringConversionAction string              // Controller string_conversion_action: warn, error or ignore
+	ConversionWarnings     bool                // Report parameters given as strings and converted to another type
+	StrictResults          bool                // Fail instead of warning when a result uses reserved keys
+	ShredTmp               bool                // Overwrite temporary files before removing them, see ShredFile
+	QuietStderr            bool                // Return debug output under debug_info instead of writing it to stderr
+	ComparisonHash         string              // Hash algorithm used to compare file content, byte by byte if empty
+	RecordCommands         bool                // Add every command run to the result under "commands" at verbosity 1 and above
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	timingsMu sync.Mutex
+	timings   []*Span
+	partialMu sync.Mutex
+	partial   map[string]interface{}
+	watchdog  *time.Timer // Armed by StartWatchdog, guarded by partialMu
+	retries   []map[string]interface{}
+	commands  []map[string]interface{}
+	debugInfo []string // Debug output kept for the result when QuietStderr is set
+	exitMu    sync.Mutex
+	remoteTmp string // Temp directory chosen by the controller (_ansible_tmpdir)
+
+	tmpMu         sync.Mutex
+	ownedTmpDir   string // Temp directory created by the module
+	removedTmpDir string // Temp directory removed by Cleanup or on exit
+	workspaces    map[string]*Workspace
+
+	noLogMu      sync.Mutex
+	runtimeNoLog []string     // Secrets registered with NoLogValue
+	digestKey    []byte       // Key for SecretDigest, generated on first use
+	savedUmask   *os.FileMode // Umask replaced by SetUmask
+	auditMu      sync.Mutex
+	auditTrail   []AuditRecord
+	exited       bool // ExitJson has written the result
 }
 
 // RequiredIfSpec defines a conditional requirement for arguments
@@ -83,32 +132,66 @@ type Result struct {
 	Diff         map[string]interface{} `json:"diff,omitempty"`
 	Debug        []string               `json:"debug_info,omitempty"`
 	Exception    string                 `json:"exception,omitempty"`
+	Data         map[string]interface{} `json:"-"` // Module specific return values, overriding the fields above
 }
 
 // CommandResult contains the results of running a command
 type CommandResult struct {
-	Cmd    string
-	Stdout string
-	Stderr string
-	Rc     int
+	Cmd         string
+	Args        []string
+	Stdout      string
+	Stderr      string
+	Rc          int
+	StdoutBytes int64         // Bytes written to stdout, more than len(Stdout) when output was limited
+	StderrBytes int64         // Bytes written to stderr, more than len(Stderr) when output was limited
+	Start       time.Time     // When the command was started
+	End         time.Time     // When the command exited or was killed
+	Delta       time.Duration // How long the command ran
+	Changed     bool          // The command ran, or would have run in check mode
+	Skipped     bool          // The command was not run because of its guards or check mode
+	Msg         string        // Why a skipped command was not run
 }
 
+// DefaultExitFunc is installed as ExitFunc on new modules, so exits during NewModule can be intercepted
+var DefaultExitFunc func(int)
+
+// ErrCheckModeUnsupported is returned by NewModule when check mode is requested
+// from a module that does not support it
+var ErrCheckModeUnsupported = errors.New("check mode is not supported for this module")
+
 // NewModule creates a new AnsibleModule instance
 func NewModule(argSpec ArgSpecMap, mutuallyExclusive [][]string,
 	requiredTogether [][]string, requiredOne [][]string,
 	requiredIf []RequiredIfSpec, supports_check_mode bool) (*AnsibleModule, error) {
 
 	module := &AnsibleModule{
-		ArgSpec:           argSpec,
-		Params:            ModuleParams{},
-		Warnings:          []string{},
-		DeprecationMsgs:   []string{},
-		NoLog:             []string{},
-		MutuallyExclusive: mutuallyExclusive,
-		RequiredTogether:  requiredTogether,
-		RequiredOne:       requiredOne,
-		RequiredIf:        requiredIf,
-		Aliases:           make(map[string]string),
+		ArgSpec:            argSpec,
+		Params:             ModuleParams{},
+		Warnings:           []string{},
+		DeprecationMsgs:    []string{},
+		NoLog:              []string{},
+		MutuallyExclusive:  mutuallyExclusive,
+		RequiredTogether:   requiredTogether,
+		RequiredOne:        requiredOne,
+		RequiredIf:         requiredIf,
+		Aliases:            make(map[string]string),
+		ExitFunc:           DefaultExitFunc,
+		Output:             defaultOutput,
+		ConversionWarnings: DefaultConversionWarnings,
+	}
+
+	// A module created by the turbo server exits through the invocation it serves
+	turbo := turboCurrent.Load()
+	if turbo != nil {
+		turbo.adopt(module)
+	}
+
+	// Normalize the locale so command output can be parsed reliably
+	if NormalizeLocale {
+		module.SetLocale(detectLocale())
+	}
+	if ModuleUmask >= 0 {
+		module.SetUmask(os.FileMode(ModuleUmask))
 	}
 
 	// Process aliases
@@ -121,28 +204,42 @@ func NewModule(argSpec ArgSpecMap, mutuallyExclusive [][]string,
 		}
 	}
 
-	// Parse input
+	// Report argument spec findings instead of running when invoked for sanity tests
+	if os.Getenv(SanityEnv) != "" {
+		module.runSanity(supports_check_mode)
+		return nil, fmt.Errorf("sanity check mode")
+	}
+
+	// Parse input, failing cleanly when the payload is too large or deep
 	if err := module.parseInput(); err != nil {
-		return nil, err
+		if _, ok := err.(*inputLimitError); ok {
+			module.FailJson(err.Error(), errorFields(err))
+			return nil, err
+		}
+		return nil, &inputError{err}
 	}
 
 	// Validate arguments
 	if err := module.validateArguments(); err != nil {
-		module.FailJson(err.Error(), nil)
+		module.FailJson(err.Error(), errorFields(err))
 		return nil, err
 	}
 
-	// Set up temporary directory
-	tmpDir, err := os.MkdirTemp("", "ansible-go-")
-	if err != nil {
-		module.FailJson(fmt.Sprintf("Failed to create temp dir: %v", err), nil)
-		return nil, err
+	// Refuse check mode before arming signal handling and the watchdog, which
+	// would otherwise outlive the module the caller never receives
+	if !supports_check_mode && module.CheckMode {
+		return nil, ErrCheckModeUnsupported
 	}
-	module.TmpDir = tmpDir
 
-	// Add check mode validation
-	if !supports_check_mode && module.CheckMode {
-		return nil, fmt.Errorf("check mode is not supported for this module")
+	// Cancel pending work and clean up when interrupted
+	module.ctx, module.cancel = context.WithCancel(context.Background())
+	if HandleSignals && turbo == nil {
+		module.handleSignals()
+	}
+	// The environment of a turbo server is not that of the invocation, so only
+	// the module option arms the watchdog there
+	if timeout := module.watchdogTimeout(turbo == nil); timeout > 0 {
+		module.StartWatchdog(timeout)
 	}
 
 	return module, nil
@@ -154,23 +251,31 @@ func (m *AnsibleModule) parseInput() error {
 
 	// Check if running from ANSIBLE_MODULE_ARGS environment
 	if moduleArgs := os.Getenv("ANSIBLE_MODULE_ARGS"); moduleArgs != "" {
+		if err := checkInputLimits([]byte(moduleArgs)); err != nil {
+			return err
+		}
 		if err := json.Unmarshal([]byte(moduleArgs), &inputData); err != nil {
-			return fmt.Errorf("failed to parse ANSIBLE_MODULE_ARGS: %v", err)
+			return fmt.Errorf("failed to parse ANSIBLE_MODULE_ARGS: %w", err)
 		}
 	} else {
 		// Read from stdin
 		stdin := bufio.NewReader(os.Stdin)
-		inputBytes, err := io.ReadAll(stdin)
-		if err != nil {
-			return fmt.Errorf("failed to read from stdin: %v", err)
+		inputBytes, err := readLimitedInput(stdin)
+		if _, ok := err.(*inputLimitError); ok {
+			return err
+		} else if err != nil {
+			return fmt.Errorf("failed to read from stdin: %w", err)
 		}
 
 		if len(inputBytes) == 0 {
 			return fmt.Errorf("empty input, expecting JSON data")
 		}
 
+		if err := checkInputLimits(inputBytes); err != nil {
+			return err
+		}
 		if err := json.Unmarshal(inputBytes, &inputData); err != nil {
-			return fmt.Errorf("failed to parse input JSON: %v", err)
+			return fmt.Errorf("failed to parse input JSON: %w", err)
 		}
 	}
 
@@ -188,6 +293,30 @@ func (m *AnsibleModule) parseInput() error {
 		}
 	}
 
+	// Check for verbosity
+	if verbosity, ok := inputData["_ansible_verbosity"]; ok {
+		if verbosityNum, ok := verbosity.(float64); ok {
+			m.Verbosity = int(verbosityNum)
+		}
+	}
+
+	// Check if logging on the target is disabled
+	if noSyslog, ok := inputData["_ansible_no_target_syslog"]; ok {
+		if noSyslogBool, ok := noSyslog.(bool); ok {
+			m.NoTargetSyslog = noSyslogBool
+		}
+	}
+
+	// Check how the controller wants string conversions reported
+	if action, ok := inputData["_ansible_string_conversion_action"].(string); ok {
+		m.StringConversionAction = action
+	}
+
+	// Use the controller temp directory, which honours remote_tmp on every platform
+	if tmpdir, ok := inputData["_ansible_tmpdir"].(string); ok {
+		m.remoteTmp = tmpdir
+	}
+
 	// Apply parameters
 	for key, value := range inputData {
 		// Skip internal Ansible params (starting with _ansible_)
@@ -196,16 +325,7 @@ func (m *AnsibleModule) parseInput() error {
 		}
 	}
 
-	// Apply default values for missing parameters
-	for argName, spec := range m.ArgSpec {
-		if _, exists := m.Params[argName]; !exists {
-			if spec.Default != nil {
-				m.Params[argName] = spec.Default
-			}
-		}
-	}
-
-	// Process aliases
+	// Process aliases before defaults, so a value given by alias is not replaced by the default
 	for alias, realName := range m.Aliases {
 		if value, exists := m.Params[alias]; exists {
 			if _, mainExists := m.Params[realName]; !mainExists {
@@ -215,6 +335,20 @@ func (m *AnsibleModule) parseInput() error {
 			delete(m.Params, alias)
 		}
 	}
+	for argName, spec := range m.ArgSpec {
+		if value, exists := m.Params[argName]; exists {
+			resolveNestedAliases(spec, value)
+		}
+	}
+
+	// Apply default values for missing parameters
+	for argName, spec := range m.ArgSpec {
+		if _, exists := m.Params[argName]; !exists {
+			if spec.Default != nil {
+				m.Params[argName] = spec.Default
+			}
+		}
+	}
 
 	return nil
 }
@@ -225,15 +359,17 @@ func (m *AnsibleModule) validateArguments() error {
 	for argName, spec := range m.ArgSpec {
 		if spec.Required {
 			if _, exists := m.Params[argName]; !exists {
-				return fmt.Errorf("missing required argument: %s", argName)
+				return invalidParam(argName, CodeMissingRequired, argName)
 			}
 		}
 
 		// Validate argument that was provided
 		if value, exists := m.Params[argName]; exists {
-			if err := m.validateArgument(argName, value, spec); err != nil {
-				return err
+			converted, err := m.validateArgument(argName, value, spec)
+			if err != nil {
+				return validationError(argName, err)
 			}
+			m.Params[argName] = converted
 		}
 	}
 
@@ -246,7 +382,7 @@ func (m *AnsibleModule) validateArguments() error {
 			}
 		}
 		if count > 1 {
-			return fmt.Errorf("parameters are mutually exclusive: %s", strings.Join(group, ", "))
+			return invalidParam("", CodeMutuallyExclusive, strings.Join(group, ", "))
 		}
 	}
 
@@ -265,7 +401,7 @@ func (m *AnsibleModule) validateArguments() error {
 		}
 
 		if foundOne && !foundAll {
-			return fmt.Errorf("parameters must be specified together: %s", strings.Join(group, ", "))
+			return invalidParam("", CodeRequiredTogether, strings.Join(group, ", "))
 		}
 	}
 
@@ -279,7 +415,7 @@ func (m *AnsibleModule) validateArguments() error {
 			}
 		}
 		if !found {
-			return fmt.Errorf("one of the following is required: %s", strings.Join(group, ", "))
+			return invalidParam("", CodeRequiredOneOf, strings.Join(group, ", "))
 		}
 	}
 
@@ -289,7 +425,7 @@ func (m *AnsibleModule) validateArguments() error {
 			if reflect.DeepEqual(value, condition.Value) {
 				for _, requiredArg := range condition.Requirements {
 					if _, exists := m.Params[requiredArg]; !exists {
-						return fmt.Errorf("%s is required when %s=%v", requiredArg, condition.Key, condition.Value)
+						return invalidParam(requiredArg, CodeRequiredIf, requiredArg, condition.Key, condition.Value)
 					}
 				}
 			}
@@ -299,128 +435,119 @@ func (m *AnsibleModule) validateArguments() error {
 	return nil
 }
 
-// validateArgument validates a single argument against its spec
-func (m *AnsibleModule) validateArgument(name string, value interface{}, spec ArgumentSpec) error {
+// validateArgument validates a single argument against its spec and returns
+// the value converted to the argument type. Nested options and list elements
+// are converted in a copy of their container, so the caller stores the result.
+func (m *AnsibleModule) validateArgument(name string, value interface{}, spec ArgumentSpec) (interface{}, error) {
 	// Type validation
 	if spec.Type != "" {
 		switch spec.Type {
 		case "str", "string":
 			if _, ok := value.(string); !ok {
-				return fmt.Errorf("%s must be a string", name)
+				return nil, invalidParam(name, CodeInvalidType, name, "a string")
 			}
 		case "bool", "boolean":
 			// Convert string representations to bool if needed
 			if strVal, ok := value.(string); ok {
 				boolVal, err := m.parseBoolean(strVal)
 				if err != nil {
-					return fmt.Errorf("%s must be a boolean: %v", name, err)
+					return nil, invalidParam(name, CodeInvalidType, name, "a boolean").because(err)
 				}
-				// Update the value in the params map
-				if m.Params == nil {
-					m.Params = make(ModuleParams)
+				if err := m.noteConversion(name, strVal, spec, "a boolean"); err != nil {
+					return nil, err
 				}
-				m.Params[name] = boolVal
+				value = boolVal
 			} else if _, ok := value.(bool); !ok {
-				return fmt.Errorf("%s must be a boolean", name)
+				return nil, invalidParam(name, CodeInvalidType, name, "a boolean")
 			}
 		case "int", "integer":
 			// Convert string representations to int if needed
 			if strVal, ok := value.(string); ok {
 				intVal, err := strconv.Atoi(strVal)
 				if err != nil {
-					return fmt.Errorf("%s must be an integer: %v", name, err)
+					return nil, invalidParam(name, CodeInvalidType, name, "an integer").because(err)
 				}
-				// Update the value in the params map
-				if m.Params == nil {
-					m.Params = make(ModuleParams)
+				if err := m.noteConversion(name, strVal, spec, "an integer"); err != nil {
+					return nil, err
 				}
-				m.Params[name] = intVal
+				value = intVal
 			} else if _, ok := value.(int); !ok {
 				// Try to convert from float if it's a whole number
-				if floatVal, ok := value.(float64); ok {
-					if floatVal == float64(int(floatVal)) {
-						if m.Params == nil {
-							m.Params = make(ModuleParams)
-						}
-						m.Params[name] = int(floatVal)
-					} else {
-						return fmt.Errorf("%s must be an integer", name)
-					}
-				} else {
-					return fmt.Errorf("%s must be an integer", name)
+				floatVal, ok := value.(float64)
+				if !ok || floatVal != float64(int(floatVal)) {
+					return nil, invalidParam(name, CodeInvalidType, name, "an integer")
 				}
+				value = int(floatVal)
 			}
 		case "float":
 			// Convert string representations to float if needed
 			if strVal, ok := value.(string); ok {
 				floatVal, err := strconv.ParseFloat(strVal, 64)
 				if err != nil {
-					return fmt.Errorf("%s must be a float: %v", name, err)
+					return nil, invalidParam(name, CodeInvalidType, name, "a float").because(err)
 				}
-				// Update the value in the params map
-				if m.Params == nil {
-					m.Params = make(ModuleParams)
+				if err := m.noteConversion(name, strVal, spec, "a float"); err != nil {
+					return nil, err
 				}
-				m.Params[name] = floatVal
+				value = floatVal
 			} else if _, ok := value.(float64); !ok {
 				// Try to convert from int
-				if intVal, ok := value.(int); ok {
-					if m.Params == nil {
-						m.Params = make(ModuleParams)
-					}
-					m.Params[name] = float64(intVal)
-				} else {
-					return fmt.Errorf("%s must be a float", name)
+				intVal, ok := value.(int)
+				if !ok {
+					return nil, invalidParam(name, CodeInvalidType, name, "a float")
 				}
+				value = float64(intVal)
 			}
 		case "list", "array":
 			// Verify it's a list/array or can be converted to one
 			if _, ok := value.([]interface{}); !ok {
-				// Try to convert from string array
 				if strArr, ok := value.([]string); ok {
 					// Convert []string to []interface{}
 					interfaceArr := make([]interface{}, len(strArr))
 					for i, v := range strArr {
 						interfaceArr[i] = v
 					}
-					if m.Params == nil {
-						m.Params = make(ModuleParams)
-					}
-					m.Params[name] = interfaceArr
+					value = interfaceArr
 				} else if strVal, ok := value.(string); ok {
 					// Try to convert from comma-separated string
-					if strVal == "" {
-						if m.Params == nil {
-							m.Params = make(ModuleParams)
-						}
-						m.Params[name] = []interface{}{}
-					} else {
-						items := strings.Split(strVal, ",")
-						itemsInterface := make([]interface{}, len(items))
-						for i, item := range items {
-							itemsInterface[i] = strings.TrimSpace(item)
-						}
-						if m.Params == nil {
-							m.Params = make(ModuleParams)
+					if err := m.noteConversion(name, strVal, spec, "a list"); err != nil {
+						return nil, err
+					}
+					itemsInterface := []interface{}{}
+					if strVal != "" {
+						for _, item := range strings.Split(strVal, ",") {
+							itemsInterface = append(itemsInterface, strings.TrimSpace(item))
 						}
-						m.Params[name] = itemsInterface
 					}
+					value = itemsInterface
 				} else {
-					return fmt.Errorf("%s must be a list", name)
+					return nil, invalidParam(name, CodeInvalidType, name, "a list")
 				}
 			}
 		case "dict", "map":
 			if _, ok := value.(map[string]interface{}); !ok {
-				return fmt.Errorf("%s must be a dictionary/map", name)
+				return nil, invalidParam(name, CodeInvalidType, name, "a dictionary/map")
 			}
 		case "path":
 			if _, ok := value.(string); !ok {
-				return fmt.Errorf("%s must be a path string", name)
+				return nil, invalidParam(name, CodeInvalidType, name, "a path string")
 			}
+		case "datetime":
+			t, err := normalizeDateTimeValue(value)
+			if err != nil {
+				return nil, invalidParam(name, CodeInvalidValue, name, err)
+			}
+			value = t
+		case "ipaddr", "cidr", "macaddr", "port":
+			normalized, err := normalizeNetworkValue(spec.Type, value)
+			if err != nil {
+				return nil, invalidParam(name, CodeInvalidValue, name, err)
+			}
+			value = normalized
 		}
 	}
 
-	// Choices validation
+	// Choices validation, against the converted value
 	if len(spec.Choices) > 0 {
 		validChoice := false
 		strValue := fmt.Sprintf("%v", value)
@@ -431,38 +558,50 @@ func (m *AnsibleModule) validateArgument(name string, value interface{}, spec Ar
 			}
 		}
 		if !validChoice {
-			return fmt.Errorf("%s must be one of: %s", name, strings.Join(spec.Choices, ", "))
+			return nil, invalidParam(name, CodeInvalidChoice, name, strings.Join(spec.Choices, ", "))
 		}
 	}
 
 	// If this is a nested data structure with options, validate each element
 	if spec.Type == "dict" && len(spec.Options) > 0 {
 		if dictVal, ok := value.(map[string]interface{}); ok {
+			converted := make(map[string]interface{}, len(dictVal))
+			for k, v := range dictVal {
+				converted[k] = v
+			}
 			for subArgName, subArgSpec := range spec.Options {
 				if subValue, exists := dictVal[subArgName]; exists {
-					if err := m.validateArgument(name+"."+subArgName, subValue, subArgSpec); err != nil {
-						return err
+					subConverted, err := m.validateArgument(name+"."+subArgName, subValue, subArgSpec)
+					if err != nil {
+						return nil, err
 					}
+					converted[subArgName] = subConverted
 				} else if subArgSpec.Required {
-					return fmt.Errorf("%s.%s is required", name, subArgName)
+					return nil, invalidParam(name+"."+subArgName, CodeMissingSuboption, name+"."+subArgName)
 				}
 			}
+			value = converted
 		}
 	}
 
-	// If this is a list with element type, validate each element
+	// If this is a list with element type, validate each element, dict elements
+	// against the suboptions
 	if spec.Type == "list" && spec.Elements != "" {
 		if listVal, ok := value.([]interface{}); ok {
-			elementSpec := ArgumentSpec{Type: spec.Elements}
+			elementSpec := ArgumentSpec{Type: spec.Elements, Options: spec.SubOptions}
+			converted := make([]interface{}, len(listVal))
 			for i, element := range listVal {
-				if err := m.validateArgument(fmt.Sprintf("%s[%d]", name, i), element, elementSpec); err != nil {
-					return err
+				elementConverted, err := m.validateArgument(fmt.Sprintf("%s[%d]", name, i), element, elementSpec)
+				if err != nil {
+					return nil, err
 				}
+				converted[i] = elementConverted
 			}
+			value = converted
 		}
 	}
 
-	return nil
+	return value, nil
 }
 
 // parseBoolean converts various string representations to boolean
@@ -481,20 +620,65 @@ func (m *AnsibleModule) parseBoolean(value string) (bool, error) {
 
 // ExitJson formats and outputs successful JSON result
 func (m *AnsibleModule) ExitJson(result map[string]interface{}) {
-	// Add invocation data
+	// Signal handling and the watchdog may exit concurrently with the module,
+	// and only the first result is written
+	m.exitMu.Lock()
+	defer m.exitMu.Unlock()
+	if m.exited {
+		return
+	}
+
+	// Status flags must be real booleans, a loosely built result fails instead.
+	// The caller's map is copied, not modified.
+	result, err := normalizeResultBooleans(result)
+	if err != nil {
+		result = invalidResultFailure(err.Error())
+	}
+
+	// Reserved keys are dropped with a warning, or fail the module in strict mode
+	if findings := reservedResultKeys(result); len(findings) > 0 {
+		if m.StrictResults {
+			result = invalidResultFailure(strings.Join(findings, "; "))
+		} else {
+			for _, finding := range findings {
+				m.AddWarning(finding)
+			}
+		}
+	}
+
+	// Add invocation data, hiding no_log options at any depth
 	invocation := make(map[string]interface{})
 	for k, v := range m.Params {
 		if m.shouldLog(k) {
-			invocation[k] = v
+			invocation[k] = censorNoLog(m.ArgSpec[k], v)
 		} else {
-			invocation[k] = "VALUE_SPECIFIED_IN_NO_LOG_PARAMETER"
+			invocation[k] = noLogPlaceholder
 		}
 	}
 	result["invocation"] = invocation
 
+	// Add phase timings at higher verbosity
+	if m.Verbosity >= 2 {
+		if timings := m.Timings(); len(timings) > 0 {
+			result["timings"] = timings
+		}
+	}
+
+	// Add retry summaries if any, and recorded commands at higher verbosity
+	m.partialMu.Lock()
+	if len(m.retries) > 0 {
+		result["retries"] = m.retries
+	}
+	if len(m.commands) > 0 && m.Verbosity >= commandRecordVerbosity {
+		result["commands"] = m.commands
+	}
+	if len(m.debugInfo) > 0 {
+		result["debug_info"] = m.debugInfo
+	}
+
 	// Add warnings if any
 	if len(m.Warnings) > 0 {
-		result["warnings"] = m.Warnings
+		result["warnings"] = slices.Clone(m.Warnings)
 	}
 
 	// Add deprecation messages if any
@@ -505,10 +689,26 @@ func (m *AnsibleModule) ExitJson(result map[string]interface{}) {
 		}
 		result["deprecations"] = deprecations
 	}
+	m.partialMu.Unlock()
 
-	// Output JSON and exit
-	output, err := json.Marshal(result)
-	if err != nil {
+	// Add the audit trail if requested
+	if m.Audit {
+		result["audit"] = m.auditResult()
+	}
+
+	// Remove no_log values wherever they appear in the result
+	result = m.scrubResult(result)
+
+	// Leave the process umask as the module found it
+	m.restoreUmask()
+
+	// Encode the result straight to the output and exit
+	encoder := json.NewEncoder(m.output())
+	if m.IndentResult {
+		encoder.SetIndent("", "  ")
+	}
+	m.exited = true
+	if err := encoder.Encode(result); err != nil {
 		// If JSON marshaling fails, fall back to a simple message
 		fmt.Fprintf(os.Stderr, "Failed to serialize JSON result: %v\n", err)
 		if m.TestMode {
@@ -521,7 +721,8 @@ func (m *AnsibleModule) ExitJson(result map[string]interface{}) {
 		}
 	}
 
-	fmt.Println(string(output))
+	m.removeWorkspaces()
+	m.removeOwnedTmpDir()
 	if m.TestMode {
 		panic("ExitJson called in test mode")
 	}
@@ -532,20 +733,23 @@ func (m *AnsibleModule) ExitJson(result map[string]interface{}) {
 	}
 }
 
+// output returns the writer the result is encoded to
+func (m *AnsibleModule) output() io.Writer {
+	if m.Output == nil {
+		return os.Stdout
+	}
+	return m.Output
+}
+
 // FailJson formats and outputs failure JSON result
 func (m *AnsibleModule) FailJson(msg string, args map[string]interface{}) {
-	result := make(map[string]interface{})
-	result["failed"] = true
-	result["msg"] = msg
-
-	// Add additional args if provided
-	maps.Copy(result, args)
-
-	m.ExitJson(result)
+	m.ExitJson(failureResult(msg, args))
 }
 
 // AddWarning adds a warning message
 func (m *AnsibleModule) AddWarning(warning string) {
+	m.partialMu.Lock()
+	defer m.partialMu.Unlock()
 	m.Warnings = append(m.Warnings, warning)
 }
 
@@ -554,6 +758,8 @@ func (m *AnsibleModule) AddDeprecation(msg string, version string) {
 	if version != "" {
 		msg = fmt.Sprintf("%s (version: %s)", msg, version)
 	}
+	m.partialMu.Lock()
+	defer m.partialMu.Unlock()
 	m.DeprecationMsgs = append(m.DeprecationMsgs, msg)
 }
 
@@ -569,70 +775,86 @@ func (m *AnsibleModule) shouldLog(param string) bool {
 
 // RunCommand executes a command and returns the result
 func (m *AnsibleModule) RunCommand(cmd string, args []string, environ map[string]string, data string) (CommandResult, error) {
-	result := CommandResult{
-		Cmd: cmd,
-	}
+	return m.RunCommandWithOptions(cmd, args, CommandOptions{Environment: environ, Data: data})
+}
 
-	// Create command
-	command := exec.Command(cmd, args...)
+// RunCommandWithOptions executes a command with the given options and returns the result
+func (m *AnsibleModule) RunCommandWithOptions(cmd string, args []string, opts CommandOptions) (CommandResult, error) {
+	environ, data := opts.Environment, opts.Data
+	reason, changed, err := m.commandGuard(opts)
+	if err != nil {
+		return CommandResult{Cmd: cmd, Args: args}, err
+	}
+	if reason != "" {
+		m.writeDebugLog("TRACE", fmt.Sprintf("skipping command %s: %s", cmd, reason))
+		return CommandResult{Cmd: cmd, Args: args, Skipped: true, Changed: changed, Msg: reason}, nil
+	}
+	m.writeDebugLog("TRACE", fmt.Sprintf("running command: %s", strings.Join(append([]string{cmd}, args...), " ")))
 
-	// Set up environment
-	if environ != nil {
-		env := os.Environ()
+	// Set up environment, letting explicit variables override the locale
+	var env []string
+	if environ != nil || m.Locale != "" {
+		env = os.Environ()
+		if m.Locale != "" {
+			env = append(env, localeEnv(m.Locale)...)
+		}
 		for k, v := range environ {
 			env = append(env, fmt.Sprintf("%s=%s", k, v))
 		}
-		command.Env = env
 	}
 
-	// Set up pipes
-	var stdout, stderr bytes.Buffer
-	command.Stdout = &stdout
-	command.Stderr = &stderr
-
-	// Provide input if specified
-	if data != "" {
-		stdin, err := command.StdinPipe()
-		if err != nil {
-			return result, fmt.Errorf("failed to create stdin pipe: %v", err)
+	// Run command, killed if the module context is cancelled or the timeout expires
+	ctx := m.Context()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	limit := opts.OutputLimit
+	if limit == 0 {
+		limit = m.OutputLimit
+	}
+	if limit > 0 {
+		ctx = WithOutputLimit(ctx, limit)
+	}
+	start := time.Now()
+	result, err := m.commandRunner().Run(ctx, cmd, args, env, data)
+	result.Cmd, result.Args, result.Changed = cmd, args, true
+	result.Start, result.End = start, time.Now()
+	result.Delta = result.End.Sub(start)
+	result.limitOutput(limit)
+	m.auditCommand(cmd, args, result.Rc)
+	if m.RecordCommands || opts.Record {
+		m.recordCommand(result)
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil && (err != nil || result.Rc != 0) {
+		result.Rc = -1
+		if m.Context().Err() == nil {
+			m.writeDebugLog("TRACE", fmt.Sprintf("command %s timed out after %s", cmd, opts.Timeout))
+			return result, commandError(result, nil, CodeCommandTimeout, cmd, opts.Timeout)
 		}
-		go func() {
-			defer stdin.Close()
-			io.WriteString(stdin, data)
-		}()
+		m.writeDebugLog("TRACE", fmt.Sprintf("command %s cancelled: %v", cmd, ctxErr))
+		return result, commandError(result, ctxErr, CodeCommandCancelled, cmd)
+	}
+	if err == nil && result.Rc != 0 {
+		err = fmt.Errorf("exit status %d", result.Rc)
 	}
-
-	// Run command
-	err := command.Run()
-
-	// Capture output
-	result.Stdout = stdout.String()
-	result.Stderr = stderr.String()
-
-	// Get exit code
 	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
-				result.Rc = status.ExitStatus()
-			} else {
-				result.Rc = 1
-			}
-		} else {
-			result.Rc = 1
-		}
-		return result, fmt.Errorf("command failed: %v", err)
+		m.writeDebugLog("TRACE", fmt.Sprintf("command %s failed with rc=%d: %v", cmd, result.Rc, err))
+		return result, commandError(result, err, CodeCommandFailed)
 	}
 
-	result.Rc = 0
+	m.writeDebugLog("TRACE", fmt.Sprintf("command %s finished with rc=0", cmd))
 	return result, nil
 }
 
 // GetBinPath locates an executable in the system path
 func (m *AnsibleModule) GetBinPath(name string, required bool) (string, error) {
-	path, err := exec.LookPath(name)
+	path, err := m.commandRunner().LookPath(name)
 	if err != nil {
 		if required {
-			return "", fmt.Errorf("failed to find required executable %s: %v", name, err)
+			return "", notFound(fmt.Errorf("failed to find required executable %s: %w", name, err))
 		}
 		return "", nil
 	}
@@ -641,14 +863,14 @@ func (m *AnsibleModule) GetBinPath(name string, required bool) (string, error) {
 
 // MD5 calculates the MD5 hash of a file
 func (m *AnsibleModule) MD5(path string) (string, error) {
-	file, err := os.Open(path)
+	file, err := m.fs().Open(path)
 	if err != nil {
 		return "", err
 	}
 	defer file.Close()
 
 	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	if _, err := copyChunked(hash, m.contextReader(file)); err != nil {
 		return "", err
 	}
 
@@ -658,70 +880,79 @@ func (m *AnsibleModule) MD5(path string) (string, error) {
 
 // AtomicMove performs an atomic file operation
 func (m *AnsibleModule) AtomicMove(src, dest string) (bool, error) {
+	return m.AtomicMoveWith(src, dest, m.ComparisonHash)
+}
+
+// AtomicMoveWith performs an atomic file operation, comparing the files with the
+// named hash algorithm, or byte by byte if algorithm is empty
+func (m *AnsibleModule) AtomicMoveWith(src, dest, algorithm string) (bool, error) {
+	defer m.auditFile("move_file", dest)()
+	return m.atomicMove(src, dest, true, algorithm)
+}
+
+// atomicMove moves src over dest without recording the move, for helpers that
+// audit the operation themselves. Callers that have already found the content
+// differs pass compare=false, so the files are not read a second time.
+func (m *AnsibleModule) atomicMove(src, dest string, compare bool, algorithm string) (bool, error) {
+	// Never replace the destination once the module has been cancelled
+	if err := m.Context().Err(); err != nil {
+		return false, err
+	}
+
 	// Check if destination exists and get stats
 	destExists := false
-	destStat, err := os.Stat(dest)
+	destStat, err := m.fs().Stat(dest)
 	if err == nil {
 		destExists = true
 	} else if !os.IsNotExist(err) {
-		return false, fmt.Errorf("failed to stat destination %s: %v", dest, err)
+		return false, fmt.Errorf("failed to stat destination %s: %w", dest, err)
 	}
 
 	// Get source stats
-	srcStat, err := os.Stat(src)
+	srcStat, err := m.fs().Stat(src)
 	if err != nil {
-		return false, fmt.Errorf("failed to stat source %s: %v", src, err)
+		return false, fmt.Errorf("failed to stat source %s: %w", src, err)
 	}
 
 	// Check if files are the same
-	if destExists {
-		// Compare sizes
-		if destStat.Size() == srcStat.Size() {
-			// Compare content with MD5
-			srcMD5, err := m.MD5(src)
-			if err != nil {
-				return false, err
-			}
-
-			destMD5, err := m.MD5(dest)
-			if err != nil {
-				return false, err
-			}
-
-			if srcMD5 == destMD5 {
-				// Files are identical, no need to move
-				return false, nil
-			}
+	if compare && destExists && destStat.Size() == srcStat.Size() {
+		same, err := m.sameFileContent(src, dest, algorithm)
+		if err != nil {
+			return false, err
+		}
+		if same {
+			// Files are identical, no need to move
+			return false, nil
 		}
 	}
 
 	// Perform atomic move
-	if err := os.Rename(src, dest); err != nil {
+	if err := m.fs().Rename(src, dest); err != nil {
 		// Try copy + remove if rename fails (e.g., across devices)
-		srcFile, err := os.Open(src)
+		srcFile, err := m.fs().Open(src)
 		if err != nil {
 			return false, err
 		}
 		defer srcFile.Close()
 
-		destFile, err := os.Create(dest)
+		destFile, err := m.fs().OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 		if err != nil {
 			return false, err
 		}
 		defer destFile.Close()
 
-		if _, err := io.Copy(destFile, srcFile); err != nil {
-			os.Remove(dest) // Clean up partial file
+		if _, err := copyChunked(destFile, m.contextReader(srcFile)); err != nil {
+			m.fs().Remove(dest) // Clean up partial file
 			return false, err
 		}
 
 		// Set permissions to match source
-		if err := os.Chmod(dest, srcStat.Mode()); err != nil {
+		if err := m.fs().Chmod(dest, srcStat.Mode()); err != nil {
 			return false, err
 		}
 
 		// Remove source
-		if err := os.Remove(src); err != nil {
+		if err := m.fs().Remove(src); err != nil {
 			return false, err
 		}
 	}
@@ -729,24 +960,74 @@ func (m *AnsibleModule) AtomicMove(src, dest string) (bool, error) {
 	return true, nil
 }
 
-// TmpFile creates a temporary file
-func (m *AnsibleModule) TmpFile(prefix string) (*os.File, error) {
-	// Ensure tmp dir exists
-	if m.TmpDir == "" {
-		var err error
-		m.TmpDir, err = os.MkdirTemp("", "ansible-go-")
+// GetTmpDir returns the module temporary directory, creating it if needed.
+// TmpDir is empty until the directory is first used.
+func (m *AnsibleModule) GetTmpDir() (string, error) {
+	return m.tmpDir()
+}
+
+// tmpDir returns the module temporary directory, creating it on first use so
+// modules that never touch disk leave nothing behind
+func (m *AnsibleModule) tmpDir() (string, error) {
+	m.tmpMu.Lock()
+	defer m.tmpMu.Unlock()
+	if m.TmpDir == "" || m.TmpDir == m.removedTmpDir {
+		dir, err := m.newTmpDir()
 		if err != nil {
-			return nil, fmt.Errorf("failed to create temp dir: %v", err)
+			return "", fmt.Errorf("failed to create temp dir: %w", err)
 		}
+		m.TmpDir = dir
+		m.ownedTmpDir = dir
 	}
+	return m.TmpDir, nil
+}
 
-	return os.CreateTemp(m.TmpDir, prefix)
+// newTmpDir creates a module temp directory in the module file system, below
+// the controller temp directory if it exists, or the system temp directory
+// (TMPDIR, or TMP/TEMP on Windows)
+func (m *AnsibleModule) newTmpDir() (string, error) {
+	base := ""
+	if m.remoteTmp != "" {
+		if info, err := m.fs().Stat(m.remoteTmp); err == nil && info.IsDir() {
+			base = m.remoteTmp
+		}
+	}
+	return m.fs().MkdirTemp(base, "ansible-go-")
+}
+
+// TmpFile creates a temporary file on disk, such as one handed to a command.
+// With a custom FileSystem, which commands cannot see, it is created in the
+// system temp directory and the caller removes it.
+func (m *AnsibleModule) TmpFile(prefix string) (*os.File, error) {
+	if m.FileSystem != nil {
+		return os.CreateTemp("", prefix)
+	}
+	dir, err := m.tmpDir()
+	if err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(dir, prefix)
 }
 
 // Cleanup removes temporary files
 func (m *AnsibleModule) Cleanup() {
+	m.removeWorkspaces()
+	m.tmpMu.Lock()
+	defer m.tmpMu.Unlock()
 	if m.TmpDir != "" {
-		os.RemoveAll(m.TmpDir)
+		m.removeTmpTree(m.TmpDir)
+		m.removedTmpDir = m.TmpDir
+	}
+}
+
+// removeOwnedTmpDir removes the temp directory on exit if the module created it,
+// leaving directories supplied through TmpDir alone
+func (m *AnsibleModule) removeOwnedTmpDir() {
+	m.tmpMu.Lock()
+	defer m.tmpMu.Unlock()
+	if m.ownedTmpDir != "" && m.ownedTmpDir != m.removedTmpDir {
+		m.removeTmpTree(m.ownedTmpDir)
+		m.removedTmpDir = m.ownedTmpDir
 	}
 }
 
@@ -755,11 +1036,59 @@ func (m *AnsibleModule) GetParam(name string) interface{} {
 	return m.Params[name]
 }
 
+// CloneParams returns a copy of Params that handlers can change during
+// reconciliation without altering the invocation reported to the controller.
+// A shallow copy shares nested lists and dictionaries with Params; a deep copy
+// duplicates them too.
+func (m *AnsibleModule) CloneParams(deep bool) ModuleParams {
+	clone := make(ModuleParams, len(m.Params))
+	for name, value := range m.Params {
+		if deep {
+			value = deepCopyValue(value)
+		}
+		clone[name] = value
+	}
+	return clone
+}
+
+// deepCopyValue copies the lists and dictionaries of a decoded JSON value
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopyValue(item)
+		}
+		return copied
+	case ModuleParams:
+		copied := make(ModuleParams, len(v))
+		for key, item := range v {
+			copied[key] = deepCopyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyValue(item)
+		}
+		return copied
+	case []string:
+		return append([]string(nil), v...)
+	case map[string]string:
+		copied := make(map[string]string, len(v))
+		for key, item := range v {
+			copied[key] = item
+		}
+		return copied
+	}
+	return value
+}
+
 // GetParamBool retrieves a boolean parameter
 func (m *AnsibleModule) GetParamBool(name string) (bool, error) {
 	value, exists := m.Params[name]
 	if !exists {
-		return false, fmt.Errorf("parameter %s not found", name)
+		return false, notFound(fmt.Errorf("parameter %s not found", name))
 	}
 
 	switch v := value.(type) {
@@ -776,7 +1105,7 @@ func (m *AnsibleModule) GetParamBool(name string) (bool, error) {
 func (m *AnsibleModule) GetParamInt(name string) (int, error) {
 	value, exists := m.Params[name]
 	if !exists {
-		return 0, fmt.Errorf("parameter %s not found", name)
+		return 0, notFound(fmt.Errorf("parameter %s not found", name))
 	}
 
 	switch v := value.(type) {
@@ -795,7 +1124,7 @@ func (m *AnsibleModule) GetParamInt(name string) (int, error) {
 func (m *AnsibleModule) GetParamString(name string) (string, error) {
 	value, exists := m.Params[name]
 	if !exists {
-		return "", fmt.Errorf("parameter %s not found", name)
+		return "", notFound(fmt.Errorf("parameter %s not found", name))
 	}
 
 	return fmt.Sprintf("%v", value), nil
@@ -805,7 +1134,7 @@ func (m *AnsibleModule) GetParamString(name string) (string, error) {
 func (m *AnsibleModule) GetParamStringList(name string) ([]string, error) {
 	value, exists := m.Params[name]
 	if !exists {
-		return nil, fmt.Errorf("parameter %s not found", name)
+		return nil, notFound(fmt.Errorf("parameter %s not found", name))
 	}
 
 	switch v := value.(type) {
@@ -827,6 +1156,20 @@ func (m *AnsibleModule) GetParamStringList(name string) ([]string, error) {
 	}
 }
 
+// GetParamTime retrieves a datetime parameter
+func (m *AnsibleModule) GetParamTime(name string) (time.Time, error) {
+	value, exists := m.Params[name]
+	if !exists {
+		return time.Time{}, notFound(fmt.Errorf("parameter %s not found", name))
+	}
+
+	t, err := normalizeDateTimeValue(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parameter %s %w", name, err)
+	}
+	return t, nil
+}
+
 // CreateDiff creates a diff structure for reporting changes
 func (m *AnsibleModule) CreateDiff(before, after string, beforeHeader, afterHeader string) map[string]interface{} {
 	diff := make(map[string]interface{})
@@ -838,23 +1181,43 @@ func (m *AnsibleModule) CreateDiff(before, after string, beforeHeader, afterHead
 		afterHeader = "after"
 	}
 
-	diff["before"] = before
-	diff["after"] = after
+	// Mask no_log values, such as a password line in a config file
+	diff["before"] = m.scrubString(before)
+	diff["after"] = m.scrubString(after)
 	diff["before_header"] = beforeHeader
 	diff["after_header"] = afterHeader
 
 	return diff
 }
 
+// CreatePreparedDiff creates a diff from text that is already formatted, such as
+// unified diff output, which Ansible displays as it is
+func (m *AnsibleModule) CreatePreparedDiff(prepared string) map[string]interface{} {
+	return map[string]interface{}{"prepared": m.scrubString(prepared)}
+}
+
 // FileExists checks if a file exists
 func (m *AnsibleModule) FileExists(path string) bool {
-	_, err := os.Stat(path)
+	_, err := m.fs().Stat(path)
 	return err == nil
 }
 
+// checkSource returns nil if the source file src exists, or an error matching
+// ErrNotFound or the error of stat, such as ErrPermission, otherwise
+func (m *AnsibleModule) checkSource(src string) error {
+	_, err := m.fs().Stat(src)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrNotFound):
+		return notFound(fmt.Errorf("source file %s does not exist", src))
+	}
+	return fmt.Errorf("failed to access source file %s: %w", src, err)
+}
+
 // IsDir checks if a path is a directory
 func (m *AnsibleModule) IsDir(path string) bool {
-	info, err := os.Stat(path)
+	info, err := m.fs().Stat(path)
 	if err != nil {
 		return false
 	}
@@ -863,34 +1226,40 @@ func (m *AnsibleModule) IsDir(path string) bool {
 
 // IsFile checks if a path is a regular file
 func (m *AnsibleModule) IsFile(path string) bool {
-	info, err := os.Stat(path)
+	info, err := m.fs().Stat(path)
 	if err != nil {
 		return false
 	}
 	return !info.IsDir()
 }
 
-// IsSymlink checks if a path is a symbolic link
+// IsSymlink checks if a path is a symbolic link, or a junction on Windows
 func (m *AnsibleModule) IsSymlink(path string) bool {
-	info, err := os.Lstat(path)
+	info, err := m.fs().Lstat(path)
 	if err != nil {
 		return false
 	}
-	return info.Mode()&os.ModeSymlink != 0
+	return linkType(path, info) != ""
+}
+
+// CanCreateSymlinks reports whether the module may create symbolic links. On
+// Windows this needs Developer Mode or SeCreateSymbolicLinkPrivilege.
+func (m *AnsibleModule) CanCreateSymlinks() bool {
+	return canCreateSymlinks()
 }
 
 // IsExecutable checks if a file is executable
 func (m *AnsibleModule) IsExecutable(path string) bool {
-	info, err := os.Stat(path)
+	info, err := m.fs().Stat(path)
 	if err != nil {
 		return false
 	}
-	return (info.Mode() & 0111) != 0
+	return isExecutableFile(path, info)
 }
 
 // FileStat gets detailed file information
 func (m *AnsibleModule) FileStat(path string) (map[string]interface{}, error) {
-	info, err := os.Lstat(path)
+	info, err := m.fs().Lstat(path)
 	if err != nil {
 		return nil, err
 	}
@@ -902,11 +1271,15 @@ func (m *AnsibleModule) FileStat(path string) (map[string]interface{}, error) {
 	result["size"] = info.Size()
 	result["isdir"] = info.IsDir()
 	result["isreg"] = info.Mode().IsRegular()
-	result["islnk"] = info.Mode()&os.ModeSymlink != 0
+	kind := linkType(path, info)
+	result["islnk"] = kind != ""
+	if kind == "junction" {
+		result["isjunction"] = true
+	}
 
-	// Get link target if it's a symlink
-	if info.Mode()&os.ModeSymlink != 0 {
-		target, err := os.Readlink(path)
+	// Get link target if it's a symlink or junction
+	if kind != "" {
+		target, err := m.fs().Readlink(path)
 		if err == nil {
 			result["lnk_target"] = target
 		}
@@ -915,53 +1288,89 @@ func (m *AnsibleModule) FileStat(path string) (map[string]interface{}, error) {
 	// Get file modification time
 	result["mtime"] = info.ModTime().Unix()
 
+	// Add ownership and platform fields such as birthtime on macOS and BSD
+	platformStat(info, result)
+
 	return result, nil
 }
 
 // CompareFiles compares the content of two files
 func (m *AnsibleModule) CompareFiles(src, dest string) (bool, error) {
+	return m.CompareFilesWith(src, dest, m.ComparisonHash)
+}
+
+// CompareFilesWith compares the content of two files using the named hash
+// algorithm, or byte by byte if algorithm is empty
+func (m *AnsibleModule) CompareFilesWith(src, dest, algorithm string) (bool, error) {
 	// Check if both files exist
-	if !m.FileExists(src) {
-		return false, fmt.Errorf("source file %s does not exist", src)
+	if err := m.checkSource(src); err != nil {
+		return false, err
 	}
 	if !m.FileExists(dest) {
 		return false, nil
 	}
 
 	// Get stats for both files
-	srcStat, err := os.Stat(src)
+	srcStat, err := m.fs().Stat(src)
 	if err != nil {
 		return false, err
 	}
-	destStat, err := os.Stat(dest)
+	destStat, err := m.fs().Stat(dest)
 	if err != nil {
 		return false, err
 	}
 
+	// Compare normalized text when the comparison options allow cosmetic differences
+	if m.CompareOptions.enabled() {
+		srcFile, err := m.fs().Open(src)
+		if err != nil {
+			return false, err
+		}
+		defer srcFile.Close()
+		destFile, err := m.fs().Open(dest)
+		if err != nil {
+			return false, err
+		}
+		defer destFile.Close()
+		return equivalentReaders(m.contextReader(srcFile), m.contextReader(destFile), m.CompareOptions)
+	}
+
 	// Quick size comparison
 	if srcStat.Size() != destStat.Size() {
 		return false, nil
 	}
 
-	// Compare MD5 sums
-	srcMD5, err := m.MD5(src)
+	return m.sameFileContent(src, dest, algorithm)
+}
+
+// sameFileContent compares two files chunk by chunk, stopping at the first
+// difference, or by their digests when a hash algorithm is given
+func (m *AnsibleModule) sameFileContent(a, b, algorithm string) (bool, error) {
+	if algorithm != "" {
+		return m.sameDigest(a, b, algorithm)
+	}
+
+	fileA, err := m.fs().Open(a)
 	if err != nil {
 		return false, err
 	}
-
-	destMD5, err := m.MD5(dest)
+	defer fileA.Close()
+	fileB, err := m.fs().Open(b)
 	if err != nil {
 		return false, err
 	}
+	defer fileB.Close()
 
-	return srcMD5 == destMD5, nil
+	return sameContent(m.contextReader(fileA), m.contextReader(fileB))
 }
 
 // CopyFile copies a file with optional mode and ownership
 func (m *AnsibleModule) CopyFile(src, dest string, mode os.FileMode) (bool, error) {
+	defer m.auditFile("copy_file", dest)()
+
 	// Check if source exists
-	if !m.FileExists(src) {
-		return false, fmt.Errorf("source file %s does not exist", src)
+	if err := m.checkSource(src); err != nil {
+		return false, err
 	}
 
 	// Check if files are already identical
@@ -977,7 +1386,7 @@ func (m *AnsibleModule) CopyFile(src, dest string, mode os.FileMode) (bool, erro
 	}
 
 	// Create temporary file for atomic operation
-	tmpFile, err := m.TmpFile("ansible-copy-")
+	tmpFile, err := m.createTemp("ansible-copy-")
 	if err != nil {
 		return false, err
 	}
@@ -985,47 +1394,47 @@ func (m *AnsibleModule) CopyFile(src, dest string, mode os.FileMode) (bool, erro
 	tmpFile.Close()
 
 	// Copy content to temporary file
-	srcFile, err := os.Open(src)
+	srcFile, err := m.fs().Open(src)
 	if err != nil {
 		return false, err
 	}
 	defer srcFile.Close()
 
-	tmpFile, err = os.Create(tmpPath)
+	tmpFile, err = m.fs().OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return false, err
 	}
 
-	if _, err := io.Copy(tmpFile, srcFile); err != nil {
+	if _, err := copyChunked(tmpFile, m.contextReader(srcFile)); err != nil {
 		tmpFile.Close()
-		os.Remove(tmpPath)
+		m.fs().Remove(tmpPath)
 		return false, err
 	}
 	tmpFile.Close()
 
 	// Set mode if provided
 	if mode != 0 {
-		if err := os.Chmod(tmpPath, mode); err != nil {
-			os.Remove(tmpPath)
+		if err := m.fs().Chmod(tmpPath, mode); err != nil {
+			m.fs().Remove(tmpPath)
 			return false, err
 		}
 	} else {
 		// Use source file mode
-		srcInfo, err := os.Stat(src)
+		srcInfo, err := m.fs().Stat(src)
 		if err != nil {
-			os.Remove(tmpPath)
+			m.fs().Remove(tmpPath)
 			return false, err
 		}
-		if err := os.Chmod(tmpPath, srcInfo.Mode().Perm()); err != nil {
-			os.Remove(tmpPath)
+		if err := m.fs().Chmod(tmpPath, srcInfo.Mode().Perm()); err != nil {
+			m.fs().Remove(tmpPath)
 			return false, err
 		}
 	}
 
-	// Move temporary file to destination
-	changed, err := m.AtomicMove(tmpPath, dest)
+	// Move temporary file to destination, already known to differ
+	changed, err := m.atomicMove(tmpPath, dest, false, "")
 	if err != nil {
-		os.Remove(tmpPath) // Clean up temp file if move failed
+		m.fs().Remove(tmpPath) // Clean up temp file if move failed
 		return false, err
 	}
 
@@ -1034,21 +1443,23 @@ func (m *AnsibleModule) CopyFile(src, dest string, mode os.FileMode) (bool, erro
 
 // CreateDirectory creates a directory with given mode
 func (m *AnsibleModule) CreateDirectory(path string, mode os.FileMode) (bool, error) {
+	defer m.auditFile("create_directory", path)()
+
 	// Check if directory already exists
 	if m.IsDir(path) {
 		// Directory exists, check mode
-		stat, err := os.Stat(path)
+		stat, err := m.fs().Stat(path)
 		if err != nil {
 			return false, err
 		}
 
-		if stat.Mode().Perm() == mode {
+		if modeMatches(stat, mode) {
 			// Mode is already correct
 			return false, nil
 		}
 
 		// Update mode
-		if err := os.Chmod(path, mode); err != nil {
+		if err := m.fs().Chmod(path, mode); err != nil {
 			return false, err
 		}
 
@@ -1056,31 +1467,38 @@ func (m *AnsibleModule) CreateDirectory(path string, mode os.FileMode) (bool, er
 	}
 
 	// Create directory with specified mode
-	if err := os.MkdirAll(path, mode); err != nil {
+	if err := m.fs().MkdirAll(path, mode); err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
 
-// CreateSymlink creates a symbolic link
+// CreateSymlink creates a symbolic link. On Windows, a directory link is created
+// as a junction when symlinks are not permitted and JunctionFallback is set.
 func (m *AnsibleModule) CreateSymlink(src, dest string) (bool, error) {
-	// Check if destination already exists
-	if m.FileExists(dest) {
+	defer m.auditFile("create_symlink", dest)()
+
+	// Check if destination already exists, including dangling links
+	if info, err := m.fs().Lstat(dest); err == nil {
 		// If it's a symlink, check the target
-		if m.IsSymlink(dest) {
-			target, err := os.Readlink(dest)
+		if kind := linkType(dest, info); kind != "" {
+			target, err := m.fs().Readlink(dest)
 			if err != nil {
 				return false, err
 			}
 
+			// Junction targets are always absolute
+			if kind == "junction" && !filepath.IsAbs(src) {
+				target, _ = filepath.Rel(filepath.Dir(dest), target)
+			}
 			if target == src {
 				// Symlink already points to the right target
 				return false, nil
 			}
 
 			// Remove existing symlink
-			if err := os.Remove(dest); err != nil {
+			if err := m.fs().Remove(dest); err != nil {
 				return false, err
 			}
 		} else {
@@ -1092,14 +1510,23 @@ func (m *AnsibleModule) CreateSymlink(src, dest string) (bool, error) {
 	// Create parent directory if needed
 	dirPath := filepath.Dir(dest)
 	if !m.IsDir(dirPath) {
-		if err := os.MkdirAll(dirPath, 0755); err != nil {
+		if err := m.fs().MkdirAll(dirPath, 0755); err != nil {
 			return false, err
 		}
 	}
 
 	// Create symlink
-	if err := os.Symlink(src, dest); err != nil {
-		return false, err
+	if err := m.fs().Symlink(src, dest); err != nil {
+		target := src
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dirPath, target)
+		}
+		if !m.JunctionFallback || !symlinkNotPermitted(err) || !m.IsDir(target) {
+			return false, err
+		}
+		if err := m.createJunction(target, dest); err != nil {
+			return false, err
+		}
 	}
 
 	return true, nil
@@ -1107,7 +1534,7 @@ func (m *AnsibleModule) CreateSymlink(src, dest string) (bool, error) {
 
 // ReadTextFile reads a file into a string
 func (m *AnsibleModule) ReadTextFile(path string) (string, error) {
-	content, err := os.ReadFile(path)
+	content, err := m.fs().ReadFile(path)
 	if err != nil {
 		return "", err
 	}
@@ -1116,62 +1543,133 @@ func (m *AnsibleModule) ReadTextFile(path string) (string, error) {
 
 // WriteTextFile writes text to a file
 func (m *AnsibleModule) WriteTextFile(path, content string, mode os.FileMode) (bool, error) {
+	defer m.auditFile("write_file", path)()
+
 	// Check if file exists with same content
 	if m.FileExists(path) {
-		existingContent, err := m.ReadTextFile(path)
+		same, err := m.hasTextContent(path, content)
 		if err != nil {
 			return false, err
 		}
+		if same {
+			return m.ensureMode(path, mode)
+		}
+	}
 
-		if existingContent == content {
-			// Check if mode needs updating
-			stat, err := os.Stat(path)
-			if err != nil {
-				return false, err
-			}
+	// Write content to a temporary file
+	tmpPath, err := m.writeTemp("ansible-write-", strings.NewReader(content), mode)
+	if err != nil {
+		return false, err
+	}
 
-			if stat.Mode().Perm() != mode {
-				// Update mode
-				if err := os.Chmod(path, mode); err != nil {
-					return false, err
-				}
-				return true, nil
-			}
+	// Move temporary file to destination, already known to differ
+	changed, err := m.atomicMove(tmpPath, path, false, "")
+	if err != nil {
+		m.fs().Remove(tmpPath)
+		return false, err
+	}
 
-			// Content and mode are the same
-			return false, nil
+	return changed, nil
+}
+
+// WriteFileFrom writes content read from r to a file. The content is streamed
+// through a temporary file and compared with the existing file chunk by chunk,
+// so content larger than memory can be written.
+func (m *AnsibleModule) WriteFileFrom(path string, r io.Reader, mode os.FileMode) (bool, error) {
+	defer m.auditFile("write_file", path)()
+
+	tmpPath, err := m.writeTemp("ansible-write-", m.contextReader(r), mode)
+	if err != nil {
+		return false, err
+	}
+
+	// Keep the existing file if the content is the same
+	if m.FileExists(path) {
+		same, err := m.CompareFiles(tmpPath, path)
+		if err != nil {
+			m.fs().Remove(tmpPath)
+			return false, err
+		}
+		if same {
+			m.fs().Remove(tmpPath)
+			return m.ensureMode(path, mode)
 		}
 	}
 
-	// Create temporary file
-	tmpFile, err := m.TmpFile("ansible-write-")
+	changed, err := m.atomicMove(tmpPath, path, false, "")
 	if err != nil {
+		m.fs().Remove(tmpPath)
 		return false, err
 	}
-	tmpPath := tmpFile.Name()
 
-	// Write content to temporary file
-	if _, err := tmpFile.WriteString(content); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return false, err
+	return changed, nil
+}
+
+// hasTextContent reports whether a file holds content, streaming the file
+func (m *AnsibleModule) hasTextContent(path, content string) (bool, error) {
+	if m.CompareOptions.enabled() {
+		file, err := m.fs().Open(path)
+		if err != nil {
+			return false, err
+		}
+		defer file.Close()
+		return equivalentReaders(m.contextReader(file), strings.NewReader(content), m.CompareOptions)
 	}
-	tmpFile.Close()
 
-	// Set mode
-	if err := os.Chmod(tmpPath, mode); err != nil {
-		os.Remove(tmpPath)
+	stat, err := m.fs().Stat(path)
+	if err != nil {
 		return false, err
 	}
+	if stat.Size() != int64(len(content)) {
+		return false, nil
+	}
+	file, err := m.fs().Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	return sameContent(m.contextReader(file), strings.NewReader(content))
+}
 
-	// Move temporary file to destination
-	changed, err := m.AtomicMove(tmpPath, path)
+// ensureMode sets the mode of a file whose content is already correct
+func (m *AnsibleModule) ensureMode(path string, mode os.FileMode) (bool, error) {
+	stat, err := m.fs().Stat(path)
 	if err != nil {
-		os.Remove(tmpPath)
 		return false, err
 	}
+	if modeMatches(stat, mode) {
+		// Content and mode are the same
+		return false, nil
+	}
+	if err := m.fs().Chmod(path, mode); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writeTemp copies r into a new temporary file with the given mode and returns its path
+func (m *AnsibleModule) writeTemp(prefix string, r io.Reader, mode os.FileMode) (string, error) {
+	tmpFile, err := m.createTemp(prefix)
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmpFile.Name()
 
-	return changed, nil
+	if _, err := copyChunked(tmpFile, r); err != nil {
+		tmpFile.Close()
+		m.fs().Remove(tmpPath)
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		m.fs().Remove(tmpPath)
+		return "", err
+	}
+
+	if err := m.fs().Chmod(tmpPath, mode); err != nil {
+		m.fs().Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
 }
 
 // RegexReplace performs regex replacement on a string
@@ -1219,7 +1717,7 @@ func (m *AnsibleModule) AppendToFile(path, content string) (bool, error) {
 	newContent += content
 
 	// Get current file mode
-	stat, err := os.Stat(path)
+	stat, err := m.fs().Stat(path)
 	if err != nil {
 		return false, err
 	}
@@ -1230,11 +1728,26 @@ func (m *AnsibleModule) AppendToFile(path, content string) (bool, error) {
 
 // DebugMsg prints debug information if debug mode is enabled
 func (m *AnsibleModule) DebugMsg(msg string) {
+	m.writeDebugLog("DEBUG", msg)
 	if m.Debug {
-		fmt.Fprintf(os.Stderr, "DEBUG: %s\n", msg)
+		m.writeStderr("DEBUG: " + msg)
 	}
 }
 
+// writeStderr shows a diagnostic line on stderr, or keeps it for the result
+// under debug_info when QuietStderr is set, since Ansible reports anything on
+// stderr as module noise. Only failures to produce a result bypass it.
+func (m *AnsibleModule) writeStderr(line string) {
+	line = m.scrubString(line)
+	if m.QuietStderr {
+		m.partialMu.Lock()
+		m.debugInfo = append(m.debugInfo, line)
+		m.partialMu.Unlock()
+		return
+	}
+	fmt.Fprintln(os.Stderr, line)
+}
+
 // BackupFile creates a backup of a file
 func (m *AnsibleModule) BackupFile(path string) (string, error) {
 	timestamp := time.Now().Format("2006-01-02-15-04-05")
@@ -380,7 +380,7 @@ func TestValidateArgument(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			err := module.validateArgument(test.name, test.value, test.spec)
+			_, err := module.validateArgument(test.name, test.value, test.spec)
 			if test.expected == nil {
 				if err != nil {
 					t.Errorf("Expected no error, got: %v", err)
@@ -727,6 +727,31 @@ func TestTmpFile(t *testing.T) {
 	}
 }
 
+func TestRemoteTmpDir(t *testing.T) {
+	remoteTmp := t.TempDir()
+	module := &AnsibleModule{remoteTmp: remoteTmp}
+	defer module.Cleanup()
+
+	dir, err := module.tmpDir()
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	if filepath.Dir(dir) != remoteTmp {
+		t.Errorf("Expected temp dir in %s, got %s", remoteTmp, dir)
+	}
+
+	// A missing controller directory falls back to the system temp directory
+	missing := &AnsibleModule{remoteTmp: filepath.Join(remoteTmp, "missing")}
+	defer missing.Cleanup()
+	dir, err = missing.tmpDir()
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	if filepath.Dir(dir) != filepath.Clean(os.TempDir()) {
+		t.Errorf("Expected temp dir in %s, got %s", os.TempDir(), dir)
+	}
+}
+
 func TestCleanup(t *testing.T) {
 	module := &AnsibleModule{}
 	module.TmpDir = os.TempDir() + "/test-tmp"
@@ -738,6 +763,43 @@ func TestCleanup(t *testing.T) {
 	}
 }
 
+func TestLazyTmpDir(t *testing.T) {
+	module := &AnsibleModule{Params: ModuleParams{}, remoteTmp: t.TempDir()}
+	if module.TmpDir != "" {
+		t.Fatal("Expected no temp dir before first use")
+	}
+
+	file, err := module.createTemp("lazy-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	file.Close()
+	dir := module.TmpDir
+	if filepath.Dir(file.Name()) != dir {
+		t.Errorf("Expected temp file in %s, got %s", dir, file.Name())
+	}
+
+	// The module removes the directory it created when it exits
+	exitOutput(t, module, map[string]interface{}{})
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed on exit", dir)
+	}
+
+	// A new directory is created if the module keeps working after Cleanup
+	if again, err := module.GetTmpDir(); err != nil || again == dir {
+		t.Errorf("Expected a new temp dir after exit, got %s (%v)", again, err)
+	}
+	module.Cleanup()
+
+	// Directories supplied by the caller are left alone on exit
+	supplied := t.TempDir()
+	other := &AnsibleModule{Params: ModuleParams{}, TmpDir: supplied}
+	exitOutput(t, other, map[string]interface{}{})
+	if _, err := os.Stat(supplied); err != nil {
+		t.Errorf("Expected supplied temp dir to be kept: %v", err)
+	}
+}
+
 func TestGetParam(t *testing.T) {
 	module := &AnsibleModule{
 		Params: ModuleParams{
@@ -765,6 +827,38 @@ func TestGetParam(t *testing.T) {
 	}
 }
 
+func TestCloneParams(t *testing.T) {
+	module := &AnsibleModule{
+		Params: ModuleParams{
+			"name":  "web",
+			"ports": []interface{}{80, 443},
+			"labels": map[string]interface{}{
+				"tier": "frontend",
+				"tags": []interface{}{"a"},
+			},
+		},
+	}
+
+	shallow := module.CloneParams(false)
+	shallow["name"] = "db"
+	if module.Params["name"] != "web" {
+		t.Error("Expected a shallow copy to have its own top-level values")
+	}
+	shallow["ports"].([]interface{})[0] = 8080
+	if module.Params["ports"].([]interface{})[0] != 8080 {
+		t.Error("Expected a shallow copy to share nested lists")
+	}
+
+	deep := module.CloneParams(true)
+	deep["labels"].(map[string]interface{})["tier"] = "backend"
+	deep["labels"].(map[string]interface{})["tags"].([]interface{})[0] = "b"
+	deep["ports"] = append(deep["ports"].([]interface{}), 22)
+	labels := module.Params["labels"].(map[string]interface{})
+	if labels["tier"] != "frontend" || labels["tags"].([]interface{})[0] != "a" || len(module.Params["ports"].([]interface{})) != 2 {
+		t.Errorf("Expected a deep copy to leave Params alone, got %v", module.Params)
+	}
+}
+
 func TestGetParamBool(t *testing.T) {
 	module := &AnsibleModule{
 		Params: ModuleParams{
@@ -1229,6 +1323,54 @@ func TestReadTextFile(t *testing.T) {
 	}
 }
 
+func TestExitJsonOutput(t *testing.T) {
+	var output bytes.Buffer
+	module := &AnsibleModule{Params: ModuleParams{}, Output: &output, IndentResult: true, ExitFunc: func(int) {}}
+	module.ExitJson(map[string]interface{}{"changed": true, "msg": "<done>"})
+
+	if !strings.HasPrefix(output.String(), "{\n  \"changed\": true,") || !strings.HasSuffix(output.String(), "}\n") {
+		t.Errorf("Expected indented result ending in a newline, got %q", output.String())
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &parsed); err != nil || parsed["msg"] != "<done>" {
+		t.Errorf("Expected result to round-trip, got %v (%v)", parsed, err)
+	}
+}
+
+func TestWriteFileFrom(t *testing.T) {
+	module := &AnsibleModule{TmpDir: t.TempDir()}
+	path := filepath.Join(t.TempDir(), "large.bin")
+	content := strings.Repeat("generated line\n", 10000)
+
+	changed, err := module.WriteFileFrom(path, strings.NewReader(content), 0644)
+	if err != nil || !changed {
+		t.Fatalf("Expected file to be written, got %v (%v)", changed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Error("Written content does not match")
+	}
+
+	// Same content is not rewritten, and the temp file is removed
+	changed, err = module.WriteFileFrom(path, strings.NewReader(content), 0644)
+	if err != nil || changed {
+		t.Errorf("Expected no change for same content, got %v (%v)", changed, err)
+	}
+	if entries, _ := os.ReadDir(module.TmpDir); len(entries) != 0 {
+		t.Errorf("Expected temp files to be removed, found %d", len(entries))
+	}
+
+	// A mode change alone is applied in place
+	changed, err = module.WriteFileFrom(path, strings.NewReader(content), 0600)
+	if err != nil || !changed {
+		t.Errorf("Expected mode change, got %v (%v)", changed, err)
+	}
+
+	changed, err = module.WriteFileFrom(path, strings.NewReader(content+"tail\n"), 0600)
+	if err != nil || !changed {
+		t.Errorf("Expected content change, got %v (%v)", changed, err)
+	}
+}
+
 func TestWriteTextFile(t *testing.T) {
 	module := &AnsibleModule{}
 
@@ -1361,6 +1503,33 @@ func TestDebugMsg(t *testing.T) {
 	module.DebugMsg("test message")
 }
 
+func TestDebugMsgQuietStderr(t *testing.T) {
+	oldStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	var output bytes.Buffer
+	module := &AnsibleModule{Debug: true, QuietStderr: true, Output: &output, ExitFunc: func(int) {}}
+	module.NoLogValue("hunter2")
+	module.DebugMsg("connecting with hunter2")
+	module.ExitJson(map[string]interface{}{"changed": false})
+
+	w.Close()
+	os.Stderr = oldStderr
+	stderr, _ := io.ReadAll(r)
+	if len(stderr) != 0 {
+		t.Errorf("Expected nothing on stderr, got %q", stderr)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	debugInfo, _ := result["debug_info"].([]interface{})
+	if len(debugInfo) != 1 || debugInfo[0] != "DEBUG: connecting with ********" {
+		t.Errorf("Expected scrubbed debug output in the result, got %v", result["debug_info"])
+	}
+}
+
 func TestBackupFile(t *testing.T) {
 	module := &AnsibleModule{}
 
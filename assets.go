@@ -0,0 +1,127 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"text/template"
+)
+
+// ReadFSText reads a file of fsys, such as an embed.FS holding templates or
+// default configuration shipped inside the module binary
+func ReadFSText(fsys fs.FS, name string) (string, error) {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// RenderFSTemplate executes the text/template name of fsys with data. Missing
+// map keys are errors rather than "<no value>".
+func RenderFSTemplate(fsys fs.FS, name string, data interface{}) (string, error) {
+	tmpl, err := template.New(path.Base(name)).Option("missingkey=error").ParseFS(fsys, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// InstallContent makes dest hold content with the given mode, like
+// WriteTextFile, but only reports the change in check mode. The diff between
+// the current and the new content is returned for the module result.
+func (m *AnsibleModule) InstallContent(dest, content string, mode os.FileMode) (bool, map[string]interface{}, error) {
+	current := ""
+	if m.FileExists(dest) {
+		var err error
+		if current, err = m.ReadTextFile(dest); err != nil {
+			return false, nil, err
+		}
+	}
+	diff := m.CreateDiff(current, content, dest+" (before)", dest+" (after)")
+	if !m.CheckMode {
+		changed, err := m.WriteTextFile(dest, content, mode)
+		return changed, diff, err
+	}
+	if !m.FileExists(dest) {
+		return true, diff, nil
+	}
+	// Decide like WriteTextFile, so check mode honors CompareOptions too
+	same, err := m.hasTextContent(dest, content)
+	if err != nil {
+		return false, nil, err
+	}
+	if !same {
+		return true, diff, nil
+	}
+	stat, err := m.fs().Stat(dest)
+	if err != nil {
+		return false, nil, err
+	}
+	return !modeMatches(stat, mode), diff, nil
+}
+
+// InstallFSFile installs the file name of fsys at dest with InstallContent
+func (m *AnsibleModule) InstallFSFile(fsys fs.FS, name, dest string, mode os.FileMode) (bool, map[string]interface{}, error) {
+	content, err := ReadFSText(fsys, name)
+	if err != nil {
+		return false, nil, err
+	}
+	return m.InstallContent(dest, content, mode)
+}
+
+// InstallFSTemplate renders the template name of fsys with data and installs the
+// result at dest with InstallContent
+func (m *AnsibleModule) InstallFSTemplate(fsys fs.FS, name, dest string, data interface{}, mode os.FileMode) (bool, map[string]interface{}, error) {
+	content, err := RenderFSTemplate(fsys, name, data)
+	if err != nil {
+		return false, nil, err
+	}
+	return m.InstallContent(dest, content, mode)
+}
+
+// InstallFSTree installs every file below root of fsys into the directory dest,
+// creating directories with mode 0755 and files with fileMode. It returns the
+// destination paths that changed, in walk order.
+func (m *AnsibleModule) InstallFSTree(fsys fs.FS, root, dest string, fileMode os.FileMode) ([]string, error) {
+	var changed []string
+	err := fs.WalkDir(fsys, root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(root), filepath.FromSlash(name))
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if entry.IsDir() {
+			if m.IsDir(target) {
+				return nil
+			}
+			changed = append(changed, target)
+			if m.CheckMode {
+				return nil
+			}
+			return m.fs().MkdirAll(target, 0755)
+		}
+		fileChanged, _, err := m.InstallFSFile(fsys, name, target, fileMode)
+		if err != nil {
+			return err
+		}
+		if fileChanged {
+			changed = append(changed, target)
+		}
+		return nil
+	})
+	if err != nil {
+		return changed, fmt.Errorf("failed to install %s: %w", root, err)
+	}
+	return changed, nil
+}
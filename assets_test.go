@@ -0,0 +1,69 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestInstallFSFiles(t *testing.T) {
+	assets := fstest.MapFS{
+		"defaults/app.conf":             {Data: []byte("workers = 4\n")},
+		"defaults/conf.d/log.conf":      {Data: []byte("level = info\n")},
+		"templates/service.conf.gotmpl": {Data: []byte("ExecStart={{.Binary}} --port {{.Port}}\n")},
+	}
+	dir := t.TempDir()
+	module := &AnsibleModule{}
+
+	dest := filepath.Join(dir, "app.service")
+	data := map[string]interface{}{"Binary": "/usr/bin/app", "Port": 8080}
+	changed, diff, err := module.InstallFSTemplate(assets, "templates/service.conf.gotmpl", dest, data, 0644)
+	if err != nil || !changed || diff["after"] != "ExecStart=/usr/bin/app --port 8080\n" {
+		t.Fatalf("Expected the rendered template to be installed, got %v %v (%v)", changed, diff, err)
+	}
+	if changed, _, _ := module.InstallFSTemplate(assets, "templates/service.conf.gotmpl", dest, data, 0644); changed {
+		t.Error("Expected no change the second time")
+	}
+	if _, _, err := module.InstallFSTemplate(assets, "templates/service.conf.gotmpl", dest, map[string]interface{}{}, 0644); err == nil {
+		t.Error("Expected a missing template value to fail")
+	}
+
+	module.CheckMode = true
+	target := filepath.Join(dir, "etc")
+	changes, err := module.InstallFSTree(assets, "defaults", target, 0640)
+	if err != nil || len(changes) != 4 {
+		t.Errorf("Expected check mode to report the whole tree, got %v (%v)", changes, err)
+	}
+	if module.FileExists(target) {
+		t.Error("Expected nothing to be written in check mode")
+	}
+
+	module.CheckMode = false
+	if _, err := module.InstallFSTree(assets, "defaults", target, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(target, "conf.d", "log.conf")); string(content) != "level = info\n" {
+		t.Errorf("Unexpected installed content %q", content)
+	}
+	os.WriteFile(filepath.Join(target, "app.conf"), []byte("workers = 8\n"), 0640)
+	changes, err = module.InstallFSTree(assets, "defaults", target, 0640)
+	if err != nil || !reflect.DeepEqual(changes, []string{filepath.Join(target, "app.conf")}) {
+		t.Errorf("Expected only the edited file to be restored, got %v (%v)", changes, err)
+	}
+}
+
+func TestInstallContentCompareOptions(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "app.conf")
+	os.WriteFile(dest, []byte("workers = 4\r\n"), 0644)
+	module := &AnsibleModule{CompareOptions: CompareOptions{NormalizeLineEndings: true}}
+
+	// Check mode agrees with the real run on equivalent content
+	for _, checkMode := range []bool{true, false} {
+		module.CheckMode = checkMode
+		if changed, _, err := module.InstallContent(dest, "workers = 4\n", 0644); err != nil || changed {
+			t.Errorf("Expected equivalent content to be unchanged in check mode %v, got %v (%v)", checkMode, changed, err)
+		}
+	}
+}
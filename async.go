@@ -0,0 +1,266 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Environment variables used to coordinate the async supervisor and worker processes
+const (
+	asyncRoleEnv      = "ANSIBLE_GO_ASYNC_ROLE"
+	asyncJobEnv       = "ANSIBLE_GO_ASYNC_JID"
+	asyncTimeLimitEnv = "ANSIBLE_GO_ASYNC_TIME_LIMIT"
+	asyncArgsEnv      = "ANSIBLE_GO_ASYNC_ARGS"
+)
+
+// AsyncJob describes a module run in the background for async_status
+type AsyncJob struct {
+	ID          string
+	ResultsFile string
+	TimeLimit   time.Duration
+}
+
+// AsyncDir returns the directory holding async job status files
+func AsyncDir() string {
+	if dir := os.Getenv("ANSIBLE_ASYNC_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, ".ansible_async")
+}
+
+// HandleAsync must be called at the start of main. When the binary is invoked as
+// "module async <jid> <time_limit> [args_file]" it starts the module in the
+// background, prints the job information for async_status and exits. When
+// re-executed as the background supervisor it runs the job and exits. Otherwise
+// it returns and the module runs normally.
+func HandleAsync() {
+	switch os.Getenv(asyncRoleEnv) {
+	case "supervisor":
+		os.Exit(runAsyncSupervisor())
+	case "worker":
+		return
+	}
+
+	if len(os.Args) < 4 || os.Args[1] != "async" {
+		return
+	}
+
+	output, err := startAsyncFromArgs(os.Args[2:])
+	if err != nil {
+		output = map[string]interface{}{"failed": 1, "msg": err.Error()}
+	}
+	data, _ := json.Marshal(output)
+	fmt.Println(string(data))
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// startAsyncFromArgs parses the async command line and starts the job
+func startAsyncFromArgs(args []string) (map[string]interface{}, error) {
+	jid := args[0]
+	seconds, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid async time limit %s: %w", args[1], err)
+	}
+
+	var input []byte
+	if len(args) > 2 {
+		input, err = os.ReadFile(args[2])
+	} else {
+		input, err = readAllStdin()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module arguments: %w", err)
+	}
+
+	job, err := StartAsync(jid, time.Duration(seconds)*time.Second, input)
+	if err != nil {
+		return nil, err
+	}
+	return job.startedResult(), nil
+}
+
+// readAllStdin reads the module arguments from stdin
+func readAllStdin() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(os.Stdin)
+	return buf.Bytes(), err
+}
+
+// StartAsync launches the current binary as a detached background job that
+// runs the module with the given JSON arguments
+func StartAsync(jid string, timeLimit time.Duration, input []byte) (*AsyncJob, error) {
+	dir := AsyncDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create async directory: %w", err)
+	}
+
+	job := &AsyncJob{
+		ID:          jid,
+		ResultsFile: filepath.Join(dir, jid),
+		TimeLimit:   timeLimit,
+	}
+	if err := job.writeStatus(job.startedResult()); err != nil {
+		return nil, err
+	}
+
+	// The arguments are handed over in a file since the supervisor is detached from stdin
+	argsFile := job.ResultsFile + ".args"
+	if err := os.WriteFile(argsFile, input, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write async arguments: %w", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate module executable: %w", err)
+	}
+	cmd := exec.Command(executable)
+	cmd.Env = append(os.Environ(),
+		asyncRoleEnv+"=supervisor",
+		asyncJobEnv+"="+jid,
+		asyncTimeLimitEnv+"="+strconv.Itoa(int(timeLimit.Seconds())),
+		asyncArgsEnv+"="+argsFile,
+	)
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		os.Remove(argsFile)
+		return nil, fmt.Errorf("failed to start async job: %w", err)
+	}
+	cmd.Process.Release()
+
+	return job, nil
+}
+
+// startedResult is the status reported while the job is running
+func (j *AsyncJob) startedResult() map[string]interface{} {
+	return map[string]interface{}{
+		"started":                         1,
+		"finished":                        0,
+		"ansible_job_id":                  j.ID,
+		"results_file":                    j.ResultsFile,
+		"_ansible_suppress_tmpdir_delete": true,
+	}
+}
+
+// writeStatus atomically replaces the job status file
+func (j *AsyncJob) writeStatus(status map[string]interface{}) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	tmpPath := j.ResultsFile + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write async status: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.ResultsFile); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write async status: %w", err)
+	}
+	return nil
+}
+
+// runAsyncSupervisor runs the worker process and records its result
+func runAsyncSupervisor() int {
+	seconds, _ := strconv.Atoi(os.Getenv(asyncTimeLimitEnv))
+	job := &AsyncJob{
+		ID:          os.Getenv(asyncJobEnv),
+		ResultsFile: filepath.Join(AsyncDir(), os.Getenv(asyncJobEnv)),
+		TimeLimit:   time.Duration(seconds) * time.Second,
+	}
+
+	argsFile := os.Getenv(asyncArgsEnv)
+	defer os.Remove(argsFile)
+
+	input, err := os.Open(argsFile)
+	if err != nil {
+		job.writeStatus(job.failedResult(fmt.Sprintf("failed to read module arguments: %v", err), "", ""))
+		return 1
+	}
+	defer input.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		job.writeStatus(job.failedResult(fmt.Sprintf("failed to locate module executable: %v", err), "", ""))
+		return 1
+	}
+	cmd := exec.Command(executable)
+	cmd.Stdin = input
+	cmd.Env = append(os.Environ(), asyncRoleEnv+"=worker")
+
+	if err := job.supervise(cmd); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// supervise runs the worker command, enforcing the time limit, and writes the final status
+func (j *AsyncJob) supervise(cmd *exec.Cmd) error {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return j.writeStatus(j.failedResult(fmt.Sprintf("failed to start module: %v", err), "", ""))
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var timeout <-chan time.Time
+	if j.TimeLimit > 0 {
+		timer := time.NewTimer(j.TimeLimit)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-done:
+	case <-timeout:
+		cmd.Process.Kill()
+		<-done
+		return j.writeStatus(j.failedResult(
+			fmt.Sprintf("Job reached maximum time limit of %d seconds.", int(j.TimeLimit.Seconds())),
+			stdout.String(), stderr.String()))
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &result); err != nil {
+		return j.writeStatus(j.failedResult("Module did not return valid JSON", stdout.String(), stderr.String()))
+	}
+
+	result["ansible_job_id"] = j.ID
+	result["finished"] = 1
+	if stderr.Len() > 0 {
+		result["stderr"] = stderr.String()
+	}
+	return j.writeStatus(result)
+}
+
+// failedResult is the final status of a job that could not produce a module result
+func (j *AsyncJob) failedResult(msg, stdout, stderr string) map[string]interface{} {
+	result := map[string]interface{}{
+		"failed":         1,
+		"finished":       1,
+		"msg":            msg,
+		"ansible_job_id": j.ID,
+	}
+	if stdout != "" {
+		result["data"] = stdout
+	}
+	if stderr != "" {
+		result["stderr"] = stderr
+	}
+	return result
+}
@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// detachedProcAttr returns nil where sessions are not supported
+func detachedProcAttr() *syscall.SysProcAttr {
+	return nil
+}
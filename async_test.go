@@ -0,0 +1,78 @@
+package ansiblemodule
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readJobStatus parses an async job status file
+func readJobStatus(t *testing.T, path string) map[string]interface{} {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read job status: %v", err)
+	}
+	var status map[string]interface{}
+	if err := json.Unmarshal(content, &status); err != nil {
+		t.Fatalf("Failed to parse job status: %v", err)
+	}
+	return status
+}
+
+func TestAsyncSupervise(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ANSIBLE_ASYNC_DIR", dir)
+
+	job := &AsyncJob{ID: "123.456", ResultsFile: filepath.Join(dir, "123.456")}
+	if err := job.writeStatus(job.startedResult()); err != nil {
+		t.Fatalf("Failed to write status: %v", err)
+	}
+	status := readJobStatus(t, job.ResultsFile)
+	if status["started"] != float64(1) || status["finished"] != float64(0) || status["ansible_job_id"] != "123.456" {
+		t.Errorf("Unexpected started status: %v", status)
+	}
+
+	// Test successful module result
+	if err := job.supervise(exec.Command("sh", "-c", `echo '{"changed": true, "msg": "ok"}'`)); err != nil {
+		t.Fatalf("Failed to supervise job: %v", err)
+	}
+	status = readJobStatus(t, job.ResultsFile)
+	if status["finished"] != float64(1) || status["changed"] != true || status["ansible_job_id"] != "123.456" {
+		t.Errorf("Unexpected finished status: %v", status)
+	}
+
+	// Test invalid module output
+	job.supervise(exec.Command("sh", "-c", "echo not json"))
+	status = readJobStatus(t, job.ResultsFile)
+	if status["failed"] != float64(1) || status["data"] != "not json\n" {
+		t.Errorf("Unexpected status for invalid output: %v", status)
+	}
+
+	// Test time limit
+	job.TimeLimit = 100 * time.Millisecond
+	start := time.Now()
+	job.supervise(exec.Command("sleep", "10"))
+	if time.Since(start) > 5*time.Second {
+		t.Error("Expected job to be killed at the time limit")
+	}
+	status = readJobStatus(t, job.ResultsFile)
+	if status["failed"] != float64(1) || status["finished"] != float64(1) {
+		t.Errorf("Unexpected status for timed out job: %v", status)
+	}
+}
+
+func TestAsyncDir(t *testing.T) {
+	t.Setenv("ANSIBLE_ASYNC_DIR", "/custom/async")
+	if AsyncDir() != "/custom/async" {
+		t.Errorf("Expected ANSIBLE_ASYNC_DIR to be used, got %s", AsyncDir())
+	}
+
+	t.Setenv("ANSIBLE_ASYNC_DIR", "")
+	t.Setenv("HOME", "/home/test")
+	if AsyncDir() != "/home/test/.ansible_async" {
+		t.Errorf("Expected default async dir, got %s", AsyncDir())
+	}
+}
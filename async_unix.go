@@ -0,0 +1,12 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// detachedProcAttr starts the process in a new session so it outlives the connection
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,17 @@
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// Process creation flags from the Windows API
+const (
+	createNewProcessGroup = 0x00000200
+	detachedProcess       = 0x00000008
+)
+
+// detachedProcAttr starts the process without a console in its own process group,
+// so it survives the end of the WinRM or SSH session
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: createNewProcessGroup | detachedProcess}
+}
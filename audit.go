@@ -0,0 +1,109 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// AuditRecord describes one state-changing operation performed by the module
+type AuditRecord struct {
+	Time      time.Time              `json:"time"`
+	Operation string                 `json:"operation"`
+	Target    string                 `json:"target"`
+	Before    map[string]interface{} `json:"before,omitempty"`
+	After     map[string]interface{} `json:"after,omitempty"`
+}
+
+// RecordAudit adds an operation to the audit trail when Audit is enabled.
+// Modules call it for changes made without the file and command helpers.
+func (m *AnsibleModule) RecordAudit(operation, target string, before, after map[string]interface{}) {
+	if !m.Audit {
+		return
+	}
+	m.auditMu.Lock()
+	defer m.auditMu.Unlock()
+	m.auditTrail = append(m.auditTrail, AuditRecord{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Target:    target,
+		Before:    before,
+		After:     after,
+	})
+}
+
+// AuditTrail returns the operations recorded so far
+func (m *AnsibleModule) AuditTrail() []AuditRecord {
+	m.auditMu.Lock()
+	defer m.auditMu.Unlock()
+	return append([]AuditRecord(nil), m.auditTrail...)
+}
+
+// auditResult converts the audit trail to plain values for the result
+func (m *AnsibleModule) auditResult() []map[string]interface{} {
+	trail := m.AuditTrail()
+	records := make([]map[string]interface{}, len(trail))
+	for i, record := range trail {
+		records[i] = map[string]interface{}{
+			"time":      record.Time.Format(time.RFC3339Nano),
+			"operation": record.Operation,
+			"target":    record.Target,
+		}
+		if record.Before != nil {
+			records[i]["before"] = record.Before
+		}
+		if record.After != nil {
+			records[i]["after"] = record.After
+		}
+	}
+	return records
+}
+
+// auditFile captures the state of path and returns a function that records the
+// operation if the state differs when it is called. It is used as
+// defer m.auditFile("write_file", path)()
+func (m *AnsibleModule) auditFile(operation, path string) func() {
+	if !m.Audit {
+		return func() {}
+	}
+	before := m.fileSummary(path)
+	return func() {
+		if after := m.fileSummary(path); !reflect.DeepEqual(before, after) {
+			m.RecordAudit(operation, path, before, after)
+		}
+	}
+}
+
+// auditCommand records a command that was run and its exit status
+func (m *AnsibleModule) auditCommand(cmd string, args []string, rc int) {
+	if !m.Audit {
+		return
+	}
+	m.RecordAudit("run_command", strings.Join(append([]string{cmd}, args...), " "), nil, map[string]interface{}{"rc": rc})
+}
+
+// fileSummary describes the state of a file for the audit trail
+func (m *AnsibleModule) fileSummary(path string) map[string]interface{} {
+	info, err := m.fs().Lstat(path)
+	if err != nil {
+		return map[string]interface{}{"state": "absent"}
+	}
+	summary := map[string]interface{}{"mode": fmt.Sprintf("%04o", info.Mode().Perm())}
+	switch {
+	case linkType(path, info) != "":
+		summary["state"] = "link"
+		if target, err := m.fs().Readlink(path); err == nil {
+			summary["target"] = target
+		}
+	case info.IsDir():
+		summary["state"] = "directory"
+	default:
+		summary["state"] = "file"
+		summary["size"] = info.Size()
+		if checksum, err := m.Checksum(path, "sha1"); err == nil {
+			summary["checksum"] = checksum
+		}
+	}
+	return summary
+}
@@ -0,0 +1,66 @@
+package ansiblemodule
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditTrail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.conf")
+	module := &AnsibleModule{
+		Params: ModuleParams{},
+		TmpDir: t.TempDir(),
+		Runner: &stubRunner{},
+		Audit:  true,
+	}
+
+	if _, err := module.WriteTextFile(path, "a=1\n", 0644); err != nil {
+		t.Fatalf("WriteTextFile failed: %v", err)
+	}
+	// Unchanged content is not recorded
+	if _, err := module.WriteTextFile(path, "a=1\n", 0644); err != nil {
+		t.Fatalf("WriteTextFile failed: %v", err)
+	}
+	if _, err := module.WriteTextFile(path, "a=1\n", 0600); err != nil {
+		t.Fatalf("WriteTextFile failed: %v", err)
+	}
+	if _, err := module.RunCommand("/stub/reload", []string{"app"}, nil, ""); err != nil {
+		t.Fatalf("RunCommand failed: %v", err)
+	}
+
+	trail := module.AuditTrail()
+	if len(trail) != 3 {
+		t.Fatalf("Expected 3 audit records, got %d: %+v", len(trail), trail)
+	}
+	if trail[0].Operation != "write_file" || trail[0].Before["state"] != "absent" || trail[0].After["state"] != "file" {
+		t.Errorf("Unexpected create record: %+v", trail[0])
+	}
+	if trail[1].Before["mode"] != "0644" || trail[1].After["mode"] != "0600" {
+		t.Errorf("Expected mode change to be recorded: %+v", trail[1])
+	}
+	if trail[1].Before["checksum"] != trail[1].After["checksum"] {
+		t.Errorf("Expected checksum to be unchanged: %+v", trail[1])
+	}
+	if trail[2].Operation != "run_command" || trail[2].Target != "/stub/reload app" {
+		t.Errorf("Unexpected command record: %+v", trail[2])
+	}
+
+	parsed := exitOutput(t, module, map[string]interface{}{"changed": true})
+	if audit, ok := parsed["audit"].([]interface{}); !ok || len(audit) != 3 {
+		t.Errorf("Expected audit key with 3 records, got %v", parsed["audit"])
+	}
+}
+
+func TestAuditDisabled(t *testing.T) {
+	module := &AnsibleModule{Params: ModuleParams{}, TmpDir: t.TempDir()}
+	if _, err := module.WriteTextFile(filepath.Join(t.TempDir(), "file"), "x", 0644); err != nil {
+		t.Fatalf("WriteTextFile failed: %v", err)
+	}
+	if len(module.AuditTrail()) != 0 {
+		t.Error("Expected no audit records when Audit is disabled")
+	}
+	if _, ok := exitOutput(t, module, map[string]interface{}{})["audit"]; ok {
+		t.Error("Expected no audit key when Audit is disabled")
+	}
+}
@@ -0,0 +1,66 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// IOChunkSize is the buffer size used to copy, hash and compare files. Larger
+// chunks suit big files on fast storage; the buffers are pooled either way.
+var IOChunkSize = 64 * 1024
+
+// defaultChunkSize is used when IOChunkSize is not positive
+const defaultChunkSize = 32 * 1024
+
+// bufferPool holds chunk buffers shared by the file helpers
+var bufferPool sync.Pool
+
+// getBuffer returns a pooled buffer of IOChunkSize bytes
+func getBuffer() *[]byte {
+	size := IOChunkSize
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	if buf, ok := bufferPool.Get().(*[]byte); ok && len(*buf) == size {
+		return buf
+	}
+	buf := make([]byte, size)
+	return &buf
+}
+
+// putBuffer returns a buffer to the pool
+func putBuffer(buf *[]byte) {
+	bufferPool.Put(buf)
+}
+
+// copyChunked copies src to dst through a pooled buffer. Both sides are wrapped
+// so os.File's ReadFrom and WriteTo cannot fall back to allocating their own.
+func copyChunked(dst io.Writer, src io.Reader) (int64, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
+}
+
+// sameContent compares two readers chunk by chunk, stopping at the first difference
+func sameContent(a, b io.Reader) (bool, error) {
+	bufA, bufB := getBuffer(), getBuffer()
+	defer putBuffer(bufA)
+	defer putBuffer(bufB)
+	for {
+		nA, errA := io.ReadFull(a, *bufA)
+		nB, errB := io.ReadFull(b, *bufB)
+		if errA != nil && errA != io.EOF && errA != io.ErrUnexpectedEOF {
+			return false, errA
+		}
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return false, errB
+		}
+		if nA != nB || !bytes.Equal((*bufA)[:nA], (*bufB)[:nB]) {
+			return false, nil
+		}
+		if errA != nil || errB != nil {
+			return errA != nil && errB != nil, nil
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyChunked(t *testing.T) {
+	oldSize := IOChunkSize
+	defer func() { IOChunkSize = oldSize }()
+
+	data := strings.Repeat("0123456789", 1000)
+	for _, size := range []int{7, 4096, 0} {
+		IOChunkSize = size
+		var dst bytes.Buffer
+		n, err := copyChunked(&dst, strings.NewReader(data))
+		if err != nil || n != int64(len(data)) || dst.String() != data {
+			t.Errorf("Chunk size %d: copied %d bytes (%v)", size, n, err)
+		}
+		if buf := getBuffer(); size > 0 && len(*buf) != size {
+			t.Errorf("Expected buffer of %d bytes, got %d", size, len(*buf))
+		}
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	oldSize := IOChunkSize
+	IOChunkSize = 8
+	defer func() { IOChunkSize = oldSize }()
+
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"", "", true},
+		{"exactly8", "exactly8", true},
+		{strings.Repeat("x", 20), strings.Repeat("x", 20), true},
+		{strings.Repeat("x", 20), strings.Repeat("x", 19) + "y", false},
+		{strings.Repeat("x", 16), strings.Repeat("x", 17), false},
+		{"short", "", false},
+	}
+	for _, test := range tests {
+		same, err := sameContent(strings.NewReader(test.a), strings.NewReader(test.b))
+		if err != nil || same != test.same {
+			t.Errorf("sameContent(%q, %q) = %v, %v; want %v", test.a, test.b, same, err, test.same)
+		}
+	}
+}
+
+// countingFS counts how often each file is opened for reading
+type countingFS struct {
+	OSFileSystem
+	opens map[string]int
+}
+
+func (c *countingFS) Open(name string) (File, error) {
+	c.opens[name]++
+	return c.OSFileSystem.Open(name)
+}
+
+func TestCopyFileReadsOnce(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest")
+	os.WriteFile(src, []byte(strings.Repeat("a", 1000)), 0644)
+	os.WriteFile(dest, []byte(strings.Repeat("b", 1000)), 0644)
+
+	fsys := &countingFS{opens: map[string]int{}}
+	module := &AnsibleModule{TmpDir: t.TempDir(), FileSystem: fsys}
+	changed, err := module.CopyFile(src, dest, 0)
+	if err != nil || !changed {
+		t.Fatalf("Expected copy to change dest, got %v (%v)", changed, err)
+	}
+	if fsys.opens[dest] != 1 {
+		t.Errorf("Expected dest to be read once, got %d", fsys.opens[dest])
+	}
+	if fsys.opens[src] != 2 {
+		t.Errorf("Expected src to be read for the comparison and the copy, got %d", fsys.opens[src])
+	}
+}
@@ -0,0 +1,298 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// capabilityNames lists Linux capabilities by bit number
+var capabilityNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner",
+	"cap_fsetid", "cap_kill", "cap_setgid", "cap_setuid",
+	"cap_setpcap", "cap_linux_immutable", "cap_net_bind_service", "cap_net_broadcast",
+	"cap_net_admin", "cap_net_raw", "cap_ipc_lock", "cap_ipc_owner",
+	"cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice",
+	"cap_sys_resource", "cap_sys_time", "cap_sys_tty_config", "cap_mknod",
+	"cap_lease", "cap_audit_write", "cap_audit_control", "cap_setfcap",
+	"cap_mac_override", "cap_mac_admin", "cap_syslog", "cap_wake_alarm",
+	"cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+// Layout of the security.capability extended attribute
+const (
+	vfsCapRevision1      = 0x01000000
+	vfsCapRevision2      = 0x02000000
+	vfsCapRevision3      = 0x03000000
+	vfsCapRevisionMask   = 0xFF000000
+	vfsCapFlagsEffective = 0x000001
+)
+
+// FileCapabilities holds the capability sets attached to a file
+type FileCapabilities struct {
+	Permitted   uint64
+	Inheritable uint64
+	Effective   bool
+}
+
+// ProcessCapabilities holds the capability sets of a running process
+type ProcessCapabilities struct {
+	Inheritable []string
+	Permitted   []string
+	Effective   []string
+	Bounding    []string
+	Ambient     []string
+}
+
+// procPath is the mount point of the proc filesystem
+var procPath = "/proc"
+
+// capabilityBit returns the bit number of a capability name
+func capabilityBit(name string) (int, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "cap_") {
+		name = "cap_" + name
+	}
+	for i, capName := range capabilityNames {
+		if capName == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown capability: %s", name)
+}
+
+// capabilityList converts a capability mask into sorted names
+func capabilityList(mask uint64) []string {
+	names := []string{}
+	for i := 0; i < 64; i++ {
+		if mask&(1<<uint(i)) == 0 {
+			continue
+		}
+		if i < len(capabilityNames) {
+			names = append(names, capabilityNames[i])
+		} else {
+			names = append(names, fmt.Sprintf("cap_%d", i))
+		}
+	}
+	return names
+}
+
+// ParseCapabilities parses capability text such as "cap_net_bind_service+ep"
+func ParseCapabilities(text string) (*FileCapabilities, error) {
+	caps := &FileCapabilities{}
+	for _, clause := range strings.Fields(text) {
+		opIndex := strings.IndexAny(clause, "+=")
+		if opIndex <= 0 {
+			return nil, fmt.Errorf("invalid capability clause: %s", clause)
+		}
+
+		var mask uint64
+		for _, name := range strings.Split(clause[:opIndex], ",") {
+			bit, err := capabilityBit(name)
+			if err != nil {
+				return nil, err
+			}
+			mask |= 1 << uint(bit)
+		}
+
+		for _, flag := range clause[opIndex+1:] {
+			switch flag {
+			case 'e':
+				caps.Effective = true
+			case 'p':
+				caps.Permitted |= mask
+			case 'i':
+				caps.Inheritable |= mask
+			default:
+				return nil, fmt.Errorf("invalid capability flag %q in %s", flag, clause)
+			}
+		}
+	}
+	return caps, nil
+}
+
+// String formats the capabilities the way getcap does
+func (c *FileCapabilities) String() string {
+	groups := make(map[string][]string)
+	for _, name := range capabilityList(c.Permitted | c.Inheritable) {
+		bit, _ := capabilityBit(name)
+		flags := ""
+		if c.Effective {
+			flags += "e"
+		}
+		if c.Inheritable&(1<<uint(bit)) != 0 {
+			flags += "i"
+		}
+		if c.Permitted&(1<<uint(bit)) != 0 {
+			flags += "p"
+		}
+		groups[flags] = append(groups[flags], name)
+	}
+
+	clauses := make([]string, 0, len(groups))
+	for flags, names := range groups {
+		clauses = append(clauses, strings.Join(names, ",")+"="+flags)
+	}
+	sort.Strings(clauses)
+	return strings.Join(clauses, " ")
+}
+
+// Equal reports whether two capability sets are identical
+func (c *FileCapabilities) Equal(other *FileCapabilities) bool {
+	if c == nil || other == nil {
+		return c.empty() && other.empty()
+	}
+	return c.Permitted == other.Permitted && c.Inheritable == other.Inheritable &&
+		c.Effective == other.Effective
+}
+
+// empty reports whether no capabilities are set
+func (c *FileCapabilities) empty() bool {
+	return c == nil || (c.Permitted == 0 && c.Inheritable == 0)
+}
+
+// encode serializes capabilities in the revision 2 xattr format
+func (c *FileCapabilities) encode() []byte {
+	data := make([]byte, 20)
+	magic := uint32(vfsCapRevision2)
+	if c.Effective {
+		magic |= vfsCapFlagsEffective
+	}
+	binary.LittleEndian.PutUint32(data[0:], magic)
+	binary.LittleEndian.PutUint32(data[4:], uint32(c.Permitted))
+	binary.LittleEndian.PutUint32(data[8:], uint32(c.Inheritable))
+	binary.LittleEndian.PutUint32(data[12:], uint32(c.Permitted>>32))
+	binary.LittleEndian.PutUint32(data[16:], uint32(c.Inheritable>>32))
+	return data
+}
+
+// decodeFileCapabilities parses the security.capability xattr
+func decodeFileCapabilities(data []byte) (*FileCapabilities, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("capability data too short")
+	}
+	magic := binary.LittleEndian.Uint32(data[0:])
+	caps := &FileCapabilities{Effective: magic&vfsCapFlagsEffective != 0}
+
+	switch magic & vfsCapRevisionMask {
+	case vfsCapRevision1:
+		if len(data) < 12 {
+			return nil, fmt.Errorf("capability data too short")
+		}
+		caps.Permitted = uint64(binary.LittleEndian.Uint32(data[4:]))
+		caps.Inheritable = uint64(binary.LittleEndian.Uint32(data[8:]))
+	case vfsCapRevision2, vfsCapRevision3:
+		if len(data) < 20 {
+			return nil, fmt.Errorf("capability data too short")
+		}
+		caps.Permitted = uint64(binary.LittleEndian.Uint32(data[4:])) |
+			uint64(binary.LittleEndian.Uint32(data[12:]))<<32
+		caps.Inheritable = uint64(binary.LittleEndian.Uint32(data[8:])) |
+			uint64(binary.LittleEndian.Uint32(data[16:]))<<32
+	default:
+		return nil, fmt.Errorf("unsupported capability revision 0x%x", magic&vfsCapRevisionMask)
+	}
+	return caps, nil
+}
+
+// GetFileCapabilities returns the capabilities of a file, or nil if it has none
+func (m *AnsibleModule) GetFileCapabilities(path string) (*FileCapabilities, error) {
+	data, err := getCapabilityXattr(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capabilities of %s: %w", path, err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	return decodeFileCapabilities(data)
+}
+
+// SetFileCapabilities sets file capabilities from text, removing them if text is empty
+func (m *AnsibleModule) SetFileCapabilities(path, text string) (bool, error) {
+	desired, err := ParseCapabilities(text)
+	if err != nil {
+		return false, err
+	}
+
+	current, err := m.GetFileCapabilities(path)
+	if err != nil {
+		return false, err
+	}
+	if desired.Equal(current) {
+		return false, nil
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+
+	if desired.empty() {
+		err = removeCapabilityXattr(path)
+	} else {
+		err = setCapabilityXattr(path, desired.encode())
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to set capabilities on %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// ProcessCapabilities returns the capability sets of a process, or the current process if pid is 0
+func (m *AnsibleModule) ProcessCapabilities(pid int) (*ProcessCapabilities, error) {
+	proc := "self"
+	if pid > 0 {
+		proc = strconv.Itoa(pid)
+	}
+
+	file, err := os.Open(filepath.Join(procPath, proc, "status"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process status: %w", err)
+	}
+	defer file.Close()
+
+	caps := &ProcessCapabilities{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found || !strings.HasPrefix(key, "Cap") {
+			continue
+		}
+		mask, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", key, err)
+		}
+		switch key {
+		case "CapInh":
+			caps.Inheritable = capabilityList(mask)
+		case "CapPrm":
+			caps.Permitted = capabilityList(mask)
+		case "CapEff":
+			caps.Effective = capabilityList(mask)
+		case "CapBnd":
+			caps.Bounding = capabilityList(mask)
+		case "CapAmb":
+			caps.Ambient = capabilityList(mask)
+		}
+	}
+
+	return caps, scanner.Err()
+}
+
+// HasCapability checks if the capability is in the effective set
+func (c *ProcessCapabilities) HasCapability(name string) bool {
+	if !strings.HasPrefix(name, "cap_") {
+		name = "cap_" + name
+	}
+	for _, capName := range c.Effective {
+		if capName == strings.ToLower(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// capabilityXattr is the extended attribute holding file capabilities
+const capabilityXattr = "security.capability"
+
+// getCapabilityXattr reads the capability attribute, returning nil if unset
+func getCapabilityXattr(path string) ([]byte, error) {
+	buf := make([]byte, 64)
+	n, err := syscall.Getxattr(path, capabilityXattr, buf)
+	if err != nil {
+		if err == syscall.ENODATA {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// setCapabilityXattr writes the capability attribute
+func setCapabilityXattr(path string, data []byte) error {
+	return syscall.Setxattr(path, capabilityXattr, data, 0)
+}
+
+// removeCapabilityXattr removes the capability attribute
+func removeCapabilityXattr(path string) error {
+	if err := syscall.Removexattr(path, capabilityXattr); err != nil && err != syscall.ENODATA {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package ansiblemodule
+
+import (
+	"fmt"
+)
+
+// errCapabilitiesUnsupported is returned where file capabilities do not exist
+var errCapabilitiesUnsupported = fmt.Errorf("file capabilities are only supported on Linux")
+
+// getCapabilityXattr reports that no capabilities are set
+func getCapabilityXattr(path string) ([]byte, error) {
+	return nil, nil
+}
+
+// setCapabilityXattr is not supported on this platform
+func setCapabilityXattr(path string, data []byte) error {
+	return errCapabilitiesUnsupported
+}
+
+// removeCapabilityXattr is not supported on this platform
+func removeCapabilityXattr(path string) error {
+	return errCapabilitiesUnsupported
+}
@@ -0,0 +1,120 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := ParseCapabilities("cap_net_bind_service,cap_net_raw+ep cap_sys_admin=i")
+	if err != nil {
+		t.Fatalf("Failed to parse capabilities: %v", err)
+	}
+	if !caps.Effective {
+		t.Error("Expected effective flag to be set")
+	}
+	if caps.Permitted != (1<<10 | 1<<13) {
+		t.Errorf("Unexpected permitted mask %x", caps.Permitted)
+	}
+	if caps.Inheritable != 1<<21 {
+		t.Errorf("Unexpected inheritable mask %x", caps.Inheritable)
+	}
+	if caps.String() != "cap_net_bind_service,cap_net_raw=ep cap_sys_admin=ei" {
+		t.Errorf("Unexpected string form '%s'", caps.String())
+	}
+
+	// Test xattr round trip
+	decoded, err := decodeFileCapabilities(caps.encode())
+	if err != nil {
+		t.Fatalf("Failed to decode capabilities: %v", err)
+	}
+	if !decoded.Equal(caps) {
+		t.Errorf("Expected %v after round trip, got %v", caps, decoded)
+	}
+
+	// Test invalid input
+	for _, text := range []string{"cap_bogus+ep", "cap_chown+x", "+ep"} {
+		if _, err := ParseCapabilities(text); err == nil {
+			t.Errorf("Expected error for %s", text)
+		}
+	}
+}
+
+func TestFileCapabilities(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpFile, err := os.CreateTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	caps, err := module.GetFileCapabilities(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to get capabilities: %v", err)
+	}
+	if caps != nil {
+		t.Errorf("Expected no capabilities, got %v", caps)
+	}
+
+	// Test check mode reports a change without applying it
+	module.CheckMode = true
+	changed, err := module.SetFileCapabilities(tmpFile.Name(), "cap_net_bind_service+ep")
+	if err != nil || !changed {
+		t.Fatalf("Expected change in check mode, got %v (%v)", changed, err)
+	}
+	module.CheckMode = false
+
+	changed, err = module.SetFileCapabilities(tmpFile.Name(), "cap_net_bind_service+ep")
+	if err != nil {
+		t.Skipf("Setting capabilities not permitted here: %v", err)
+	}
+	if !changed {
+		t.Error("Expected capabilities to change")
+	}
+
+	changed, err = module.SetFileCapabilities(tmpFile.Name(), "cap_net_bind_service=ep")
+	if err != nil || changed {
+		t.Errorf("Expected no change for identical capabilities, got %v (%v)", changed, err)
+	}
+
+	changed, err = module.SetFileCapabilities(tmpFile.Name(), "")
+	if err != nil || !changed {
+		t.Errorf("Expected capabilities to be removed, got %v (%v)", changed, err)
+	}
+}
+
+func TestProcessCapabilities(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldProc := procPath
+	procPath = tmpDir
+	defer func() { procPath = oldProc }()
+
+	os.MkdirAll(filepath.Join(tmpDir, "42"), 0755)
+	status := "Name:\ttest\nCapInh:\t0000000000000000\nCapPrm:\t0000000000000400\nCapEff:\t0000000000000400\nCapBnd:\t000001ffffffffff\nCapAmb:\t0000000000000000\n"
+	os.WriteFile(filepath.Join(tmpDir, "42", "status"), []byte(status), 0644)
+
+	caps, err := module.ProcessCapabilities(42)
+	if err != nil {
+		t.Fatalf("Failed to get process capabilities: %v", err)
+	}
+	if len(caps.Effective) != 1 || !caps.HasCapability("net_bind_service") {
+		t.Errorf("Unexpected effective capabilities: %v", caps.Effective)
+	}
+	if len(caps.Bounding) != 41 {
+		t.Errorf("Expected 41 bounding capabilities, got %d", len(caps.Bounding))
+	}
+
+	if _, err := module.ProcessCapabilities(43); err == nil {
+		t.Error("Expected error for missing process")
+	}
+}
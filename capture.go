@@ -0,0 +1,209 @@
+package ansiblemodule
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CommandOptions controls how RunCommandWithOptions runs a command
+type CommandOptions struct {
+	Environment map[string]string // Extra environment variables
+	Data        string            // Written to the command's stdin
+	OutputLimit int               // Keep only the last OutputLimit bytes of stdout and stderr, 0 uses the module OutputLimit
+	Record      bool              // Add the command to the result under "commands", as the module RecordCommands does
+	Timeout     time.Duration     // Kill the command if it runs longer, 0 for no limit
+	// Creates skips the command when a path matching this glob exists, and
+	// Removes when none does, like the options of the command module. In check
+	// mode a guarded command that would run is reported as changed but not run.
+	Creates string
+	Removes string
+}
+
+// commandRecordVerbosity is the verbosity from which recorded commands are returned
+const commandRecordVerbosity = 1
+
+// outputLimitKey carries the output limit to the command runner
+type outputLimitKey struct{}
+
+// WithOutputLimit returns a context asking the command runner to keep only the
+// last limit bytes of output. Custom runners can read it with OutputLimitFrom.
+func WithOutputLimit(ctx context.Context, limit int) context.Context {
+	return context.WithValue(ctx, outputLimitKey{}, limit)
+}
+
+// OutputLimitFrom returns the output limit requested for a command, 0 for no limit
+func OutputLimitFrom(ctx context.Context) int {
+	limit, _ := ctx.Value(outputLimitKey{}).(int)
+	return limit
+}
+
+// tailBuffer is a writer keeping the last limit bytes written and the total count
+type tailBuffer struct {
+	limit int
+	data  []byte
+	start int // Index of the oldest byte once data is full
+	total int64
+}
+
+// newTailBuffer returns a buffer keeping the last limit bytes, or everything if limit is not positive
+func newTailBuffer(limit int) *tailBuffer {
+	return &tailBuffer{limit: limit}
+}
+
+// Write keeps the end of p, overwriting the oldest bytes once the buffer is full
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+	if b.limit <= 0 {
+		b.data = append(b.data, p...)
+		return len(p), nil
+	}
+	written := len(p)
+	if len(p) >= b.limit {
+		b.data = append(b.data[:0], p[len(p)-b.limit:]...)
+		b.start = 0
+		return written, nil
+	}
+	if room := b.limit - len(b.data); room > 0 {
+		n := min(room, len(p))
+		b.data = append(b.data, p[:n]...)
+		p = p[n:]
+	}
+	for len(p) > 0 {
+		n := copy(b.data[b.start:], p)
+		p = p[n:]
+		b.start = (b.start + n) % b.limit
+	}
+	return written, nil
+}
+
+// String returns the retained output in order. A character cut by the limit is dropped.
+func (b *tailBuffer) String() string {
+	ordered := append(append([]byte{}, b.data[b.start:]...), b.data[:b.start]...)
+	if b.truncated() {
+		for len(ordered) > 0 && !utf8.RuneStart(ordered[0]) {
+			ordered = ordered[1:]
+		}
+	}
+	return string(ordered)
+}
+
+// truncated reports whether output was dropped
+func (b *tailBuffer) truncated() bool {
+	return b.total > int64(len(b.data))
+}
+
+// tailString keeps the last limit bytes of s, for runners that ignore the limit
+func tailString(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	buffer := newTailBuffer(limit)
+	buffer.Write([]byte(s))
+	return buffer.String()
+}
+
+// limitOutput fills in the byte counts and applies the limit to output from
+// runners that do not honour OutputLimitFrom
+func (r *CommandResult) limitOutput(limit int) {
+	if r.StdoutBytes == 0 {
+		r.StdoutBytes = int64(len(r.Stdout))
+	}
+	if r.StderrBytes == 0 {
+		r.StderrBytes = int64(len(r.Stderr))
+	}
+	r.Stdout = tailString(r.Stdout, limit)
+	r.Stderr = tailString(r.Stderr, limit)
+}
+
+// commandGuard returns why a command is skipped by its Creates and Removes
+// guards, or an empty string if it should run, and whether a skipped command
+// counts as a change because it only did not run in check mode
+func (m *AnsibleModule) commandGuard(opts CommandOptions) (string, bool, error) {
+	if opts.Creates != "" {
+		matches, err := filepath.Glob(opts.Creates)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid creates pattern %s: %w", opts.Creates, err)
+		}
+		if len(matches) > 0 {
+			return fmt.Sprintf("Did not run command since '%s' exists", opts.Creates), false, nil
+		}
+	}
+	if opts.Removes != "" {
+		matches, err := filepath.Glob(opts.Removes)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid removes pattern %s: %w", opts.Removes, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Sprintf("Did not run command since '%s' does not exist", opts.Removes), false, nil
+		}
+	}
+	if m.CheckMode && (opts.Creates != "" || opts.Removes != "") {
+		return "Command would have run if not in check mode", true, nil
+	}
+	return "", false, nil
+}
+
+// commandTimeLayout is how the command module reports start and end times
+const commandTimeLayout = "2006-01-02 15:04:05.000000"
+
+// formatCommandDelta formats a duration like a Python timedelta, as the delta
+// of the command module
+func formatCommandDelta(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	hours := d / time.Hour
+	minutes := d % time.Hour / time.Minute
+	seconds := d % time.Minute / time.Second
+	micros := d % time.Second / time.Microsecond
+	return fmt.Sprintf("%d:%02d:%02d.%06d", hours, minutes, seconds, micros)
+}
+
+// commandLines splits output into lines like Python splitlines
+func commandLines(output string) []string {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// ToResultFields returns the result keys the command module returns for a
+// command: changed, cmd, rc, stdout, stderr, their _lines variants, start, end
+// and delta, or skipped and msg for a command skipped by its guards
+func (r CommandResult) ToResultFields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"changed":      r.Changed,
+		"cmd":          append([]string{r.Cmd}, r.Args...),
+		"rc":           r.Rc,
+		"stdout":       strings.TrimSuffix(r.Stdout, "\n"),
+		"stderr":       strings.TrimSuffix(r.Stderr, "\n"),
+		"stdout_lines": commandLines(r.Stdout),
+		"stderr_lines": commandLines(r.Stderr),
+	}
+	if r.Skipped {
+		fields["skipped"] = true
+		fields["msg"] = r.Msg
+	}
+	if !r.Start.IsZero() {
+		fields["start"] = r.Start.Format(commandTimeLayout)
+		fields["end"] = r.End.Format(commandTimeLayout)
+		fields["delta"] = formatCommandDelta(r.Delta)
+	}
+	return fields
+}
+
+// recordCommand keeps a summary of a command for the module results
+func (m *AnsibleModule) recordCommand(result CommandResult) {
+	fields := result.ToResultFields()
+	for _, key := range []string{"changed", "stdout", "stderr", "stdout_lines", "stderr_lines"} {
+		delete(fields, key)
+	}
+	m.partialMu.Lock()
+	defer m.partialMu.Unlock()
+	m.commands = append(m.commands, fields)
+}
@@ -0,0 +1,169 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailBuffer(t *testing.T) {
+	buffer := newTailBuffer(10)
+	for _, chunk := range []string{"abc", "defgh", "ijklmno", "p"} {
+		buffer.Write([]byte(chunk))
+	}
+	if got := buffer.String(); got != "ghijklmnop" {
+		t.Errorf("Expected last 10 bytes, got %q", got)
+	}
+	if buffer.total != 16 || !buffer.truncated() {
+		t.Errorf("Expected 16 bytes in total, got %d", buffer.total)
+	}
+
+	buffer = newTailBuffer(4)
+	buffer.Write([]byte("a large write"))
+	if got := buffer.String(); got != "rite" {
+		t.Errorf("Expected end of large write, got %q", got)
+	}
+
+	// A character cut in half by the limit is dropped
+	buffer = newTailBuffer(5)
+	buffer.Write([]byte("xxéabcd"))
+	if got := buffer.String(); got != "abcd" {
+		t.Errorf("Expected partial character dropped, got %q", got)
+	}
+
+	unlimited := newTailBuffer(0)
+	unlimited.Write([]byte("everything"))
+	if unlimited.String() != "everything" || unlimited.truncated() {
+		t.Errorf("Expected unlimited buffer to keep everything, got %q", unlimited.String())
+	}
+}
+
+func TestRunCommandOutputLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires sh")
+	}
+	module := &AnsibleModule{OutputLimit: 100}
+	result, err := module.RunCommand("sh", []string{"-c", "yes line | head -n 10000; echo tail"}, nil, "")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if len(result.Stdout) != 100 || !strings.HasSuffix(result.Stdout, "line\ntail\n") {
+		t.Errorf("Expected last 100 bytes, got %d: %q", len(result.Stdout), result.Stdout)
+	}
+	if result.StdoutBytes != 50005 {
+		t.Errorf("Expected 50005 bytes in total, got %d", result.StdoutBytes)
+	}
+
+	// A per-command limit overrides the module limit
+	result, err = module.RunCommandWithOptions("sh", []string{"-c", "echo 0123456789"}, CommandOptions{OutputLimit: 4})
+	if err != nil || result.Stdout != "789\n" || result.StdoutBytes != 11 {
+		t.Errorf("Expected per-command limit, got %q (%d bytes, %v)", result.Stdout, result.StdoutBytes, err)
+	}
+}
+
+func TestOutputLimitCustomRunner(t *testing.T) {
+	runner := &stubRunner{result: CommandResult{Stdout: strings.Repeat("x", 50) + "end", Stderr: "err"}}
+	module := &AnsibleModule{Runner: runner}
+	result, err := module.RunCommandWithOptions("tool", nil, CommandOptions{OutputLimit: 5})
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if result.Stdout != "xxend" || result.StdoutBytes != 53 || result.Stderr != "err" || result.StderrBytes != 3 {
+		t.Errorf("Expected output limited after the runner, got %+v", result)
+	}
+}
+
+func TestCommandResultFields(t *testing.T) {
+	start := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	result := CommandResult{Cmd: "/usr/bin/tool", Args: []string{"--apply"}, Stdout: "one\r\ntwo\n", Rc: 3,
+		Start: start, End: start.Add(time.Hour + 2*time.Second + 1500*time.Microsecond),
+		Delta: time.Hour + 2*time.Second + 1500*time.Microsecond, Changed: true}
+	fields := result.ToResultFields()
+	expected := map[string]interface{}{
+		"changed": true, "cmd": []string{"/usr/bin/tool", "--apply"}, "rc": 3, "stdout": "one\r\ntwo", "stderr": "",
+		"stdout_lines": []string{"one", "two"}, "stderr_lines": []string{},
+		"start": "2024-01-31 12:00:00.000000", "end": "2024-01-31 13:00:02.001500", "delta": "1:00:02.001500",
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Unexpected result fields:\n%v\n%v", fields, expected)
+	}
+}
+
+func TestRecordCommands(t *testing.T) {
+	var output bytes.Buffer
+	module := &AnsibleModule{Runner: &stubRunner{}, Output: &output, ExitFunc: func(int) {}}
+	module.RunCommand("quiet", nil, nil, "")
+	module.RunCommandWithOptions("tool", []string{"--apply"}, CommandOptions{Record: true})
+	module.RecordCommands = true
+	module.RunCommand("other", nil, nil, "")
+
+	module.Verbosity = 1
+	module.ExitJson(map[string]interface{}{"changed": true})
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	commands, _ := parsed["commands"].([]interface{})
+	if len(commands) != 2 {
+		t.Fatalf("Expected 2 recorded commands, got %v", parsed["commands"])
+	}
+	first := commands[0].(map[string]interface{})
+	if !reflect.DeepEqual(first["cmd"], []interface{}{"tool", "--apply"}) || first["delta"] == nil || first["stdout"] != nil {
+		t.Errorf("Unexpected recorded command: %v", first)
+	}
+}
+
+func TestRunCommandTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires sleep")
+	}
+	module := &AnsibleModule{}
+	result, err := module.RunCommandWithOptions("sleep", []string{"5"}, CommandOptions{Timeout: 100 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") || result.Rc != -1 {
+		t.Errorf("Expected the command to time out, got rc %d (%v)", result.Rc, err)
+	}
+	if result.Delta < 100*time.Millisecond || result.Delta > 4*time.Second {
+		t.Errorf("Expected the delta to reflect the timeout, got %v", result.Delta)
+	}
+
+	result, err = module.RunCommandWithOptions("sleep", []string{"0"}, CommandOptions{Timeout: 5 * time.Second})
+	if err != nil || result.Delta <= 0 || !result.End.After(result.Start) {
+		t.Errorf("Expected timing of a completed command, got %+v (%v)", result, err)
+	}
+}
+
+func TestCommandGuards(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app-1.2.tar.gz"), nil, 0644)
+	runner := &stubRunner{}
+	module := &AnsibleModule{Runner: runner}
+
+	result, err := module.RunCommandWithOptions("extract", nil, CommandOptions{Creates: filepath.Join(dir, "app-*.tar.gz")})
+	if err != nil || !result.Skipped || result.Changed || runner.argv != nil {
+		t.Errorf("Expected creates to skip the command, got %+v (%v)", result, err)
+	}
+	if fields := result.ToResultFields(); fields["skipped"] != true || !strings.Contains(fields["msg"].(string), "exists") {
+		t.Errorf("Expected skipped result fields, got %v", fields)
+	}
+	result, _ = module.RunCommandWithOptions("cleanup", nil, CommandOptions{Removes: filepath.Join(dir, "*.tmp")})
+	if !result.Skipped || result.Changed || runner.argv != nil {
+		t.Errorf("Expected removes to skip the command, got %+v", result)
+	}
+
+	module.CheckMode = true
+	result, _ = module.RunCommandWithOptions("cleanup", nil, CommandOptions{Removes: filepath.Join(dir, "*.tar.gz")})
+	if !result.Skipped || !result.Changed || runner.argv != nil {
+		t.Errorf("Expected check mode to report the change without running, got %+v", result)
+	}
+	module.CheckMode = false
+	result, err = module.RunCommandWithOptions("cleanup", nil, CommandOptions{Removes: filepath.Join(dir, "*.tar.gz")})
+	if err != nil || result.Skipped || !result.Changed || runner.argv == nil {
+		t.Errorf("Expected the command to run, got %+v (%v)", result, err)
+	}
+}
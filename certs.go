@@ -0,0 +1,173 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CertificateInfo describes a certificate in a form suited to module results
+type CertificateInfo struct {
+	Subject           string    `json:"subject"`
+	Issuer            string    `json:"issuer"`
+	SerialNumber      string    `json:"serial_number"`
+	DNSNames          []string  `json:"subject_alt_name_dns"`
+	IPAddresses       []string  `json:"subject_alt_name_ip"`
+	EmailAddresses    []string  `json:"subject_alt_name_email"`
+	NotBefore         time.Time `json:"not_before"`
+	NotAfter          time.Time `json:"not_after"`
+	IsCA              bool      `json:"is_ca"`
+	PublicKeyType     string    `json:"public_key_type"`
+	SignatureType     string    `json:"signature_algorithm"`
+	FingerprintSHA1   string    `json:"fingerprint_sha1"`
+	FingerprintSHA256 string    `json:"fingerprint_sha256"`
+}
+
+// ParseCertificates parses every certificate in PEM data, or a single DER certificate
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		cert, err := x509.ParseCertificate(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DER certificate: %w", err)
+		}
+		return []*x509.Certificate{cert}, nil
+	}
+
+	var certs []*x509.Certificate
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" && block.Type != "TRUSTED CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate %d: %w", len(certs)+1, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found")
+	}
+	return certs, nil
+}
+
+// ParsePrivateKey parses a PEM or DER private key in PKCS#1, PKCS#8 or SEC 1
+// form. Encrypted keys are not supported.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	der := data
+	if block, _ := pem.Decode(data); block != nil {
+		if strings.Contains(block.Type, "ENCRYPTED") || block.Headers["Proc-Type"] != "" {
+			return nil, fmt.Errorf("encrypted private keys are not supported")
+		}
+		der = block.Bytes
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("failed to parse private key")
+}
+
+// ReadCertificates reads and parses the certificates in a PEM or DER file
+func (m *AnsibleModule) ReadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := m.fs().ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return certs, nil
+}
+
+// ReadPrivateKey reads and parses a PEM or DER private key file
+func (m *AnsibleModule) ReadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := m.fs().ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	key, err := ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// InspectCertificate returns the details of a certificate
+func InspectCertificate(cert *x509.Certificate) CertificateInfo {
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+	info := CertificateInfo{
+		Subject:           cert.Subject.String(),
+		Issuer:            cert.Issuer.String(),
+		SerialNumber:      cert.SerialNumber.String(),
+		DNSNames:          cert.DNSNames,
+		EmailAddresses:    cert.EmailAddresses,
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+		IsCA:              cert.IsCA,
+		PublicKeyType:     publicKeyType(cert.PublicKey),
+		SignatureType:     cert.SignatureAlgorithm.String(),
+		FingerprintSHA1:   hex.EncodeToString(sha1Sum[:]),
+		FingerprintSHA256: hex.EncodeToString(sha256Sum[:]),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info
+}
+
+// publicKeyType names the algorithm and size of a public key
+func publicKeyType(key crypto.PublicKey) string {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return fmt.Sprintf("%T", key)
+}
+
+// CertificateExpiresWithin reports whether a certificate is expired or expires
+// within the given duration, as in "renew if expiring within 30 days"
+func CertificateExpiresWithin(cert *x509.Certificate, within time.Duration) bool {
+	return !time.Now().Add(within).Before(cert.NotAfter)
+}
+
+// CertificateMatchesHost reports whether a certificate is valid for a host name or IP address
+func CertificateMatchesHost(cert *x509.Certificate, host string) bool {
+	return cert.VerifyHostname(host) == nil
+}
+
+// KeyMatchesCertificate reports whether a private key belongs to a certificate,
+// the equivalent of comparing their openssl moduli
+func KeyMatchesCertificate(cert *x509.Certificate, key crypto.Signer) bool {
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && public.Equal(cert.PublicKey)
+}
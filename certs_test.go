@@ -0,0 +1,115 @@
+package ansiblemodule
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCertificate creates a self-signed certificate and returns it with its
+// key, both PEM encoded
+func newTestCertificate(t *testing.T, name string, validFor time.Duration) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validFor),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestInspectCertificate(t *testing.T) {
+	certPEM, keyPEM := newTestCertificate(t, "web.example.com", 10*24*time.Hour)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "cert.pem"), append([]byte("comment\n"), certPEM...), 0644)
+	os.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0600)
+	module := &AnsibleModule{}
+
+	certs, err := module.ReadCertificates(filepath.Join(dir, "cert.pem"))
+	if err != nil || len(certs) != 1 {
+		t.Fatalf("Expected one certificate, got %d (%v)", len(certs), err)
+	}
+	info := InspectCertificate(certs[0])
+	if info.Subject != "CN=web.example.com" || info.PublicKeyType != "ECDSA-P-256" || len(info.FingerprintSHA256) != 64 {
+		t.Errorf("Unexpected certificate info: %+v", info)
+	}
+	if len(info.IPAddresses) != 1 || info.IPAddresses[0] != "127.0.0.1" {
+		t.Errorf("Expected the IP SAN, got %v", info.IPAddresses)
+	}
+	if !CertificateMatchesHost(certs[0], "web.example.com") || CertificateMatchesHost(certs[0], "other.example.com") {
+		t.Error("Unexpected host match")
+	}
+	if !CertificateExpiresWithin(certs[0], 30*24*time.Hour) || CertificateExpiresWithin(certs[0], 24*time.Hour) {
+		t.Error("Unexpected expiry check")
+	}
+
+	key, err := module.ReadPrivateKey(filepath.Join(dir, "key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !KeyMatchesCertificate(certs[0], key) {
+		t.Error("Expected key to match its certificate")
+	}
+	other, _ := rsa.GenerateKey(rand.Reader, 1024)
+	if KeyMatchesCertificate(certs[0], other) {
+		t.Error("Expected another key not to match")
+	}
+}
+
+func TestParseCertificatesFormats(t *testing.T) {
+	first, _ := newTestCertificate(t, "a.example.com", time.Hour)
+	second, _ := newTestCertificate(t, "b.example.com", time.Hour)
+	certs, err := ParseCertificates(append(first, second...))
+	if err != nil || len(certs) != 2 {
+		t.Fatalf("Expected a bundle of two certificates, got %d (%v)", len(certs), err)
+	}
+
+	block, _ := pem.Decode(first)
+	certs, err = ParseCertificates(block.Bytes)
+	if err != nil || certs[0].Subject.CommonName != "a.example.com" {
+		t.Errorf("Expected DER certificate to parse: %v", err)
+	}
+	if _, err := ParseCertificates([]byte("-----BEGIN NOTHING-----\n-----END NOTHING-----\n")); err == nil {
+		t.Error("Expected PEM without certificates to fail")
+	}
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	if key, err := ParsePrivateKey(pkcs1); err != nil || !rsaKey.PublicKey.Equal(key.Public()) {
+		t.Errorf("Expected PKCS#1 key to parse: %v", err)
+	}
+	encrypted := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte{1}})
+	if _, err := ParsePrivateKey(encrypted); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("Expected encrypted key to be rejected: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package ansiblemodule
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"hash/fnv"
+)
+
+// hashAlgorithms maps the checksum algorithm names accepted by Ansible, and a
+// few more for comparisons, to their constructors
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":      md5.New,
+	"sha1":     sha1.New,
+	"sha224":   sha256.New224,
+	"sha256":   sha256.New,
+	"sha384":   sha512.New384,
+	"sha512":   sha512.New,
+	"sha3-256": func() hash.Hash { return sha3.New256() },
+	"sha3-512": func() hash.Hash { return sha3.New512() },
+	"fnv128a":  fnv.New128a,
+}
+
+// RegisterHashAlgorithm makes a hash available to Checksum and file comparisons
+// by name, for algorithms outside the standard library such as BLAKE2 or xxHash.
+// It must be called before the module starts work, typically from init.
+func RegisterHashAlgorithm(name string, constructor func() hash.Hash) {
+	hashAlgorithms[name] = constructor
+}
+
+// newHash returns a hash for an algorithm name such as sha256
+func newHash(algorithm string) (hash.Hash, error) {
+	constructor, ok := hashAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported checksum algorithm %s", algorithm)
+	}
+	return constructor(), nil
+}
+
+// Checksum returns the hex digest of a file using the named algorithm
+func (m *AnsibleModule) Checksum(path, algorithm string) (string, error) {
+	hash, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	file, err := m.fs().Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := copyChunked(hash, m.contextReader(file)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// ChecksumAll hashes many files concurrently with at most workers goroutines
+// (the number of CPUs if workers is not positive). It returns the digest of each
+// file that could be read and the error for each file that could not.
+func (m *AnsibleModule) ChecksumAll(paths []string, algorithm string, workers int) (map[string]string, map[string]error) {
+	digests := make(map[string]string, len(paths))
+	errors := make(map[string]error)
+	if _, err := newHash(algorithm); err != nil {
+		for _, path := range paths {
+			errors[path] = err
+		}
+		return digests, errors
+	}
+
+	sums := make([]string, len(paths))
+	result := RunParallel(m, paths, workers, func(ctx context.Context, task *ParallelTask, path string) (bool, error) {
+		var err error
+		sums[task.Index], err = m.Checksum(path, algorithm)
+		return false, err
+	})
+	for i, path := range paths {
+		if err := result.Errors[i]; err != nil {
+			errors[path] = err
+		} else {
+			digests[path] = sums[i]
+		}
+	}
+	return digests, errors
+}
+
+// sameDigest compares two files by their digests, hashing both concurrently
+func (m *AnsibleModule) sameDigest(a, b, algorithm string) (bool, error) {
+	digests, errors := m.ChecksumAll([]string{a, b}, algorithm, 2)
+	for _, path := range []string{a, b} {
+		if err := errors[path]; err != nil {
+			return false, err
+		}
+	}
+	return digests[a] == digests[b], nil
+}
@@ -0,0 +1,95 @@
+package ansiblemodule
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(path, []byte("hello\n"), 0644)
+	module := &AnsibleModule{}
+
+	expected := map[string]string{
+		"md5":    "b1946ac92492d2347c6235b4d2611184",
+		"sha1":   "f572d396fae9206628714fb2ce00f72e94f2258f",
+		"sha256": "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+	}
+	for algorithm, digest := range expected {
+		if got, err := module.Checksum(path, algorithm); err != nil || got != digest {
+			t.Errorf("%s: expected %s, got %s (%v)", algorithm, digest, got, err)
+		}
+	}
+	if _, err := module.Checksum(path, "crc32"); err == nil {
+		t.Error("Expected error for unsupported algorithm")
+	}
+}
+
+func TestChecksumAll(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("file%d", i))
+		os.WriteFile(path, []byte(fmt.Sprintf("content %d", i)), 0644)
+		paths = append(paths, path)
+	}
+	missing := filepath.Join(dir, "missing")
+	paths = append(paths, missing)
+
+	module := &AnsibleModule{}
+	digests, errs := module.ChecksumAll(paths, "sha256", 4)
+	if len(digests) != 20 || len(errs) != 1 || errs[missing] == nil {
+		t.Fatalf("Expected 20 digests and 1 error, got %d and %v", len(digests), errs)
+	}
+	for _, path := range paths[:20] {
+		if expected, _ := module.Checksum(path, "sha256"); digests[path] != expected {
+			t.Errorf("Wrong digest for %s", path)
+		}
+	}
+
+	if _, errs := module.ChecksumAll(paths[:2], "nope", 0); len(errs) != 2 {
+		t.Errorf("Expected every path to fail for an unknown algorithm, got %v", errs)
+	}
+}
+
+func TestCompareFilesWithHash(t *testing.T) {
+	dir := t.TempDir()
+	a, b, c := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")
+	os.WriteFile(a, []byte("same content"), 0644)
+	os.WriteFile(b, []byte("same content"), 0644)
+	os.WriteFile(c, []byte("other things"), 0644)
+
+	var hashed atomic.Int32
+	RegisterHashAlgorithm("counting-sha256", func() hash.Hash {
+		hashed.Add(1)
+		return sha256.New()
+	})
+	defer delete(hashAlgorithms, "counting-sha256")
+
+	module := &AnsibleModule{ComparisonHash: "counting-sha256"}
+	if same, err := module.CompareFiles(a, b); err != nil || !same {
+		t.Errorf("Expected equal files, got %v (%v)", same, err)
+	}
+	if same, err := module.CompareFiles(a, c); err != nil || same {
+		t.Errorf("Expected different files, got %v (%v)", same, err)
+	}
+	if hashed.Load() == 0 {
+		t.Errorf("Expected the module comparison hash to be used")
+	}
+
+	if same, err := module.CompareFilesWith(a, b, "sha3-256"); err != nil || !same {
+		t.Errorf("Expected per-call algorithm to compare equal, got %v (%v)", same, err)
+	}
+	if _, err := module.AtomicMoveWith(a, b, "crc1"); err == nil {
+		t.Error("Expected unknown algorithm to fail")
+	}
+	changed, err := module.AtomicMoveWith(a, b, "")
+	if err != nil || changed {
+		t.Errorf("Expected byte comparison to find the files equal, got %v (%v)", changed, err)
+	}
+}
@@ -0,0 +1,74 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CloneFile copies src to dest as cheaply as the filesystem allows. On Linux it
+// first asks for a copy-on-write clone (FICLONE), which shares the extents on
+// btrfs or XFS with reflinks, then lets the kernel copy the data with
+// copy_file_range, and finally copies it through userspace. Elsewhere, including
+// APFS on macOS, the data is copied. dest is replaced atomically and gets the
+// mode of src. The result reports whether a copy-on-write clone was made.
+func (m *AnsibleModule) CloneFile(src, dest string) (bool, error) {
+	// Clones need real files, a custom file system gets a regular copy
+	if m.FileSystem != nil {
+		_, err := m.CopyFile(src, dest, 0)
+		return false, err
+	}
+	defer m.auditFile("copy_file", dest)()
+
+	// reflinkFile needs the descriptors of real files, so src and the staged
+	// copy are opened directly
+	in, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() {
+		return false, fmt.Errorf("%s is not a regular file", src)
+	}
+
+	// The copy is staged next to dest, as clones cannot cross filesystems
+	out, err := os.CreateTemp(filepath.Dir(dest), ".ansible-clone-")
+	if err != nil {
+		return false, err
+	}
+	tmpPath := out.Name()
+	cloned := true
+	if err := reflinkFile(out, in); err != nil {
+		m.writeDebugLog("TRACE", fmt.Sprintf("clone of %s not possible, copying: %v", src, err))
+		cloned = false
+		// os.File.ReadFrom uses copy_file_range where the kernel supports it
+		if _, err := out.ReadFrom(in); err != nil {
+			out.Close()
+			m.fs().Remove(tmpPath)
+			return false, fmt.Errorf("failed to copy %s: %w", src, err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		m.fs().Remove(tmpPath)
+		return false, err
+	}
+	if err := m.fs().Chmod(tmpPath, info.Mode().Perm()); err != nil {
+		m.fs().Remove(tmpPath)
+		return false, err
+	}
+
+	// Never replace the destination once the module has been cancelled
+	if err := m.Context().Err(); err != nil {
+		m.fs().Remove(tmpPath)
+		return false, err
+	}
+	if err := m.fs().Rename(tmpPath, dest); err != nil {
+		m.fs().Remove(tmpPath)
+		return false, fmt.Errorf("failed to replace %s: %w", dest, err)
+	}
+	return cloned, nil
+}
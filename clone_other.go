@@ -0,0 +1,16 @@
+//go:build !linux
+
+package ansiblemodule
+
+import (
+	"errors"
+	"os"
+)
+
+// errReflinkUnsupported is returned where files cannot be cloned
+var errReflinkUnsupported = errors.New("copy-on-write clones are not supported on this platform")
+
+// reflinkFile reports that the content has to be copied
+func reflinkFile(dst, src *os.File) error {
+	return errReflinkUnsupported
+}
@@ -0,0 +1,35 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCloneFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "disk.img")
+	content := bytes.Repeat([]byte("block"), 100000)
+	os.WriteFile(src, content, 0640)
+	dest := filepath.Join(dir, "disk.img.bak")
+	os.WriteFile(dest, []byte("stale"), 0644)
+
+	module := &AnsibleModule{}
+	if _, err := module.CloneFile(src, dest); err != nil {
+		t.Fatal(err)
+	}
+	if copied, _ := os.ReadFile(dest); !bytes.Equal(copied, content) {
+		t.Errorf("Expected the clone to hold the source content, got %d bytes", len(copied))
+	}
+	if info, _ := os.Stat(dest); info.Mode().Perm() != 0640 && os.PathSeparator == '/' {
+		t.Errorf("Expected the source mode, got %v", info.Mode())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected no temporary files left, got %v", entries)
+	}
+
+	if _, err := module.CloneFile(dir, dest); err == nil {
+		t.Error("Expected cloning a directory to fail")
+	}
+}
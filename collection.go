@@ -0,0 +1,109 @@
+package ansiblemodule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Collection is a set of modules compiled into a single binary. The module to
+// run is chosen by the _ansible_module_name argument Ansible passes, matched by
+// its full or short name, or by the name of the executable so that the binary
+// can be linked under each module name.
+type Collection struct {
+	Modules map[string]Module
+	// CommonSpec is merged into the spec of every module, for options such as
+	// connection settings shared by the collection
+	CommonSpec ArgSpecMap
+	// Setup runs before the chosen module, for validation and client setup shared
+	// by the collection. Its error fails the module.
+	Setup func(ctx context.Context, m *AnsibleModule) error
+}
+
+// Main runs the chosen module with the lifecycle of Main
+func (c Collection) Main() {
+	HandleAsync()
+
+	// Keep the arguments read from stdin for NewModule to parse again
+	input, err := readModuleInput()
+	if err != nil {
+		exitWithoutModule(&inputError{err})
+		return
+	}
+	if len(input) > 0 {
+		os.Setenv("ANSIBLE_MODULE_ARGS", string(input))
+	}
+
+	name, module := c.lookup(input)
+	if module == nil {
+		exitWithoutModule(&inputError{fmt.Errorf("unknown module %s, expected one of: %s", name, strings.Join(c.names(), ", "))})
+		return
+	}
+	spec, err := MergeArgSpecs(c.CommonSpec, module.Spec())
+	if err != nil {
+		exitWithoutModule(&inputError{fmt.Errorf("invalid argument spec for module %s: %w", name, err)})
+		return
+	}
+	Main(collectionModule{Module: module, spec: spec, setup: c.Setup})
+}
+
+// lookup returns the module named by the arguments or the executable
+func (c Collection) lookup(input []byte) (string, Module) {
+	var args map[string]interface{}
+	json.Unmarshal(input, &args)
+	name, _ := args["_ansible_module_name"].(string)
+	if name != "" {
+		if module, ok := c.Modules[name]; ok {
+			return name, module
+		}
+		// Fully qualified names such as my.collection.module match by short name
+		if module, ok := c.Modules[name[strings.LastIndex(name, ".")+1:]]; ok {
+			return name, module
+		}
+		return name, nil
+	}
+
+	name = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	return name, c.Modules[name]
+}
+
+// names returns the sorted module names
+func (c Collection) names() []string {
+	names := make([]string, 0, len(c.Modules))
+	for name := range c.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectionModule runs a module of a collection with the merged spec and shared setup
+type collectionModule struct {
+	Module
+	spec  ArgSpecMap
+	setup func(ctx context.Context, m *AnsibleModule) error
+}
+
+func (c collectionModule) Spec() ArgSpecMap {
+	return c.spec
+}
+
+func (c collectionModule) Config() ModuleConfig {
+	if configured, ok := c.Module.(ConfiguredModule); ok {
+		return configured.Config()
+	}
+	return ModuleConfig{SupportsCheckMode: true}
+}
+
+func (c collectionModule) Run(ctx context.Context, m *AnsibleModule) (Result, error) {
+	if c.setup != nil {
+		if err := c.setup(ctx, m); err != nil {
+			return Result{}, err
+		}
+	}
+	return c.Module.Run(ctx, m)
+}
@@ -0,0 +1,57 @@
+package ansiblemodule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// shouter is a second Module for testing collections
+type shouter struct{}
+
+func (shouter) Spec() ArgSpecMap {
+	return ArgSpecMap{"word": {Type: "str", Required: true}}
+}
+
+func (shouter) Run(ctx context.Context, m *AnsibleModule) (Result, error) {
+	return Result{Msg: strings.ToUpper(m.Params["word"].(string)) + " " + m.Params["endpoint"].(string)}, nil
+}
+
+func TestCollectionDispatch(t *testing.T) {
+	var setups int
+	collection := Collection{
+		Modules:    map[string]Module{"greeter": greeter{}, "shouter": shouter{}},
+		CommonSpec: ArgSpecMap{"endpoint": {Type: "str", Default: "local"}},
+		Setup: func(ctx context.Context, m *AnsibleModule) error {
+			setups++
+			if m.Params["endpoint"] == "down" {
+				return fmt.Errorf("endpoint unreachable")
+			}
+			return nil
+		},
+	}
+
+	_, result := runEntry(t, collection.Main, `{"word": "hi", "_ansible_module_name": "shouter"}`)
+	if result["msg"] != "HI local" {
+		t.Errorf("Unexpected shouter result: %v", result)
+	}
+
+	_, result = runEntry(t, collection.Main, `{"name": "you", "_ansible_module_name": "acme.tools.greeter"}`)
+	if result["msg"] != "hello you" {
+		t.Errorf("Expected fully qualified name to dispatch, got %v", result)
+	}
+	if setups != 2 {
+		t.Errorf("Expected setup to run for each module, ran %d times", setups)
+	}
+
+	_, result = runEntry(t, collection.Main, `{"word": "hi", "endpoint": "down", "_ansible_module_name": "shouter"}`)
+	if result["failed"] != true || result["msg"] != "endpoint unreachable" {
+		t.Errorf("Expected setup failure, got %v", result)
+	}
+
+	_, result = runEntry(t, collection.Main, `{"_ansible_module_name": "acme.tools.missing"}`)
+	if result["failed"] != true || !strings.Contains(result["msg"].(string), "expected one of: greeter, shouter") {
+		t.Errorf("Expected unknown module failure, got %v", result)
+	}
+}
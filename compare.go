@@ -0,0 +1,98 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// CompareOptions relaxes how CompareFiles and WriteTextFile decide whether
+// file contents differ, so that cosmetic differences are not reported as changes
+type CompareOptions struct {
+	NormalizeLineEndings     bool // Treat CRLF and CR line endings as LF
+	IgnoreTrailingWhitespace bool // Ignore spaces and tabs at the end of lines
+	NormalizeUnicode         bool // Treat canonically equivalent text (NFC and NFD) as equal
+	IgnoreCase               bool // Treat text differing only in case as equal, using Unicode case folding
+}
+
+// enabled reports whether any option relaxes the comparison
+func (o CompareOptions) enabled() bool {
+	return o.NormalizeLineEndings || o.IgnoreTrailingWhitespace || o.NormalizeUnicode || o.IgnoreCase
+}
+
+// normalize applies the options to text before comparison
+func (o CompareOptions) normalize(text string) string {
+	if o.NormalizeLineEndings {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+		text = strings.ReplaceAll(text, "\r", "\n")
+	}
+	if o.IgnoreTrailingWhitespace {
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			// A CR kept by NormalizeLineEndings=false still ends the line
+			cr := strings.HasSuffix(line, "\r")
+			line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " \t")
+			if cr {
+				line += "\r"
+			}
+			lines[i] = line
+		}
+		text = strings.Join(lines, "\n")
+	}
+	if o.NormalizeUnicode {
+		text = norm.NFD.String(text)
+	}
+	if o.IgnoreCase {
+		text = cases.Fold().String(text)
+		// Folding may leave text unnormalized, so canonical caseless matching
+		// normalizes again (Unicode section 3.13)
+		if o.NormalizeUnicode {
+			text = norm.NFD.String(text)
+		}
+	}
+	return text
+}
+
+// EquivalentContent reports whether two texts are equal under the comparison options
+func EquivalentContent(a, b string, opts CompareOptions) bool {
+	if a == b {
+		return true
+	}
+	if !opts.enabled() {
+		return false
+	}
+	return opts.normalize(a) == opts.normalize(b)
+}
+
+// equivalentReaders reports whether two streams are equal under the comparison
+// options, holding a line of each at a time rather than the whole content
+func equivalentReaders(a, b io.Reader, opts CompareOptions) (bool, error) {
+	return sameContent(&normalizedReader{r: bufio.NewReader(a), opts: opts}, &normalizedReader{r: bufio.NewReader(b), opts: opts})
+}
+
+// normalizedReader reads the normalized form of r. The options only change
+// text within a line, so normalizing line by line gives the same result as
+// normalizing the whole text.
+type normalizedReader struct {
+	r    *bufio.Reader
+	opts CompareOptions
+	buf  []byte
+	err  error
+}
+
+func (n *normalizedReader) Read(p []byte) (int, error) {
+	for len(n.buf) == 0 {
+		if n.err != nil {
+			return 0, n.err
+		}
+		line, err := n.r.ReadString('\n')
+		n.buf = []byte(n.opts.normalize(line))
+		n.err = err
+	}
+	count := copy(p, n.buf)
+	n.buf = n.buf[count:]
+	return count, nil
+}
@@ -0,0 +1,94 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCanonicalDecompose(t *testing.T) {
+	tests := map[string]string{
+		"\u1ec7":        "e\u0323\u0302", // ệ, decomposed recursively and reordered
+		"\u212b":        "A\u030a",       // Angstrom sign
+		"\ud55c":        "\u1112\u1161\u11ab",
+		"q\u0307\u0323": "q\u0323\u0307", // marks reordered by combining class
+		"plain ascii":   "plain ascii",
+	}
+	for input, expected := range tests {
+		if result := (CompareOptions{NormalizeUnicode: true}).normalize(input); result != expected {
+			t.Errorf("NFD of %q: expected %q, got %q", input, expected, result)
+		}
+	}
+}
+
+func TestEquivalentContent(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		opts     CompareOptions
+		expected bool
+	}{
+		{"a\r\nb\r\n", "a\nb\n", CompareOptions{}, false},
+		{"a\r\nb\r\n", "a\nb\n", CompareOptions{NormalizeLineEndings: true}, true},
+		{"a  \nb\t\n", "a\nb\n", CompareOptions{IgnoreTrailingWhitespace: true}, true},
+		{"a  \r\nb\r\n", "a\r\nb\r\n", CompareOptions{IgnoreTrailingWhitespace: true}, true},
+		{"a b\n", "ab\n", CompareOptions{IgnoreTrailingWhitespace: true}, false},
+		{"caf\u00e9\n", "cafe\u0301\n", CompareOptions{}, false},
+		{"caf\u00e9\n", "cafe\u0301\n", CompareOptions{NormalizeUnicode: true}, true},
+		{"caf\u00e9 \r\n", "cafe\u0301\n", CompareOptions{true, true, true, false}, true},
+		{"Key=Value\n", "key=value\n", CompareOptions{}, false},
+		{"Key=Value\n", "key=value\n", CompareOptions{IgnoreCase: true}, true},
+		{"STRASSE\n", "stra\u00dfe\n", CompareOptions{IgnoreCase: true}, true},
+		{"CAF\u00c9\n", "cafe\u0301\n", CompareOptions{IgnoreCase: true}, false},
+		{"CAF\u00c9\n", "cafe\u0301\n", CompareOptions{NormalizeUnicode: true, IgnoreCase: true}, true},
+		{"key=a\n", "key=b\n", CompareOptions{IgnoreCase: true}, false},
+	}
+	for _, test := range tests {
+		if result := EquivalentContent(test.a, test.b, test.opts); result != test.expected {
+			t.Errorf("EquivalentContent(%q, %q, %+v): expected %v, got %v", test.a, test.b, test.opts, test.expected, result)
+		}
+		if !test.opts.enabled() {
+			continue
+		}
+		a, b := iotest.OneByteReader(strings.NewReader(test.a)), strings.NewReader(test.b)
+		if result, err := equivalentReaders(a, b, test.opts); err != nil || result != test.expected {
+			t.Errorf("equivalentReaders(%q, %q, %+v): expected %v, got %v (%v)", test.a, test.b, test.opts, test.expected, result, err)
+		}
+	}
+}
+
+func TestCompareOptionsFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src.conf")
+	dest := filepath.Join(tmpDir, "dest.conf")
+	if err := os.WriteFile(src, []byte("key=value\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(dest, []byte("key=value\r\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	module := &AnsibleModule{}
+	if same, err := module.CompareFiles(src, dest); err != nil || same {
+		t.Errorf("Expected files to differ without options, got %v (%v)", same, err)
+	}
+
+	module.CompareOptions = CompareOptions{NormalizeLineEndings: true}
+	if same, err := module.CompareFiles(src, dest); err != nil || !same {
+		t.Errorf("Expected files to match with NormalizeLineEndings, got %v (%v)", same, err)
+	}
+
+	changed, err := module.WriteTextFile(dest, "key=value\n", 0644)
+	if err != nil || changed {
+		t.Errorf("Expected no change for equivalent content, got %v (%v)", changed, err)
+	}
+	changed, err = module.WriteFileFrom(dest, strings.NewReader("key=value\n"), 0644)
+	if err != nil || changed {
+		t.Errorf("Expected no change for equivalent streamed content, got %v (%v)", changed, err)
+	}
+	data, _ := os.ReadFile(dest)
+	if string(data) != "key=value\r\n" {
+		t.Errorf("Expected file to be left untouched, got %q", data)
+	}
+}
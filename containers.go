@@ -0,0 +1,121 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// containerEngines are the supported container runtimes in detection order
+var containerEngines = []string{"docker", "podman"}
+
+// containerNotFound matches the errors runtimes give for missing containers and images
+var containerNotFound = regexp.MustCompile(`(?i)no such (container|image|object)|image not known|not found`)
+
+// ContainerRuntime runs commands inside containers with the docker or podman CLI
+type ContainerRuntime struct {
+	Name string // docker or podman
+	m    *AnsibleModule
+	path string
+}
+
+// ContainerExecOptions configures ContainerRuntime.Exec. Environment sets
+// variables inside the container and Data is written to the command's stdin.
+type ContainerExecOptions struct {
+	CommandOptions
+	User    string // User to run as inside the container
+	Workdir string // Working directory inside the container
+}
+
+// ContainerRuntime returns the container runtime with the given name, docker or
+// podman, or the first one installed if name is empty
+func (m *AnsibleModule) ContainerRuntime(name string) (*ContainerRuntime, error) {
+	for _, engine := range containerEngines {
+		if name != "" && engine != name {
+			continue
+		}
+		path, err := m.GetBinPath(engine, name != "")
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			return &ContainerRuntime{Name: engine, m: m, path: path}, nil
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("unsupported container runtime %s", name)
+	}
+	return nil, fmt.Errorf("no supported container runtime found")
+}
+
+// inspect runs an inspect command, reporting false if the object does not exist
+func (r *ContainerRuntime) inspect(kind, name, format string) (string, bool, error) {
+	result, err := r.m.RunCommand(r.path, []string{kind, "inspect", "--format", format, name}, nil, "")
+	if err != nil {
+		if result.Rc > 0 && containerNotFound.MatchString(result.Stderr) {
+			return "", false, nil
+		}
+		return "", false, commandFailure(err, result, "%s %s inspect %s failed", r.Name, kind, name)
+	}
+	return strings.TrimSpace(result.Stdout), true, nil
+}
+
+// ContainerExists reports whether a container exists, running or not
+func (r *ContainerRuntime) ContainerExists(name string) (bool, error) {
+	_, exists, err := r.inspect("container", name, "{{.Id}}")
+	return exists, err
+}
+
+// ContainerRunning reports whether a container exists and is running
+func (r *ContainerRuntime) ContainerRunning(name string) (bool, error) {
+	running, exists, err := r.inspect("container", name, "{{.State.Running}}")
+	return exists && running == "true", err
+}
+
+// ImageExists reports whether an image is present locally
+func (r *ContainerRuntime) ImageExists(image string) (bool, error) {
+	_, exists, err := r.inspect("image", image, "{{.Id}}")
+	return exists, err
+}
+
+// Exec runs a command inside a running container and returns its result like
+// RunCommandWithOptions, failing early with a clear error if the container does
+// not exist or is stopped
+func (r *ContainerRuntime) Exec(container, cmd string, args []string, opts ContainerExecOptions) (CommandResult, error) {
+	state, exists, err := r.inspect("container", container, "{{.State.Running}}")
+	if err != nil {
+		return CommandResult{}, err
+	}
+	if !exists {
+		return CommandResult{}, notFound(fmt.Errorf("container %s does not exist", container))
+	}
+	if state != "true" {
+		return CommandResult{}, fmt.Errorf("container %s is not running", container)
+	}
+
+	execArgs := []string{"exec"}
+	if opts.Data != "" {
+		execArgs = append(execArgs, "--interactive")
+	}
+	if opts.User != "" {
+		execArgs = append(execArgs, "--user", opts.User)
+	}
+	if opts.Workdir != "" {
+		execArgs = append(execArgs, "--workdir", opts.Workdir)
+	}
+	keys := make([]string, 0, len(opts.Environment))
+	for key := range opts.Environment {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		execArgs = append(execArgs, "--env", key+"="+opts.Environment[key])
+	}
+	execArgs = append(append(execArgs, container, cmd), args...)
+
+	// The environment belongs to the container, not the runtime CLI
+	hostOpts := opts.CommandOptions
+	hostOpts.Environment = nil
+	return r.m.RunCommandWithOptions(r.path, execArgs, hostOpts)
+}
@@ -0,0 +1,84 @@
+package ansiblemodule
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// containerRunner fakes a container runtime CLI with one running and one stopped container
+type containerRunner struct {
+	binaries []string
+	commands []string
+	data     string
+}
+
+func (c *containerRunner) Run(ctx context.Context, cmd string, args []string, env []string, data string) (CommandResult, error) {
+	line := strings.Join(args, " ")
+	c.commands = append(c.commands, line)
+	switch {
+	case strings.HasPrefix(line, "container inspect --format {{.State.Running}} web"):
+		return CommandResult{Stdout: "true\n"}, nil
+	case strings.HasPrefix(line, "container inspect --format {{.State.Running}} batch"):
+		return CommandResult{Stdout: "false\n"}, nil
+	case strings.HasPrefix(line, "image inspect --format {{.Id}} nginx:1.27"):
+		return CommandResult{Stdout: "sha256:abc\n"}, nil
+	case strings.HasPrefix(line, "exec"):
+		c.data = data
+		return CommandResult{Stdout: "ok\n"}, nil
+	case strings.Contains(line, "inspect"):
+		return CommandResult{Stderr: "Error: No such object: " + args[len(args)-1], Rc: 1}, nil
+	}
+	return CommandResult{Rc: 125, Stderr: "unknown command"}, nil
+}
+
+func (c *containerRunner) LookPath(name string) (string, error) {
+	for _, binary := range c.binaries {
+		if binary == name {
+			return "/usr/bin/" + name, nil
+		}
+	}
+	return "", exec.ErrNotFound
+}
+
+func TestContainerRuntime(t *testing.T) {
+	runner := &containerRunner{binaries: []string{"podman"}}
+	module := &AnsibleModule{Runner: runner}
+	runtime, err := module.ContainerRuntime("")
+	if err != nil || runtime.Name != "podman" {
+		t.Fatalf("Expected podman to be detected, got %v (%v)", runtime, err)
+	}
+	if _, err := module.ContainerRuntime("docker"); err == nil {
+		t.Error("Expected a missing runtime to fail")
+	}
+
+	if running, err := runtime.ContainerRunning("web"); err != nil || !running {
+		t.Errorf("Expected web to be running: %v", err)
+	}
+	if exists, err := runtime.ContainerExists("missing"); err != nil || exists {
+		t.Errorf("Expected missing container not to exist: %v", err)
+	}
+	if exists, err := runtime.ImageExists("nginx:1.27"); err != nil || !exists {
+		t.Errorf("Expected image to exist: %v", err)
+	}
+
+	result, err := runtime.Exec("web", "psql", []string{"-c", "select 1"}, ContainerExecOptions{
+		CommandOptions: CommandOptions{Environment: map[string]string{"PGUSER": "app", "LANG": "C"}, Data: "input"},
+		User:           "postgres",
+	})
+	if err != nil || result.Stdout != "ok\n" {
+		t.Fatalf("Expected exec to succeed: %v", err)
+	}
+	expected := "exec --interactive --user postgres --env LANG=C --env PGUSER=app web psql -c select 1"
+	if last := runner.commands[len(runner.commands)-1]; last != expected || runner.data != "input" {
+		t.Errorf("Unexpected exec command %q with data %q", last, runner.data)
+	}
+
+	if _, err := runtime.Exec("batch", "true", nil, ContainerExecOptions{}); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("Expected exec in a stopped container to fail: %v", err)
+	}
+	if _, err := runtime.Exec("missing", "true", nil, ContainerExecOptions{}); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected exec in a missing container to fail: %v", err)
+	}
+}
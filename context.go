@@ -0,0 +1,47 @@
+package ansiblemodule
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Context returns the module context, which is cancelled when the module is
+// interrupted or its deadline passes
+func (m *AnsibleModule) Context() context.Context {
+	if m.ctx == nil {
+		m.ctx, m.cancel = context.WithCancel(context.Background())
+	}
+	return m.ctx
+}
+
+// SetDeadline limits the module context so long operations stop at the deadline
+func (m *AnsibleModule) SetDeadline(deadline time.Time) {
+	parent := m.Context()
+	cancelParent := m.cancel
+	ctx, cancel := context.WithDeadline(parent, deadline)
+	m.ctx = ctx
+	m.cancel = func() {
+		cancel()
+		cancelParent()
+	}
+}
+
+// contextReader stops reading once its context is done
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+// Read reads from the underlying reader unless the context is done
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// contextReader wraps a reader so copies are interrupted with the module context
+func (m *AnsibleModule) contextReader(reader io.Reader) io.Reader {
+	return &contextReader{ctx: m.Context(), reader: reader}
+}
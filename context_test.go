@@ -0,0 +1,82 @@
+package ansiblemodule
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContextCancellation(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	module := &AnsibleModule{TmpDir: tmpDir}
+	if module.Context().Err() != nil {
+		t.Fatal("Expected fresh module context to be active")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	src := filepath.Join(tmpDir, "src")
+	dest := filepath.Join(tmpDir, "dest")
+	if err := os.WriteFile(src, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	module.cancel()
+
+	// Test RunCommand
+	start := time.Now()
+	result, err := module.RunCommand("sleep", []string{"5"}, nil, "")
+	if err == nil {
+		t.Error("Expected cancelled command to fail")
+	}
+	if result.Rc != -1 {
+		t.Errorf("Expected rc -1 for cancelled command, got %d", result.Rc)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("Expected cancelled command to return immediately")
+	}
+
+	// Test FetchURL
+	if _, err := module.FetchURL(http.MethodGet, server.URL, nil, nil); err == nil {
+		t.Error("Expected cancelled request to fail")
+	}
+
+	// Test file helpers
+	if changed, err := module.CopyFile(src, dest, 0644); err == nil || changed {
+		t.Errorf("Expected cancelled copy to fail, got changed=%v err=%v", changed, err)
+	}
+	if module.FileExists(dest) {
+		t.Error("Expected destination not to be written after cancellation")
+	}
+}
+
+func TestSetDeadline(t *testing.T) {
+	module := &AnsibleModule{}
+	module.SetDeadline(time.Now().Add(50 * time.Millisecond))
+
+	deadline, ok := module.Context().Deadline()
+	if !ok || time.Until(deadline) > time.Second {
+		t.Errorf("Expected context deadline to be set, got %v", deadline)
+	}
+
+	select {
+	case <-module.Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected context to expire at the deadline")
+	}
+	if module.Context().Err() != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", module.Context().Err())
+	}
+}
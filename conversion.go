@@ -0,0 +1,25 @@
+package ansiblemodule
+
+import (
+	"fmt"
+)
+
+// DefaultConversionWarnings is installed as ConversionWarnings on new modules,
+// so conversions are reported while NewModule validates the arguments
+var DefaultConversionWarnings = false
+
+// noteConversion reports that a string parameter was converted to another type
+func (m *AnsibleModule) noteConversion(name, value string, spec ArgumentSpec, target string) error {
+	if !m.ConversionWarnings || m.StringConversionAction == "ignore" {
+		return nil
+	}
+	msg := fmt.Sprintf("The value of parameter %s was converted from a string to %s", name, target)
+	if !spec.NoLog {
+		msg = fmt.Sprintf("The value %q of parameter %s was converted from a string to %s", value, name, target)
+	}
+	if m.StringConversionAction == "error" {
+		return &ValidationError{Param: name, Code: CodeStringConversion, Msg: msg}
+	}
+	m.AddWarning(msg + ". Give the value as " + target + " to avoid this warning.")
+	return nil
+}
@@ -0,0 +1,46 @@
+package ansiblemodule
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestConversionWarnings(t *testing.T) {
+	spec := ArgSpecMap{
+		"port":     {Type: "int"},
+		"enabled":  {Type: "bool"},
+		"tags":     {Type: "list"},
+		"ratio":    {Type: "float"},
+		"password": {Type: "int", NoLog: true},
+	}
+	params := ModuleParams{"port": "8080", "enabled": "yes", "tags": "a,b", "ratio": 0.5, "password": "1234"}
+
+	module := &AnsibleModule{ArgSpec: spec, Params: maps.Clone(params)}
+	if err := module.validateArguments(); err != nil || len(module.Warnings) != 0 {
+		t.Fatalf("Expected no warnings by default, got %v (%v)", module.Warnings, err)
+	}
+
+	module = &AnsibleModule{ArgSpec: spec, Params: maps.Clone(params), ConversionWarnings: true}
+	if err := module.validateArguments(); err != nil {
+		t.Fatal(err)
+	}
+	warnings := strings.Join(module.Warnings, "\n")
+	if len(module.Warnings) != 4 || !strings.Contains(warnings, `"8080" of parameter port was converted from a string to an integer`) ||
+		!strings.Contains(warnings, "parameter tags was converted from a string to a list") {
+		t.Errorf("Unexpected warnings:\n%s", warnings)
+	}
+	if strings.Contains(warnings, "1234") {
+		t.Error("Expected no_log values to be left out of warnings")
+	}
+
+	module = &AnsibleModule{ArgSpec: spec, Params: maps.Clone(params), StringConversionAction: "ignore", ConversionWarnings: true}
+	if err := module.validateArguments(); err != nil || len(module.Warnings) != 0 {
+		t.Errorf("Expected ignore to suppress warnings, got %v (%v)", module.Warnings, err)
+	}
+
+	module = &AnsibleModule{ArgSpec: spec, Params: maps.Clone(params), StringConversionAction: "error", ConversionWarnings: true}
+	if err := module.validateArguments(); err == nil || !strings.Contains(err.Error(), "converted from a string") {
+		t.Errorf("Expected error action to fail validation, got %v", err)
+	}
+}
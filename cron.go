@@ -0,0 +1,317 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cronMarker prefixes the comment line identifying a managed cron entry
+const cronMarker = "#Ansible: "
+
+// cronDir is the directory holding system cron files
+var cronDir = "/etc/cron.d"
+
+// CronEntry describes a managed cron job
+type CronEntry struct {
+	Name     string
+	Minute   string
+	Hour     string
+	Day      string
+	Month    string
+	Weekday  string
+	Special  string // reboot, hourly, daily, ... used instead of the time fields
+	User     string // Only used in /etc/cron.d files, defaults to root
+	Job      string
+	Disabled bool
+}
+
+// Line renders the crontab line for the entry
+func (e CronEntry) Line(withUser bool) string {
+	var fields []string
+	if e.Special != "" {
+		fields = append(fields, "@"+strings.TrimPrefix(e.Special, "@"))
+	} else {
+		for _, field := range []string{e.Minute, e.Hour, e.Day, e.Month, e.Weekday} {
+			if field == "" {
+				field = "*"
+			}
+			fields = append(fields, field)
+		}
+	}
+	if withUser {
+		// An empty user field would make cron read the command as the user
+		user := e.User
+		if user == "" {
+			user = "root"
+		}
+		fields = append(fields, user)
+	}
+	fields = append(fields, e.Job)
+
+	line := strings.Join(fields, " ")
+	if e.Disabled {
+		line = "#" + line
+	}
+	return line
+}
+
+// parseCronLine parses a crontab line into an entry
+func parseCronLine(name, line string, withUser bool) CronEntry {
+	entry := CronEntry{Name: name}
+	if strings.HasPrefix(line, "#") {
+		entry.Disabled = true
+		line = strings.TrimPrefix(line, "#")
+	}
+
+	timeFields := 5
+	fields := strings.Fields(line)
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+		entry.Special = strings.TrimPrefix(fields[0], "@")
+		timeFields = 1
+	}
+	userFields := 0
+	if withUser {
+		userFields = 1
+	}
+	if len(fields) <= timeFields+userFields {
+		entry.Job = strings.TrimSpace(line)
+		return entry
+	}
+
+	if entry.Special == "" {
+		entry.Minute, entry.Hour, entry.Day, entry.Month, entry.Weekday =
+			fields[0], fields[1], fields[2], fields[3], fields[4]
+	}
+	if withUser {
+		entry.User = fields[timeFields]
+	}
+
+	// Keep the job text as written, including its internal spacing
+	rest := strings.TrimSpace(line)
+	for i := 0; i < timeFields+userFields; i++ {
+		rest = strings.TrimSpace(rest[len(fields[i]):])
+	}
+	entry.Job = rest
+	return entry
+}
+
+// CronTab is an editable crontab, either a user crontab or a file in /etc/cron.d
+type CronTab struct {
+	User     string
+	File     string
+	original string
+	lines    []string
+}
+
+// isSystemFile reports whether the crontab is a cron.d file, which has a user field
+func (c *CronTab) isSystemFile() bool {
+	return c.File != ""
+}
+
+// path returns the location of a cron.d file
+func (c *CronTab) path() string {
+	if filepath.IsAbs(c.File) {
+		return c.File
+	}
+	return filepath.Join(cronDir, c.File)
+}
+
+// LoadCronTab reads the crontab of a user, or a cron.d file if cronFile is set
+func (m *AnsibleModule) LoadCronTab(user, cronFile string) (*CronTab, error) {
+	tab := &CronTab{User: user, File: cronFile}
+
+	if tab.isSystemFile() {
+		if m.FileExists(tab.path()) {
+			content, err := m.ReadTextFile(tab.path())
+			if err != nil {
+				return nil, err
+			}
+			tab.original = content
+		}
+	} else {
+		crontab, err := m.GetBinPath("crontab", true)
+		if err != nil {
+			return nil, err
+		}
+		args := []string{"-l"}
+		if user != "" {
+			args = []string{"-u", user, "-l"}
+		}
+		result, err := m.RunCommand(crontab, args, nil, "")
+		if err != nil {
+			// crontab exits 1 when the user has no crontab yet
+			if !strings.Contains(strings.ToLower(result.Stderr), "no crontab") {
+				return nil, commandFailure(err, result, "failed to read crontab")
+			}
+		} else {
+			tab.original = result.Stdout
+		}
+	}
+
+	if tab.original != "" {
+		tab.lines = strings.Split(strings.TrimSuffix(tab.original, "\n"), "\n")
+	}
+	return tab, nil
+}
+
+// Entries returns all managed entries in the crontab
+func (c *CronTab) Entries() []CronEntry {
+	var entries []CronEntry
+	for i := 0; i < len(c.lines)-1; i++ {
+		if strings.HasPrefix(c.lines[i], cronMarker) {
+			name := strings.TrimPrefix(c.lines[i], cronMarker)
+			entries = append(entries, parseCronLine(name, c.lines[i+1], c.isSystemFile()))
+			i++
+		}
+	}
+	return entries
+}
+
+// Find returns the managed entry with the given name
+func (c *CronTab) Find(name string) (CronEntry, bool) {
+	for _, entry := range c.Entries() {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+	return CronEntry{}, false
+}
+
+// indexOf returns the line index of the marker for an entry, or -1
+func (c *CronTab) indexOf(name string) int {
+	for i := 0; i < len(c.lines)-1; i++ {
+		if c.lines[i] == cronMarker+name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Set adds or replaces a managed entry, reporting whether the crontab changed
+func (c *CronTab) Set(entry CronEntry) bool {
+	line := entry.Line(c.isSystemFile())
+	if i := c.indexOf(entry.Name); i >= 0 {
+		if c.lines[i+1] == line {
+			return false
+		}
+		c.lines[i+1] = line
+		return true
+	}
+	c.lines = append(c.lines, cronMarker+entry.Name, line)
+	return true
+}
+
+// Remove deletes a managed entry, reporting whether the crontab changed
+func (c *CronTab) Remove(name string) bool {
+	i := c.indexOf(name)
+	if i < 0 {
+		return false
+	}
+	c.lines = append(c.lines[:i], c.lines[i+2:]...)
+	return true
+}
+
+// Render returns the crontab content
+func (c *CronTab) Render() string {
+	if len(c.lines) == 0 {
+		return ""
+	}
+	return strings.Join(c.lines, "\n") + "\n"
+}
+
+// Changed reports whether the crontab differs from what was loaded
+func (c *CronTab) Changed() bool {
+	return c.Render() != c.original
+}
+
+// Diff returns a diff of the loaded and current crontab content
+func (c *CronTab) Diff(m *AnsibleModule) map[string]interface{} {
+	header := "crontab"
+	if c.isSystemFile() {
+		header = c.path()
+	} else if c.User != "" {
+		header = "crontab for " + c.User
+	}
+	return m.CreateDiff(c.original, c.Render(), header+" (before)", header+" (after)")
+}
+
+// SaveCronTab writes the crontab if it changed, returning the backup path if requested
+func (m *AnsibleModule) SaveCronTab(tab *CronTab, backup bool) (bool, string, error) {
+	if !tab.Changed() {
+		return false, "", nil
+	}
+	if m.CheckMode {
+		return true, "", nil
+	}
+
+	backupPath := ""
+	if backup && tab.original != "" {
+		if tab.isSystemFile() {
+			path, err := m.BackupFile(tab.path())
+			if err != nil {
+				return false, "", err
+			}
+			backupPath = path
+		} else {
+			// User crontabs have no file of their own, so keep a copy outside TmpDir
+			file, err := os.CreateTemp("", "crontab-backup-")
+			if err != nil {
+				return false, "", err
+			}
+			_, err = file.WriteString(tab.original)
+			file.Close()
+			if err != nil {
+				return false, "", err
+			}
+			backupPath = file.Name()
+		}
+	}
+
+	content := tab.Render()
+	if tab.isSystemFile() {
+		// An empty cron.d file is removed entirely
+		if content == "" {
+			if err := os.Remove(tab.path()); err != nil && !os.IsNotExist(err) {
+				return false, backupPath, err
+			}
+		} else if _, err := m.WriteTextFile(tab.path(), content, 0644); err != nil {
+			return false, backupPath, err
+		}
+	} else {
+		if err := m.installUserCronTab(tab.User, content); err != nil {
+			return false, backupPath, err
+		}
+	}
+
+	tab.original = content
+	return true, backupPath, nil
+}
+
+// installUserCronTab replaces a user crontab using the crontab command
+func (m *AnsibleModule) installUserCronTab(user, content string) error {
+	crontab, err := m.GetBinPath("crontab", true)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := m.TmpFile("crontab-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.WriteString(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	tmpFile.Close()
+
+	args := []string{tmpFile.Name()}
+	if user != "" {
+		args = []string{"-u", user, tmpFile.Name()}
+	}
+	if result, err := m.RunCommand(crontab, args, nil, ""); err != nil {
+		return commandFailure(err, result, "failed to install crontab")
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCronEntryLine(t *testing.T) {
+	entry := CronEntry{Minute: "*/5", Job: "/usr/bin/true"}
+	if entry.Line(false) != "*/5 * * * * /usr/bin/true" {
+		t.Errorf("Unexpected line '%s'", entry.Line(false))
+	}
+
+	entry = CronEntry{Special: "reboot", User: "root", Job: "/bin/start", Disabled: true}
+	if entry.Line(true) != "#@reboot root /bin/start" {
+		t.Errorf("Unexpected line '%s'", entry.Line(true))
+	}
+
+	entry = CronEntry{Hour: "3", Job: "/bin/rotate"}
+	if entry.Line(true) != "* 3 * * * root /bin/rotate" {
+		t.Errorf("Expected the user to default to root, got '%s'", entry.Line(true))
+	}
+
+	parsed := parseCronLine("test", "0 2 * * 1 backup  /usr/local/bin/backup --full", true)
+	if parsed.Minute != "0" || parsed.Hour != "2" || parsed.Weekday != "1" || parsed.User != "backup" {
+		t.Errorf("Unexpected parsed entry: %+v", parsed)
+	}
+	if parsed.Job != "/usr/local/bin/backup --full" {
+		t.Errorf("Unexpected job '%s'", parsed.Job)
+	}
+}
+
+func TestCronFile(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldDir := cronDir
+	cronDir = tmpDir
+	defer func() { cronDir = oldDir }()
+
+	cronFile := filepath.Join(tmpDir, "app")
+	os.WriteFile(cronFile, []byte("SHELL=/bin/sh\n#Ansible: cleanup\n0 * * * * root /bin/old\n"), 0644)
+
+	tab, err := module.LoadCronTab("", "app")
+	if err != nil {
+		t.Fatalf("Failed to load cron file: %v", err)
+	}
+	entry, found := tab.Find("cleanup")
+	if !found || entry.Job != "/bin/old" || entry.User != "root" {
+		t.Fatalf("Expected cleanup entry, got %+v", entry)
+	}
+
+	// Test updating and adding entries
+	if !tab.Set(CronEntry{Name: "cleanup", Minute: "30", User: "root", Job: "/bin/new"}) {
+		t.Error("Expected update to change crontab")
+	}
+	if !tab.Set(CronEntry{Name: "report", Special: "daily", User: "nobody", Job: "/bin/report"}) {
+		t.Error("Expected add to change crontab")
+	}
+	if tab.Set(CronEntry{Name: "report", Special: "daily", User: "nobody", Job: "/bin/report"}) {
+		t.Error("Expected identical entry not to change crontab")
+	}
+
+	diff := tab.Diff(module)
+	if !strings.Contains(diff["after"].(string), "/bin/new") {
+		t.Error("Expected diff to contain the new job")
+	}
+
+	changed, backupPath, err := module.SaveCronTab(tab, true)
+	if err != nil || !changed {
+		t.Fatalf("Expected crontab to be saved, got %v (%v)", changed, err)
+	}
+	if backupPath == "" || !module.FileExists(backupPath) {
+		t.Error("Expected backup to be created")
+	}
+
+	content, _ := os.ReadFile(cronFile)
+	expected := "SHELL=/bin/sh\n#Ansible: cleanup\n30 * * * * root /bin/new\n#Ansible: report\n@daily nobody /bin/report\n"
+	if string(content) != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+
+	// Test idempotent save
+	changed, _, err = module.SaveCronTab(tab, false)
+	if err != nil || changed {
+		t.Errorf("Expected no change, got %v (%v)", changed, err)
+	}
+
+	// Test removal
+	if !tab.Remove("cleanup") || tab.Remove("cleanup") {
+		t.Error("Expected entry to be removed once")
+	}
+	if len(tab.Entries()) != 1 {
+		t.Errorf("Expected 1 entry, got %d", len(tab.Entries()))
+	}
+}
+
+func TestUserCronTab(t *testing.T) {
+	module := &AnsibleModule{}
+
+	binDir := t.TempDir()
+	store := filepath.Join(binDir, "stored")
+	script := `#!/bin/sh
+if [ "$1" = "-l" ]; then
+  if [ -f ` + store + ` ]; then cat ` + store + `; else echo "no crontab for test" >&2; exit 1; fi
+else
+  cp "$1" ` + store + `
+fi
+`
+	os.WriteFile(filepath.Join(binDir, "crontab"), []byte(script), 0755)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tab, err := module.LoadCronTab("", "")
+	if err != nil {
+		t.Fatalf("Failed to load empty crontab: %v", err)
+	}
+	if len(tab.Entries()) != 0 {
+		t.Error("Expected no entries")
+	}
+
+	tab.Set(CronEntry{Name: "job", Minute: "0", Hour: "3", Job: "/bin/job"})
+	changed, _, err := module.SaveCronTab(tab, false)
+	if err != nil || !changed {
+		t.Fatalf("Expected crontab to be installed, got %v (%v)", changed, err)
+	}
+
+	tab, err = module.LoadCronTab("", "")
+	if err != nil {
+		t.Fatalf("Failed to reload crontab: %v", err)
+	}
+	entry, found := tab.Find("job")
+	if !found || entry.Hour != "3" {
+		t.Errorf("Expected job entry after reload, got %+v", entry)
+	}
+}
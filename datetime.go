@@ -0,0 +1,102 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateTimeLayouts are the formats accepted for datetime arguments, tried in order
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"20060102T150405Z",
+	"20060102",
+}
+
+// normalizeDateTimeValue converts a datetime argument to a time.Time. Strings may
+// use RFC3339 or one of the common date formats, read as UTC when they have no
+// zone; numbers are seconds since the Unix epoch.
+func normalizeDateTimeValue(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case int:
+		return time.Unix(int64(v), 0).UTC(), nil
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case float64:
+		seconds, fraction := math.Modf(v)
+		return time.Unix(int64(seconds), int64(fraction*1e9)).UTC(), nil
+	case string:
+		strVal := strings.TrimSpace(v)
+		for _, layout := range dateTimeLayouts {
+			if t, err := time.Parse(layout, strVal); err == nil {
+				return t, nil
+			}
+		}
+		if seconds, err := strconv.ParseFloat(strVal, 64); err == nil {
+			return normalizeDateTimeValue(seconds)
+		}
+		return time.Time{}, fmt.Errorf("must be a date and time such as 2024-01-31T12:00:00Z: %s", strVal)
+	}
+	return time.Time{}, fmt.Errorf("must be a date and time string or a Unix timestamp")
+}
+
+// ageFormat is the age syntax of the find module: a number of seconds, or of
+// minutes, hours, days or weeks with a unit suffix
+var ageFormat = regexp.MustCompile(`^(-?\d+)([smhdw]?)$`)
+
+// ageUnits are the durations of the age suffixes
+var ageUnits = map[string]time.Duration{
+	"": time.Second, "s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+}
+
+// ParseAge parses an age such as "90", "30m", "2d" or "3w". A negative age
+// selects things at most that old rather than at least, as in the find module.
+func ParseAge(age string) (time.Duration, error) {
+	match := ageFormat.FindStringSubmatch(strings.ToLower(strings.TrimSpace(age)))
+	if match == nil {
+		return 0, fmt.Errorf("invalid age %q, expected a number with an optional s, m, h, d or w suffix", age)
+	}
+	count, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q: %w", age, err)
+	}
+	return time.Duration(count) * ageUnits[match[2]], nil
+}
+
+// MatchesAge reports whether a timestamp is at least age old at now, or for a
+// negative age at most -age old
+func MatchesAge(stamp time.Time, age time.Duration, now time.Time) bool {
+	elapsed := now.Sub(stamp)
+	if age < 0 {
+		return elapsed <= -age
+	}
+	return elapsed >= age
+}
+
+// FileMatchesAge reports whether the modification time of a file matches an
+// age as parsed by ParseAge, for cleanup and rotation of old files
+func (m *AnsibleModule) FileMatchesAge(path, age string) (bool, error) {
+	duration, err := ParseAge(age)
+	if err != nil {
+		return false, err
+	}
+	info, err := m.fs().Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return MatchesAge(info.ModTime(), duration, time.Now()), nil
+}
@@ -0,0 +1,69 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalizeDateTimeValue(t *testing.T) {
+	expected := time.Date(2024, 1, 31, 12, 30, 0, 0, time.UTC)
+	for _, value := range []interface{}{
+		"2024-01-31T12:30:00Z", "2024-01-31T14:30:00+02:00", "2024-01-31 12:30:00", "2024-01-31 12:30",
+		"Wed, 31 Jan 2024 12:30:00 +0000", "20240131T123000Z", 1706704200, float64(1706704200), "1706704200",
+	} {
+		result, err := normalizeDateTimeValue(value)
+		if err != nil || !result.Equal(expected) {
+			t.Errorf("Expected %v for %v, got %v (%v)", expected, value, result, err)
+		}
+	}
+	if result, _ := normalizeDateTimeValue("2024-01-31"); !result.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a date to be midnight UTC, got %v", result)
+	}
+	for _, value := range []interface{}{"yesterday", "2024-13-01", true} {
+		if _, err := normalizeDateTimeValue(value); err == nil {
+			t.Errorf("Expected %v to fail", value)
+		}
+	}
+
+	module := &AnsibleModule{ArgSpec: ArgSpecMap{"since": {Type: "datetime"}}, Params: ModuleParams{"since": "2024-01-31T12:30:00Z"}}
+	if err := module.validateArguments(); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	if since, err := module.GetParamTime("since"); err != nil || !since.Equal(expected) {
+		t.Errorf("Expected %v, got %v (%v)", expected, since, err)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	for age, expected := range map[string]time.Duration{
+		"90": 90 * time.Second, "30m": 30 * time.Minute, "2d": 48 * time.Hour, "3W": 21 * 24 * time.Hour, "-1h": -time.Hour,
+	} {
+		if result, err := ParseAge(age); err != nil || result != expected {
+			t.Errorf("Expected %v for %s, got %v (%v)", expected, age, result, err)
+		}
+	}
+	if _, err := ParseAge("2 days"); err == nil {
+		t.Error("Expected an invalid age to fail")
+	}
+
+	now := time.Now()
+	if !MatchesAge(now.Add(-72*time.Hour), 48*time.Hour, now) || MatchesAge(now.Add(-time.Hour), 48*time.Hour, now) {
+		t.Error("Expected a positive age to select older timestamps")
+	}
+	if !MatchesAge(now.Add(-time.Hour), -48*time.Hour, now) || MatchesAge(now.Add(-72*time.Hour), -48*time.Hour, now) {
+		t.Error("Expected a negative age to select newer timestamps")
+	}
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("old"), 0644)
+	os.Chtimes(path, now, now.Add(-10*24*time.Hour))
+	module := &AnsibleModule{}
+	if old, err := module.FileMatchesAge(path, "1w"); err != nil || !old {
+		t.Errorf("Expected a ten day old file to match 1w, got %v (%v)", old, err)
+	}
+	if recent, _ := module.FileMatchesAge(path, "-1w"); recent {
+		t.Error("Expected a ten day old file not to match -1w")
+	}
+}
@@ -0,0 +1,796 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dbusSystemBusAddress is the system bus used when DBUS_SYSTEM_BUS_ADDRESS is unset
+var dbusSystemBusAddress = "unix:path=/run/dbus/system_bus_socket"
+
+// DBusTimeout bounds connecting to the bus and waiting for a method reply
+var DBusTimeout = 25 * time.Second
+
+// ErrDBusUnavailable is returned by SystemBus when there is no usable system bus,
+// so modules can fall back to command line tools
+var ErrDBusUnavailable = errors.New("d-bus system bus is not available")
+
+// dbusMaxMessage is the largest message accepted from the bus
+const dbusMaxMessage = 128 << 20
+
+// D-Bus message types
+const (
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusErrorReply   = 3
+)
+
+// D-Bus header field codes
+const (
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSignature   = 8
+)
+
+// DBusObjectPath is a D-Bus object path argument or result
+type DBusObjectPath string
+
+// DBusSignature is a D-Bus type signature argument or result
+type DBusSignature string
+
+// DBusVariant sends a value with an explicit signature, for variants and for
+// values whose D-Bus type cannot be inferred from the Go type
+type DBusVariant struct {
+	Signature string
+	Value     interface{}
+}
+
+// DBusError is an error reply from a D-Bus method call
+type DBusError struct {
+	Name    string
+	Message string
+}
+
+func (e *DBusError) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return e.Name + ": " + e.Message
+}
+
+// DBusConn is a connection to the D-Bus system bus. Calls are made one at a
+// time and signals are ignored.
+type DBusConn struct {
+	Name   string // Unique bus name of the connection
+	conn   net.Conn
+	reader *bufio.Reader
+	ctx    context.Context
+	mu     sync.Mutex
+	serial uint32
+}
+
+// dbusMessage is a decoded D-Bus message
+type dbusMessage struct {
+	Type        byte
+	Serial      uint32
+	ReplySerial uint32
+	ErrorName   string
+	Body        []interface{}
+}
+
+// SystemBus connects to the D-Bus system bus, so modules can talk to systemd,
+// NetworkManager or firewalld directly. The error wraps ErrDBusUnavailable when
+// the bus cannot be reached, for instance in containers without D-Bus.
+func (m *AnsibleModule) SystemBus() (*DBusConn, error) {
+	address := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS")
+	if address == "" {
+		address = dbusSystemBusAddress
+	}
+	conn, err := dialDBus(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBusUnavailable, err)
+	}
+	bus := &DBusConn{conn: conn, reader: bufio.NewReader(conn), ctx: m.Context()}
+	if err := bus.authenticate(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %w", ErrDBusUnavailable, err)
+	}
+	reply, err := bus.Call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello")
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %w", ErrDBusUnavailable, err)
+	}
+	if len(reply) > 0 {
+		bus.Name, _ = reply[0].(string)
+	}
+	return bus, nil
+}
+
+// dialDBus connects to the first reachable unix address of a bus address list
+func dialDBus(address string) (net.Conn, error) {
+	err := fmt.Errorf("no supported transport in %s", address)
+	for _, entry := range strings.Split(address, ";") {
+		transport, params, _ := strings.Cut(entry, ":")
+		if transport != "unix" {
+			continue
+		}
+		path := ""
+		for _, param := range strings.Split(params, ",") {
+			key, value, _ := strings.Cut(param, "=")
+			switch key {
+			case "path":
+				path = value
+			case "abstract":
+				path = "@" + value
+			}
+		}
+		if path == "" {
+			continue
+		}
+		var conn net.Conn
+		if conn, err = net.DialTimeout("unix", path, DBusTimeout); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// authenticate runs the EXTERNAL authentication handshake with the bus
+func (c *DBusConn) authenticate() error {
+	c.conn.SetDeadline(time.Now().Add(DBusTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := fmt.Fprintf(c.conn, "\x00AUTH EXTERNAL %s\r\n", uid); err != nil {
+		return err
+	}
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to authenticate: %w", err)
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("authentication rejected: %s", strings.TrimSpace(line))
+	}
+	_, err = io.WriteString(c.conn, "BEGIN\r\n")
+	return err
+}
+
+// Close closes the connection
+func (c *DBusConn) Close() error {
+	return c.conn.Close()
+}
+
+// Call invokes a method and returns the values of the reply. Arguments are
+// typed from their Go types: string, bool, the sized integers, int (as int32),
+// float64, DBusObjectPath, DBusSignature, DBusVariant, slices and maps of
+// those. Variants in the reply are unwrapped, arrays become []interface{} (or
+// []byte), dictionaries map[string]interface{} and structs []interface{}.
+func (c *DBusConn) Call(destination, path, iface, method string, args ...interface{}) ([]interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	signature, body := "", &dbusEncoder{}
+	for _, arg := range args {
+		sig, err := dbusSignatureOf(arg)
+		if err != nil {
+			return nil, err
+		}
+		if err := body.encode(sig, arg); err != nil {
+			return nil, err
+		}
+		signature += sig
+	}
+
+	fields := []interface{}{
+		[]interface{}{byte(dbusFieldPath), DBusVariant{"o", path}},
+		[]interface{}{byte(dbusFieldMember), DBusVariant{"s", method}},
+	}
+	if iface != "" {
+		fields = append(fields, []interface{}{byte(dbusFieldInterface), DBusVariant{"s", iface}})
+	}
+	if destination != "" {
+		fields = append(fields, []interface{}{byte(dbusFieldDestination), DBusVariant{"s", destination}})
+	}
+	if signature != "" {
+		fields = append(fields, []interface{}{byte(dbusFieldSignature), DBusVariant{"g", signature}})
+	}
+
+	deadline := time.Now().Add(DBusTimeout)
+	if ctxDeadline, ok := c.ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	c.conn.SetDeadline(deadline)
+	stop := context.AfterFunc(c.ctx, func() { c.conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c.serial++
+	serial := c.serial
+	if err := c.send(dbusMethodCall, serial, fields, body.buf); err != nil {
+		return nil, c.callError(method, err)
+	}
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return nil, c.callError(method, err)
+		}
+		if msg.ReplySerial != serial {
+			continue
+		}
+		switch msg.Type {
+		case dbusMethodReturn:
+			return msg.Body, nil
+		case dbusErrorReply:
+			dbusErr := &DBusError{Name: msg.ErrorName}
+			if len(msg.Body) > 0 {
+				dbusErr.Message, _ = msg.Body[0].(string)
+			}
+			return nil, dbusErr
+		}
+	}
+}
+
+// callError reports a failed call, preferring the cancellation of the module
+func (c *DBusConn) callError(method string, err error) error {
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	return fmt.Errorf("d-bus call %s failed: %w", method, err)
+}
+
+// GetProperty reads a property of an object through org.freedesktop.DBus.Properties
+func (c *DBusConn) GetProperty(destination, path, iface, property string) (interface{}, error) {
+	reply, err := c.Call(destination, path, "org.freedesktop.DBus.Properties", "Get", iface, property)
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) != 1 {
+		return nil, fmt.Errorf("unexpected reply to property %s: %v", property, reply)
+	}
+	return reply[0], nil
+}
+
+// send writes a message with the given header fields and encoded body
+func (c *DBusConn) send(msgType byte, serial uint32, fields []interface{}, body []byte) error {
+	header := &dbusEncoder{buf: []byte{'l', msgType, 0, 1}}
+	header.uint32(uint32(len(body)))
+	header.uint32(serial)
+	if err := header.encode("a(yv)", fields); err != nil {
+		return err
+	}
+	header.align(8)
+	_, err := c.conn.Write(append(header.buf, body...))
+	return err
+}
+
+// readMessage reads and decodes the next message from the bus
+func (c *DBusConn) readMessage() (*dbusMessage, error) {
+	head := make([]byte, 16)
+	if _, err := io.ReadFull(c.reader, head); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder
+	switch head[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid message byte order %q", head[0])
+	}
+	bodyLength, fieldsLength := order.Uint32(head[4:]), order.Uint32(head[12:])
+	headerLength := 16 + int(fieldsLength)
+	padded := (headerLength + 7) / 8 * 8
+	if uint64(padded)+uint64(bodyLength) > dbusMaxMessage {
+		return nil, fmt.Errorf("message of %d bytes is too large", uint64(padded)+uint64(bodyLength))
+	}
+	data := make([]byte, padded+int(bodyLength))
+	copy(data, head)
+	if _, err := io.ReadFull(c.reader, data[16:]); err != nil {
+		return nil, err
+	}
+
+	msg := &dbusMessage{Type: head[1], Serial: order.Uint32(head[8:])}
+	header := &dbusDecoder{buf: data[:headerLength], pos: 12, order: order}
+	fields, err := header.decode("a(yv)")
+	if err != nil {
+		return nil, fmt.Errorf("invalid message header: %w", err)
+	}
+	signature := ""
+	for _, field := range fields.([]interface{}) {
+		entry := field.([]interface{})
+		switch entry[0].(byte) {
+		case dbusFieldReplySerial:
+			msg.ReplySerial, _ = entry[1].(uint32)
+		case dbusFieldErrorName:
+			msg.ErrorName, _ = entry[1].(string)
+		case dbusFieldSignature:
+			if sig, ok := entry[1].(DBusSignature); ok {
+				signature = string(sig)
+			}
+		}
+	}
+
+	body := &dbusDecoder{buf: data[padded:], order: order}
+	for signature != "" {
+		var sig string
+		if sig, signature, err = nextDBusType(signature); err != nil {
+			return nil, err
+		}
+		value, err := body.decode(sig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message body: %w", err)
+		}
+		msg.Body = append(msg.Body, value)
+	}
+	return msg, nil
+}
+
+// nextDBusType splits the first complete type off a signature
+func nextDBusType(signature string) (string, string, error) {
+	if signature == "" {
+		return "", "", fmt.Errorf("empty signature")
+	}
+	switch signature[0] {
+	case 'a':
+		elem, rest, err := nextDBusType(signature[1:])
+		if err != nil {
+			return "", "", err
+		}
+		return "a" + elem, rest, nil
+	case '(', '{':
+		closing := byte(')')
+		if signature[0] == '{' {
+			closing = '}'
+		}
+		for rest := signature[1:]; rest != ""; {
+			if rest[0] == closing {
+				length := len(signature) - len(rest) + 1
+				return signature[:length], signature[length:], nil
+			}
+			var err error
+			if _, rest, err = nextDBusType(rest); err != nil {
+				return "", "", err
+			}
+		}
+		return "", "", fmt.Errorf("unterminated signature %s", signature)
+	case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 'h', 's', 'o', 'g', 'v':
+		return signature[:1], signature[1:], nil
+	}
+	return "", "", fmt.Errorf("unsupported signature %s", signature)
+}
+
+// dbusAlignment returns the alignment of the values of a type
+func dbusAlignment(sig string) int {
+	switch sig[0] {
+	case 'y', 'g', 'v':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	}
+	return 4
+}
+
+// dbusSignatureOf infers the D-Bus type of a Go value
+func dbusSignatureOf(value interface{}) (string, error) {
+	if variant, ok := value.(DBusVariant); ok {
+		return variant.Signature, nil
+	}
+	if value == nil {
+		return "", fmt.Errorf("cannot send nil over d-bus")
+	}
+	return dbusSignatureOfType(reflect.TypeOf(value))
+}
+
+// dbusSignatureOfType infers the D-Bus type of a Go type
+func dbusSignatureOfType(t reflect.Type) (string, error) {
+	switch t {
+	case reflect.TypeOf(DBusObjectPath("")):
+		return "o", nil
+	case reflect.TypeOf(DBusSignature("")):
+		return "g", nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "b", nil
+	case reflect.Uint8:
+		return "y", nil
+	case reflect.Int16:
+		return "n", nil
+	case reflect.Uint16:
+		return "q", nil
+	case reflect.Int32, reflect.Int:
+		return "i", nil
+	case reflect.Uint32, reflect.Uint:
+		return "u", nil
+	case reflect.Int64:
+		return "x", nil
+	case reflect.Uint64:
+		return "t", nil
+	case reflect.Float64, reflect.Float32:
+		return "d", nil
+	case reflect.String:
+		return "s", nil
+	case reflect.Interface, reflect.Struct:
+		return "v", nil
+	case reflect.Slice, reflect.Array:
+		elem, err := dbusSignatureOfType(t.Elem())
+		return "a" + elem, err
+	case reflect.Map:
+		key, err := dbusSignatureOfType(t.Key())
+		if err != nil {
+			return "", err
+		}
+		value, err := dbusSignatureOfType(t.Elem())
+		return "a{" + key + value + "}", err
+	}
+	return "", fmt.Errorf("cannot send %s over d-bus", t)
+}
+
+// dbusEncoder marshals values in little endian D-Bus wire format
+type dbusEncoder struct {
+	buf []byte
+}
+
+func (e *dbusEncoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *dbusEncoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+// encode appends a value of a single complete type
+func (e *dbusEncoder) encode(sig string, value interface{}) error {
+	if variant, ok := value.(DBusVariant); ok && sig != "v" {
+		value = variant.Value
+	}
+	v := reflect.ValueOf(value)
+	switch sig[0] {
+	case 'y', 'n', 'q', 'i', 'u', 'h', 'x', 't':
+		n, ok := dbusInteger(v)
+		if !ok {
+			return fmt.Errorf("expected an integer for d-bus type %s, got %T", sig, value)
+		}
+		switch sig[0] {
+		case 'y':
+			e.buf = append(e.buf, byte(n))
+		case 'n', 'q':
+			e.align(2)
+			e.buf = binary.LittleEndian.AppendUint16(e.buf, uint16(n))
+		case 'x', 't':
+			e.align(8)
+			e.buf = binary.LittleEndian.AppendUint64(e.buf, n)
+		default:
+			e.uint32(uint32(n))
+		}
+	case 'b':
+		if v.Kind() != reflect.Bool {
+			return fmt.Errorf("expected a bool for d-bus type b, got %T", value)
+		}
+		if v.Bool() {
+			e.uint32(1)
+		} else {
+			e.uint32(0)
+		}
+	case 'd':
+		if !v.CanFloat() {
+			return fmt.Errorf("expected a float for d-bus type d, got %T", value)
+		}
+		e.align(8)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+	case 's', 'o', 'g':
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("expected a string for d-bus type %s, got %T", sig, value)
+		}
+		if sig[0] == 'g' {
+			e.buf = append(e.buf, byte(v.Len()))
+		} else {
+			e.uint32(uint32(v.Len()))
+		}
+		e.buf = append(append(e.buf, v.String()...), 0)
+	case 'v':
+		inner, err := dbusSignatureOf(value)
+		if err != nil {
+			return err
+		}
+		if variant, ok := value.(DBusVariant); ok {
+			value = variant.Value
+		}
+		e.buf = append(append(append(e.buf, byte(len(inner))), inner...), 0)
+		return e.encode(inner, value)
+	case 'a':
+		return e.encodeArray(sig[1:], v)
+	case '(':
+		fields, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected []interface{} for d-bus struct %s, got %T", sig, value)
+		}
+		e.align(8)
+		inner := sig[1 : len(sig)-1]
+		for _, field := range fields {
+			var fieldSig string
+			var err error
+			if fieldSig, inner, err = nextDBusType(inner); err != nil {
+				return err
+			}
+			if err := e.encode(fieldSig, field); err != nil {
+				return err
+			}
+		}
+		if inner != "" {
+			return fmt.Errorf("too few fields for d-bus struct %s", sig)
+		}
+	default:
+		return fmt.Errorf("unsupported d-bus type %s", sig)
+	}
+	return nil
+}
+
+// encodeArray appends an array or dictionary with elements of type elem
+func (e *dbusEncoder) encodeArray(elem string, v reflect.Value) error {
+	e.uint32(0)
+	lengthAt := len(e.buf) - 4
+	e.align(dbusAlignment(elem))
+	start := len(e.buf)
+
+	if elem[0] == '{' {
+		if v.Kind() != reflect.Map {
+			return fmt.Errorf("expected a map for d-bus dictionary a%s", elem)
+		}
+		keySig, valueSig, _ := nextDBusType(elem[1 : len(elem)-1])
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			e.align(8)
+			if err := e.encode(keySig, key.Interface()); err != nil {
+				return err
+			}
+			if err := e.encode(valueSig, v.MapIndex(key).Interface()); err != nil {
+				return err
+			}
+		}
+	} else {
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fmt.Errorf("expected a slice for d-bus array a%s", elem)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(elem, v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	binary.LittleEndian.PutUint32(e.buf[lengthAt:], uint32(len(e.buf)-start))
+	return nil
+}
+
+// dbusInteger reads any Go integer as its two's complement bits
+func dbusInteger(v reflect.Value) (uint64, bool) {
+	switch {
+	case v.CanInt():
+		return uint64(v.Int()), true
+	case v.CanUint():
+		return v.Uint(), true
+	}
+	return 0, false
+}
+
+// dbusDecoder unmarshals values in D-Bus wire format
+type dbusDecoder struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (d *dbusDecoder) align(n int) error {
+	d.pos = (d.pos + n - 1) / n * n
+	if d.pos > len(d.buf) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (d *dbusDecoder) take(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// fixed reads an aligned fixed size value
+func (d *dbusDecoder) fixed(size int) ([]byte, error) {
+	if err := d.align(size); err != nil {
+		return nil, err
+	}
+	return d.take(size)
+}
+
+// decode reads a value of a single complete type
+func (d *dbusDecoder) decode(sig string) (interface{}, error) {
+	switch sig[0] {
+	case 'y':
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'n', 'q':
+		b, err := d.fixed(2)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'n' {
+			return int16(d.order.Uint16(b)), nil
+		}
+		return d.order.Uint16(b), nil
+	case 'b', 'i', 'u', 'h':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		n := d.order.Uint32(b)
+		switch sig[0] {
+		case 'b':
+			return n != 0, nil
+		case 'i':
+			return int32(n), nil
+		}
+		return n, nil
+	case 'x', 't', 'd':
+		b, err := d.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		n := d.order.Uint64(b)
+		switch sig[0] {
+		case 'x':
+			return int64(n), nil
+		case 'd':
+			return math.Float64frombits(n), nil
+		}
+		return n, nil
+	case 's', 'o':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		text, err := d.take(int(d.order.Uint32(b)) + 1)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'o' {
+			return DBusObjectPath(text[:len(text)-1]), nil
+		}
+		return string(text[:len(text)-1]), nil
+	case 'g':
+		value, err := d.signature()
+		return DBusSignature(value), err
+	case 'v':
+		inner, err := d.signature()
+		if err != nil {
+			return nil, err
+		}
+		if single, rest, err := nextDBusType(inner); err != nil || rest != "" || single == "" {
+			return nil, fmt.Errorf("invalid variant signature %q", inner)
+		}
+		return d.decode(inner)
+	case 'a':
+		return d.decodeArray(sig[1:])
+	case '(':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		var fields []interface{}
+		for inner := sig[1 : len(sig)-1]; inner != ""; {
+			var fieldSig string
+			var err error
+			if fieldSig, inner, err = nextDBusType(inner); err != nil {
+				return nil, err
+			}
+			field, err := d.decode(fieldSig)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field)
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("unsupported d-bus type %s", sig)
+}
+
+// signature reads a signature value
+func (d *dbusDecoder) signature() (string, error) {
+	b, err := d.take(1)
+	if err != nil {
+		return "", err
+	}
+	text, err := d.take(int(b[0]) + 1)
+	if err != nil {
+		return "", err
+	}
+	return string(text[:len(text)-1]), nil
+}
+
+// decodeArray reads an array or dictionary with elements of type elem
+func (d *dbusDecoder) decodeArray(elem string) (interface{}, error) {
+	b, err := d.fixed(4)
+	if err != nil {
+		return nil, err
+	}
+	length := int(d.order.Uint32(b))
+	if err := d.align(dbusAlignment(elem)); err != nil {
+		return nil, err
+	}
+	end := d.pos + length
+	if length < 0 || end > len(d.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if elem == "y" {
+		data, _ := d.take(length)
+		return append([]byte(nil), data...), nil
+	}
+
+	if elem[0] == '{' {
+		keySig, valueSig, err := nextDBusType(elem[1 : len(elem)-1])
+		if err != nil {
+			return nil, err
+		}
+		stringKeys := strings.ContainsRune("sog", rune(keySig[0]))
+		byString, byValue := map[string]interface{}{}, map[interface{}]interface{}{}
+		for d.pos < end {
+			if err := d.align(8); err != nil {
+				return nil, err
+			}
+			key, err := d.decode(keySig)
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.decode(valueSig)
+			if err != nil {
+				return nil, err
+			}
+			if stringKeys {
+				byString[fmt.Sprint(key)] = value
+			} else {
+				byValue[key] = value
+			}
+		}
+		if stringKeys {
+			return byString, nil
+		}
+		return byValue, nil
+	}
+
+	items := []interface{}{}
+	for d.pos < end {
+		item, err := d.decode(elem)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
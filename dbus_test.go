@@ -0,0 +1,138 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDBusEncoding(t *testing.T) {
+	e := &dbusEncoder{}
+	if err := e.encode("s", "ab"); err != nil {
+		t.Fatal(err)
+	}
+	e.encode("y", byte(7))
+	e.encode("x", int64(-2))
+	expected := []byte{2, 0, 0, 0, 'a', 'b', 0, 7, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if !bytes.Equal(e.buf, expected) {
+		t.Errorf("Expected %v, got %v", expected, e.buf)
+	}
+
+	value := map[string]interface{}{"enabled": true, "ports": []string{"22", "80"}, "weight": DBusVariant{"q", 3}}
+	e = &dbusEncoder{}
+	if err := e.encode("(oa{sv})", []interface{}{DBusObjectPath("/org/example"), value}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := (&dbusDecoder{buf: e.buf, order: binary.LittleEndian}).decode("(oa{sv})")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedValue := []interface{}{DBusObjectPath("/org/example"), map[string]interface{}{
+		"enabled": true, "ports": []interface{}{"22", "80"}, "weight": uint16(3),
+	}}
+	if !reflect.DeepEqual(decoded, expectedValue) {
+		t.Errorf("Expected %#v, got %#v", expectedValue, decoded)
+	}
+
+	if sig, err := dbusSignatureOf(map[string][]int32{}); err != nil || sig != "a{sai}" {
+		t.Errorf("Expected a{sai}, got %s (%v)", sig, err)
+	}
+	if _, _, err := nextDBusType("a(si"); err == nil {
+		t.Error("Expected an unterminated struct signature to fail")
+	}
+}
+
+// fakeDBus serves a bus on a unix socket answering Hello and Properties.Get
+func fakeDBus(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "bus")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("cannot listen on unix socket: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		bus := &DBusConn{conn: conn, reader: bufio.NewReader(conn)}
+		if line, _ := bus.reader.ReadString('\n'); !strings.HasPrefix(line, "\x00AUTH EXTERNAL ") {
+			conn.Write([]byte("REJECTED EXTERNAL\r\n"))
+			return
+		}
+		conn.Write([]byte("OK 0123456789abcdef\r\n"))
+		bus.reader.ReadString('\n')
+
+		for serial := uint32(100); ; serial++ {
+			msg, err := bus.readMessage()
+			if err != nil {
+				return
+			}
+			reply := []interface{}{[]interface{}{byte(dbusFieldReplySerial), DBusVariant{"u", msg.Serial}}}
+			// A signal first, which the client must skip
+			bus.send(4, serial, []interface{}{
+				[]interface{}{byte(dbusFieldPath), DBusVariant{"o", "/"}},
+				[]interface{}{byte(dbusFieldMember), DBusVariant{"s", "Noise"}},
+			}, nil)
+			body := &dbusEncoder{}
+			switch {
+			case len(msg.Body) == 0:
+				body.encode("s", ":1.42")
+				reply = append(reply, []interface{}{byte(dbusFieldSignature), DBusVariant{"g", "s"}})
+				bus.send(dbusMethodReturn, serial, reply, body.buf)
+			case msg.Body[1] == "ActiveState":
+				body.encode("v", "active")
+				reply = append(reply, []interface{}{byte(dbusFieldSignature), DBusVariant{"g", "v"}})
+				bus.send(dbusMethodReturn, serial, reply, body.buf)
+			default:
+				body.encode("s", "no such property")
+				reply = append(reply,
+					[]interface{}{byte(dbusFieldErrorName), DBusVariant{"s", "org.freedesktop.DBus.Error.UnknownProperty"}},
+					[]interface{}{byte(dbusFieldSignature), DBusVariant{"g", "s"}})
+				bus.send(dbusErrorReply, serial, reply, body.buf)
+			}
+		}
+	}()
+	return socket
+}
+
+func TestSystemBus(t *testing.T) {
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", "tcp:host=localhost;unix:path="+fakeDBus(t))
+	module := &AnsibleModule{}
+	bus, err := module.SystemBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+	if bus.Name != ":1.42" {
+		t.Errorf("Expected unique name from Hello, got %q", bus.Name)
+	}
+
+	unit := "/org/freedesktop/systemd1/unit/sshd_2eservice"
+	state, err := bus.GetProperty("org.freedesktop.systemd1", unit, "org.freedesktop.systemd1.Unit", "ActiveState")
+	if err != nil || state != "active" {
+		t.Errorf("Expected active, got %v (%v)", state, err)
+	}
+	_, err = bus.GetProperty("org.freedesktop.systemd1", unit, "org.freedesktop.systemd1.Unit", "Missing")
+	var dbusErr *DBusError
+	if !errors.As(err, &dbusErr) || dbusErr.Name != "org.freedesktop.DBus.Error.UnknownProperty" || dbusErr.Message != "no such property" {
+		t.Errorf("Expected a D-Bus error reply, got %v", err)
+	}
+}
+
+func TestSystemBusUnavailable(t *testing.T) {
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", "unix:path="+filepath.Join(t.TempDir(), "missing"))
+	if _, err := (&AnsibleModule{}).SystemBus(); !errors.Is(err, ErrDBusUnavailable) {
+		t.Errorf("Expected ErrDBusUnavailable, got %v", err)
+	}
+}
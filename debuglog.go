@@ -0,0 +1,30 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DebugLogEnv names the environment variable holding the path of the debug log file
+const DebugLogEnv = "ANSIBLE_GO_MODULE_LOG"
+
+// writeDebugLog appends a timestamped, scrubbed line to the debug log file if one is configured
+func (m *AnsibleModule) writeDebugLog(level, msg string) {
+	path := os.Getenv(DebugLogEnv)
+	if path == "" {
+		return
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	// Keep one record per line so the file stays greppable
+	msg = strings.ReplaceAll(m.scrubString(msg), "\n", "\\n")
+	fmt.Fprintf(file, "%s %s[%d] %s: %s\n",
+		time.Now().UTC().Format(time.RFC3339Nano), m.moduleName(), os.Getpid(), level, msg)
+}
@@ -0,0 +1,54 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugLogFile(t *testing.T) {
+	module := &AnsibleModule{
+		Params:         ModuleParams{"token": "abc123"},
+		NoLog:          []string{"token"},
+		NoTargetSyslog: true,
+	}
+
+	logFile := filepath.Join(t.TempDir(), "module.log")
+	t.Setenv(DebugLogEnv, logFile)
+
+	module.DebugMsg("using token abc123")
+	module.LogPriority(LogWarning, "multi\nline")
+	if _, err := module.RunCommand("echo", []string{"abc123"}, nil, ""); err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read debug log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 log lines, got %d: %s", len(lines), content)
+	}
+	if strings.Contains(string(content), "abc123") {
+		t.Error("Expected no_log value to be scrubbed from debug log")
+	}
+	if !strings.Contains(lines[0], "DEBUG: using token ********") {
+		t.Errorf("Unexpected debug line: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "WARNING: multi\\nline") {
+		t.Errorf("Unexpected warning line: %s", lines[1])
+	}
+	if !strings.Contains(lines[2], "TRACE: running command: echo ********") {
+		t.Errorf("Unexpected trace line: %s", lines[2])
+	}
+
+	// Test nothing is written when the variable is unset
+	os.Remove(logFile)
+	t.Setenv(DebugLogEnv, "")
+	module.DebugMsg("ignored")
+	if _, err := os.Stat(logFile); err == nil {
+		t.Error("Expected no debug log without the environment variable")
+	}
+}
@@ -0,0 +1,339 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ModuleDoc holds the module level metadata of a DOCUMENTATION block
+type ModuleDoc struct {
+	Module           string
+	ShortDescription string
+	Description      []string
+	VersionAdded     string
+	Author           []string
+	Requirements     []string
+	Notes            []string
+}
+
+// commonReturnKeys are result keys documented by Ansible itself, not in RETURN
+var commonReturnKeys = map[string]bool{
+	"changed":      true,
+	"failed":       true,
+	"msg":          true,
+	"invocation":   true,
+	"warnings":     true,
+	"deprecations": true,
+	"timings":      true,
+	"retries":      true,
+	"diff":         true,
+}
+
+// yamlPlainPattern matches strings that can be written as plain YAML scalars
+var yamlPlainPattern = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_ ./,()@+=-]*$`)
+
+// yamlReserved are plain scalars YAML would not read back as strings
+var yamlReserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"y": true, "n": true, "null": true,
+}
+
+// yamlString quotes a string when it cannot be written as a plain YAML scalar
+func yamlString(s string) string {
+	if yamlPlainPattern.MatchString(s) && !strings.HasSuffix(s, " ") && !yamlReserved[strings.ToLower(s)] {
+		return s
+	}
+	// JSON strings are valid YAML double-quoted scalars
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// yamlScalar renders a value in YAML flow style
+func yamlScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return yamlString(v)
+	case bool, int, int64, float64, json.Number:
+		return fmt.Sprint(v)
+	case []string:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = yamlString(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = yamlScalar(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = yamlString(k) + ": " + yamlScalar(v[k])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return yamlString(fmt.Sprint(v))
+	}
+}
+
+// writeYAMLList writes a block list of strings
+func writeYAMLList(b *strings.Builder, indent, key string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s%s:\n", indent, key)
+	for _, item := range items {
+		fmt.Fprintf(b, "%s  - %s\n", indent, yamlString(item))
+	}
+}
+
+// descriptionParagraphs splits a description into the paragraphs of a YAML list
+func descriptionParagraphs(description string) []string {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(description, "\n\n") {
+		paragraph = strings.Join(strings.Fields(paragraph), " ")
+		if paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return paragraphs
+}
+
+// Documentation renders the DOCUMENTATION YAML for a module with the given argument spec
+func (d ModuleDoc) Documentation(spec ArgSpecMap) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "module: %s\n", yamlString(d.Module))
+	if d.ShortDescription != "" {
+		fmt.Fprintf(&b, "short_description: %s\n", yamlString(d.ShortDescription))
+	}
+	if d.VersionAdded != "" {
+		fmt.Fprintf(&b, "version_added: %s\n", yamlString(d.VersionAdded))
+	}
+	writeYAMLList(&b, "", "description", d.Description)
+	writeYAMLList(&b, "", "author", d.Author)
+	writeYAMLList(&b, "", "requirements", d.Requirements)
+	writeYAMLList(&b, "", "notes", d.Notes)
+	if len(spec) > 0 {
+		b.WriteString("options:\n")
+		writeOptionDocs(&b, "  ", spec)
+	}
+	return b.String()
+}
+
+// writeOptionDocs writes the documentation of each option, sorted by name
+func writeOptionDocs(b *strings.Builder, indent string, spec ArgSpecMap) {
+	names := make([]string, 0, len(spec))
+	for name := range spec {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		option := spec[name]
+		fmt.Fprintf(b, "%s%s:\n", indent, yamlString(name))
+		inner := indent + "  "
+
+		writeYAMLList(b, inner, "description", descriptionParagraphs(option.Description))
+		optionType := option.Type
+		if optionType == "" {
+			optionType = "str"
+		}
+		fmt.Fprintf(b, "%stype: %s\n", inner, optionType)
+		if option.Elements != "" {
+			fmt.Fprintf(b, "%selements: %s\n", inner, option.Elements)
+		}
+		if option.Required {
+			fmt.Fprintf(b, "%srequired: true\n", inner)
+		}
+		if option.Default != nil {
+			fmt.Fprintf(b, "%sdefault: %s\n", inner, yamlScalar(option.Default))
+		}
+		writeYAMLList(b, inner, "choices", option.Choices)
+		writeYAMLList(b, inner, "aliases", option.Aliases)
+		if option.VersionAdded != "" {
+			fmt.Fprintf(b, "%sversion_added: %s\n", inner, yamlString(option.VersionAdded))
+		}
+
+		suboptions := option.Options
+		if suboptions == nil {
+			suboptions = option.SubOptions
+		}
+		if len(suboptions) > 0 {
+			fmt.Fprintf(b, "%ssuboptions:\n", inner)
+			writeOptionDocs(b, inner+"  ", suboptions)
+		}
+	}
+}
+
+// ReturnSkeleton renders a RETURN YAML skeleton from a sample module result.
+// Types and samples are taken from the values; descriptions are left as TODO.
+func ReturnSkeleton(sample map[string]interface{}) string {
+	var b strings.Builder
+	writeReturnDocs(&b, "", sample, true)
+	return b.String()
+}
+
+// writeReturnDocs writes the RETURN entry for each key of a result
+func writeReturnDocs(b *strings.Builder, indent string, values map[string]interface{}, topLevel bool) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if topLevel && (commonReturnKeys[key] || strings.HasPrefix(key, "_ansible_")) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := values[key]
+		inner := indent + "  "
+		fmt.Fprintf(b, "%s%s:\n", indent, yamlString(key))
+		fmt.Fprintf(b, "%sdescription: TODO\n", inner)
+		fmt.Fprintf(b, "%sreturned: success\n", inner)
+		fmt.Fprintf(b, "%stype: %s\n", inner, returnType(value))
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if len(v) > 0 {
+				fmt.Fprintf(b, "%scontains:\n", inner)
+				writeReturnDocs(b, inner+"  ", v, false)
+			}
+		case []interface{}:
+			if len(v) > 0 {
+				fmt.Fprintf(b, "%selements: %s\n", inner, returnType(v[0]))
+				if element, ok := v[0].(map[string]interface{}); ok {
+					fmt.Fprintf(b, "%scontains:\n", inner)
+					writeReturnDocs(b, inner+"  ", element, false)
+					continue
+				}
+			}
+			fmt.Fprintf(b, "%ssample: %s\n", inner, yamlScalar(v))
+		default:
+			if value != nil {
+				fmt.Fprintf(b, "%ssample: %s\n", inner, yamlScalar(value))
+			}
+		}
+	}
+}
+
+// returnType maps a result value to its Ansible documentation type
+func returnType(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "bool"
+	case int, int64:
+		return "int"
+	case float64:
+		if v := value.(float64); v == float64(int64(v)) {
+			return "int"
+		}
+		return "float"
+	case []interface{}, []string:
+		return "list"
+	case map[string]interface{}:
+		return "dict"
+	default:
+		return "str"
+	}
+}
+
+// ArgSpecFromDocumentation builds an argument spec from the options of a
+// DOCUMENTATION YAML block, so modules ported from Python keep their interface
+func ArgSpecFromDocumentation(documentation string) (ArgSpecMap, error) {
+	doc, err := parseYAML(documentation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse documentation: %w", err)
+	}
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("documentation must be a mapping")
+	}
+
+	options, ok := root["options"]
+	if !ok {
+		return ArgSpecMap{}, nil
+	}
+	return argSpecFromOptions(options, "options")
+}
+
+// argSpecFromOptions converts a documented options mapping
+func argSpecFromOptions(options interface{}, path string) (ArgSpecMap, error) {
+	if options == nil {
+		return ArgSpecMap{}, nil
+	}
+	optionMap, ok := options.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a mapping", path)
+	}
+
+	spec := make(ArgSpecMap, len(optionMap))
+	for name, value := range optionMap {
+		optionPath := path + "." + name
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a mapping", optionPath)
+		}
+
+		option := ArgumentSpec{Type: "str", Default: fields["default"]}
+		if t, ok := fields["type"].(string); ok {
+			option.Type = t
+		}
+		option.Required, _ = fields["required"].(bool)
+		option.NoLog, _ = fields["no_log"].(bool)
+		if elements, ok := fields["elements"].(string); ok {
+			option.Elements = elements
+		}
+		if version := fields["version_added"]; version != nil {
+			option.VersionAdded = fmt.Sprint(version)
+		}
+		option.Choices = yamlStringList(fields["choices"])
+		option.Aliases = yamlStringList(fields["aliases"])
+		option.Description = strings.Join(yamlStringList(fields["description"]), "\n\n")
+
+		if suboptions, ok := fields["suboptions"]; ok {
+			nested, err := argSpecFromOptions(suboptions, optionPath)
+			if err != nil {
+				return nil, err
+			}
+			if option.Type == "list" {
+				option.SubOptions = nested
+			} else {
+				option.Options = nested
+			}
+		}
+		spec[name] = option
+	}
+	return spec, nil
+}
+
+// yamlStringList converts a YAML string or list into strings
+func yamlStringList(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return items
+	default:
+		return []string{strings.TrimSpace(fmt.Sprint(v))}
+	}
+}
@@ -0,0 +1,177 @@
+package ansiblemodule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocumentation(t *testing.T) {
+	doc := ModuleDoc{
+		Module:           "demo_service",
+		ShortDescription: "Manage demo services",
+		Description:      []string{"Starts and stops demo services."},
+		VersionAdded:     "1.0.0",
+		Author:           []string{"Demo Author (@demo)"},
+	}
+	spec := ArgSpecMap{
+		"name": {Type: "str", Required: true, Aliases: []string{"service"},
+			Description: "Name of the service.\n\nMust be unique."},
+		"state": {Type: "str", Default: "started", Choices: []string{"started", "stopped"},
+			Description: "Desired state: started or stopped.", VersionAdded: "1.1.0"},
+		"enabled": {Type: "bool", Default: false},
+		"ports": {Type: "list", Elements: "dict", Options: ArgSpecMap{
+			"port": {Type: "int", Required: true, Description: "Port number."},
+		}},
+	}
+
+	expected := `module: demo_service
+short_description: Manage demo services
+version_added: "1.0.0"
+description:
+  - Starts and stops demo services.
+author:
+  - Demo Author (@demo)
+options:
+  enabled:
+    type: bool
+    default: false
+  name:
+    description:
+      - Name of the service.
+      - Must be unique.
+    type: str
+    required: true
+    aliases:
+      - service
+  ports:
+    type: list
+    elements: dict
+    suboptions:
+      port:
+        description:
+          - Port number.
+        type: int
+        required: true
+  state:
+    description:
+      - "Desired state: started or stopped."
+    type: str
+    default: started
+    choices:
+      - started
+      - stopped
+    version_added: "1.1.0"
+`
+	if actual := doc.Documentation(spec); actual != expected {
+		t.Errorf("Unexpected documentation:\n%s", actual)
+	}
+}
+
+func TestReturnSkeleton(t *testing.T) {
+	skeleton := ReturnSkeleton(map[string]interface{}{
+		"changed": true,
+		"path":    "/etc/demo.conf",
+		"count":   float64(3),
+		"items":   []interface{}{"a", "b"},
+		"status":  map[string]interface{}{"active": true},
+	})
+
+	for _, expected := range []string{
+		"path:\n  description: TODO\n  returned: success\n  type: str\n  sample: /etc/demo.conf\n",
+		"count:\n  description: TODO\n  returned: success\n  type: int\n  sample: 3\n",
+		"items:\n  description: TODO\n  returned: success\n  type: list\n  elements: str\n  sample: [a, b]\n",
+		"status:\n  description: TODO\n  returned: success\n  type: dict\n  contains:\n    active:\n",
+	} {
+		if !strings.Contains(skeleton, expected) {
+			t.Errorf("Expected skeleton to contain %q, got:\n%s", expected, skeleton)
+		}
+	}
+	if strings.Contains(skeleton, "changed:") {
+		t.Error("Expected common return values to be skipped")
+	}
+}
+
+func TestYAMLString(t *testing.T) {
+	tests := map[string]string{
+		"simple":       "simple",
+		"yes":          `"yes"`,
+		"1.0":          `"1.0"`,
+		"a: b":         `"a: b"`,
+		"":             `""`,
+		"/etc/a&b.txt": `"/etc/a&b.txt"`,
+	}
+	for input, expected := range tests {
+		if actual := yamlString(input); actual != expected {
+			t.Errorf("Expected %s for %q, got %s", expected, input, actual)
+		}
+	}
+}
+
+func TestArgSpecFromDocumentation(t *testing.T) {
+	documentation := `
+module: demo_service
+short_description: Manage demo services
+options:
+  name:
+    description:
+      - Name of the service.
+      - Must be
+        unique.
+    type: str
+    required: true
+    aliases: [service]
+  state:
+    description: Desired state.
+    choices: [ started, stopped ]
+    default: started
+  port:
+    type: int
+    default: 8080
+    version_added: "1.1.0"
+  password:
+    type: str
+    no_log: true
+  ports:
+    type: list
+    elements: dict
+    suboptions:
+      port:
+        type: int
+        required: true
+`
+	spec, err := ArgSpecFromDocumentation(documentation)
+	if err != nil {
+		t.Fatalf("Failed to import documentation: %v", err)
+	}
+
+	name := spec["name"]
+	if name.Type != "str" || !name.Required || len(name.Aliases) != 1 || name.Aliases[0] != "service" {
+		t.Errorf("Unexpected name spec: %+v", name)
+	}
+	if name.Description != "Name of the service.\n\nMust be unique." {
+		t.Errorf("Unexpected description %q", name.Description)
+	}
+	state := spec["state"]
+	if state.Type != "str" || state.Default != "started" || len(state.Choices) != 2 {
+		t.Errorf("Unexpected state spec: %+v", state)
+	}
+	if spec["port"].Default != 8080 || spec["port"].VersionAdded != "1.1.0" {
+		t.Errorf("Unexpected port spec: %+v", spec["port"])
+	}
+	if !spec["password"].NoLog {
+		t.Error("Expected password to be no_log")
+	}
+	if sub := spec["ports"].SubOptions["port"]; sub.Type != "int" || !sub.Required {
+		t.Errorf("Unexpected ports suboptions: %+v", spec["ports"].SubOptions)
+	}
+
+	// Round trip through the documentation generator
+	roundTrip, err := ArgSpecFromDocumentation(ModuleDoc{Module: "demo_service"}.Documentation(spec))
+	if err != nil {
+		t.Fatalf("Failed to import generated documentation: %v", err)
+	}
+	if roundTrip["name"].Description != name.Description || roundTrip["state"].Default != "started" ||
+		roundTrip["ports"].SubOptions["port"].Type != "int" {
+		t.Errorf("Round trip lost information: %+v", roundTrip)
+	}
+}
@@ -0,0 +1,167 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EntryListOptions controls how ReconcileEntries manages a list of lines
+type EntryListOptions struct {
+	// BeginMarker and EndMarker delimit the managed section. Without them the
+	// whole file is the section. A missing section is appended with its markers.
+	BeginMarker string
+	EndMarker   string
+	// Exclusive removes entries in the section that are not desired; otherwise
+	// they are left in place
+	Exclusive bool
+	// Key identifies an entry, so that a desired line with the same key replaces
+	// an existing one, as for an authorized key with new options. Lines are
+	// compared with surrounding whitespace trimmed by default.
+	Key func(line string) string
+	// Backup keeps a timestamped copy of the file before changing it
+	Backup bool
+	// Mode of a newly created file, 0644 if zero
+	Mode os.FileMode
+}
+
+// EntryListResult reports what ReconcileEntries changed
+type EntryListResult struct {
+	Changed bool
+	Added   []string
+	Removed []string
+	Backup  string
+	Diff    map[string]interface{}
+}
+
+// key returns the identity of an entry line
+func (o EntryListOptions) key(line string) string {
+	if o.Key != nil {
+		return o.Key(line)
+	}
+	return strings.TrimSpace(line)
+}
+
+// isEntry reports whether a line is an entry rather than a blank line or comment
+func isEntry(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && !strings.HasPrefix(trimmed, "#")
+}
+
+// ReconcileEntries makes the section of a file hold the desired entries, such
+// as lines of authorized_keys, exports or hosts. Missing entries are added at
+// the end of the section, changed entries are replaced where they are, and
+// extra entries are removed when the options are exclusive. Comments and blank
+// lines are kept. No changes are written in check mode.
+func (m *AnsibleModule) ReconcileEntries(path string, desired []string, opts EntryListOptions) (EntryListResult, error) {
+	var result EntryListResult
+	if (opts.BeginMarker == "") != (opts.EndMarker == "") {
+		return result, fmt.Errorf("both section markers must be given")
+	}
+
+	original := ""
+	if m.FileExists(path) {
+		content, err := m.ReadTextFile(path)
+		if err != nil {
+			return result, err
+		}
+		original = content
+	}
+	var lines []string
+	if original != "" {
+		lines = strings.Split(strings.TrimSuffix(original, "\n"), "\n")
+	}
+
+	// Find the section, appending it if the file has none
+	start, end := 0, len(lines)
+	if opts.BeginMarker != "" {
+		start, end = -1, -1
+		for i, line := range lines {
+			if start < 0 && line == opts.BeginMarker {
+				start = i + 1
+			} else if start >= 0 && line == opts.EndMarker {
+				end = i
+				break
+			}
+		}
+		if start < 0 || end < 0 {
+			if len(desired) == 0 {
+				return result, nil
+			}
+			lines = append(lines, opts.BeginMarker, opts.EndMarker)
+			start, end = len(lines)-1, len(lines)-1
+		}
+	}
+
+	wanted := make(map[string]string, len(desired))
+	for _, line := range desired {
+		wanted[opts.key(line)] = line
+	}
+
+	section := make([]string, 0, end-start+len(desired))
+	present := make(map[string]bool)
+	for _, line := range lines[start:end] {
+		if !isEntry(line) {
+			section = append(section, line)
+			continue
+		}
+		key := opts.key(line)
+		want, ok := wanted[key]
+		switch {
+		case ok && present[key]:
+			// A duplicate of an entry already seen
+			result.Removed = append(result.Removed, line)
+		case ok:
+			present[key] = true
+			if want != line {
+				result.Removed = append(result.Removed, line)
+				result.Added = append(result.Added, want)
+			}
+			section = append(section, want)
+		case opts.Exclusive:
+			result.Removed = append(result.Removed, line)
+		default:
+			section = append(section, line)
+		}
+	}
+	for _, line := range desired {
+		if key := opts.key(line); !present[key] {
+			present[key] = true
+			section = append(section, line)
+			result.Added = append(result.Added, line)
+		}
+	}
+
+	updated := append(append(append([]string{}, lines[:start]...), section...), lines[end:]...)
+	content := ""
+	if len(updated) > 0 {
+		content = strings.Join(updated, "\n") + "\n"
+	}
+	if content == original {
+		return EntryListResult{}, nil
+	}
+	result.Changed = true
+	result.Diff = m.CreateDiff(original, content, path+" (before)", path+" (after)")
+	if m.CheckMode {
+		return result, nil
+	}
+
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	if info, err := m.fs().Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if opts.Backup && original != "" {
+		backup, err := m.BackupFile(path)
+		if err != nil {
+			return result, err
+		}
+		result.Backup = backup
+	}
+	if _, err := m.WriteTextFile(path, content, mode); err != nil {
+		return result, err
+	}
+	return result, nil
+}
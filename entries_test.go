@@ -0,0 +1,75 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// authorizedKey identifies an authorized_keys line by its key, ignoring options and comment
+func authorizedKey(line string) string {
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "ssh-") && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+	return line
+}
+
+func TestReconcileEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+	os.WriteFile(path, []byte("# keys\nssh-ed25519 AAAA alice\nssh-rsa BBBB old\n"), 0600)
+	module := &AnsibleModule{}
+	desired := []string{`from="10.0.0.0/8" ssh-ed25519 AAAA alice`, "ssh-ed25519 CCCC bob"}
+
+	result, err := module.ReconcileEntries(path, desired, EntryListOptions{Key: authorizedKey})
+	if err != nil || !result.Changed {
+		t.Fatalf("Expected change, got %+v (%v)", result, err)
+	}
+	content, _ := os.ReadFile(path)
+	expected := "# keys\nfrom=\"10.0.0.0/8\" ssh-ed25519 AAAA alice\nssh-rsa BBBB old\nssh-ed25519 CCCC bob\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the mode to be kept, got %v", info.Mode())
+	}
+
+	result, err = module.ReconcileEntries(path, desired, EntryListOptions{Key: authorizedKey, Exclusive: true})
+	if err != nil || !reflect.DeepEqual(result.Removed, []string{"ssh-rsa BBBB old"}) || result.Added != nil {
+		t.Errorf("Expected exclusive to remove the extra key, got %+v (%v)", result, err)
+	}
+
+	result, err = module.ReconcileEntries(path, desired, EntryListOptions{Key: authorizedKey, Exclusive: true})
+	if err != nil || result.Changed {
+		t.Errorf("Expected no change the second time, got %+v (%v)", result, err)
+	}
+}
+
+func TestReconcileEntriesSection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	original := "127.0.0.1 localhost\n"
+	os.WriteFile(path, []byte(original), 0644)
+	opts := EntryListOptions{BeginMarker: "# BEGIN cluster", EndMarker: "# END cluster", Exclusive: true}
+
+	module := &AnsibleModule{CheckMode: true}
+	result, err := module.ReconcileEntries(path, []string{"10.0.0.1 node1"}, opts)
+	if err != nil || !result.Changed || result.Diff == nil {
+		t.Fatalf("Expected check mode change with diff, got %+v (%v)", result, err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != original {
+		t.Error("Expected check mode not to write")
+	}
+
+	module.CheckMode = false
+	module.ReconcileEntries(path, []string{"10.0.0.1 node1", "10.0.0.2 node2"}, opts)
+	module.ReconcileEntries(path, []string{"10.0.0.2 node2"}, opts)
+	content, _ := os.ReadFile(path)
+	expected := "127.0.0.1 localhost\n# BEGIN cluster\n10.0.0.2 node2\n# END cluster\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+}
@@ -0,0 +1,138 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"strings"
+)
+
+// ErrNotFound and ErrPermission classify errors of the helpers, so module code
+// can branch with errors.Is. They are the io/fs errors, so errors from the
+// file system match them too.
+var (
+	ErrNotFound   = fs.ErrNotExist   // A file, executable, parameter or object does not exist
+	ErrPermission = fs.ErrPermission // The module lacks the privileges for an operation
+)
+
+// ValidationError is an invalid module argument
+type ValidationError struct {
+	Param string // Option at fault, empty for constraints between options
+	Code  MessageCode
+	Msg   string
+	Err   error // Underlying cause, such as a parse error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.Error()
+	}
+	return e.Msg
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) messageCode() MessageCode {
+	return e.Code
+}
+
+// invalidParam returns the validation error code for param, with the catalog
+// message formatted with args
+func invalidParam(param string, code MessageCode, args ...interface{}) *ValidationError {
+	return &ValidationError{Param: param, Code: code, Msg: formatMessage(code, args...)}
+}
+
+// because sets the underlying cause of the validation error
+func (e *ValidationError) because(err error) *ValidationError {
+	e.Err = err
+	return e
+}
+
+// validationError reports err as an invalid value of param, keeping the
+// innermost option of nested errors
+func validationError(param string, err error) error {
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		return err
+	}
+	return invalidParam(param, CodeInvalidValue, param, err)
+}
+
+// CommandError is a command that failed to run, exited with a non-zero status,
+// timed out or was cancelled. Result holds what the command produced.
+type CommandError struct {
+	Result CommandResult
+	Code   MessageCode
+	Msg    string
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.Error()
+	}
+	return e.Msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CommandError) messageCode() MessageCode {
+	return e.Code
+}
+
+// commandError returns the error code for result, caused by err
+func commandError(result CommandResult, err error, code MessageCode, args ...interface{}) *CommandError {
+	return &CommandError{Result: result, Code: code, Msg: formatMessage(code, args...), Err: err}
+}
+
+// kindError gives an error one of the classes above without changing its message
+type kindError struct {
+	err  error
+	kind error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.err, e.kind}
+}
+
+// notFound marks err as ErrNotFound
+func notFound(err error) error {
+	return &kindError{err: err, kind: ErrNotFound}
+}
+
+// commandFailure wraps the error of a failed command with what was being done
+// and the error output of the command, keeping the CommandError for errors.As
+func commandFailure(err error, result CommandResult, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if stderr := strings.TrimSpace(result.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// FailWithError fails the module with the message of err and its error_code.
+// The output of a failed command is included as the command module returns it,
+// and rc defaults to 1 otherwise so the failure is not mistaken for success.
+func (m *AnsibleModule) FailWithError(err error, args map[string]interface{}) {
+	result := map[string]interface{}{}
+	maps.Copy(result, errorFields(err))
+	var command *CommandError
+	if errors.As(err, &command) {
+		maps.Copy(result, command.Result.ToResultFields())
+		delete(result, "changed")
+	}
+	if _, ok := result["rc"]; !ok {
+		result["rc"] = 1
+	}
+	maps.Copy(result, args)
+	m.FailJson(fmt.Sprint(err), result)
+}
@@ -0,0 +1,96 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+type missingRunner struct{ stubRunner }
+
+type deniedFileSystem struct{ OSFileSystem }
+
+func (deniedFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrPermission}
+}
+
+func (r *missingRunner) LookPath(name string) (string, error) {
+	return "", errors.New("executable file not found in $PATH")
+}
+
+func TestErrorKinds(t *testing.T) {
+	module := &AnsibleModule{Runner: &missingRunner{}}
+	if _, err := module.GetBinPath("tool", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing executable to match ErrNotFound, got %v", err)
+	}
+	if _, err := module.GetParamString("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing parameter to match ErrNotFound, got %v", err)
+	}
+	if _, err := module.ReadTextFile(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing file to match ErrNotFound, got %v", err)
+	}
+	if !errors.Is(&KubeAPIError{Status: 403}, ErrPermission) || errors.Is(&KubeAPIError{Status: 409}, ErrNotFound) {
+		t.Error("Expected Kubernetes statuses to map to error kinds")
+	}
+	denied := &AnsibleModule{FileSystem: deniedFileSystem{}}
+	if _, err := denied.CopyFile("/etc/shadow", "/tmp/shadow", 0600); !errors.Is(err, ErrPermission) || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected an unreadable source to match ErrPermission, got %v", err)
+	}
+
+	runner := &stubRunner{result: CommandResult{Stderr: "mount: only root can do that\n", Rc: 1}}
+	module = &AnsibleModule{Runner: runner}
+	var command *CommandError
+	err := module.runMount("mount", "/mnt")
+	if !errors.As(err, &command) || command.Result.Rc != 1 || ErrorCode(err) != CodeCommandFailed {
+		t.Errorf("Expected a failed helper command to keep its command error, got %v", err)
+	}
+	if err == nil || err.Error() != "mount /mnt failed: mount: only root can do that: command failed: exit status 1" {
+		t.Errorf("Unexpected message %v", err)
+	}
+
+	module = &AnsibleModule{
+		ArgSpec: ArgSpecMap{"name": {Type: "str", Required: true}, "port": {Type: "int"}},
+		Params:  ModuleParams{"name": "web", "port": "http"},
+	}
+	var validation *ValidationError
+	if err := module.validateArguments(); !errors.As(err, &validation) || validation.Param != "port" {
+		t.Errorf("Expected a validation error for port, got %v", err)
+	}
+	module.Params = ModuleParams{}
+	if err := module.validateArguments(); !errors.As(err, &validation) || validation.Param != "name" {
+		t.Errorf("Expected a validation error for name, got %v", err)
+	}
+}
+
+func TestFailWithCommandError(t *testing.T) {
+	var output bytes.Buffer
+	runner := &stubRunner{result: CommandResult{Stdout: "partial\n", Stderr: "disk full\n", Rc: 4}}
+	module := &AnsibleModule{Runner: runner, Output: &output, ExitFunc: func(int) {}}
+
+	_, err := module.RunCommand("tool", []string{"--apply"}, nil, "")
+	var command *CommandError
+	if !errors.As(err, &command) || command.Result.Rc != 4 {
+		t.Fatalf("Expected a command error with rc 4, got %v", err)
+	}
+	module.FailWithError(err, map[string]interface{}{"changed": true})
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed["failed"] != true || parsed["rc"] != float64(4) || parsed["stderr"] != "disk full" ||
+		parsed["changed"] != true || parsed["msg"] != "command failed: exit status 4" {
+		t.Errorf("Unexpected failure result: %v", parsed)
+	}
+
+	output.Reset()
+	module = &AnsibleModule{Output: &output, ExitFunc: func(int) {}}
+	module.FailWithError(errors.New("no such thing"), nil)
+	json.Unmarshal(output.Bytes(), &parsed)
+	if parsed["rc"] != float64(1) || parsed["msg"] != "no such thing" {
+		t.Errorf("Expected rc 1 for other errors, got %v", parsed)
+	}
+}
@@ -0,0 +1,73 @@
+// Command command_wrapper is an example module running a command with idempotence guards
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+var argSpec = ansiblemodule.ArgSpecMap{
+	"cmd": {Type: "str", Required: true, Description: "Name or path of the executable to run."},
+	"args": {Type: "list", Elements: "str", Default: []interface{}{},
+		Description: "Arguments passed to the command."},
+	"creates": {Type: "path",
+		Description: "If this path exists, the command is not run."},
+	"environment": {Type: "dict", Description: "Extra environment variables for the command."},
+	"stdin":       {Type: "str", Description: "Data written to the standard input of the command."},
+}
+
+func main() {
+	module, err := ansiblemodule.NewModule(argSpec, nil, nil, nil, nil, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer module.Cleanup()
+
+	run(module)
+}
+
+// run executes the command unless its guard says it already ran
+func run(m *ansiblemodule.AnsibleModule) {
+	name, _ := m.GetParamString("cmd")
+	args, err := m.GetParamStringList("args")
+	if err != nil {
+		m.FailJson(err.Error(), nil)
+	}
+	stdin, _ := m.Params["stdin"].(string)
+
+	cmd, err := m.GetBinPath(name, true)
+	if err != nil {
+		m.FailJson(err.Error(), nil)
+	}
+	result := map[string]interface{}{"cmd": append([]string{cmd}, args...)}
+
+	if creates, ok := m.Params["creates"].(string); ok && creates != "" && m.FileExists(creates) {
+		result["msg"] = fmt.Sprintf("skipped, since %s exists", creates)
+		m.ExitJson(m.HasChanged(false, result))
+	}
+	if m.CheckMode {
+		result["msg"] = "skipped in check mode"
+		m.ExitJson(m.HasChanged(true, result))
+	}
+
+	environ := make(map[string]string)
+	if environment, ok := m.Params["environment"].(map[string]interface{}); ok {
+		for key, value := range environment {
+			environ[key] = fmt.Sprint(value)
+		}
+	}
+
+	output, err := m.RunCommand(cmd, args, environ, stdin)
+	result["stdout"] = output.Stdout
+	result["stderr"] = output.Stderr
+	result["rc"] = output.Rc
+	result["stdout_lines"] = strings.Split(strings.TrimSuffix(output.Stdout, "\n"), "\n")
+	if err != nil {
+		m.FailJson(err.Error(), result)
+	}
+	m.ExitJson(m.HasChanged(true, result))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+	"github.com/the-mclain-train/ansigo-module/ansiblemoduletest"
+)
+
+// withRunner runs the module body with a fake command runner
+func withRunner(runner ansiblemodule.CommandRunner) func(m *ansiblemodule.AnsibleModule) {
+	return func(m *ansiblemodule.AnsibleModule) {
+		m.Runner = runner
+		run(m)
+	}
+}
+
+func TestCommandWrapper(t *testing.T) {
+	runner := ansiblemoduletest.NewFakeRunner()
+	runner.On([]string{"deploy", "--env", "prod"}, "deployed\n", "", 0)
+	runner.On([]string{"deploy", "*"}, "", "unknown environment\n", 2)
+
+	input := map[string]interface{}{
+		"cmd":         "deploy",
+		"args":        []interface{}{"--env", "prod"},
+		"environment": map[string]interface{}{"RELEASE": 42},
+	}
+	result := ansiblemoduletest.RunModule(t, argSpec, input, withRunner(runner))
+	result.AssertChanged(t, true)
+	if result.Output["stdout"] != "deployed\n" {
+		t.Errorf("Expected stdout to be %q, got %v", "deployed\n", result.Output["stdout"])
+	}
+	if len(runner.Calls()) != 1 {
+		t.Fatalf("Expected 1 command call, got %d", len(runner.Calls()))
+	}
+
+	input["args"] = []interface{}{"--env", "staging"}
+	result = ansiblemoduletest.RunModule(t, argSpec, input, withRunner(runner))
+	result.AssertFailedContains(t, "exit status 2")
+	if result.Output["rc"] != float64(2) {
+		t.Errorf("Expected rc to be 2, got %v", result.Output["rc"])
+	}
+}
+
+func TestCommandWrapperGuards(t *testing.T) {
+	tmpDir := t.TempDir()
+	marker := filepath.Join(tmpDir, "done")
+
+	runner := ansiblemoduletest.NewFakeRunner()
+	runner.On([]string{"deploy"}, "", "", 0)
+	input := map[string]interface{}{"cmd": "deploy", "creates": marker}
+
+	result := ansiblemoduletest.RunModule(t, argSpec,
+		map[string]interface{}{"cmd": "deploy", "creates": marker, "_ansible_check_mode": true}, withRunner(runner))
+	result.AssertChanged(t, true)
+	if len(runner.Calls()) != 0 {
+		t.Errorf("Expected no command calls in check mode, got %d", len(runner.Calls()))
+	}
+
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatalf("Failed to create marker: %v", err)
+	}
+	result = ansiblemoduletest.RunModule(t, argSpec, input, withRunner(runner))
+	result.AssertChanged(t, false)
+	if len(runner.Calls()) != 0 {
+		t.Errorf("Expected no command calls when creates exists, got %d", len(runner.Calls()))
+	}
+}
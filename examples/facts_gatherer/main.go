@@ -0,0 +1,158 @@
+// Command facts_gatherer is an example module returning facts about the managed host
+package main
+
+import (
+	"fmt"
+	"os"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+// subsets maps each fact subset to the function gathering it
+var subsets = map[string]func(m *ansiblemodule.AnsibleModule, facts map[string]interface{}) error{
+	"platform":   gatherPlatform,
+	"hardware":   gatherHardware,
+	"mounts":     gatherMounts,
+	"interfaces": gatherInterfaces,
+}
+
+// subsetOrder is the order in which subsets are gathered
+var subsetOrder = []string{"platform", "hardware", "mounts", "interfaces"}
+
+var argSpec = ansiblemodule.ArgSpecMap{
+	"gather_subset": {Type: "list", Elements: "str", Default: []interface{}{"all"},
+		Description: "Fact subsets to gather: all, platform, hardware, mounts or interfaces. " +
+			"Prefix a subset with ! to exclude it."},
+}
+
+func main() {
+	module, err := ansiblemodule.NewModule(argSpec, nil, nil, nil, nil, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer module.Cleanup()
+
+	run(module)
+}
+
+// run gathers the selected fact subsets and exits with them as ansible_facts
+func run(m *ansiblemodule.AnsibleModule) {
+	requested, err := m.GetParamStringList("gather_subset")
+	if err != nil {
+		m.FailJson(err.Error(), nil)
+	}
+	selected, err := selectSubsets(requested)
+	if err != nil {
+		m.FailJson(err.Error(), nil)
+	}
+
+	// A subset that cannot be gathered on this host is reported as a warning
+	facts := make(map[string]interface{})
+	for _, name := range selected {
+		if err := subsets[name](m, facts); err != nil {
+			m.AddWarning(fmt.Sprintf("failed to gather %s facts: %v", name, err))
+		}
+	}
+	facts["gather_subset"] = selected
+
+	m.ExitJson(map[string]interface{}{"changed": false, "ansible_facts": facts})
+}
+
+// selectSubsets resolves the gather_subset option into subset names
+func selectSubsets(requested []string) ([]string, error) {
+	include := make(map[string]bool)
+	exclude := make(map[string]bool)
+	for _, name := range requested {
+		excluded := len(name) > 0 && name[0] == '!'
+		if excluded {
+			name = name[1:]
+		}
+		if _, ok := subsets[name]; !ok && name != "all" {
+			return nil, fmt.Errorf("unknown gather_subset %q", name)
+		}
+		if excluded {
+			exclude[name] = true
+		} else {
+			include[name] = true
+		}
+	}
+
+	var selected []string
+	for _, name := range subsetOrder {
+		if (include["all"] || include[name] || len(include) == 0) && !exclude[name] && !exclude["all"] {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
+
+// gatherPlatform adds operating system facts
+func gatherPlatform(m *ansiblemodule.AnsibleModule, facts map[string]interface{}) error {
+	platform, err := m.PlatformFacts()
+	if err != nil {
+		return err
+	}
+	for key, value := range platform.AsFacts() {
+		facts[key] = value
+	}
+	return nil
+}
+
+// gatherHardware adds processor and memory facts
+func gatherHardware(m *ansiblemodule.AnsibleModule, facts map[string]interface{}) error {
+	hardware, err := m.HardwareFacts()
+	if err != nil {
+		return err
+	}
+	for key, value := range hardware.AsFacts() {
+		facts[key] = value
+	}
+	return nil
+}
+
+// gatherMounts adds the mounted filesystems
+func gatherMounts(m *ansiblemodule.AnsibleModule, facts map[string]interface{}) error {
+	mounts, err := m.ListMounts()
+	if err != nil {
+		return err
+	}
+	list := make([]interface{}, 0, len(mounts))
+	for _, mount := range mounts {
+		list = append(list, map[string]interface{}{
+			"mount":          mount.MountPoint,
+			"device":         mount.Source,
+			"fstype":         mount.FSType,
+			"options":        mount.Options,
+			"size_total":     mount.SizeTotal,
+			"size_available": mount.SizeAvailable,
+			"block_size":     mount.BlockSize,
+			"inode_total":    mount.InodesTotal,
+			"inode_used":     mount.InodesUsed,
+		})
+	}
+	facts["mounts"] = list
+	return nil
+}
+
+// gatherInterfaces adds network interfaces and addresses
+func gatherInterfaces(m *ansiblemodule.AnsibleModule, facts map[string]interface{}) error {
+	interfaces, err := m.ListInterfaces()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(interfaces))
+	for _, iface := range interfaces {
+		names = append(names, iface.Name)
+		facts[iface.Name] = map[string]interface{}{
+			"device":     iface.Name,
+			"macaddress": iface.MAC,
+			"mtu":        iface.MTU,
+			"active":     iface.Up,
+			"ipv4":       iface.IPv4,
+			"ipv6":       iface.IPv6,
+		}
+	}
+	facts["interfaces"] = names
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/the-mclain-train/ansigo-module/ansiblemoduletest"
+)
+
+func TestSelectSubsets(t *testing.T) {
+	tests := []struct {
+		requested []string
+		expected  []string
+	}{
+		{[]string{"all"}, []string{"platform", "hardware", "mounts", "interfaces"}},
+		{[]string{"mounts", "platform"}, []string{"platform", "mounts"}},
+		{[]string{"all", "!hardware"}, []string{"platform", "mounts", "interfaces"}},
+		{[]string{"!interfaces"}, []string{"platform", "hardware", "mounts"}},
+	}
+	for _, test := range tests {
+		selected, err := selectSubsets(test.requested)
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", test.requested, err)
+		}
+		if !reflect.DeepEqual(selected, test.expected) {
+			t.Errorf("Expected %v for %v, got %v", test.expected, test.requested, selected)
+		}
+	}
+
+	if _, err := selectSubsets([]string{"bogus"}); err == nil {
+		t.Error("Expected error for unknown subset")
+	}
+}
+
+func TestFactsGatherer(t *testing.T) {
+	result := ansiblemoduletest.RunModule(t, argSpec,
+		map[string]interface{}{"gather_subset": []interface{}{"platform"}}, run)
+	result.AssertChanged(t, false)
+
+	facts, ok := result.Output["ansible_facts"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected ansible_facts in result, got %v", result.Output)
+	}
+	if runtime.GOOS == "linux" && facts["system"] != "Linux" {
+		t.Errorf("Expected system to be Linux, got %v", facts["system"])
+	}
+	if _, ok := facts["mounts"]; ok {
+		t.Error("Expected mounts not to be gathered")
+	}
+
+	result = ansiblemoduletest.RunModule(t, argSpec,
+		map[string]interface{}{"gather_subset": []interface{}{"nope"}}, run)
+	result.AssertFailedContains(t, "unknown gather_subset")
+}
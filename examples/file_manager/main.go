@@ -0,0 +1,124 @@
+// Command file_manager is an example module managing files, directories and symlinks
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+var argSpec = ansiblemodule.ArgSpecMap{
+	"path": {Type: "path", Required: true, Aliases: []string{"dest"},
+		Description: "Path of the file, directory or link to manage."},
+	"state": {Type: "str", Default: "file", Choices: []string{"file", "directory", "link", "absent"},
+		Description: "Type of object to create, or absent to remove it."},
+	"content": {Type: "str", Description: "Content of the file when state is file."},
+	"src":     {Type: "path", Description: "Target of the link when state is link."},
+	"mode":    {Type: "str", Description: "Permissions in octal notation, such as 0644."},
+	"backup": {Type: "bool", Default: false,
+		Description: "Keep a timestamped copy of a file before replacing its content."},
+}
+
+var requiredIf = []ansiblemodule.RequiredIfSpec{
+	{Key: "state", Value: "link", Requirements: []string{"src"}},
+	{Key: "state", Value: "file", Requirements: []string{"content"}},
+}
+
+func main() {
+	module, err := ansiblemodule.NewModule(argSpec, nil, nil, nil, requiredIf, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer module.Cleanup()
+
+	run(module)
+}
+
+// run applies the requested state and exits with the result
+func run(m *ansiblemodule.AnsibleModule) {
+	path, _ := m.GetParamString("path")
+	state, _ := m.GetParamString("state")
+	result := map[string]interface{}{"path": path, "state": state}
+
+	mode, err := fileMode(m, state)
+	if err != nil {
+		m.FailJson(err.Error(), result)
+	}
+
+	var changed bool
+	switch state {
+	case "absent":
+		changed = m.FileExists(path) || m.IsSymlink(path)
+		if changed && !m.CheckMode {
+			err = os.RemoveAll(path)
+		}
+	case "directory":
+		if m.CheckMode {
+			changed = !m.IsDir(path)
+		} else {
+			changed, err = m.CreateDirectory(path, mode)
+		}
+	case "link":
+		src, _ := m.GetParamString("src")
+		if m.CheckMode {
+			target, readErr := os.Readlink(path)
+			changed = readErr != nil || target != src
+		} else {
+			changed, err = m.CreateSymlink(src, path)
+		}
+	case "file":
+		changed, err = writeFile(m, path, mode, result)
+	}
+	if err != nil {
+		m.FailJson(err.Error(), result)
+	}
+
+	m.ExitJson(m.HasChanged(changed, result))
+}
+
+// fileMode returns the requested permissions or the default for the state
+func fileMode(m *ansiblemodule.AnsibleModule, state string) (os.FileMode, error) {
+	mode, ok := m.Params["mode"].(string)
+	if !ok || mode == "" {
+		if state == "directory" {
+			return 0755, nil
+		}
+		return 0644, nil
+	}
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mode %s: %v", mode, err)
+	}
+	return os.FileMode(value), nil
+}
+
+// writeFile replaces the file content, recording a diff and optional backup in result
+func writeFile(m *ansiblemodule.AnsibleModule, path string, mode os.FileMode, result map[string]interface{}) (bool, error) {
+	content, _ := m.GetParamString("content")
+
+	before := ""
+	if m.FileExists(path) {
+		var err error
+		if before, err = m.ReadTextFile(path); err != nil {
+			return false, err
+		}
+	}
+	if before != content {
+		result["diff"] = m.CreateDiff(before, content, path+" (before)", path+" (after)")
+	}
+	if m.CheckMode {
+		return before != content, nil
+	}
+
+	if backup, _ := m.GetParamBool("backup"); backup && before != "" && before != content {
+		backupPath, err := m.BackupFile(path)
+		if err != nil {
+			return false, err
+		}
+		result["backup_file"] = backupPath
+	}
+	return m.WriteTextFile(path, content, mode)
+}
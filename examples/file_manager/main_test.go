@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/the-mclain-train/ansigo-module/ansiblemoduletest"
+)
+
+func TestFileManager(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.conf")
+	input := map[string]interface{}{"path": path, "content": "port=80\n", "mode": "0600"}
+
+	result := ansiblemoduletest.RunModule(t, argSpec, input, run)
+	result.AssertChanged(t, true)
+	if content, _ := os.ReadFile(path); string(content) != "port=80\n" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	result = ansiblemoduletest.RunModule(t, argSpec, input, run)
+	result.AssertChanged(t, false)
+
+	input["content"] = "port=8080\n"
+	input["backup"] = true
+	result = ansiblemoduletest.RunModule(t, argSpec, input, run)
+	result.AssertChanged(t, true)
+	if _, ok := result.Output["backup_file"].(string); !ok {
+		t.Error("Expected backup file to be reported")
+	}
+	if _, ok := result.Output["diff"]; !ok {
+		t.Error("Expected diff to be reported")
+	}
+
+	link := filepath.Join(dir, "current")
+	result = ansiblemoduletest.RunModule(t, argSpec, map[string]interface{}{"dest": link, "state": "link", "src": path}, run)
+	result.AssertChanged(t, true)
+
+	result = ansiblemoduletest.RunModule(t, argSpec, map[string]interface{}{"path": link, "state": "absent"}, run)
+	result.AssertChanged(t, true)
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Error("Expected link to be removed")
+	}
+}
+
+func TestFileManagerCheckMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new")
+	result := ansiblemoduletest.RunModule(t, argSpec,
+		map[string]interface{}{"path": path, "state": "directory", "_ansible_check_mode": true}, run)
+	result.AssertChanged(t, true)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected check mode not to create the directory")
+	}
+}
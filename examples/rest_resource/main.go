@@ -0,0 +1,162 @@
+// Command rest_resource is an example module managing a named resource through a REST API
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	ansiblemodule "github.com/the-mclain-train/ansigo-module"
+)
+
+var argSpec = ansiblemodule.ArgSpecMap{
+	"url": {Type: "str", Required: true,
+		Description: "Base URL of the resource collection, such as https://api.example.com/v1/widgets."},
+	"name": {Type: "str", Required: true, Description: "Name of the resource."},
+	"state": {Type: "str", Default: "present", Choices: []string{"present", "absent"},
+		Description: "Whether the resource should exist."},
+	"attributes": {Type: "dict", Description: "Attributes the resource should have."},
+	"api_token":  {Type: "str", NoLog: true, Description: "Bearer token used to authenticate."},
+	"wait": {Type: "bool", Default: false,
+		Description: "Wait until the API reports the requested state."},
+	"rate_limit": {Type: "float", Default: 10.0, Description: "Maximum requests per second."},
+}
+
+func main() {
+	module, err := ansiblemodule.NewModule(argSpec, nil, nil, nil, nil, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer module.Cleanup()
+
+	run(module)
+}
+
+// client performs requests against the resource URL
+type client struct {
+	module  *ansiblemodule.AnsibleModule
+	url     string
+	headers map[string]string
+}
+
+// get returns the current resource attributes, or nil if it does not exist
+func (c *client) get() (map[string]interface{}, error) {
+	resp, err := c.module.FetchURL(http.MethodGet, c.url, nil, c.headers)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.Status == http.StatusNotFound:
+		return nil, nil
+	case resp.Status != http.StatusOK:
+		return nil, fmt.Errorf("GET %s returned status %d", c.url, resp.Status)
+	}
+
+	var attributes map[string]interface{}
+	if err := json.Unmarshal(resp.Body, &attributes); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %v", c.url, err)
+	}
+	return attributes, nil
+}
+
+// send performs a request with an optional JSON body, accepting any 2xx status
+func (c *client) send(method string, body interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	resp, err := c.module.FetchURL(method, c.url, bytes.NewReader(data), c.headers)
+	if err != nil {
+		return err
+	}
+	if resp.Status < 200 || resp.Status > 299 {
+		return fmt.Errorf("%s %s returned status %d: %s", method, c.url, resp.Status, strings.TrimSpace(string(resp.Body)))
+	}
+	return nil
+}
+
+// run converges the resource and exits with the result
+func run(m *ansiblemodule.AnsibleModule) {
+	baseURL, _ := m.GetParamString("url")
+	name, _ := m.GetParamString("name")
+	state, _ := m.GetParamString("state")
+	desired, _ := m.Params["attributes"].(map[string]interface{})
+
+	c := &client{
+		module:  m,
+		url:     strings.TrimSuffix(baseURL, "/") + "/" + name,
+		headers: map[string]string{"Accept": "application/json", "Content-Type": "application/json"},
+	}
+	if token, ok := m.Params["api_token"].(string); ok && token != "" {
+		c.headers["Authorization"] = "Bearer " + token
+	}
+	if rate, ok := m.Params["rate_limit"].(float64); ok && rate > 0 {
+		m.SetRateLimit(ansiblemodule.RateLimitConfig{RequestsPerSecond: rate, Burst: 1, MaxRetries: 3})
+	}
+
+	current, err := c.get()
+	if err != nil {
+		m.FailJson(err.Error(), nil)
+	}
+	result := map[string]interface{}{"url": c.url}
+
+	changed := false
+	switch state {
+	case "present":
+		changed = current == nil || !hasAttributes(current, desired)
+		if changed && !m.CheckMode {
+			err = c.send(http.MethodPut, desired)
+		}
+	case "absent":
+		changed = current != nil
+		if changed && !m.CheckMode {
+			err = c.send(http.MethodDelete, nil)
+		}
+	}
+	if err != nil {
+		m.FailJson(err.Error(), result)
+	}
+
+	// Some APIs apply changes asynchronously
+	if wait, _ := m.GetParamBool("wait"); wait && changed && !m.CheckMode {
+		summary, err := m.RetryUntil(10, time.Second, 1.5, func(attempt int) (bool, error) {
+			current, err = c.get()
+			if err != nil {
+				return false, err
+			}
+			if state == "absent" {
+				return current == nil, nil
+			}
+			return current != nil && hasAttributes(current, desired), nil
+		})
+		if err != nil {
+			m.FailJson(err.Error(), result)
+		}
+		result["wait_attempts"] = summary.Attempts
+	}
+
+	if state == "present" && !m.CheckMode && changed {
+		current = desired
+	}
+	result["resource"] = current
+	m.ExitJson(m.HasChanged(changed, result))
+}
+
+// hasAttributes reports whether current contains every desired attribute value
+func hasAttributes(current, desired map[string]interface{}) bool {
+	for key, value := range desired {
+		if !reflect.DeepEqual(current[key], value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/the-mclain-train/ansigo-module/ansiblemoduletest"
+)
+
+// fakeAPI stores resources in memory
+type fakeAPI struct {
+	mu        sync.Mutex
+	resources map[string]map[string]interface{}
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/widgets/")
+	switch r.Method {
+	case http.MethodGet:
+		resource, ok := a.resources[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(resource)
+	case http.MethodPut:
+		var resource map[string]interface{}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &resource)
+		a.resources[name] = resource
+	case http.MethodDelete:
+		delete(a.resources, name)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestRestResource(t *testing.T) {
+	api := &fakeAPI{resources: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	input := map[string]interface{}{
+		"url":        server.URL + "/widgets",
+		"name":       "blue",
+		"attributes": map[string]interface{}{"color": "blue", "size": float64(3)},
+		"api_token":  "secret",
+		"wait":       true,
+	}
+
+	result := ansiblemoduletest.RunModule(t, argSpec, input, run)
+	result.AssertChanged(t, true)
+	if api.resources["blue"]["color"] != "blue" {
+		t.Errorf("Expected resource to be created, got %v", api.resources)
+	}
+	if result.Output["invocation"].(map[string]interface{})["api_token"] == "secret" {
+		t.Error("Expected api_token to be hidden in the invocation")
+	}
+
+	result = ansiblemoduletest.RunModule(t, argSpec, input, run)
+	result.AssertChanged(t, false)
+
+	input["state"] = "absent"
+	input["_ansible_check_mode"] = true
+	result = ansiblemoduletest.RunModule(t, argSpec, input, run)
+	result.AssertChanged(t, true)
+	if _, ok := api.resources["blue"]; !ok {
+		t.Error("Expected check mode not to delete the resource")
+	}
+
+	delete(input, "_ansible_check_mode")
+	result = ansiblemoduletest.RunModule(t, argSpec, input, run)
+	result.AssertChanged(t, true)
+	if _, ok := api.resources["blue"]; ok {
+		t.Error("Expected resource to be deleted")
+	}
+
+	input["api_token"] = "wrong"
+	result = ansiblemoduletest.RunModule(t, argSpec, input, run)
+	result.AssertFailedContains(t, "status 401")
+}
@@ -0,0 +1,174 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"time"
+)
+
+// ExpectTimeout is how long Expect waits for a prompt when the options set no timeout
+var ExpectTimeout = 30 * time.Second
+
+// ExpectResponse answers a prompt of an interactive command
+type ExpectResponse struct {
+	Pattern string // Regular expression matched against the output since the last answer
+	// Responses are the lines sent when the pattern matches. A single response is
+	// sent on every match, several are sent in turn and running out of them fails.
+	Responses []string
+}
+
+// ExpectOptions controls how Expect drives a command
+type ExpectOptions struct {
+	Responses   []ExpectResponse
+	Timeout     time.Duration // Time to wait for each prompt, ExpectTimeout if zero, unlimited if negative
+	Echo        bool          // Keep terminal echo, so responses appear in the output
+	Environment map[string]string
+}
+
+// Expect runs a command that cannot be driven non-interactively, answering its
+// prompts. On Linux the command runs on a pseudo-terminal so that tools reading
+// from the terminal can be answered; elsewhere it reads from a pipe. The
+// combined output is returned as Stdout and, at verbosity 4 and above, the
+// transcript is logged.
+func (m *AnsibleModule) Expect(cmd string, args []string, opts ExpectOptions) (CommandResult, error) {
+	result := CommandResult{Cmd: cmd, Rc: -1}
+	patterns := make([]*regexp.Regexp, len(opts.Responses))
+	for i, response := range opts.Responses {
+		pattern, err := regexp.Compile(response.Pattern)
+		if err != nil {
+			return result, fmt.Errorf("invalid prompt pattern %q: %w", response.Pattern, err)
+		}
+		if len(response.Responses) == 0 {
+			return result, fmt.Errorf("no responses given for prompt %q", response.Pattern)
+		}
+		patterns[i] = pattern
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = ExpectTimeout
+	}
+
+	ctx, cancel := context.WithCancel(m.Context())
+	defer cancel()
+	command := exec.CommandContext(ctx, cmd, args...)
+	if opts.Environment != nil || m.Locale != "" {
+		command.Env = os.Environ()
+		if m.Locale != "" {
+			command.Env = append(command.Env, localeEnv(m.Locale)...)
+		}
+		for k, v := range opts.Environment {
+			command.Env = append(command.Env, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	m.writeDebugLog("TRACE", fmt.Sprintf("expecting command: %s %v", cmd, args))
+
+	console, err := startInteractive(command, opts.Echo)
+	if err != nil {
+		return result, fmt.Errorf("failed to start %s: %w", cmd, err)
+	}
+	defer console.Close()
+
+	// Read the output in the background so waiting can time out
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, 4096)
+		for {
+			n, err := console.Read(buf)
+			if n > 0 {
+				chunks <- append([]byte(nil), buf[:n]...)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var transcript, pending bytes.Buffer
+	sent := make([]int, len(opts.Responses))
+	var timer <-chan time.Time
+	if timeout > 0 {
+		timer = time.After(timeout)
+	}
+	var runErr error
+
+read:
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				break read
+			}
+			transcript.Write(chunk)
+			pending.Write(chunk)
+
+			// Answer every prompt found in the output so far
+			for matched := true; matched; {
+				matched = false
+				for i, pattern := range patterns {
+					loc := pattern.FindIndex(pending.Bytes())
+					if loc == nil {
+						continue
+					}
+					responses := opts.Responses[i].Responses
+					if len(responses) > 1 && sent[i] >= len(responses) {
+						runErr = fmt.Errorf("no remaining responses for %q, output was %q", opts.Responses[i].Pattern, pending.String())
+						break read
+					}
+					response := responses[min(sent[i], len(responses)-1)]
+					sent[i]++
+					m.writeDebugLog("TRACE", fmt.Sprintf("answering prompt %q", opts.Responses[i].Pattern))
+					if _, err := io.WriteString(console, response+"\n"); err != nil {
+						runErr = fmt.Errorf("failed to answer prompt %q: %w", opts.Responses[i].Pattern, err)
+						break read
+					}
+					pending.Next(loc[1])
+					if timeout > 0 {
+						timer = time.After(timeout)
+					}
+					matched = true
+					break
+				}
+			}
+		case <-timer:
+			runErr = fmt.Errorf("timed out after %v waiting for a prompt, output was %q", timeout, pending.String())
+			break read
+		}
+	}
+	if runErr != nil {
+		cancel()
+	}
+	go func() {
+		for range chunks {
+		}
+	}()
+
+	waitErr := command.Wait()
+	result.Stdout = transcript.String()
+	result.StdoutBytes = int64(transcript.Len())
+	var exitError *exec.ExitError
+	switch {
+	case errors.As(waitErr, &exitError):
+		result.Rc = exitCode(exitError)
+	case waitErr == nil:
+		result.Rc = 0
+	}
+	m.auditCommand(cmd, args, result.Rc)
+	if m.Verbosity >= 4 {
+		m.LogPriority(LogDebug, fmt.Sprintf("expect transcript of %s:\n%s", cmd, result.Stdout))
+	}
+
+	if runErr != nil {
+		return result, runErr
+	}
+	if waitErr != nil {
+		return result, fmt.Errorf("command failed: %v", waitErr)
+	}
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package ansiblemodule
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// startInteractive starts a command on a new pseudo-terminal and returns its
+// controlling side
+func startInteractive(command *exec.Cmd, echo bool) (io.ReadWriteCloser, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	var unlock int32
+	var number uint32
+	if err := ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		master.Close()
+		return nil, err
+	}
+	if err := ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&number)); err != nil {
+		master.Close()
+		return nil, err
+	}
+	terminal, err := os.OpenFile("/dev/pts/"+strconv.Itoa(int(number)), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, err
+	}
+	defer terminal.Close()
+
+	if !echo {
+		var termios syscall.Termios
+		if err := ioctl(terminal, syscall.TCGETS, unsafe.Pointer(&termios)); err == nil {
+			termios.Lflag &^= syscall.ECHO
+			ioctl(terminal, syscall.TCSETS, unsafe.Pointer(&termios))
+		}
+	}
+
+	command.Stdin, command.Stdout, command.Stderr = terminal, terminal, terminal
+	command.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if err := command.Start(); err != nil {
+		master.Close()
+		return nil, err
+	}
+	return ptyMaster{master}, nil
+}
+
+// ioctl issues an ioctl on a file
+func ioctl(file *os.File, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// ptyMaster reads the terminal output, treating the EIO returned once the
+// command has exited and closed the terminal as the end of the output
+type ptyMaster struct {
+	*os.File
+}
+
+func (p ptyMaster) Read(b []byte) (int, error) {
+	n, err := p.File.Read(b)
+	if pathErr, ok := err.(*os.PathError); ok && pathErr.Err == syscall.EIO {
+		return n, io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,45 @@
+//go:build !linux
+
+package ansiblemodule
+
+import (
+	"io"
+	"os"
+	"os/exec"
+)
+
+// startInteractive starts a command reading from a pipe, with its output and
+// errors combined. Without a terminal there is no echo to disable.
+func startInteractive(command *exec.Cmd, echo bool) (io.ReadWriteCloser, error) {
+	stdin, err := command.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	command.Stdout, command.Stderr = writer, writer
+	if err := command.Start(); err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, err
+	}
+	writer.Close()
+	return pipeConsole{reader, stdin}, nil
+}
+
+// pipeConsole reads the command output and writes its input
+type pipeConsole struct {
+	io.ReadCloser
+	stdin io.WriteCloser
+}
+
+func (p pipeConsole) Write(b []byte) (int, error) {
+	return p.stdin.Write(b)
+}
+
+func (p pipeConsole) Close() error {
+	p.stdin.Close()
+	return p.ReadCloser.Close()
+}
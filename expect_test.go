@@ -0,0 +1,54 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpect(t *testing.T) {
+	module := &AnsibleModule{}
+	script := `printf "Username: "; read user; printf "Password: "; read pass; echo "hello $user/$pass"; exit 3`
+
+	result, err := module.Expect("/bin/sh", []string{"-c", script}, ExpectOptions{
+		Responses: []ExpectResponse{
+			{Pattern: `(?i)username:\s*$`, Responses: []string{"admin"}},
+			{Pattern: `Password: $`, Responses: []string{"s3cret"}},
+		},
+	})
+	if err == nil || result.Rc != 3 {
+		t.Errorf("Expected exit code 3 to fail, got rc=%d (%v)", result.Rc, err)
+	}
+	if !strings.Contains(result.Stdout, "hello admin/s3cret") {
+		t.Errorf("Expected answers in output, got %q", result.Stdout)
+	}
+	if strings.Count(result.Stdout, "admin") != 1 {
+		t.Errorf("Expected responses not to be echoed, got %q", result.Stdout)
+	}
+}
+
+func TestExpectFailures(t *testing.T) {
+	module := &AnsibleModule{}
+
+	start := time.Now()
+	_, err := module.Expect("/bin/sh", []string{"-c", `printf "Continue? "; sleep 10`}, ExpectOptions{
+		Responses: []ExpectResponse{{Pattern: "Password:", Responses: []string{"x"}}},
+		Timeout:   200 * time.Millisecond,
+	})
+	if err == nil || !strings.Contains(err.Error(), "Continue?") || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected timeout reporting the output, got %v", err)
+	}
+
+	_, err = module.Expect("/bin/sh", []string{"-c", `for i in 1 2 3; do printf "Next: "; read x; done`}, ExpectOptions{
+		Responses: []ExpectResponse{{Pattern: "Next: ", Responses: []string{"a", "b"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "no remaining responses") {
+		t.Errorf("Expected running out of responses to fail, got %v", err)
+	}
+
+	if _, err := module.Expect("/bin/true", nil, ExpectOptions{Responses: []ExpectResponse{{Pattern: "("}}}); err == nil {
+		t.Error("Expected invalid pattern to fail")
+	}
+}
@@ -0,0 +1,192 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// PlatformFacts describes the operating system the module is running on
+type PlatformFacts struct {
+	System                   string
+	Kernel                   string
+	KernelVersion            string
+	Architecture             string
+	OSFamily                 string
+	Distribution             string
+	DistributionVersion      string
+	DistributionMajorVersion string
+	DistributionRelease      string
+}
+
+// osReleasePaths are the locations searched for os-release data
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// distributionNames maps os-release IDs to Ansible distribution names
+var distributionNames = map[string]string{
+	"almalinux":           "AlmaLinux",
+	"alpine":              "Alpine",
+	"amzn":                "Amazon",
+	"arch":                "Archlinux",
+	"centos":              "CentOS",
+	"debian":              "Debian",
+	"fedora":              "Fedora",
+	"gentoo":              "Gentoo",
+	"linuxmint":           "Linux Mint",
+	"ol":                  "OracleLinux",
+	"opensuse-leap":       "openSUSE Leap",
+	"opensuse-tumbleweed": "openSUSE Tumbleweed",
+	"raspbian":            "Debian",
+	"rhel":                "RedHat",
+	"rocky":               "Rocky",
+	"sles":                "SLES",
+	"ubuntu":              "Ubuntu",
+}
+
+// osFamilies maps os-release IDs to Ansible OS families
+var osFamilies = map[string]string{
+	"almalinux":           "RedHat",
+	"alpine":              "Alpine",
+	"amzn":                "RedHat",
+	"arch":                "Archlinux",
+	"centos":              "RedHat",
+	"debian":              "Debian",
+	"fedora":              "RedHat",
+	"gentoo":              "Gentoo",
+	"linuxmint":           "Debian",
+	"ol":                  "RedHat",
+	"opensuse":            "Suse",
+	"opensuse-leap":       "Suse",
+	"opensuse-tumbleweed": "Suse",
+	"raspbian":            "Debian",
+	"rhel":                "RedHat",
+	"rocky":               "RedHat",
+	"sles":                "Suse",
+	"suse":                "Suse",
+	"ubuntu":              "Debian",
+}
+
+// goArchNames maps GOARCH values to uname machine names
+var goArchNames = map[string]string{
+	"amd64":   "x86_64",
+	"386":     "i386",
+	"arm64":   "aarch64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// PlatformFacts gathers operating system, distribution and kernel information
+func (m *AnsibleModule) PlatformFacts() (*PlatformFacts, error) {
+	facts := &PlatformFacts{}
+
+	// Kernel information from uname
+	sysname, release, version, machine := uname()
+	facts.System = sysname
+	facts.Kernel = release
+	facts.KernelVersion = version
+	facts.Architecture = machine
+	if facts.System == "" {
+		facts.System = strings.ToUpper(runtime.GOOS[:1]) + runtime.GOOS[1:]
+	}
+	if facts.Architecture == "" {
+		facts.Architecture = runtime.GOARCH
+		if name, ok := goArchNames[runtime.GOARCH]; ok {
+			facts.Architecture = name
+		}
+	}
+
+	// Distribution information from os-release
+	var osRelease map[string]string
+	for _, path := range osReleasePaths {
+		if values, err := parseKeyValueFile(path); err == nil {
+			osRelease = values
+			break
+		}
+	}
+
+	if osRelease == nil {
+		// Systems without os-release (macOS, BSDs) report the system as distribution
+		facts.OSFamily = facts.System
+		facts.Distribution = facts.System
+		facts.DistributionVersion = facts.Kernel
+	} else {
+		facts.applyOSRelease(osRelease)
+	}
+
+	if facts.DistributionVersion != "" {
+		facts.DistributionMajorVersion = strings.SplitN(facts.DistributionVersion, ".", 2)[0]
+	}
+
+	return facts, nil
+}
+
+// applyOSRelease fills distribution facts from parsed os-release values
+func (f *PlatformFacts) applyOSRelease(values map[string]string) {
+	id := strings.ToLower(values["ID"])
+
+	f.Distribution = distributionNames[id]
+	if f.Distribution == "" {
+		f.Distribution = values["NAME"]
+	}
+	f.DistributionVersion = values["VERSION_ID"]
+	f.DistributionRelease = values["VERSION_CODENAME"]
+
+	// Fall back to ID_LIKE for derivatives not known by ID
+	f.OSFamily = osFamilies[id]
+	if f.OSFamily == "" {
+		for _, like := range strings.Fields(values["ID_LIKE"]) {
+			if family, ok := osFamilies[strings.ToLower(like)]; ok {
+				f.OSFamily = family
+				break
+			}
+		}
+	}
+	if f.OSFamily == "" {
+		f.OSFamily = f.Distribution
+	}
+}
+
+// AsFacts returns the facts using Ansible fact names
+func (f *PlatformFacts) AsFacts() map[string]interface{} {
+	return map[string]interface{}{
+		"system":                     f.System,
+		"kernel":                     f.Kernel,
+		"kernel_version":             f.KernelVersion,
+		"architecture":               f.Architecture,
+		"os_family":                  f.OSFamily,
+		"distribution":               f.Distribution,
+		"distribution_version":       f.DistributionVersion,
+		"distribution_major_version": f.DistributionMajorVersion,
+		"distribution_release":       f.DistributionRelease,
+	}
+}
+
+// parseKeyValueFile reads a shell-style KEY=value file such as os-release into a map
+func parseKeyValueFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+
+	return values, scanner.Err()
+}
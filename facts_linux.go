@@ -0,0 +1,27 @@
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// uname returns the system name, kernel release, kernel version and machine
+func uname() (string, string, string, string) {
+	var buf syscall.Utsname
+	if err := syscall.Uname(&buf); err != nil {
+		return "", "", "", ""
+	}
+	return utsString(buf.Sysname[:]), utsString(buf.Release[:]),
+		utsString(buf.Version[:]), utsString(buf.Machine[:])
+}
+
+// utsString converts a NUL terminated utsname field to a string
+func utsString[T int8 | uint8](field []T) string {
+	b := make([]byte, 0, len(field))
+	for _, c := range field {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package ansiblemodule
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// uname returns the system name, kernel release, kernel version and machine
+func uname() (string, string, string, string) {
+	fields := make([]string, 4)
+	for i, flag := range []string{"-s", "-r", "-v", "-m"} {
+		out, err := exec.Command("uname", flag).Output()
+		if err != nil {
+			return "", "", "", ""
+		}
+		fields[i] = strings.TrimSpace(string(out))
+	}
+	return fields[0], fields[1], fields[2], fields[3]
+}
@@ -0,0 +1,67 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlatformFacts(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	osRelease := filepath.Join(tmpDir, "os-release")
+	content := `# comment
+NAME="Rocky Linux"
+ID="rocky"
+ID_LIKE="rhel centos fedora"
+VERSION_ID="9.3"
+VERSION_CODENAME='blue onyx'
+`
+	if err := os.WriteFile(osRelease, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write os-release: %v", err)
+	}
+
+	oldPaths := osReleasePaths
+	osReleasePaths = []string{filepath.Join(tmpDir, "missing"), osRelease}
+	defer func() { osReleasePaths = oldPaths }()
+
+	facts, err := module.PlatformFacts()
+	if err != nil {
+		t.Fatalf("Failed to gather facts: %v", err)
+	}
+	if facts.Distribution != "Rocky" {
+		t.Errorf("Expected distribution 'Rocky', got '%s'", facts.Distribution)
+	}
+	if facts.OSFamily != "RedHat" {
+		t.Errorf("Expected os family 'RedHat', got '%s'", facts.OSFamily)
+	}
+	if facts.DistributionVersion != "9.3" || facts.DistributionMajorVersion != "9" {
+		t.Errorf("Expected version 9.3 (major 9), got %s (major %s)", facts.DistributionVersion, facts.DistributionMajorVersion)
+	}
+	if facts.DistributionRelease != "blue onyx" {
+		t.Errorf("Expected release 'blue onyx', got '%s'", facts.DistributionRelease)
+	}
+	if facts.System == "" || facts.Architecture == "" {
+		t.Error("Expected system and architecture to be set")
+	}
+
+	// Test unknown derivative falls back to ID_LIKE
+	unknown := &PlatformFacts{}
+	unknown.applyOSRelease(map[string]string{"ID": "pop", "NAME": "Pop!_OS", "ID_LIKE": "ubuntu debian"})
+	if unknown.OSFamily != "Debian" {
+		t.Errorf("Expected os family 'Debian', got '%s'", unknown.OSFamily)
+	}
+	if unknown.Distribution != "Pop!_OS" {
+		t.Errorf("Expected distribution 'Pop!_OS', got '%s'", unknown.Distribution)
+	}
+
+	if facts.AsFacts()["os_family"] != "RedHat" {
+		t.Error("Expected os_family in facts map")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package ansiblemodule
+
+import (
+	"os"
+)
+
+// modeMatches reports whether a file already has the requested permission bits
+func modeMatches(info os.FileInfo, mode os.FileMode) bool {
+	return info.Mode().Perm() == mode.Perm()
+}
+
+// isExecutableFile reports whether the execute bit for the current user is set
+func isExecutableFile(path string, info os.FileInfo) bool {
+	return info.Mode()&executeMask(info) != 0
+}
@@ -0,0 +1,55 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestModeMatches(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "file.txt")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatalf("Failed to chmod file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+
+	if !modeMatches(info, 0644) {
+		t.Error("Expected 0644 to match")
+	}
+	if modeMatches(info, 0444) {
+		t.Error("Expected read-only mode not to match")
+	}
+	// Only the write bit is meaningful on Windows
+	if modeMatches(info, 0600) != (runtime.GOOS == "windows") {
+		t.Errorf("Unexpected result comparing 0600 on %s", runtime.GOOS)
+	}
+}
+
+func TestIsExecutableFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	module := &AnsibleModule{}
+
+	script := filepath.Join(tmpDir, "run.cmd")
+	if err := os.WriteFile(script, []byte("echo"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	data := filepath.Join(tmpDir, "data.txt")
+	if err := os.WriteFile(data, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if !module.IsExecutable(script) {
+		t.Errorf("Expected %s to be executable", script)
+	}
+	if module.IsExecutable(data) {
+		t.Errorf("Expected %s not to be executable", data)
+	}
+}
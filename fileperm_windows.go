@@ -0,0 +1,34 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultPathExt is used when PATHEXT is not set
+const defaultPathExt = ".COM;.EXE;.BAT;.CMD"
+
+// modeMatches reports whether a file already has the requested permissions. Windows
+// only maps the owner write bit, to the read-only attribute, so the other bits are ignored.
+func modeMatches(info os.FileInfo, mode os.FileMode) bool {
+	return info.Mode().Perm()&0200 == mode.Perm()&0200
+}
+
+// isExecutableFile reports whether the file extension is listed in PATHEXT
+func isExecutableFile(path string, info os.FileInfo) bool {
+	if info.IsDir() {
+		return false
+	}
+	pathExt := os.Getenv("PATHEXT")
+	if pathExt == "" {
+		pathExt = defaultPathExt
+	}
+	ext := filepath.Ext(path)
+	for _, candidate := range strings.Split(pathExt, ";") {
+		if candidate != "" && strings.EqualFold(candidate, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// File is an open file of a FileSystem
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Name() string
+}
+
+// FileSystem is the set of operations used by the module file helpers.
+// Tests can replace the module FileSystem with an in-memory implementation.
+type FileSystem interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	CreateTemp(dir, pattern string) (File, error)
+	MkdirTemp(dir, pattern string) (string, error)
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	Readlink(name string) (string, error)
+	Symlink(oldname, newname string) error
+	MkdirAll(path string, perm fs.FileMode) error
+	Chmod(name string, mode fs.FileMode) error
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	RemoveAll(path string) error
+}
+
+// OSFileSystem is the FileSystem backed by the operating system
+type OSFileSystem struct{}
+
+// Open opens a file for reading
+func (OSFileSystem) Open(name string) (File, error) {
+	return os.Open(name)
+}
+
+// OpenFile opens a file with the given flags
+func (OSFileSystem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+// CreateTemp creates a new temporary file in dir
+func (OSFileSystem) CreateTemp(dir, pattern string) (File, error) {
+	return os.CreateTemp(dir, pattern)
+}
+
+// MkdirTemp creates a new temporary directory in dir
+func (OSFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	return os.MkdirTemp(dir, pattern)
+}
+
+// ReadFile reads the whole file
+func (OSFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// ReadDir returns the entries of a directory sorted by name
+func (OSFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// Stat returns file information, following symlinks
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Lstat returns file information without following symlinks
+func (OSFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+// Readlink returns the target of a symlink
+func (OSFileSystem) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+// Symlink creates newname as a symlink to oldname
+func (OSFileSystem) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+// MkdirAll creates a directory and any missing parents
+func (OSFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// Chmod changes the mode of a file
+func (OSFileSystem) Chmod(name string, mode fs.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// Rename renames a file
+func (OSFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// Remove removes a file or empty directory
+func (OSFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// RemoveAll removes a path and everything below it
+func (OSFileSystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// fs returns the module file system, defaulting to OSFileSystem
+func (m *AnsibleModule) fs() FileSystem {
+	if m.FileSystem == nil {
+		return OSFileSystem{}
+	}
+	return m.FileSystem
+}
+
+// createTemp creates a temporary file for the file helpers in the module file system
+func (m *AnsibleModule) createTemp(prefix string) (File, error) {
+	dir, err := m.tmpDir()
+	if err != nil {
+		return nil, err
+	}
+	return m.fs().CreateTemp(dir, prefix)
+}
+
+// ensurePrivateDir creates dir with mode 0700 if needed and checks that it is
+// a real directory owned by the current user and closed to everyone else, so
+// files in it cannot be planted or replaced by other users
+func ensurePrivateDir(dir string) error {
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() || info.Mode().Perm()&0077 != 0 || !ownedByCurrentUser(info) {
+		return fmt.Errorf("%s is not a private directory of the current user", dir)
+	}
+	return nil
+}
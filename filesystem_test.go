@@ -0,0 +1,42 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOSFileSystem(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	module := &AnsibleModule{TmpDir: tmpDir}
+	if _, ok := module.fs().(OSFileSystem); !ok {
+		t.Fatalf("Expected OSFileSystem by default, got %T", module.fs())
+	}
+
+	file, err := module.createTemp("test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	file.Write([]byte("data"))
+	file.Close()
+	if filepath.Dir(file.Name()) != tmpDir {
+		t.Errorf("Expected temp file in TmpDir, got %s", file.Name())
+	}
+
+	dest := filepath.Join(tmpDir, "sub", "dest")
+	if err := module.fs().MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := module.fs().Rename(file.Name(), dest); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	data, err := module.fs().ReadFile(dest)
+	if err != nil || string(data) != "data" {
+		t.Errorf("Expected renamed content, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,123 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// FilesSpec returns the options of Ansible's files doc fragment, for modules that
+// set the mode, ownership, SELinux context and attributes of what they create
+func FilesSpec() ArgSpecMap {
+	return ArgSpecMap{
+		"mode":          {Type: "raw", Description: "Permissions of the file, as an octal number or symbolic mode."},
+		"owner":         {Type: "str", Description: "Name of the user that should own the file."},
+		"group":         {Type: "str", Description: "Name of the group that should own the file."},
+		"seuser":        {Type: "str", Description: "User part of the SELinux context."},
+		"serole":        {Type: "str", Description: "Role part of the SELinux context."},
+		"setype":        {Type: "str", Description: "Type part of the SELinux context."},
+		"selevel":       {Type: "str", Description: "Level part of the SELinux context."},
+		"unsafe_writes": {Type: "bool", Default: false, Description: "Fall back to unsafe writes when atomic moves fail."},
+		"attributes":    {Type: "str", Aliases: []string{"attr"}, Description: "Attributes of the file, as shown by lsattr."},
+	}
+}
+
+// BackupSpec returns the options for keeping a backup of a file before changing it
+func BackupSpec() ArgSpecMap {
+	return ArgSpecMap{
+		"backup":     {Type: "bool", Default: false, Description: "Create a backup file including the timestamp information."},
+		"backup_dir": {Type: "path", Description: "Directory for backup files, next to the original if not set."},
+	}
+}
+
+// ValidateSpec returns the option for a command validating a file before it is put in place
+func ValidateSpec() ArgSpecMap {
+	return ArgSpecMap{
+		"validate": {Type: "str", Description: "Command validating the temporary file, with %s replaced by its path."},
+	}
+}
+
+// URLSpec returns the options of Ansible's url doc fragment, for modules fetching URLs
+func URLSpec() ArgSpecMap {
+	return ArgSpecMap{
+		"url":              {Type: "str", Description: "HTTP, HTTPS, or FTP URL."},
+		"force":            {Type: "bool", Default: false, Aliases: []string{"thirsty"}, Description: "Download the file every time."},
+		"http_agent":       {Type: "str", Default: "ansible-httpget", Description: "Header to identify as."},
+		"use_proxy":        {Type: "bool", Default: true, Description: "Use proxies set in the environment."},
+		"validate_certs":   {Type: "bool", Default: true, Description: "Validate SSL certificates."},
+		"url_username":     {Type: "str", Description: "Username for HTTP basic authentication."},
+		"url_password":     {Type: "str", NoLog: true, Description: "Password for HTTP basic authentication."},
+		"force_basic_auth": {Type: "bool", Default: false, Description: "Send the basic authentication header on the first request."},
+		"client_cert":      {Type: "path", Description: "PEM certificate for SSL client authentication."},
+		"client_key":       {Type: "path", Description: "PEM private key for SSL client authentication."},
+		"use_gssapi":       {Type: "bool", Default: false, Description: "Use GSSAPI for authentication."},
+	}
+}
+
+// KubeSpec returns the connection options shared by modules talking to a
+// Kubernetes API server, read by KubeClient
+func KubeSpec() ArgSpecMap {
+	return ArgSpecMap{
+		"kubeconfig":     {Type: "path", Description: "Path to a kubeconfig file, defaults to K8S_AUTH_KUBECONFIG, KUBECONFIG or ~/.kube/config."},
+		"context":        {Type: "str", Description: "Kubeconfig context to use, defaults to the current context."},
+		"namespace":      {Type: "str", Description: "Namespace of the objects, defaults to the namespace of the context."},
+		"host":           {Type: "str", Description: "URL of the API server, used instead of the kubeconfig."},
+		"api_key":        {Type: "str", NoLog: true, Description: "Token to authenticate with the API server."},
+		"validate_certs": {Type: "bool", Aliases: []string{"verify_ssl"}, Description: "Verify the API server certificate, defaults to the kubeconfig setting."},
+		"ca_cert":        {Type: "path", Aliases: []string{"ssl_ca_cert"}, Description: "CA certificate of the API server."},
+		"client_cert":    {Type: "path", Aliases: []string{"cert_file"}, Description: "Client certificate to authenticate with."},
+		"client_key":     {Type: "path", Aliases: []string{"key_file"}, Description: "Private key of the client certificate."},
+	}
+}
+
+// WatchdogSpec returns the option limiting how long the module may run, read
+// by NewModule
+func WatchdogSpec() ArgSpecMap {
+	return ArgSpecMap{
+		WatchdogOption: {Type: "float", Description: "Fail the module if it runs longer than this many seconds, 0 for no limit."},
+	}
+}
+
+// MergeArgSpecs combines argument specs, such as a module's own options and
+// fragments. An option may appear in several specs only with the same definition,
+// and no option name or alias may be used by two different options.
+func MergeArgSpecs(specs ...ArgSpecMap) (ArgSpecMap, error) {
+	merged := ArgSpecMap{}
+	for _, spec := range specs {
+		names := make([]string, 0, len(spec))
+		for name := range spec {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			option := spec[name]
+			if existing, ok := merged[name]; ok {
+				if !reflect.DeepEqual(existing, option) {
+					return nil, fmt.Errorf("option %s is defined differently by two specs", name)
+				}
+				continue
+			}
+			merged[name] = option
+		}
+	}
+
+	// Every name and alias must lead to a single option
+	owners := map[string]string{}
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		owners[name] = name
+	}
+	for _, name := range names {
+		for _, alias := range merged[name].Aliases {
+			if owner, ok := owners[alias]; ok && owner != name {
+				return nil, fmt.Errorf("alias %s of option %s conflicts with option %s", alias, name, owner)
+			}
+			owners[alias] = name
+		}
+	}
+	return merged, nil
+}
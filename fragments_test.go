@@ -0,0 +1,47 @@
+package ansiblemodule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeArgSpecs(t *testing.T) {
+	own := ArgSpecMap{
+		"path":  {Type: "path", Required: true},
+		"state": {Type: "str", Choices: []string{"present", "absent"}},
+	}
+	merged, err := MergeArgSpecs(own, FilesSpec(), BackupSpec(), ValidateSpec())
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	for _, name := range []string{"path", "state", "mode", "owner", "attributes", "backup", "backup_dir", "validate"} {
+		if _, ok := merged[name]; !ok {
+			t.Errorf("Expected option %s in merged spec", name)
+		}
+	}
+
+	// Fragments included twice are merged
+	if _, err := MergeArgSpecs(FilesSpec(), FilesSpec()); err != nil {
+		t.Errorf("Expected identical options to merge: %v", err)
+	}
+
+	_, err = MergeArgSpecs(FilesSpec(), ArgSpecMap{"mode": {Type: "str"}})
+	if err == nil || !strings.Contains(err.Error(), "option mode") {
+		t.Errorf("Expected conflicting option error, got %v", err)
+	}
+
+	_, err = MergeArgSpecs(URLSpec(), ArgSpecMap{"thirsty": {Type: "bool"}})
+	if err == nil || !strings.Contains(err.Error(), "alias thirsty") {
+		t.Errorf("Expected alias conflict error, got %v", err)
+	}
+}
+
+func TestFragmentsAreSane(t *testing.T) {
+	for name, spec := range map[string]ArgSpecMap{"files": FilesSpec(), "backup": BackupSpec(), "validate": ValidateSpec(), "url": URLSpec(), "kube": KubeSpec(), "watchdog": WatchdogSpec()} {
+		for _, finding := range SanityCheck(SanityDefinition{ArgSpec: spec, SupportsCheckMode: true}) {
+			if finding.Severity == "error" {
+				t.Errorf("%s fragment: %s %s", name, finding.Path, finding.Msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// fstabPath is the default filesystem table
+var fstabPath = "/etc/fstab"
+
+// FstabEntry describes a line of the filesystem table
+type FstabEntry struct {
+	Source  string // Device, LABEL=, UUID= or remote share
+	Path    string // Mount point
+	FSType  string
+	Options string // Comma separated, defaults to "defaults"
+	Dump    int
+	Pass    int
+}
+
+// Line renders the fstab line for the entry
+func (e FstabEntry) Line() string {
+	options := e.Options
+	if options == "" {
+		options = "defaults"
+	}
+	return strings.Join([]string{
+		escapeFstabField(e.Source),
+		escapeFstabField(e.Path),
+		e.FSType,
+		options,
+		strconv.Itoa(e.Dump),
+		strconv.Itoa(e.Pass),
+	}, "\t")
+}
+
+// escapeFstabField encodes whitespace and backslashes with the octal escapes fstab(5) uses
+func escapeFstabField(field string) string {
+	var b strings.Builder
+	for _, r := range field {
+		switch r {
+		case ' ', '\t', '\n', '\\':
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseFstabLine parses an fstab line, reporting false for comments and blank lines
+func parseFstabLine(line string) (FstabEntry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+		return FstabEntry{}, false
+	}
+	entry := FstabEntry{
+		Source:  unescapeMountField(fields[0]),
+		Path:    unescapeMountField(fields[1]),
+		FSType:  fields[2],
+		Options: "defaults",
+	}
+	if len(fields) > 3 {
+		entry.Options = fields[3]
+	}
+	if len(fields) > 4 {
+		entry.Dump, _ = strconv.Atoi(fields[4])
+	}
+	if len(fields) > 5 {
+		entry.Pass, _ = strconv.Atoi(fields[5])
+	}
+	return entry, true
+}
+
+// Fstab holds the content of a filesystem table being edited
+type Fstab struct {
+	File     string // Path of the table, defaults to /etc/fstab
+	original string
+	lines    []string
+	noEOL    bool        // The loaded table did not end in a newline
+	mode     os.FileMode // Permissions of the loaded table, kept on save
+}
+
+// LoadFstab reads a filesystem table, or /etc/fstab if file is empty. A missing
+// file loads as an empty table.
+func (m *AnsibleModule) LoadFstab(file string) (*Fstab, error) {
+	if file == "" {
+		file = fstabPath
+	}
+	tab := &Fstab{File: file, mode: 0644}
+	if m.FileExists(file) {
+		content, err := m.ReadTextFile(file)
+		if err != nil {
+			return nil, err
+		}
+		tab.original = content
+		if info, err := m.fs().Stat(file); err == nil {
+			tab.mode = info.Mode().Perm()
+		}
+	}
+	if tab.original != "" {
+		tab.noEOL = !strings.HasSuffix(tab.original, "\n")
+		tab.lines = strings.Split(strings.TrimSuffix(tab.original, "\n"), "\n")
+	}
+	return tab, nil
+}
+
+// Entries returns all entries of the table
+func (t *Fstab) Entries() []FstabEntry {
+	var entries []FstabEntry
+	for _, line := range t.lines {
+		if entry, ok := parseFstabLine(line); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Find returns the entry mounted at path
+func (t *Fstab) Find(path string) (FstabEntry, bool) {
+	if i := t.indexOf(path); i >= 0 {
+		entry, _ := parseFstabLine(t.lines[i])
+		return entry, true
+	}
+	return FstabEntry{}, false
+}
+
+// indexOf returns the index of the first line mounting path, or -1
+func (t *Fstab) indexOf(path string) int {
+	for i, line := range t.lines {
+		if entry, ok := parseFstabLine(line); ok && entry.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// Set adds or replaces the entry for a mount point, reporting whether the
+// table changed. Existing lines that already match keep their formatting.
+func (t *Fstab) Set(entry FstabEntry) bool {
+	if entry.Options == "" {
+		entry.Options = "defaults"
+	}
+	if i := t.indexOf(entry.Path); i >= 0 {
+		if current, _ := parseFstabLine(t.lines[i]); current == entry {
+			return false
+		}
+		t.lines[i] = entry.Line()
+		return true
+	}
+	t.lines = append(t.lines, entry.Line())
+	return true
+}
+
+// Remove deletes every entry for a mount point, reporting whether the table changed
+func (t *Fstab) Remove(path string) bool {
+	changed := false
+	for i := t.indexOf(path); i >= 0; i = t.indexOf(path) {
+		t.lines = append(t.lines[:i], t.lines[i+1:]...)
+		changed = true
+	}
+	return changed
+}
+
+// Render returns the table content, ending in a newline unless the loaded
+// table did not
+func (t *Fstab) Render() string {
+	if len(t.lines) == 0 {
+		return ""
+	}
+	if t.noEOL {
+		return strings.Join(t.lines, "\n")
+	}
+	return strings.Join(t.lines, "\n") + "\n"
+}
+
+// Changed reports whether the table differs from what was loaded
+func (t *Fstab) Changed() bool {
+	return t.Render() != t.original
+}
+
+// Diff returns a diff of the loaded and current table content
+func (t *Fstab) Diff(m *AnsibleModule) map[string]interface{} {
+	return m.CreateDiff(t.original, t.Render(), t.File+" (before)", t.File+" (after)")
+}
+
+// SaveFstab writes the table if it changed, returning the backup path if requested
+func (m *AnsibleModule) SaveFstab(tab *Fstab, backup bool) (bool, string, error) {
+	if !tab.Changed() {
+		return false, "", nil
+	}
+	if m.CheckMode {
+		return true, "", nil
+	}
+
+	backupPath := ""
+	if backup && tab.original != "" {
+		path, err := m.BackupFile(tab.File)
+		if err != nil {
+			return false, "", err
+		}
+		backupPath = path
+	}
+
+	content := tab.Render()
+	if _, err := m.WriteTextFile(tab.File, content, tab.mode); err != nil {
+		return false, backupPath, err
+	}
+	tab.original = content
+	return true, backupPath, nil
+}
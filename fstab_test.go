@@ -0,0 +1,118 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFstabEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fstab")
+	content := "# /etc/fstab\nUUID=abcd  /  ext4  errors=remount-ro  0  1\n/dev/sdb1 /mnt/my\\040data xfs defaults 0 2\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	module := &AnsibleModule{}
+
+	tab, err := module.LoadFstab(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := tab.Entries(); len(entries) != 2 || entries[1].Path != "/mnt/my data" {
+		t.Fatalf("Expected two entries with unescaped path, got %+v", entries)
+	}
+
+	if tab.Set(FstabEntry{Source: "UUID=abcd", Path: "/", FSType: "ext4", Options: "errors=remount-ro", Pass: 1}) {
+		t.Error("Expected matching entry to be unchanged")
+	}
+	if !tab.Set(FstabEntry{Source: "/dev/sdb1", Path: "/mnt/my data", FSType: "xfs", Options: "noatime", Pass: 2}) {
+		t.Error("Expected changed options to update the entry")
+	}
+	tab.Set(FstabEntry{Source: "server:/export", Path: "/srv/nfs", FSType: "nfs"})
+	if tab.Remove("/missing") {
+		t.Error("Expected removing a missing entry to be unchanged")
+	}
+
+	diff := tab.Diff(module)
+	if diff["before"] != content {
+		t.Errorf("Expected diff before to be the loaded table, got %v", diff["before"])
+	}
+
+	changed, backup, err := module.SaveFstab(tab, true)
+	if err != nil || !changed || backup == "" {
+		t.Fatalf("Expected save with backup, got %v %q (%v)", changed, backup, err)
+	}
+	saved, _ := os.ReadFile(path)
+	expected := "# /etc/fstab\nUUID=abcd  /  ext4  errors=remount-ro  0  1\n" +
+		"/dev/sdb1\t/mnt/my\\040data\txfs\tnoatime\t0\t2\n" +
+		"server:/export\t/srv/nfs\tnfs\tdefaults\t0\t0\n"
+	if string(saved) != expected {
+		t.Errorf("Unexpected fstab:\n%s", saved)
+	}
+	if original, _ := os.ReadFile(backup); string(original) != content {
+		t.Errorf("Expected backup to hold the original table, got:\n%s", original)
+	}
+
+	changed, _, err = module.SaveFstab(tab, false)
+	if err != nil || changed {
+		t.Errorf("Expected second save to be unchanged, got %v (%v)", changed, err)
+	}
+}
+
+func TestFstabCheckMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fstab")
+	module := &AnsibleModule{CheckMode: true}
+
+	tab, err := module.LoadFstab(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tab.Set(FstabEntry{Source: "tmpfs", Path: "/tmp", FSType: "tmpfs"}) {
+		t.Fatal("Expected new entry to change the table")
+	}
+	changed, _, err := module.SaveFstab(tab, false)
+	if err != nil || !changed || module.FileExists(path) {
+		t.Errorf("Expected check mode to report a change without writing, got %v (%v)", changed, err)
+	}
+	if !strings.Contains(tab.Render(), "tmpfs\t/tmp\ttmpfs\tdefaults\t0\t0") {
+		t.Errorf("Unexpected render: %s", tab.Render())
+	}
+}
+
+func TestFstabKeepsFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fstab")
+	content := "UUID=abcd / ext4 defaults 0 1"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	module := &AnsibleModule{}
+
+	// A table without a final newline is unchanged until edited
+	tab, err := module.LoadFstab(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tab.Changed() {
+		t.Error("Expected the loaded table to be unchanged")
+	}
+	if changed, _, err := module.SaveFstab(tab, false); err != nil || changed {
+		t.Errorf("Expected no save for an unchanged table, got %v (%v)", changed, err)
+	}
+
+	tab.Set(FstabEntry{Source: "tmpfs", Path: "/tmp", FSType: "tmpfs"})
+	if _, _, err := module.SaveFstab(tab, false); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := os.ReadFile(path)
+	if string(saved) != content+"\ntmpfs\t/tmp\ttmpfs\tdefaults\t0\t0" {
+		t.Errorf("Unexpected fstab: %q", saved)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the table mode to be kept, got %v", info.Mode())
+	}
+}
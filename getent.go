@@ -0,0 +1,152 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// etcPath is the directory holding the system databases used when getent is unavailable
+var etcPath = "/etc"
+
+// colonDatabases are getent databases whose fields are separated by colons
+var colonDatabases = map[string]bool{
+	"passwd":  true,
+	"group":   true,
+	"shadow":  true,
+	"gshadow": true,
+}
+
+// Getent queries a system database, returning entries keyed by their first field.
+// An empty key returns every entry of the database. A missing key
+// returns an error matching ErrNotFound.
+func (m *AnsibleModule) Getent(database, key string) (map[string][]string, error) {
+	var lines []string
+
+	if getent, _ := m.GetBinPath("getent", false); getent != "" {
+		args := []string{database}
+		if key != "" {
+			args = append(args, key)
+		}
+		result, err := m.RunCommand(getent, args, nil, "")
+		if err != nil {
+			switch result.Rc {
+			case 1:
+				return nil, fmt.Errorf("missing arguments, or database %s unknown", database)
+			case 2:
+				return nil, notFound(getentNotFound{database, key})
+			case 3:
+				return nil, fmt.Errorf("enumeration not supported on database %s", database)
+			}
+			return nil, commandFailure(err, result, "getent %s failed", database)
+		}
+		lines = strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	} else {
+		var err error
+		lines, err = getentFromFile(database, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parseGetentLines(database, lines), nil
+}
+
+// parseGetentLines splits database lines into fields keyed by the first field
+func parseGetentLines(database string, lines []string) map[string][]string {
+	entries := make(map[string][]string)
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		var fields []string
+		if colonDatabases[database] {
+			fields = strings.Split(line, ":")
+		} else {
+			fields = strings.Fields(line)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		// getent prints the first match only, so keep the first occurrence of a key
+		if _, exists := entries[fields[0]]; !exists {
+			entries[fields[0]] = fields[1:]
+		}
+	}
+	return entries
+}
+
+// getentNotFound is returned by Getent when a key has no entry
+type getentNotFound struct {
+	database, key string
+}
+
+func (e getentNotFound) Error() string {
+	return fmt.Sprintf("key %s not found in database %s", e.key, e.database)
+}
+
+// getentFromFile emulates getent using the files in etcPath
+func getentFromFile(database, key string) ([]string, error) {
+	file, err := os.Open(filepath.Join(etcPath, database))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("missing arguments, or database %s unknown", database)
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 && !colonDatabases[database] {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key == "" || getentMatches(database, key, line) {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if key != "" && len(lines) == 0 {
+		return nil, notFound(getentNotFound{database, key})
+	}
+	return lines, nil
+}
+
+// getentMatches reports whether a database line matches a key the way getent does
+func getentMatches(database, key, line string) bool {
+	if colonDatabases[database] {
+		fields := strings.Split(line, ":")
+		// passwd and group can also be looked up by numeric id
+		return fields[0] == key || ((database == "passwd" || database == "group") && len(fields) > 2 && fields[2] == key)
+	}
+
+	fields := strings.Fields(line)
+	switch database {
+	case "services":
+		// Services match by name, alias, port or port/protocol
+		if len(fields) > 1 {
+			port, _, _ := strings.Cut(fields[1], "/")
+			if key == port || key == fields[1] {
+				return true
+			}
+			fields = append(fields[:1:1], fields[2:]...)
+		}
+	}
+	for _, field := range fields {
+		if field == key {
+			return true
+		}
+	}
+	return false
+}
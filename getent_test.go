@@ -0,0 +1,95 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetent(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Force the file fallback by hiding the getent binary
+	t.Setenv("PATH", tmpDir)
+	oldEtc := etcPath
+	etcPath = tmpDir
+	defer func() { etcPath = oldEtc }()
+
+	os.WriteFile(filepath.Join(tmpDir, "passwd"), []byte("root:x:0:0:root:/root:/bin/bash\napp:x:1000:1000:App User:/home/app:/bin/sh\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "hosts"), []byte("127.0.0.1 localhost\n# comment\n10.0.0.5 db db.example.com # database\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "services"), []byte("ssh 22/tcp\nhttp 80/tcp www\n"), 0644)
+
+	// Test passwd lookup by name and uid
+	entries, err := module.Getent("passwd", "app")
+	if err != nil {
+		t.Fatalf("Failed to query passwd: %v", err)
+	}
+	fields := entries["app"]
+	if len(fields) != 6 || fields[1] != "1000" || fields[4] != "/home/app" {
+		t.Errorf("Unexpected passwd entry: %v", fields)
+	}
+	entries, err = module.Getent("passwd", "0")
+	if err != nil || entries["root"] == nil {
+		t.Errorf("Expected lookup by uid to find root, got %v (%v)", entries, err)
+	}
+
+	// Test enumeration
+	entries, err = module.Getent("passwd", "")
+	if err != nil || len(entries) != 2 {
+		t.Errorf("Expected 2 passwd entries, got %v (%v)", entries, err)
+	}
+
+	// Test hosts lookup by alias strips comments
+	entries, err = module.Getent("hosts", "db.example.com")
+	if err != nil {
+		t.Fatalf("Failed to query hosts: %v", err)
+	}
+	if len(entries["10.0.0.5"]) != 2 {
+		t.Errorf("Unexpected hosts entry: %v", entries)
+	}
+
+	// Test services lookup by port and alias
+	entries, err = module.Getent("services", "80/tcp")
+	if err != nil || entries["http"] == nil {
+		t.Errorf("Expected http service, got %v (%v)", entries, err)
+	}
+	entries, err = module.Getent("services", "www")
+	if err != nil || entries["http"] == nil {
+		t.Errorf("Expected http service by alias, got %v (%v)", entries, err)
+	}
+
+	// Test missing key and database
+	if _, err := module.Getent("passwd", "nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing key to match ErrNotFound, got %v", err)
+	}
+	if _, err := module.Getent("bogus", ""); err == nil {
+		t.Error("Expected error for unknown database")
+	}
+}
+
+func TestGetentCommand(t *testing.T) {
+	module := &AnsibleModule{}
+
+	if path, _ := module.GetBinPath("getent", false); path == "" {
+		t.Skip("getent not available")
+	}
+
+	entries, err := module.Getent("passwd", "root")
+	if err != nil {
+		t.Fatalf("Failed to query passwd: %v", err)
+	}
+	if len(entries["root"]) < 2 || entries["root"][1] != "0" {
+		t.Errorf("Unexpected root entry: %v", entries["root"])
+	}
+
+	if _, err := module.Getent("passwd", "no-such-user-xyz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing user to match ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ErrBinaryFile is returned when searching a file that looks binary
+var ErrBinaryFile = errors.New("binary file")
+
+// binarySniffSize is how much of a file is checked for NUL bytes, as grep and git do
+const binarySniffSize = 8000
+
+// GrepOptions controls how Grep and FileContains match lines
+type GrepOptions struct {
+	Literal     bool // Match the pattern as plain text instead of a regular expression
+	IgnoreCase  bool
+	MaxMatches  int  // Stop after this many matching lines, 0 for no limit
+	AllowBinary bool // Search files containing NUL bytes instead of returning ErrBinaryFile
+}
+
+// GrepMatch is a line matching a Grep pattern
+type GrepMatch struct {
+	Line   int      // Line number, starting at 1
+	Text   string   // Line without its line ending
+	Groups []string // Capture groups of the first match in the line
+}
+
+// compileGrepPattern builds the regular expression for a pattern and options
+func compileGrepPattern(pattern string, opts GrepOptions) (*regexp.Regexp, error) {
+	if opts.Literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// Grep returns the lines of a file matching a pattern. The file is scanned a
+// line at a time, so large files are not read into memory.
+func (m *AnsibleModule) Grep(path, pattern string, opts GrepOptions) ([]GrepMatch, error) {
+	re, err := compileGrepPattern(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+	file, err := m.fs().Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(m.contextReader(file), IOChunkSize)
+	if !opts.AllowBinary {
+		head, err := reader.Peek(binarySniffSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+		if bytes.IndexByte(head, 0) >= 0 {
+			return nil, fmt.Errorf("%s: %w", path, ErrBinaryFile)
+		}
+	}
+
+	var matches []GrepMatch
+	for number := 1; ; number++ {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			text := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if groups := re.FindStringSubmatch(text); groups != nil {
+				matches = append(matches, GrepMatch{Line: number, Text: text, Groups: groups[1:]})
+				if opts.MaxMatches > 0 && len(matches) >= opts.MaxMatches {
+					return matches, nil
+				}
+			}
+		}
+		if err == io.EOF {
+			return matches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// FileContains reports whether any line of a file matches a pattern, stopping
+// at the first match
+func (m *AnsibleModule) FileContains(path, pattern string, opts GrepOptions) (bool, error) {
+	opts.MaxMatches = 1
+	matches, err := m.Grep(path, pattern, opts)
+	return len(matches) > 0, err
+}
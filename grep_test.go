@@ -0,0 +1,50 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGrep(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sshd_config")
+	os.WriteFile(path, []byte("Port 22\r\n#PermitRootLogin yes\nPasswordAuthentication no\nport 2222"), 0644)
+	module := &AnsibleModule{}
+
+	matches, err := module.Grep(path, `^Port (\d+)`, GrepOptions{IgnoreCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []GrepMatch{
+		{Line: 1, Text: "Port 22", Groups: []string{"22"}},
+		{Line: 4, Text: "port 2222", Groups: []string{"2222"}},
+	}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, matches)
+	}
+
+	if found, err := module.FileContains(path, "#PermitRootLogin yes", GrepOptions{Literal: true}); err != nil || !found {
+		t.Errorf("Expected literal match, got %v (%v)", found, err)
+	}
+	if found, err := module.FileContains(path, "UsePAM", GrepOptions{}); err != nil || found {
+		t.Errorf("Expected no match, got %v (%v)", found, err)
+	}
+	if _, err := module.Grep(path, "(", GrepOptions{}); err == nil {
+		t.Error("Expected invalid pattern to fail")
+	}
+}
+
+func TestGrepBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blob")
+	os.WriteFile(path, []byte("ELF\x00\x01header\nmagic marker\n"), 0644)
+	module := &AnsibleModule{}
+
+	if _, err := module.FileContains(path, "marker", GrepOptions{}); !errors.Is(err, ErrBinaryFile) {
+		t.Errorf("Expected ErrBinaryFile, got %v", err)
+	}
+	if found, err := module.FileContains(path, "marker", GrepOptions{AllowBinary: true}); err != nil || !found {
+		t.Errorf("Expected binary search to match, got %v (%v)", found, err)
+	}
+}
@@ -0,0 +1,144 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// HardwareFacts describes the processors and memory of the host
+type HardwareFacts struct {
+	ProcessorModel string
+	ProcessorCount int // Physical sockets
+	ProcessorCores int // Cores per socket
+	ProcessorVcpus int // Logical processors
+	MemTotalMB     uint64
+	MemFreeMB      uint64
+	MemAvailableMB uint64
+	SwapTotalMB    uint64
+	SwapFreeMB     uint64
+}
+
+// cpuInfoPath and memInfoPath are the locations of the kernel hardware tables
+var (
+	cpuInfoPath = "/proc/cpuinfo"
+	memInfoPath = "/proc/meminfo"
+)
+
+// HardwareFacts gathers CPU and memory information
+func (m *AnsibleModule) HardwareFacts() (*HardwareFacts, error) {
+	facts := &HardwareFacts{}
+
+	if err := facts.readCPUInfo(cpuInfoPath); err != nil || facts.ProcessorVcpus == 0 {
+		// Without /proc, fall back to what the Go runtime can see
+		facts.ProcessorVcpus = runtime.NumCPU()
+		facts.ProcessorCount = 1
+		facts.ProcessorCores = facts.ProcessorVcpus
+	}
+
+	if err := facts.readMemInfo(memInfoPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return facts, nil
+}
+
+// readCPUInfo parses processor information from a cpuinfo file
+func (f *HardwareFacts) readCPUInfo(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sockets := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "processor":
+			f.ProcessorVcpus++
+		case "model name", "Processor", "cpu model":
+			if f.ProcessorModel == "" {
+				f.ProcessorModel = value
+			}
+		case "physical id":
+			sockets[value] = true
+		case "cpu cores":
+			if cores, err := strconv.Atoi(value); err == nil {
+				f.ProcessorCores = cores
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	f.ProcessorCount = len(sockets)
+	if f.ProcessorCount == 0 {
+		f.ProcessorCount = 1
+	}
+	if f.ProcessorCores == 0 {
+		f.ProcessorCores = f.ProcessorVcpus / f.ProcessorCount
+	}
+	return nil
+}
+
+// readMemInfo parses memory information from a meminfo file
+func (f *HardwareFacts) readMemInfo(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		mb := kb / 1024
+
+		switch strings.TrimSuffix(fields[0], ":") {
+		case "MemTotal":
+			f.MemTotalMB = mb
+		case "MemFree":
+			f.MemFreeMB = mb
+		case "MemAvailable":
+			f.MemAvailableMB = mb
+		case "SwapTotal":
+			f.SwapTotalMB = mb
+		case "SwapFree":
+			f.SwapFreeMB = mb
+		}
+	}
+	return scanner.Err()
+}
+
+// AsFacts returns the facts using Ansible fact names
+func (f *HardwareFacts) AsFacts() map[string]interface{} {
+	return map[string]interface{}{
+		"processor":       f.ProcessorModel,
+		"processor_count": f.ProcessorCount,
+		"processor_cores": f.ProcessorCores,
+		"processor_vcpus": f.ProcessorVcpus,
+		"memtotal_mb":     f.MemTotalMB,
+		"memfree_mb":      f.MemFreeMB,
+		"memavailable_mb": f.MemAvailableMB,
+		"swaptotal_mb":    f.SwapTotalMB,
+		"swapfree_mb":     f.SwapFreeMB,
+	}
+}
@@ -0,0 +1,82 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHardwareFacts(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cpuInfo := `processor	: 0
+model name	: Test CPU @ 2.00GHz
+physical id	: 0
+cpu cores	: 2
+
+processor	: 1
+model name	: Test CPU @ 2.00GHz
+physical id	: 0
+cpu cores	: 2
+
+processor	: 2
+model name	: Test CPU @ 2.00GHz
+physical id	: 1
+cpu cores	: 2
+
+processor	: 3
+model name	: Test CPU @ 2.00GHz
+physical id	: 1
+cpu cores	: 2
+`
+	memInfo := `MemTotal:        8388608 kB
+MemFree:         1048576 kB
+MemAvailable:    4194304 kB
+SwapTotal:       2097152 kB
+SwapFree:        2097152 kB
+`
+	oldCPU, oldMem := cpuInfoPath, memInfoPath
+	cpuInfoPath = filepath.Join(tmpDir, "cpuinfo")
+	memInfoPath = filepath.Join(tmpDir, "meminfo")
+	defer func() { cpuInfoPath, memInfoPath = oldCPU, oldMem }()
+
+	if err := os.WriteFile(cpuInfoPath, []byte(cpuInfo), 0644); err != nil {
+		t.Fatalf("Failed to write cpuinfo: %v", err)
+	}
+	if err := os.WriteFile(memInfoPath, []byte(memInfo), 0644); err != nil {
+		t.Fatalf("Failed to write meminfo: %v", err)
+	}
+
+	facts, err := module.HardwareFacts()
+	if err != nil {
+		t.Fatalf("Failed to gather facts: %v", err)
+	}
+	if facts.ProcessorModel != "Test CPU @ 2.00GHz" {
+		t.Errorf("Unexpected processor model '%s'", facts.ProcessorModel)
+	}
+	if facts.ProcessorVcpus != 4 || facts.ProcessorCount != 2 || facts.ProcessorCores != 2 {
+		t.Errorf("Unexpected processor counts: %+v", facts)
+	}
+	if facts.MemTotalMB != 8192 || facts.MemFreeMB != 1024 || facts.MemAvailableMB != 4096 {
+		t.Errorf("Unexpected memory values: %+v", facts)
+	}
+	if facts.SwapTotalMB != 2048 || facts.SwapFreeMB != 2048 {
+		t.Errorf("Unexpected swap values: %+v", facts)
+	}
+
+	// Test fallback when cpuinfo is missing
+	os.Remove(cpuInfoPath)
+	facts, err = module.HardwareFacts()
+	if err != nil {
+		t.Fatalf("Failed to gather facts: %v", err)
+	}
+	if facts.ProcessorVcpus < 1 {
+		t.Error("Expected at least one vcpu from runtime fallback")
+	}
+}
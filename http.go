@@ -0,0 +1,70 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// URLResponse contains the results of an HTTP request made with FetchURL
+type URLResponse struct {
+	URL     string
+	Status  int
+	Headers http.Header
+	Body    []byte
+}
+
+// httpClient returns the module HTTP client, creating a default one if needed
+func (m *AnsibleModule) httpClient() *http.Client {
+	if m.HTTPClient == nil {
+		m.HTTPClient = &http.Client{
+			Transport: http.DefaultTransport,
+			Timeout:   30 * time.Second,
+		}
+	}
+	if m.HTTPClient.Transport == nil {
+		m.HTTPClient.Transport = http.DefaultTransport
+	}
+	return m.HTTPClient
+}
+
+// wrapTransport installs a RoundTripper around the current HTTP client transport
+func (m *AnsibleModule) wrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	client := m.httpClient()
+	client.Transport = wrap(client.Transport)
+}
+
+// FetchURL performs an HTTP request and returns the response
+func (m *AnsibleModule) FetchURL(method, url string, body io.Reader, headers map[string]string) (*URLResponse, error) {
+	return m.fetch(m.httpClient(), method, url, body, headers)
+}
+
+// fetch performs an HTTP request with the given client, bound to the module context
+func (m *AnsibleModule) fetch(client *http.Client, method, url string, body io.Reader, headers map[string]string) (*URLResponse, error) {
+	req, err := http.NewRequestWithContext(m.Context(), method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", url, err)
+	}
+
+	return &URLResponse{
+		URL:     url,
+		Status:  resp.StatusCode,
+		Headers: resp.Header,
+		Body:    content,
+	}, nil
+}
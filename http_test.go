@@ -0,0 +1,52 @@
+package ansiblemodule
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchURL(t *testing.T) {
+	module := &AnsibleModule{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "value" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello " + r.Method))
+	}))
+	defer server.Close()
+
+	// Test successful request
+	resp, err := module.FetchURL("GET", server.URL, nil, map[string]string{"X-Test": "value"})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Status != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.Status)
+	}
+	if string(resp.Body) != "hello GET" {
+		t.Errorf("Expected body 'hello GET', got '%s'", resp.Body)
+	}
+	if resp.Headers.Get("Content-Type") != "text/plain" {
+		t.Error("Expected Content-Type header to be returned")
+	}
+
+	// Test non-2xx status is returned without error
+	resp, err = module.FetchURL("POST", server.URL, strings.NewReader("data"), nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Status != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.Status)
+	}
+
+	// Test invalid URL
+	_, err = module.FetchURL("GET", "://invalid", nil, nil)
+	if err == nil {
+		t.Error("Expected error for invalid URL")
+	}
+}
@@ -0,0 +1,157 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// InterfaceInfo describes a network interface
+type InterfaceInfo struct {
+	Name         string
+	Index        int
+	MAC          string
+	MTU          int
+	Up           bool
+	Loopback     bool
+	Flags        []string
+	IPv4         []string // Addresses in CIDR notation
+	IPv6         []string // Addresses in CIDR notation
+	DefaultRoute bool     // Interface carries the IPv4 or IPv6 default route
+}
+
+// DefaultRoute describes a default gateway
+type DefaultRoute struct {
+	Interface string
+	Gateway   string
+}
+
+// Route tables exposed by the kernel
+var (
+	ipv4RoutePath = "/proc/net/route"
+	ipv6RoutePath = "/proc/net/ipv6_route"
+)
+
+// ListInterfaces returns the network interfaces with their addresses
+func (m *AnsibleModule) ListInterfaces() ([]InterfaceInfo, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+
+	defaults := make(map[string]bool)
+	if route, err := m.DefaultIPv4Route(); err == nil && route != nil {
+		defaults[route.Interface] = true
+	}
+	if route, err := m.DefaultIPv6Route(); err == nil && route != nil {
+		defaults[route.Interface] = true
+	}
+
+	result := make([]InterfaceInfo, 0, len(ifaces))
+	for _, iface := range ifaces {
+		info := InterfaceInfo{
+			Name:         iface.Name,
+			Index:        iface.Index,
+			MAC:          strings.ToLower(iface.HardwareAddr.String()),
+			MTU:          iface.MTU,
+			Up:           iface.Flags&net.FlagUp != 0,
+			Loopback:     iface.Flags&net.FlagLoopback != 0,
+			Flags:        strings.Split(iface.Flags.String(), "|"),
+			IPv4:         []string{},
+			IPv6:         []string{},
+			DefaultRoute: defaults[iface.Name],
+		}
+		if iface.Flags == 0 {
+			info.Flags = []string{}
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get addresses for %s: %w", iface.Name, err)
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if ipNet.IP.To4() != nil {
+				info.IPv4 = append(info.IPv4, ipNet.String())
+			} else {
+				info.IPv6 = append(info.IPv6, ipNet.String())
+			}
+		}
+
+		result = append(result, info)
+	}
+
+	return result, nil
+}
+
+// DefaultIPv4Route returns the IPv4 default route, or nil if there is none
+func (m *AnsibleModule) DefaultIPv4Route() (*DefaultRoute, error) {
+	file, err := os.Open(ipv4RoutePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Skip header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&0x1 == 0 {
+			continue // Route is not up
+		}
+
+		// The kernel prints the address bytes as a number in host byte order
+		gw, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil {
+			continue
+		}
+		return &DefaultRoute{
+			Interface: fields[0],
+			Gateway:   net.IP(binary.NativeEndian.AppendUint32(nil, uint32(gw))).String(),
+		}, nil
+	}
+
+	return nil, scanner.Err()
+}
+
+// DefaultIPv6Route returns the IPv6 default route, or nil if there is none
+func (m *AnsibleModule) DefaultIPv6Route() (*DefaultRoute, error) {
+	file, err := os.Open(ipv6RoutePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[9] == "lo" {
+			continue
+		}
+		if fields[0] != strings.Repeat("0", 32) || fields[1] != "00" {
+			continue
+		}
+		gw, err := hex.DecodeString(fields[4])
+		if err != nil || len(gw) != net.IPv6len {
+			continue
+		}
+		return &DefaultRoute{
+			Interface: fields[9],
+			Gateway:   net.IP(gw).String(),
+		}, nil
+	}
+
+	return nil, scanner.Err()
+}
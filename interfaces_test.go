@@ -0,0 +1,81 @@
+package ansiblemodule
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListInterfaces(t *testing.T) {
+	module := &AnsibleModule{}
+
+	ifaces, err := module.ListInterfaces()
+	if err != nil {
+		t.Fatalf("Failed to list interfaces: %v", err)
+	}
+
+	foundLoopback := false
+	for _, iface := range ifaces {
+		if iface.Name == "" {
+			t.Error("Expected interface name to be set")
+		}
+		if iface.Loopback {
+			foundLoopback = true
+		}
+	}
+	if len(ifaces) > 0 && !foundLoopback {
+		t.Log("No loopback interface found")
+	}
+}
+
+func TestDefaultRoutes(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// The gateway is printed in host byte order
+	gateway := fmt.Sprintf("%08X", binary.NativeEndian.Uint32(net.ParseIP("192.0.2.1").To4()))
+	ipv4 := `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth1	000200C0	00000000	0001	0	0	0	00FFFFFF	0	0	0
+eth0	00000000	` + gateway + `	0003	0	0	0	00000000	0	0	0
+`
+	ipv6 := `fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000002 00000000 00000001     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 fd000000000000000000000000000001 00000400 00000001 00000000 00000003     eth2
+`
+	oldIPv4, oldIPv6 := ipv4RoutePath, ipv6RoutePath
+	ipv4RoutePath = filepath.Join(tmpDir, "route")
+	ipv6RoutePath = filepath.Join(tmpDir, "ipv6_route")
+	defer func() { ipv4RoutePath, ipv6RoutePath = oldIPv4, oldIPv6 }()
+
+	os.WriteFile(ipv4RoutePath, []byte(ipv4), 0644)
+	os.WriteFile(ipv6RoutePath, []byte(ipv6), 0644)
+
+	route, err := module.DefaultIPv4Route()
+	if err != nil {
+		t.Fatalf("Failed to read IPv4 route: %v", err)
+	}
+	if route == nil || route.Interface != "eth0" || route.Gateway != "192.0.2.1" {
+		t.Errorf("Unexpected IPv4 default route: %+v", route)
+	}
+
+	route, err = module.DefaultIPv6Route()
+	if err != nil {
+		t.Fatalf("Failed to read IPv6 route: %v", err)
+	}
+	if route == nil || route.Interface != "eth2" || route.Gateway != "fd00::1" {
+		t.Errorf("Unexpected IPv6 default route: %+v", route)
+	}
+
+	// Test missing route table
+	os.Remove(ipv4RoutePath)
+	if _, err := module.DefaultIPv4Route(); err == nil {
+		t.Error("Expected error for missing route table")
+	}
+}
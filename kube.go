@@ -0,0 +1,420 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// kubeServiceAccountDir holds the credentials of pods running in a cluster
+var kubeServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// KubeClient sends requests to a Kubernetes API server, using the connection
+// options of KubeSpec
+type KubeClient struct {
+	Server    string // API server URL
+	Namespace string // Namespace from the options or context, default if unset
+	Context   string // Kubeconfig context in use, empty without a kubeconfig
+	m         *AnsibleModule
+	client    *http.Client
+}
+
+// KubeAPIError is an error status returned by the API server
+type KubeAPIError struct {
+	Status  int
+	Reason  string
+	Message string
+}
+
+func (e *KubeAPIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("kubernetes API returned status %d", e.Status)
+	}
+	return fmt.Sprintf("kubernetes API returned status %d (%s): %s", e.Status, e.Reason, e.Message)
+}
+
+// Is matches ErrNotFound for status 404 and ErrPermission for 401 and 403
+func (e *KubeAPIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrPermission:
+		return e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden
+	}
+	return false
+}
+
+// IsKubeNotFound reports whether an error is a 404 from the API server
+func IsKubeNotFound(err error) bool {
+	var apiErr *KubeAPIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// kubeCredentials is the resolved connection of a KubeClient
+type kubeCredentials struct {
+	server, namespace, context string
+	token, username, password  string
+	caData, certData, keyData  []byte
+	insecure                   bool
+}
+
+// KubeClient connects to the API server named by the KubeSpec options: host and
+// api_key when given, else the kubeconfig context, else the service account of
+// the pod the module runs in. Explicit options override the kubeconfig.
+func (m *AnsibleModule) KubeClient() (*KubeClient, error) {
+	param := func(name string) string {
+		value, _ := m.Params[name].(string)
+		return value
+	}
+
+	creds := &kubeCredentials{}
+	if host := param("host"); host != "" {
+		creds.server = host
+	} else if path := kubeconfigPath(param("kubeconfig")); path != "" {
+		var err error
+		if creds, err = m.loadKubeconfig(path, param("context")); err != nil {
+			return nil, err
+		}
+	} else if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		var err error
+		if creds, err = m.inClusterCredentials(); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("no kubeconfig found and not running in a cluster, set kubeconfig or host")
+	}
+
+	if token := param("api_key"); token != "" {
+		creds.token = token
+	}
+	for option, target := range map[string]*[]byte{"ca_cert": &creds.caData, "client_cert": &creds.certData, "client_key": &creds.keyData} {
+		if path := param(option); path != "" {
+			data, err := m.fs().ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", option, err)
+			}
+			*target = data
+		}
+	}
+	if validate, ok := m.Params["validate_certs"].(bool); ok {
+		creds.insecure = !validate
+	}
+	if namespace := param("namespace"); namespace != "" {
+		creds.namespace = namespace
+	}
+	if creds.namespace == "" {
+		creds.namespace = "default"
+	}
+	return m.newKubeClient(creds)
+}
+
+// kubeconfigPath returns the kubeconfig to use, or empty if there is none
+func kubeconfigPath(path string) string {
+	if path != "" {
+		return path
+	}
+	candidates := []string{os.Getenv("K8S_AUTH_KUBECONFIG")}
+	candidates = append(candidates, filepath.SplitList(os.Getenv("KUBECONFIG"))...)
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".kube", "config"))
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); candidate != "" && err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// loadKubeconfig reads the cluster and user of a kubeconfig context, the
+// current context if name is empty. Kubeconfigs may be YAML or JSON.
+func (m *AnsibleModule) loadKubeconfig(path, name string) (*kubeCredentials, error) {
+	content, err := m.fs().ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		parsed, err := parseYAML(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig %s: %w", path, err)
+		}
+		if config, _ = parsed.(map[string]interface{}); config == nil {
+			return nil, fmt.Errorf("kubeconfig %s is not a mapping", path)
+		}
+	}
+
+	if name == "" {
+		name, _ = config["current-context"].(string)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("kubeconfig %s has no current context, set context", path)
+	}
+	context := kubeconfigEntry(config, "contexts", "context", name)
+	if context == nil {
+		return nil, notFound(fmt.Errorf("context %s not found in kubeconfig %s", name, path))
+	}
+	clusterName, _ := context["cluster"].(string)
+	cluster := kubeconfigEntry(config, "clusters", "cluster", clusterName)
+	if cluster == nil {
+		return nil, notFound(fmt.Errorf("cluster %s of context %s not found in kubeconfig %s", clusterName, name, path))
+	}
+	userName, _ := context["user"].(string)
+	user := kubeconfigEntry(config, "users", "user", userName)
+	if user == nil {
+		user = map[string]interface{}{}
+	}
+
+	// Relative file references are relative to the kubeconfig
+	dir := filepath.Dir(path)
+	creds := &kubeCredentials{context: name}
+	creds.server, _ = cluster["server"].(string)
+	creds.namespace, _ = context["namespace"].(string)
+	creds.insecure, _ = cluster["insecure-skip-tls-verify"].(bool)
+	creds.token, _ = user["token"].(string)
+	creds.username, _ = user["username"].(string)
+	creds.password, _ = user["password"].(string)
+	for _, field := range []struct {
+		data, file string
+		source     map[string]interface{}
+		target     *[]byte
+	}{
+		{"certificate-authority-data", "certificate-authority", cluster, &creds.caData},
+		{"client-certificate-data", "client-certificate", user, &creds.certData},
+		{"client-key-data", "client-key", user, &creds.keyData},
+		{"", "tokenFile", user, nil},
+	} {
+		if encoded, _ := field.source[field.data].(string); field.data != "" && encoded != "" {
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s in kubeconfig %s: %w", field.data, path, err)
+			}
+			*field.target = data
+			continue
+		}
+		file, _ := field.source[field.file].(string)
+		if file == "" {
+			continue
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		data, err := m.fs().ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s of kubeconfig %s: %w", field.file, path, err)
+		}
+		if field.target == nil {
+			creds.token = strings.TrimSpace(string(data))
+		} else {
+			*field.target = data
+		}
+	}
+
+	if exec, ok := user["exec"].(map[string]interface{}); ok && creds.token == "" && creds.certData == nil {
+		if err := m.runKubeExecPlugin(exec, creds); err != nil {
+			return nil, err
+		}
+	}
+	return creds, nil
+}
+
+// kubeconfigEntry finds a named entry of a kubeconfig list and returns its body
+func kubeconfigEntry(config map[string]interface{}, list, field, name string) map[string]interface{} {
+	entries, _ := config[list].([]interface{})
+	for _, entry := range entries {
+		item, _ := entry.(map[string]interface{})
+		if item["name"] == name {
+			body, _ := item[field].(map[string]interface{})
+			return body
+		}
+	}
+	return nil
+}
+
+// runKubeExecPlugin runs a client-go credential plugin and reads the token or
+// client certificate it returns
+func (m *AnsibleModule) runKubeExecPlugin(exec map[string]interface{}, creds *kubeCredentials) error {
+	command, _ := exec["command"].(string)
+	if command == "" {
+		return fmt.Errorf("kubeconfig exec credential plugin has no command")
+	}
+	var args []string
+	if list, ok := exec["args"].([]interface{}); ok {
+		for _, arg := range list {
+			args = append(args, fmt.Sprint(arg))
+		}
+	}
+	apiVersion, _ := exec["apiVersion"].(string)
+	info, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "ExecCredential",
+		"spec":       map[string]interface{}{"interactive": false},
+	})
+	env := map[string]string{"KUBERNETES_EXEC_INFO": string(info)}
+	if list, ok := exec["env"].([]interface{}); ok {
+		for _, item := range list {
+			if variable, ok := item.(map[string]interface{}); ok {
+				env[fmt.Sprint(variable["name"])] = fmt.Sprint(variable["value"])
+			}
+		}
+	}
+
+	result, err := m.RunCommand(command, args, env, "")
+	if err != nil {
+		return commandFailure(err, result, "kubeconfig exec credential plugin %s failed", command)
+	}
+	var credential struct {
+		Status struct {
+			Token                 string `json:"token"`
+			ClientCertificateData string `json:"clientCertificateData"`
+			ClientKeyData         string `json:"clientKeyData"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &credential); err != nil {
+		return fmt.Errorf("invalid output of kubeconfig exec credential plugin %s: %w", command, err)
+	}
+	creds.token = credential.Status.Token
+	if credential.Status.ClientCertificateData != "" {
+		creds.certData = []byte(credential.Status.ClientCertificateData)
+		creds.keyData = []byte(credential.Status.ClientKeyData)
+	}
+	if creds.token != "" {
+		m.NoLogValue(creds.token)
+	}
+	return nil
+}
+
+// inClusterCredentials reads the service account of the pod the module runs in
+func (m *AnsibleModule) inClusterCredentials() (*kubeCredentials, error) {
+	token, err := m.fs().ReadFile(filepath.Join(kubeServiceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	creds := &kubeCredentials{
+		server: "https://" + net.JoinHostPort(os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")),
+		token:  strings.TrimSpace(string(token)),
+	}
+	creds.caData, _ = m.fs().ReadFile(filepath.Join(kubeServiceAccountDir, "ca.crt"))
+	if namespace, err := m.fs().ReadFile(filepath.Join(kubeServiceAccountDir, "namespace")); err == nil {
+		creds.namespace = strings.TrimSpace(string(namespace))
+	}
+	return creds, nil
+}
+
+// newKubeClient builds the HTTP client for resolved credentials
+func (m *AnsibleModule) newKubeClient(creds *kubeCredentials) (*KubeClient, error) {
+	if creds.server == "" {
+		return nil, fmt.Errorf("no kubernetes API server configured")
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: creds.insecure}
+	if len(creds.caData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(creds.caData) {
+			return nil, fmt.Errorf("no valid certificate in the API server CA")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(creds.certData) > 0 {
+		cert, err := tls.X509KeyPair(creds.certData, creds.keyData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	// Start from the module client so its timeout and proxy settings apply
+	client := *m.httpClient()
+	base, ok := client.Transport.(*http.Transport)
+	if !ok {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.TLSClientConfig = tlsConfig
+	client.Transport = &kubeAuthTransport{base: transport, creds: creds}
+
+	return &KubeClient{
+		Server:    strings.TrimSuffix(creds.server, "/"),
+		Namespace: creds.namespace,
+		Context:   creds.context,
+		m:         m,
+		client:    &client,
+	}, nil
+}
+
+// kubeAuthTransport adds the bearer token or basic credentials to requests
+type kubeAuthTransport struct {
+	base  http.RoundTripper
+	creds *kubeCredentials
+}
+
+func (t *kubeAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	switch {
+	case t.creds.token != "":
+		req.Header.Set("Authorization", "Bearer "+t.creds.token)
+	case t.creds.username != "":
+		req.SetBasicAuth(t.creds.username, t.creds.password)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Request sends a request to an API path such as /api/v1/namespaces, encoding
+// body as JSON unless it is nil. PATCH requests are sent as JSON merge patches.
+// Error statuses are returned as *KubeAPIError along with the response.
+func (c *KubeClient) Request(method, path string, body interface{}) (*URLResponse, error) {
+	var reader io.Reader
+	headers := map[string]string{"Accept": "application/json"}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		headers["Content-Type"] = "application/json"
+		if method == http.MethodPatch {
+			headers["Content-Type"] = "application/merge-patch+json"
+		}
+	}
+	response, err := c.m.fetch(c.client, method, c.Server+path, reader, headers)
+	if err != nil {
+		return nil, err
+	}
+	if response.Status >= 400 {
+		apiErr := &KubeAPIError{Status: response.Status}
+		var status struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(response.Body, &status) == nil {
+			apiErr.Reason, apiErr.Message = status.Reason, status.Message
+		}
+		return response, apiErr
+	}
+	return response, nil
+}
+
+// Get reads an object or list, returning nil without an error if it does not exist
+func (c *KubeClient) Get(path string) (map[string]interface{}, error) {
+	response, err := c.Request(http.MethodGet, path, nil)
+	if IsKubeNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(response.Body, &object); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", path, err)
+	}
+	return object, nil
+}
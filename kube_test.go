@@ -0,0 +1,81 @@
+package ansiblemodule
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKubeClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/namespaces/apps/configmaps/present" {
+			w.Write([]byte(`{"kind": "ConfigMap", "data": {"key": "value"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind": "Status", "reason": "NotFound", "message": "configmaps \"absent\" not found"}`))
+	}))
+	defer server.Close()
+
+	ca := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: test-cluster
+  cluster:
+    server: `+server.URL+`
+    certificate-authority-data: `+ca+`
+contexts:
+- name: test
+  context:
+    cluster: test-cluster
+    user: test-user
+    namespace: apps
+users:
+- name: test-user
+  user:
+    token: s3cret
+`), 0600)
+
+	module := &AnsibleModule{Params: map[string]interface{}{"kubeconfig": kubeconfig}}
+	client, err := module.KubeClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Namespace != "apps" || client.Context != "test" {
+		t.Errorf("Expected namespace and context from kubeconfig, got %s %s", client.Namespace, client.Context)
+	}
+
+	object, err := client.Get("/api/v1/namespaces/apps/configmaps/present")
+	if err != nil || object["kind"] != "ConfigMap" {
+		t.Errorf("Expected ConfigMap, got %v (%v)", object, err)
+	}
+	if object, err := client.Get("/api/v1/namespaces/apps/configmaps/absent"); object != nil || err != nil {
+		t.Errorf("Expected a missing object to return nil, got %v (%v)", object, err)
+	}
+	_, err = client.Request(http.MethodDelete, "/api/v1/namespaces/apps/configmaps/absent", nil)
+	if !IsKubeNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+
+	module.Params["context"] = "missing"
+	if _, err := module.KubeClient(); err == nil {
+		t.Error("Expected an unknown context to fail")
+	}
+	module.Params = map[string]interface{}{"host": server.URL, "api_key": "wrong", "validate_certs": false}
+	client, _ = module.KubeClient()
+	if _, err := client.Get("/api/v1/namespaces"); err == nil {
+		t.Error("Expected a rejected token to fail")
+	}
+}
@@ -0,0 +1,65 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// MaxInputSize is the largest module arguments payload accepted, in bytes
+var MaxInputSize int64 = 64 << 20
+
+// MaxInputDepth is the deepest nesting of objects and arrays accepted in the module arguments
+var MaxInputDepth = 100
+
+// inputLimitError reports module arguments rejected by MaxInputSize or MaxInputDepth
+type inputLimitError struct {
+	code MessageCode
+	msg  string
+}
+
+func (e *inputLimitError) Error() string {
+	return e.msg
+}
+
+func (e *inputLimitError) messageCode() MessageCode {
+	return e.code
+}
+
+// readLimitedInput reads at most MaxInputSize bytes, failing if there is more
+func readLimitedInput(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxInputSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxInputSize {
+		return nil, &inputLimitError{CodeInputTooLarge, formatMessage(CodeInputTooLarge, MaxInputSize)}
+	}
+	return data, nil
+}
+
+// checkInputLimits rejects a payload that is too large or nested too deeply.
+// Nesting is measured by scanning tokens, so hostile input cannot exhaust the stack.
+func checkInputLimits(data []byte) error {
+	if int64(len(data)) > MaxInputSize {
+		return &inputLimitError{CodeInputTooLarge, formatMessage(CodeInputTooLarge, MaxInputSize)}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			// Syntax errors are reported by the full parse
+			return nil
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > MaxInputDepth {
+				return &inputLimitError{CodeInputTooDeep, formatMessage(CodeInputTooDeep, MaxInputDepth)}
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
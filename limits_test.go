@@ -0,0 +1,63 @@
+package ansiblemodule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInputDepthLimit(t *testing.T) {
+	oldDepth := MaxInputDepth
+	MaxInputDepth = 5
+	defer func() { MaxInputDepth = oldDepth }()
+
+	t.Setenv("ANSIBLE_MODULE_ARGS", `{"a": {"b": [{"c": 1}]}}`)
+	module := &AnsibleModule{Params: ModuleParams{}}
+	if err := module.parseInput(); err != nil {
+		t.Fatalf("Expected shallow input to parse: %v", err)
+	}
+
+	t.Setenv("ANSIBLE_MODULE_ARGS", `{"a":`+strings.Repeat("[", 10)+strings.Repeat("]", 10)+`}`)
+	err := module.parseInput()
+	if err == nil || !strings.Contains(err.Error(), "nesting limit of 5") {
+		t.Errorf("Expected nesting limit error, got %v", err)
+	}
+}
+
+func TestInputSizeLimit(t *testing.T) {
+	oldSize := MaxInputSize
+	MaxInputSize = 32
+	defer func() { MaxInputSize = oldSize }()
+
+	t.Setenv("ANSIBLE_MODULE_ARGS", `{"data": "`+strings.Repeat("x", 64)+`"}`)
+	module := &AnsibleModule{Params: ModuleParams{}}
+	err := module.parseInput()
+	if err == nil || !strings.Contains(err.Error(), "size limit of 32 bytes") {
+		t.Errorf("Expected size limit error, got %v", err)
+	}
+
+	if _, err := readLimitedInput(strings.NewReader(strings.Repeat("x", 33))); err == nil {
+		t.Error("Expected oversized stdin to be rejected")
+	}
+	if data, err := readLimitedInput(strings.NewReader(strings.Repeat("x", 32))); err != nil || len(data) != 32 {
+		t.Errorf("Expected input at the limit to be read, got %d bytes, %v", len(data), err)
+	}
+}
+
+func TestInputLimitFailsModule(t *testing.T) {
+	oldDepth, oldExit, oldSignals := MaxInputDepth, DefaultExitFunc, HandleSignals
+	MaxInputDepth = 2
+	HandleSignals = false
+	exitCode := -1
+	DefaultExitFunc = func(code int) { exitCode = code }
+	defer func() { MaxInputDepth, DefaultExitFunc, HandleSignals = oldDepth, oldExit, oldSignals }()
+
+	t.Setenv("ANSIBLE_MODULE_ARGS", `{"a": [[1]]}`)
+	output := captureStdout(t, func() {
+		if _, err := NewModule(ArgSpecMap{}, nil, nil, nil, nil, false); err == nil {
+			t.Error("Expected NewModule to fail")
+		}
+	})
+	if exitCode == -1 || !strings.Contains(output, `"failed":true`) || !strings.Contains(output, "nesting limit") {
+		t.Errorf("Expected FailJson output, got exit %d: %s", exitCode, output)
+	}
+}
@@ -0,0 +1,60 @@
+package ansiblemodule
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// NormalizeLocale controls whether NewModule switches to a C-style locale so
+// that command output is stable and parseable, as Python's AnsibleModule does
+var NormalizeLocale = true
+
+// preferredLocales lists locales in order of preference
+var preferredLocales = []string{"C.utf8", "C.UTF-8", "en_US.utf8", "en_US.UTF-8", "C", "POSIX"}
+
+// localeVariables are the environment variables set to the normalized locale
+var localeVariables = []string{"LANG", "LC_ALL", "LC_MESSAGES"}
+
+// detectLocale returns the most preferred locale installed on the system
+func detectLocale() string {
+	out, err := exec.Command("locale", "-a").Output()
+	if err != nil {
+		return "C"
+	}
+	return pickLocale(strings.Fields(string(out)))
+}
+
+// pickLocale chooses the most preferred locale from those available
+func pickLocale(available []string) string {
+	installed := make(map[string]bool, len(available))
+	for _, name := range available {
+		installed[name] = true
+	}
+	for _, name := range preferredLocales {
+		if installed[name] {
+			return name
+		}
+	}
+	return "C"
+}
+
+// localeEnv returns environment entries selecting the given locale
+func localeEnv(locale string) []string {
+	env := make([]string, len(localeVariables))
+	for i, name := range localeVariables {
+		env[i] = name + "=" + locale
+	}
+	return env
+}
+
+// SetLocale sets the locale for the module process and all commands it runs
+func (m *AnsibleModule) SetLocale(locale string) {
+	m.Locale = locale
+	if locale == "" {
+		return
+	}
+	for _, name := range localeVariables {
+		os.Setenv(name, locale)
+	}
+}
@@ -0,0 +1,57 @@
+package ansiblemodule
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPickLocale(t *testing.T) {
+	tests := []struct {
+		available []string
+		expected  string
+	}{
+		{[]string{"C", "C.utf8", "POSIX"}, "C.utf8"},
+		{[]string{"en_US.UTF-8", "POSIX"}, "en_US.UTF-8"},
+		{[]string{"de_DE.utf8"}, "C"},
+		{nil, "C"},
+	}
+
+	for _, test := range tests {
+		result := pickLocale(test.available)
+		if result != test.expected {
+			t.Errorf("Expected %s for %v, got %s", test.expected, test.available, result)
+		}
+	}
+}
+
+func TestSetLocale(t *testing.T) {
+	module := &AnsibleModule{}
+
+	for _, name := range localeVariables {
+		t.Setenv(name, os.Getenv(name))
+	}
+
+	module.SetLocale("C")
+	if os.Getenv("LC_ALL") != "C" {
+		t.Errorf("Expected LC_ALL to be C, got %s", os.Getenv("LC_ALL"))
+	}
+
+	// Test locale is passed to commands even with a custom environment
+	module.Locale = "POSIX"
+	result, err := module.RunCommand("sh", []string{"-c", "echo $LC_ALL $LANG $FOO"}, map[string]string{"FOO": "bar"}, "")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if result.Stdout != "POSIX POSIX bar\n" {
+		t.Errorf("Expected 'POSIX POSIX bar', got '%s'", result.Stdout)
+	}
+
+	// Test explicit environment overrides the locale
+	result, err = module.RunCommand("sh", []string{"-c", "echo $LANG"}, map[string]string{"LANG": "C.UTF-8"}, "")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if result.Stdout != "C.UTF-8\n" {
+		t.Errorf("Expected 'C.UTF-8', got '%s'", result.Stdout)
+	}
+}
@@ -0,0 +1,172 @@
+package ansiblemodule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// localFactsDir is where the setup module looks for local facts
+var localFactsDir = "/etc/ansible/facts.d"
+
+// localFactName is the set of names WriteLocalFact accepts
+var localFactName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// ReadLocalFacts returns the local facts in dir, or /etc/ansible/facts.d if dir
+// is empty, keyed by name as ansible_local presents them. Like the setup module,
+// it runs executable .fact files and parses their output, reads the others as
+// JSON or INI, and reports a fact that cannot be loaded as an error string.
+func (m *AnsibleModule) ReadLocalFacts(dir string) (map[string]interface{}, error) {
+	if dir == "" {
+		dir = localFactsDir
+	}
+	facts := map[string]interface{}{}
+	entries, err := m.fs().ReadDir(dir)
+	if os.IsNotExist(err) {
+		return facts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, isFact := strings.CutSuffix(entry.Name(), ".fact")
+		if !isFact {
+			continue
+		}
+		value, err := m.readLocalFact(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			m.AddWarning(fmt.Sprintf("failure loading local fact %s: %v", name, err))
+			value = fmt.Sprintf("error loading fact - %v", err)
+		}
+		facts[name] = value
+	}
+	return facts, nil
+}
+
+// ReadLocalFact returns a single local fact from dir, or /etc/ansible/facts.d
+// if dir is empty. It reports false if the fact does not exist.
+func (m *AnsibleModule) ReadLocalFact(dir, name string) (interface{}, bool, error) {
+	if dir == "" {
+		dir = localFactsDir
+	}
+	path := filepath.Join(dir, name+".fact")
+	if !m.FileExists(path) {
+		return nil, false, nil
+	}
+	value, err := m.readLocalFact(path)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to load local fact %s: %w", name, err)
+	}
+	return value, true, nil
+}
+
+// readLocalFact loads a fact file, running it if it is executable
+func (m *AnsibleModule) readLocalFact(path string) (interface{}, error) {
+	info, err := m.fs().Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var content string
+	if info.Mode()&0111 != 0 {
+		result, err := m.RunCommand(path, nil, nil, "")
+		if err != nil {
+			return nil, commandFailure(err, result, "fact script failed")
+		}
+		content = result.Stdout
+	} else if content, err = m.ReadTextFile(path); err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err == nil {
+		return value, nil
+	}
+	return parseFactINI(content)
+}
+
+// parseFactINI parses the INI format accepted for local facts into sections of
+// string values
+func parseFactINI(content string) (map[string]interface{}, error) {
+	sections := map[string]interface{}{}
+	var section map[string]interface{}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = map[string]interface{}{}
+			sections[strings.TrimSpace(line[1:len(line)-1])] = section
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			key, value, ok = strings.Cut(line, ":")
+		}
+		if !ok || section == nil {
+			return nil, fmt.Errorf("not valid JSON or INI: line %d", i+1)
+		}
+		section[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	if len(sections) == 0 && strings.TrimSpace(content) != "" {
+		return nil, fmt.Errorf("not valid JSON or INI")
+	}
+	return sections, nil
+}
+
+// WriteLocalFact stores a value as the JSON local fact name in dir, or
+// /etc/ansible/facts.d if dir is empty, so it is available to later runs and as
+// ansible_local.name once facts are gathered. The file is replaced atomically
+// and left alone when it already holds the same value.
+func (m *AnsibleModule) WriteLocalFact(dir, name string, value interface{}) (bool, error) {
+	if dir == "" {
+		dir = localFactsDir
+	}
+	if !localFactName.MatchString(name) {
+		return false, fmt.Errorf("invalid local fact name %q", name)
+	}
+	content, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return false, fmt.Errorf("failed to encode local fact %s: %w", name, err)
+	}
+
+	path := filepath.Join(dir, name+".fact")
+	if current, err := m.fs().ReadFile(path); err == nil {
+		var existing, desired interface{}
+		json.Unmarshal(content, &desired)
+		if json.Unmarshal(current, &existing) == nil && reflect.DeepEqual(existing, desired) {
+			return false, nil
+		}
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+	if err := m.fs().MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+	return m.WriteTextFile(path, string(content)+"\n", 0644)
+}
+
+// RemoveLocalFact deletes the local fact name from dir, or /etc/ansible/facts.d
+// if dir is empty, reporting whether it existed
+func (m *AnsibleModule) RemoveLocalFact(dir, name string) (bool, error) {
+	if dir == "" {
+		dir = localFactsDir
+	}
+	path := filepath.Join(dir, name+".fact")
+	if !m.FileExists(path) {
+		return false, nil
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+	defer m.auditFile("remove_file", path)()
+	if err := m.fs().Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,61 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLocalFacts(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "facts.d")
+	module := &AnsibleModule{}
+
+	state := map[string]interface{}{"version": "1.2", "nodes": []interface{}{"a", "b"}}
+	if changed, err := module.WriteLocalFact(dir, "app", state); err != nil || !changed {
+		t.Fatalf("Expected fact to be written: %v", err)
+	}
+	if changed, _ := module.WriteLocalFact(dir, "app", state); changed {
+		t.Error("Expected writing the same value to change nothing")
+	}
+	if _, err := module.WriteLocalFact(dir, "../escape", state); err == nil {
+		t.Error("Expected an invalid name to fail")
+	}
+
+	os.WriteFile(filepath.Join(dir, "legacy.fact"), []byte("[general]\nRole = web\nport: 80\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.fact"), []byte("{not json"), 0644)
+	if runtime.GOOS != "windows" {
+		os.WriteFile(filepath.Join(dir, "dynamic.fact"), []byte("#!/bin/sh\necho '{\"uptime\": 5}'\n"), 0755)
+	}
+
+	facts, err := module.ReadLocalFacts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(facts["app"], state) {
+		t.Errorf("Expected written fact to load back, got %v", facts["app"])
+	}
+	if expected := map[string]interface{}{"general": map[string]interface{}{"role": "web", "port": "80"}}; !reflect.DeepEqual(facts["legacy"], expected) {
+		t.Errorf("Expected INI fact %v, got %v", expected, facts["legacy"])
+	}
+	if message, _ := facts["broken"].(string); !strings.HasPrefix(message, "error loading fact") || len(module.Warnings) != 1 {
+		t.Errorf("Expected broken fact to be reported, got %v (%v)", facts["broken"], module.Warnings)
+	}
+	if runtime.GOOS != "windows" && !reflect.DeepEqual(facts["dynamic"], map[string]interface{}{"uptime": float64(5)}) {
+		t.Errorf("Expected executable fact output, got %v", facts["dynamic"])
+	}
+
+	module.CheckMode = true
+	if changed, _ := module.RemoveLocalFact(dir, "app"); !changed {
+		t.Error("Expected check mode to report the removal")
+	}
+	module.CheckMode = false
+	if changed, _ := module.RemoveLocalFact(dir, "app"); !changed {
+		t.Error("Expected fact to be removed")
+	}
+	if _, found, _ := module.ReadLocalFact(dir, "app"); found {
+		t.Error("Expected removed fact to be gone")
+	}
+}
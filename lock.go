@@ -0,0 +1,145 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// moduleLockDir is the preferred directory for module locks, shared by every
+// user allowed to write to it. Locks fall back to a private directory of the
+// user in the system temp directory, where other users cannot plant or hold them.
+var moduleLockDir = "/run/ansigo-module"
+
+// lockPollInterval is how often AcquireModuleLock retries a held lock
+var lockPollInterval = 100 * time.Millisecond
+
+// unsafeLockChars are replaced in lock names to keep them single file names
+var unsafeLockChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// ModuleLock is a held lock returned by AcquireModuleLock
+type ModuleLock struct {
+	Path string
+	file *os.File
+}
+
+// AcquireModuleLock takes a host-wide lock named after the module, waiting up
+// to timeout for another instance holding it, so concurrent plays cannot run
+// conflicting instances of a stateful module at the same time. An empty name
+// uses the executable name. The lock lives under /run/ansigo-module, or a
+// private directory in the system temp directory when that is not writable, and
+// is released by Release or when the process exits.
+func (m *AnsibleModule) AcquireModuleLock(name string, timeout time.Duration) (*ModuleLock, error) {
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	}
+	file, err := openModuleLock(unsafeLockChars.ReplaceAllString(name, "_") + ".lock")
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := lockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", file.Name(), err)
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("timed out after %v waiting for module lock %s held by %s", timeout, file.Name(), lockHolder(file.Name()))
+		}
+		select {
+		case <-m.Context().Done():
+			file.Close()
+			return nil, m.Context().Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	// Record the holder for whoever waits next, in lock files of our own
+	if info, err := file.Stat(); err == nil && ownedByCurrentUser(info) {
+		file.Truncate(0)
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	m.writeDebugLog("TRACE", fmt.Sprintf("acquired module lock %s", file.Name()))
+	return &ModuleLock{Path: file.Name(), file: file}, nil
+}
+
+// openModuleLock opens the lock file in the first usable lock directory. The
+// temp directory is per user on Windows, elsewhere a private directory in it
+// is used.
+func openModuleLock(name string) (*os.File, error) {
+	dirs := []string{os.TempDir()}
+	if runtime.GOOS != "windows" {
+		dirs = []string{moduleLockDir, filepath.Join(os.TempDir(), fmt.Sprintf("ansigo-module-%d", os.Geteuid()))}
+	}
+	var lastErr error
+	for i, dir := range dirs {
+		var err error
+		if i == 0 {
+			err = os.MkdirAll(dir, 0755)
+		} else {
+			err = ensurePrivateDir(dir)
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		file, err := openLockFile(filepath.Join(dir, name))
+		if err == nil {
+			return file, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to create module lock %s: %w", name, lastErr)
+}
+
+// openLockFile opens or creates a lock file without following a symlink in its
+// place, which could make the module overwrite the file it points to
+func openLockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|lockNoFollow, 0644)
+	if err != nil {
+		return nil, err
+	}
+	opened, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if linked, err := os.Lstat(path); err != nil || !os.SameFile(opened, linked) {
+		file.Close()
+		return nil, fmt.Errorf("lock file %s was replaced while opening it", path)
+	}
+	return file, nil
+}
+
+// lockHolder describes the process recorded in a lock file
+func lockHolder(path string) string {
+	content, err := os.ReadFile(path)
+	if pid := strings.TrimSpace(string(content)); err == nil && pid != "" {
+		return "pid " + pid
+	}
+	return "another process"
+}
+
+// Release gives the lock up. It is safe to call more than once.
+func (l *ModuleLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
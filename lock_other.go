@@ -0,0 +1,19 @@
+//go:build !unix && !windows
+
+package ansiblemodule
+
+import "os"
+
+// lockNoFollow is not available on these platforms
+const lockNoFollow = 0
+
+// lockFile always succeeds on platforms without advisory file locks, where the
+// lock file only records the holder
+func lockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile releases a lock taken by lockFile
+func unlockFile(file *os.File) error {
+	return nil
+}
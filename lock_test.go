@@ -0,0 +1,85 @@
+//go:build unix || windows
+
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireModuleLock(t *testing.T) {
+	old := moduleLockDir
+	moduleLockDir = t.TempDir()
+	defer func() { moduleLockDir = old }()
+	module := &AnsibleModule{}
+
+	lock, err := module.AcquireModuleLock("my/module", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(lock.Path) != "my_module.lock" {
+		t.Errorf("Unexpected lock path %s", lock.Path)
+	}
+
+	start := time.Now()
+	_, err = module.AcquireModuleLock("my/module", 150*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "pid "+strconv.Itoa(os.Getpid())) {
+		t.Errorf("Expected a held lock to time out naming its holder: %v", err)
+	}
+	if time.Since(start) < 150*time.Millisecond {
+		t.Error("Expected the lock to be waited for")
+	}
+
+	time.AfterFunc(100*time.Millisecond, func() { lock.Release() })
+	second, err := module.AcquireModuleLock("my/module", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Expected the lock once released: %v", err)
+	}
+	if err := second.Release(); err != nil || second.Release() != nil {
+		t.Errorf("Expected release to be repeatable: %v", err)
+	}
+}
+
+func TestModuleLockSymlink(t *testing.T) {
+	dir := t.TempDir()
+	victim := filepath.Join(dir, "victim")
+	os.WriteFile(victim, []byte("keep\n"), 0644)
+	if err := os.Symlink(victim, filepath.Join(dir, "planted.lock")); err != nil {
+		t.Skipf("Cannot create symlink: %v", err)
+	}
+
+	if _, err := openLockFile(filepath.Join(dir, "planted.lock")); err == nil {
+		t.Error("Expected a symlinked lock file to be refused")
+	}
+	if content, _ := os.ReadFile(victim); string(content) != "keep\n" {
+		t.Errorf("Expected the symlink target to be left alone, got %q", content)
+	}
+}
+
+func TestModuleLockFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Locks are always in the per-user temp directory on Windows")
+	}
+	old := moduleLockDir
+	moduleLockDir = filepath.Join(t.TempDir(), "file")
+	os.WriteFile(moduleLockDir, nil, 0644)
+	defer func() { moduleLockDir = old }()
+
+	lock, err := (&AnsibleModule{}).AcquireModuleLock("fallback-test", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+	defer os.Remove(lock.Path)
+	if filepath.Dir(filepath.Dir(lock.Path)) != filepath.Clean(os.TempDir()) {
+		t.Errorf("Expected the lock in the temp directory, got %s", lock.Path)
+	}
+	if err := ensurePrivateDir(filepath.Dir(lock.Path)); err != nil {
+		t.Errorf("Expected a private lock directory: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockNoFollow makes opening a lock file fail on a symlink
+const lockNoFollow = syscall.O_NOFOLLOW
+
+// lockFile takes an exclusive flock without blocking, reporting false if it is held
+func lockFile(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK || err == syscall.EAGAIN {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unlockFile releases a lock taken by lockFile
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,212 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LogPriority is a syslog priority level
+type LogPriority int
+
+// Syslog priority levels used by Log
+const (
+	LogErr     LogPriority = 3
+	LogWarning LogPriority = 4
+	LogNotice  LogPriority = 5
+	LogInfo    LogPriority = 6
+	LogDebug   LogPriority = 7
+)
+
+// priorityNames are the labels used for priorities in the debug log
+var priorityNames = map[LogPriority]string{
+	LogErr:     "ERROR",
+	LogWarning: "WARNING",
+	LogNotice:  "NOTICE",
+	LogInfo:    "INFO",
+	LogDebug:   "DEBUG",
+}
+
+// Logger receives log messages from the module after no_log values have been removed
+type Logger interface {
+	Log(priority LogPriority, msg string, attrs ...slog.Attr)
+}
+
+// journalSocketPath is the native protocol socket of systemd-journald
+var journalSocketPath = "/run/systemd/journal/socket"
+
+// moduleName returns the name of the running module
+func (m *AnsibleModule) moduleName() string {
+	return strings.TrimSuffix(filepath.Base(os.Args[0]), filepath.Ext(os.Args[0]))
+}
+
+// logger returns the configured logger, defaulting to the journal and syslog
+func (m *AnsibleModule) logger() Logger {
+	if m.Logger == nil {
+		m.Logger = &systemLogger{module: m}
+	}
+	return m.Logger
+}
+
+// SetLogHandler routes module logs to an slog handler
+func (m *AnsibleModule) SetLogHandler(handler slog.Handler) {
+	m.Logger = NewSlogLogger(handler)
+}
+
+// Log writes an informational message to the module logger
+func (m *AnsibleModule) Log(msg string) {
+	m.LogAttrs(LogInfo, msg)
+}
+
+// LogPriority writes a message with the given priority to the module logger
+func (m *AnsibleModule) LogPriority(priority LogPriority, msg string) {
+	m.LogAttrs(priority, msg)
+}
+
+// LogAttrs writes a message with structured attributes to the module logger
+func (m *AnsibleModule) LogAttrs(priority LogPriority, msg string, attrs ...slog.Attr) {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		scrubbed[i] = m.scrubAttr(attr)
+	}
+	m.writeDebugLog(priorityNames[priority], msg)
+	m.logger().Log(priority, m.scrubString(msg), scrubbed...)
+}
+
+// scrubAttr removes no_log values from an attribute, including nested groups
+func (m *AnsibleModule) scrubAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, m.scrubString(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		scrubbed := make([]any, len(group))
+		for i, member := range group {
+			scrubbed[i] = m.scrubAttr(member)
+		}
+		return slog.Group(attr.Key, scrubbed...)
+	case slog.KindAny:
+		return slog.String(attr.Key, m.scrubString(fmt.Sprint(value.Any())))
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+// slogLogger adapts an slog.Handler to the Logger interface
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger creates a Logger writing to an slog handler
+func NewSlogLogger(handler slog.Handler) Logger {
+	return &slogLogger{logger: slog.New(handler)}
+}
+
+// Log implements Logger
+func (l *slogLogger) Log(priority LogPriority, msg string, attrs ...slog.Attr) {
+	level := slog.LevelInfo
+	switch {
+	case priority <= LogErr:
+		level = slog.LevelError
+	case priority == LogWarning:
+		level = slog.LevelWarn
+	case priority >= LogDebug:
+		level = slog.LevelDebug
+	}
+	l.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+// systemLogger writes to the systemd journal, falling back to syslog and then stderr
+type systemLogger struct {
+	module *AnsibleModule
+}
+
+// Log implements Logger
+func (l *systemLogger) Log(priority LogPriority, msg string, attrs ...slog.Attr) {
+	if l.module.NoTargetSyslog {
+		return
+	}
+
+	name := l.module.moduleName()
+	identifier := "ansible-" + name
+	if err := writeJournal(identifier, name, priority, msg, attrs); err == nil {
+		return
+	}
+
+	// Syslog has no structured fields, so attributes are appended to the message
+	for _, attr := range attrs {
+		msg += " " + attr.String()
+	}
+
+	// Logging is best effort and must never fail the module, but in debug
+	// mode messages that could not be delivered are shown on stderr
+	if err := writeSyslog(identifier, priority, msg); err != nil && l.module.Debug {
+		l.module.writeStderr("LOG: " + msg)
+	}
+}
+
+// writeJournal sends a message to journald using its native protocol
+func writeJournal(identifier, module string, priority LogPriority, msg string, attrs []slog.Attr) error {
+	if _, err := os.Stat(journalSocketPath); err != nil {
+		return err
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocketPath, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", msg)
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(int(priority)))
+	appendJournalField(&buf, "SYSLOG_IDENTIFIER", identifier)
+	appendJournalField(&buf, "MODULE", module)
+	for _, attr := range attrs {
+		if name := journalFieldName(attr.Key); name != "" {
+			appendJournalField(&buf, name, attr.Value.String())
+		}
+	}
+
+	_, err = conn.Write(buf.Bytes())
+	return err
+}
+
+// journalFieldName converts an attribute key to a valid journal field name
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		}
+		return '_'
+	}, key)
+	// Leading underscores are reserved for trusted fields set by journald
+	name = strings.TrimLeft(name, "_0123456789")
+	return name
+}
+
+// appendJournalField encodes a field, using the binary form for multi-line values
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
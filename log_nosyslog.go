@@ -0,0 +1,12 @@
+//go:build windows || plan9
+
+package ansiblemodule
+
+import (
+	"fmt"
+)
+
+// writeSyslog reports that syslog is not available on this platform
+func writeSyslog(identifier string, priority LogPriority, msg string) error {
+	return fmt.Errorf("syslog is not supported on this platform")
+}
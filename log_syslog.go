@@ -0,0 +1,29 @@
+//go:build !windows && !plan9
+
+package ansiblemodule
+
+import (
+	"log/syslog"
+)
+
+// writeSyslog sends a message to the local syslog daemon
+func writeSyslog(identifier string, priority LogPriority, msg string) error {
+	writer, err := syslog.New(syslog.Priority(priority)|syslog.LOG_USER, identifier)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	switch priority {
+	case LogErr:
+		return writer.Err(msg)
+	case LogWarning:
+		return writer.Warning(msg)
+	case LogNotice:
+		return writer.Notice(msg)
+	case LogDebug:
+		return writer.Debug(msg)
+	default:
+		return writer.Info(msg)
+	}
+}
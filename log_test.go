@@ -0,0 +1,89 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogJournal(t *testing.T) {
+	module := &AnsibleModule{}
+
+	socketPath := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets not available: %v", err)
+	}
+	defer conn.Close()
+
+	oldPath := journalSocketPath
+	journalSocketPath = socketPath
+	defer func() { journalSocketPath = oldPath }()
+
+	module.LogPriority(LogWarning, "line one\nline two")
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read journal message: %v", err)
+	}
+	data := buf[:n]
+
+	// Multi-line values use the binary encoding
+	var expected bytes.Buffer
+	expected.WriteString("MESSAGE\n")
+	binary.Write(&expected, binary.LittleEndian, uint64(len("line one\nline two")))
+	expected.WriteString("line one\nline two\n")
+	if !bytes.HasPrefix(data, expected.Bytes()) {
+		t.Errorf("Unexpected MESSAGE encoding: %q", data)
+	}
+	if !strings.Contains(string(data), "PRIORITY=4\n") {
+		t.Error("Expected PRIORITY field")
+	}
+	if !strings.Contains(string(data), "SYSLOG_IDENTIFIER=ansible-") || !strings.Contains(string(data), "MODULE=") {
+		t.Error("Expected SYSLOG_IDENTIFIER and MODULE fields")
+	}
+
+	// Test logging can be disabled
+	module.NoTargetSyslog = true
+	module.Log("should not be sent")
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := conn.Read(buf); err == nil {
+		t.Error("Expected no message when target logging is disabled")
+	}
+}
+
+func TestSetLogHandler(t *testing.T) {
+	module := &AnsibleModule{
+		Params: ModuleParams{
+			"password": "s3cret",
+			"user":     "admin",
+		},
+		NoLog: []string{"password"},
+	}
+
+	var buf bytes.Buffer
+	module.SetLogHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	module.LogAttrs(LogWarning, "login with s3cret failed",
+		slog.String("user", "admin"),
+		slog.Group("request", slog.String("body", "password=s3cret")),
+		slog.Int("attempt", 2))
+
+	output := buf.String()
+	if strings.Contains(output, "s3cret") {
+		t.Errorf("Expected no_log value to be scrubbed, got %s", output)
+	}
+	if !strings.Contains(output, "level=WARN") || !strings.Contains(output, "login with ******** failed") {
+		t.Errorf("Unexpected log output: %s", output)
+	}
+	if !strings.Contains(output, "request.body=\"password=********\"") || !strings.Contains(output, "attempt=2") {
+		t.Errorf("Expected attributes in log output: %s", output)
+	}
+}
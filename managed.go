@@ -0,0 +1,127 @@
+package ansiblemodule
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultManagedTemplate is the header text used when a ManagedHeader has no template
+var DefaultManagedTemplate = "ANSIBLE MANAGED: generated by {module}, do not edit"
+
+// managedChecksumLabel starts the last header line, which holds the checksum of
+// the body and marks the end of the header
+const managedChecksumLabel = "managed-checksum: sha256:"
+
+// ManagedHeader describes the comment block marking a generated file
+type ManagedHeader struct {
+	// Template is the header text, one comment line per line, with {module},
+	// {file} and {timestamp} placeholders
+	Template string
+	// Comment prefixes each header line, "#" if empty
+	Comment string
+	// TimeFormat formats {timestamp}, time.RFC3339 if empty
+	TimeFormat string
+}
+
+// comment returns the comment prefix of the header
+func (h ManagedHeader) comment() string {
+	if h.Comment == "" {
+		return "#"
+	}
+	return h.Comment
+}
+
+// render returns the header lines for a file and body checksum
+func (h ManagedHeader) render(module, path, checksum string, now time.Time) string {
+	template := h.Template
+	if template == "" {
+		template = DefaultManagedTemplate
+	}
+	format := h.TimeFormat
+	if format == "" {
+		format = time.RFC3339
+	}
+	text := strings.NewReplacer("{module}", module, "{file}", path, "{timestamp}", now.Format(format)).Replace(template)
+
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(strings.TrimRight(h.comment()+" "+line, " ") + "\n")
+	}
+	b.WriteString(h.comment() + " " + managedChecksumLabel + checksum + "\n")
+	return b.String()
+}
+
+// split separates content into a leading shebang line, the managed header and
+// the body. ok is false when the content has no managed header.
+func (h ManagedHeader) split(content string) (shebang, header, body, checksum string, ok bool) {
+	rest := content
+	if strings.HasPrefix(rest, "#!") {
+		end := strings.IndexByte(rest, '\n') + 1
+		if end == 0 {
+			return "", "", content, "", false
+		}
+		shebang, rest = rest[:end], rest[end:]
+	}
+
+	prefix := h.comment()
+	for offset := 0; offset < len(rest); {
+		end := strings.IndexByte(rest[offset:], '\n')
+		if end < 0 {
+			break
+		}
+		line := rest[offset : offset+end]
+		if !strings.HasPrefix(line, prefix) {
+			break
+		}
+		offset += end + 1
+		if sum, found := strings.CutPrefix(line, prefix+" "+managedChecksumLabel); found {
+			return shebang, rest[:offset], rest[offset:], sum, true
+		}
+	}
+	return "", "", content, "", false
+}
+
+// bodyChecksum returns the hex sha256 of a body
+func bodyChecksum(body string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(body)))
+}
+
+// ManagedContent returns body with a managed header, ready to write to path.
+// If path already holds the same body, its header is kept as it is, so that a
+// {timestamp} only changes when the content does and writes stay idempotent.
+// A shebang line at the start of body stays first.
+func (m *AnsibleModule) ManagedContent(path, body string, header ManagedHeader) (string, error) {
+	shebang := ""
+	if strings.HasPrefix(body, "#!") {
+		if end := strings.IndexByte(body, '\n') + 1; end > 0 {
+			shebang, body = body[:end], body[end:]
+		}
+	}
+	checksum := bodyChecksum(body)
+
+	existing, err := m.fs().ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if _, oldHeader, oldBody, oldChecksum, ok := header.split(string(existing)); ok && oldBody == body && oldChecksum == checksum {
+		return shebang + oldHeader + body, nil
+	}
+	return shebang + header.render(m.moduleName(), path, checksum, time.Now()) + body, nil
+}
+
+// CheckManagedFile reports whether path has a managed header, and whether its
+// body was edited since the header was written
+func (m *AnsibleModule) CheckManagedFile(path string, header ManagedHeader) (managed bool, edited bool, err error) {
+	content, err := m.fs().ReadFile(path)
+	if err != nil {
+		return false, false, err
+	}
+	_, _, body, checksum, ok := header.split(string(content))
+	if !ok {
+		return false, false, nil
+	}
+	return true, bodyChecksum(body) != checksum, nil
+}
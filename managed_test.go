@@ -0,0 +1,62 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManagedContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sh")
+	module := &AnsibleModule{}
+	header := ManagedHeader{Template: "ANSIBLE MANAGED by {module}\nGenerated {timestamp}", TimeFormat: time.RFC3339Nano}
+	body := "#!/bin/sh\necho hello\n"
+
+	content, err := module.ManagedContent(path, body, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(content, "#!/bin/sh\n# ANSIBLE MANAGED by ") || !strings.HasSuffix(content, "\necho hello\n") {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+	os.WriteFile(path, []byte(content), 0755)
+
+	managed, edited, err := module.CheckManagedFile(path, header)
+	if err != nil || !managed || edited {
+		t.Errorf("Expected an unedited managed file, got %v %v (%v)", managed, edited, err)
+	}
+
+	// The timestamp is kept while the body is unchanged
+	time.Sleep(time.Millisecond)
+	again, _ := module.ManagedContent(path, body, header)
+	if again != content {
+		t.Errorf("Expected the header to be kept for the same body, got:\n%s", again)
+	}
+	if changed, _ := module.ManagedContent(path, "#!/bin/sh\necho bye\n", header); changed == content {
+		t.Error("Expected a new header for a changed body")
+	}
+
+	os.WriteFile(path, []byte(strings.Replace(content, "hello", "hacked", 1)), 0755)
+	if managed, edited, _ := module.CheckManagedFile(path, header); !managed || !edited {
+		t.Errorf("Expected the edit to be detected, got %v %v", managed, edited)
+	}
+
+	os.WriteFile(path, []byte("# just a comment\nkey=value\n"), 0644)
+	if managed, _, _ := module.CheckManagedFile(path, header); managed {
+		t.Error("Expected a file without header to be unmanaged")
+	}
+}
+
+func TestManagedContentComment(t *testing.T) {
+	module := &AnsibleModule{}
+	content, err := module.ManagedContent(filepath.Join(t.TempDir(), "missing.ini"), "[main]\n", ManagedHeader{Comment: ";"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(content, "\n")
+	if !strings.HasPrefix(lines[0], "; ANSIBLE MANAGED: generated by ") || !strings.HasPrefix(lines[1], "; managed-checksum: sha256:") {
+		t.Errorf("Unexpected header:\n%s", content)
+	}
+}
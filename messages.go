@@ -0,0 +1,98 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MessageCode identifies a user-facing error message. Codes do not change
+// between releases or with the wording of the message, so tooling can match on
+// the error_code of a failed result instead of its English msg.
+type MessageCode string
+
+// Message codes. Codes without an entry in Messages, such as those of not found
+// and permission errors, keep the message of the underlying error.
+const (
+	CodeMissingRequired      MessageCode = "missing_required"
+	CodeMissingSuboption     MessageCode = "missing_suboption"
+	CodeMutuallyExclusive    MessageCode = "mutually_exclusive"
+	CodeRequiredTogether     MessageCode = "required_together"
+	CodeRequiredOneOf        MessageCode = "required_one_of"
+	CodeRequiredIf           MessageCode = "required_if"
+	CodeInvalidType          MessageCode = "invalid_type"
+	CodeInvalidValue         MessageCode = "invalid_value"
+	CodeInvalidChoice        MessageCode = "invalid_choice"
+	CodeStringConversion     MessageCode = "string_conversion"
+	CodeUnsupportedParameter MessageCode = "unsupported_parameter"
+	CodeAliasConflict        MessageCode = "alias_conflict"
+	CodeDuplicateKey         MessageCode = "duplicate_key"
+	CodeInputTooLarge        MessageCode = "input_too_large"
+	CodeInputTooDeep         MessageCode = "input_too_deep"
+	CodeInvalidResult        MessageCode = "invalid_result"
+	CodeCommandFailed        MessageCode = "command_failed"
+	CodeCommandTimeout       MessageCode = "command_timeout"
+	CodeCommandCancelled     MessageCode = "command_cancelled"
+	CodeNotFound             MessageCode = "not_found"
+	CodePermissionDenied     MessageCode = "permission_denied"
+)
+
+// Messages is the catalog of message formats by code. Modules may replace
+// entries before creating the module, for instance to translate them. The
+// arguments are passed in the order of the default format, so a replacement
+// can reorder them with explicit indexes such as %[2]s.
+var Messages = map[MessageCode]string{
+	CodeMissingRequired:      "missing required argument: %s",
+	CodeMissingSuboption:     "%s is required",
+	CodeMutuallyExclusive:    "parameters are mutually exclusive: %s",
+	CodeRequiredTogether:     "parameters must be specified together: %s",
+	CodeRequiredOneOf:        "one of the following is required: %s",
+	CodeRequiredIf:           "%s is required when %s=%v",
+	CodeInvalidType:          "%s must be %s",
+	CodeInvalidValue:         "%s %s",
+	CodeInvalidChoice:        "%s must be one of: %s",
+	CodeUnsupportedParameter: "%s has unsupported parameter %s",
+	CodeAliasConflict:        "%s sets both %s and its alias %s",
+	CodeDuplicateKey:         "%s has the same %s %q as %s[%d]",
+	CodeInputTooLarge:        "module arguments exceed the size limit of %d bytes",
+	CodeInputTooDeep:         "module arguments exceed the nesting limit of %d levels",
+	CodeInvalidResult:        "invalid module result: %s",
+	CodeCommandFailed:        "command failed",
+	CodeCommandTimeout:       "command %s timed out after %s",
+	CodeCommandCancelled:     "command %s cancelled",
+}
+
+// formatMessage formats the catalog message code with args
+func formatMessage(code MessageCode, args ...interface{}) string {
+	format, ok := Messages[code]
+	if !ok {
+		return fmt.Sprintf("%s %v", code, args)
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// codedError is an error carrying a message code
+type codedError interface {
+	messageCode() MessageCode
+}
+
+// ErrorCode returns the message code of err, or an empty code when err has none
+func ErrorCode(err error) MessageCode {
+	var coded codedError
+	switch {
+	case errors.As(err, &coded):
+		return coded.messageCode()
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrPermission):
+		return CodePermissionDenied
+	}
+	return ""
+}
+
+// errorFields returns the result fields identifying err for fail_json
+func errorFields(err error) map[string]interface{} {
+	if code := ErrorCode(err); code != "" {
+		return map[string]interface{}{"error_code": string(code)}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestErrorCodes(t *testing.T) {
+	module := &AnsibleModule{
+		ArgSpec: ArgSpecMap{
+			"state": {Type: "str", Choices: []string{"present", "absent"}},
+			"count": {Type: "int"},
+		},
+		MutuallyExclusive: [][]string{{"state", "count"}},
+	}
+	tests := []struct {
+		params ModuleParams
+		code   MessageCode
+		msg    string
+	}{
+		{ModuleParams{"state": "gone"}, CodeInvalidChoice, "state must be one of: present, absent"},
+		{ModuleParams{"count": "many"}, CodeInvalidType, "count must be an integer: strconv.Atoi: parsing \"many\": invalid syntax"},
+		{ModuleParams{"state": "present", "count": 1}, CodeMutuallyExclusive, "parameters are mutually exclusive: state, count"},
+	}
+	for _, test := range tests {
+		module.Params = test.params
+		err := module.validateArguments()
+		if ErrorCode(err) != test.code || err.Error() != test.msg {
+			t.Errorf("Expected %s %q, got %s %v", test.code, test.msg, ErrorCode(err), err)
+		}
+	}
+
+	if _, err := module.ReadTextFile(filepath.Join(t.TempDir(), "missing")); ErrorCode(err) != CodeNotFound {
+		t.Errorf("Expected not_found for a missing file, got %q", ErrorCode(err))
+	}
+}
+
+func TestMessagesCatalog(t *testing.T) {
+	original := Messages[CodeMissingRequired]
+	Messages[CodeMissingRequired] = "Argument fehlt: %s"
+	defer func() { Messages[CodeMissingRequired] = original }()
+
+	var output bytes.Buffer
+	module := &AnsibleModule{ArgSpec: ArgSpecMap{"name": {Type: "str", Required: true}}, Params: ModuleParams{},
+		Output: &output, ExitFunc: func(int) {}}
+	module.FailWithError(module.validateArguments(), nil)
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed["error_code"] != "missing_required" || parsed["msg"] != "Argument fehlt: name" {
+		t.Errorf("Expected the replaced message with a stable code, got %v", parsed)
+	}
+}
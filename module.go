@@ -0,0 +1,120 @@
+package ansiblemodule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// Module is a Go Ansible module run by Main
+type Module interface {
+	// Spec returns the argument spec of the module
+	Spec() ArgSpecMap
+	// Run does the work of the module. The returned result is reported on
+	// success and added to the failure when an error is returned.
+	Run(ctx context.Context, m *AnsibleModule) (Result, error)
+}
+
+// ModuleConfig declares argument constraints and check mode support
+type ModuleConfig struct {
+	SupportsCheckMode bool
+	MutuallyExclusive [][]string
+	RequiredTogether  [][]string
+	RequiredOne       [][]string
+	RequiredIf        []RequiredIfSpec
+}
+
+// ConfiguredModule is implemented by modules declaring a ModuleConfig.
+// Modules without it support check mode and have no constraints.
+type ConfiguredModule interface {
+	Config() ModuleConfig
+}
+
+// Main runs a module with the standard lifecycle: async handling, argument
+// parsing and validation, check mode declaration, panic recovery, temp file
+// cleanup and exit. It is meant to be the whole of a module's main function.
+func Main(module Module) {
+	HandleAsync()
+
+	config := ModuleConfig{SupportsCheckMode: true}
+	if configured, ok := module.(ConfiguredModule); ok {
+		config = configured.Config()
+	}
+
+	m, err := NewModule(module.Spec(), config.MutuallyExclusive, config.RequiredTogether,
+		config.RequiredOne, config.RequiredIf, config.SupportsCheckMode)
+	if err != nil {
+		exitWithoutModule(err)
+		return
+	}
+	runModule(module, m)
+}
+
+// runModule runs the module and reports its result, failing on errors and panics
+func runModule(module Module, m *AnsibleModule) {
+	defer m.Cleanup()
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		// Panics used by exit functions to unwind are passed on
+		if m.exited {
+			panic(r)
+		}
+		m.FailJson(fmt.Sprintf("module panicked: %v", r), map[string]interface{}{
+			"exception": string(debug.Stack()),
+		})
+	}()
+
+	result, err := module.Run(m.Context(), m)
+	if err != nil {
+		fields := result.ToMap()
+		delete(fields, "msg")
+		m.FailJson(err.Error(), fields)
+		return
+	}
+	m.ExitJson(result.ToMap())
+}
+
+// ToMap converts the result to the map passed to ExitJson, adding Data to the standard fields
+func (r Result) ToMap() map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, _ := json.Marshal(r)
+	json.Unmarshal(data, &fields)
+	for key, value := range r.Data {
+		fields[key] = value
+	}
+	return fields
+}
+
+// inputError reports module arguments that could not be read, which happens
+// before there is a module to report the failure
+type inputError struct {
+	err error
+}
+
+func (e *inputError) Error() string {
+	return e.err.Error()
+}
+
+func (e *inputError) Unwrap() error {
+	return e.err
+}
+
+// exitWithoutModule reports a NewModule error. Check mode requests are skipped
+// as Ansible expects and unreadable arguments fail; other errors have already
+// been reported by NewModule.
+func exitWithoutModule(err error) {
+	var inputErr *inputError
+	switch {
+	case errors.Is(err, ErrCheckModeUnsupported):
+		m := &AnsibleModule{Params: ModuleParams{}, ExitFunc: DefaultExitFunc, Output: defaultOutput}
+		m.ExitJson(map[string]interface{}{"skipped": true, "changed": false, "msg": err.Error()})
+	case errors.As(err, &inputErr):
+		m := &AnsibleModule{Params: ModuleParams{}, ExitFunc: DefaultExitFunc, Output: defaultOutput}
+		m.FailJson(err.Error(), nil)
+	}
+}
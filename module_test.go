@@ -0,0 +1,125 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// greeter is a Module used to test Main
+type greeter struct {
+	config *ModuleConfig
+}
+
+func (g greeter) Spec() ArgSpecMap {
+	return ArgSpecMap{"name": {Type: "str", Required: true}}
+}
+
+func (g greeter) Config() ModuleConfig {
+	if g.config != nil {
+		return *g.config
+	}
+	return ModuleConfig{SupportsCheckMode: true}
+}
+
+func (g greeter) Run(ctx context.Context, m *AnsibleModule) (Result, error) {
+	name := m.Params["name"].(string)
+	switch name {
+	case "panic":
+		panic("greeter exploded")
+	case "error":
+		return Result{Data: map[string]interface{}{"partial": 1}}, fmt.Errorf("cannot greet %s", name)
+	}
+	return Result{Changed: !m.CheckMode, Msg: "hello " + name}, nil
+}
+
+// runMain runs Main with the given arguments and returns the exit code and parsed output
+func runMain(t *testing.T, module Module, args string) (int, map[string]interface{}) {
+	t.Helper()
+	return runEntry(t, func() { Main(module) }, args)
+}
+
+// exitPanic unwinds a module entry point when it exits
+type exitPanic struct {
+	code int
+}
+
+// runEntry runs a module entry point with the given arguments and returns the
+// exit code and parsed output
+func runEntry(t *testing.T, main func(), args string) (int, map[string]interface{}) {
+	t.Helper()
+	var output bytes.Buffer
+	exitCode := -1
+	oldExit, oldOutput, oldSignals, oldLocale := DefaultExitFunc, defaultOutput, HandleSignals, NormalizeLocale
+	DefaultExitFunc = func(code int) {
+		exitCode = code
+		panic(exitPanic{code: code})
+	}
+	defaultOutput, HandleSignals, NormalizeLocale = &output, false, false
+	defer func() {
+		DefaultExitFunc, defaultOutput, HandleSignals, NormalizeLocale = oldExit, oldOutput, oldSignals, oldLocale
+	}()
+	t.Setenv("ANSIBLE_MODULE_ARGS", args)
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(exitPanic); !ok {
+					t.Fatalf("Unexpected panic: %v", r)
+				}
+			}
+		}()
+		main()
+	}()
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &parsed); err != nil {
+		t.Fatalf("Failed to parse output %q: %v", output.String(), err)
+	}
+	return exitCode, parsed
+}
+
+func TestModuleMain(t *testing.T) {
+	_, result := runMain(t, greeter{}, `{"name": "world"}`)
+	if result["msg"] != "hello world" || result["changed"] != true {
+		t.Errorf("Unexpected result: %v", result)
+	}
+
+	_, result = runMain(t, greeter{}, `{"name": "world", "_ansible_check_mode": true}`)
+	if result["changed"] != false {
+		t.Errorf("Expected changed=false to be reported in check mode: %v", result)
+	}
+
+	_, result = runMain(t, greeter{}, `{"name": "error"}`)
+	if result["failed"] != true || result["msg"] != "cannot greet error" || result["partial"] != float64(1) {
+		t.Errorf("Expected error to fail the module with partial result: %v", result)
+	}
+
+	_, result = runMain(t, greeter{}, `{"name": "panic"}`)
+	if result["failed"] != true || !strings.Contains(result["msg"].(string), "greeter exploded") || result["exception"] == nil {
+		t.Errorf("Expected panic to be reported: %v", result)
+	}
+
+	_, result = runMain(t, greeter{}, `{}`)
+	if result["failed"] != true || !strings.Contains(result["msg"].(string), "name") {
+		t.Errorf("Expected validation failure: %v", result)
+	}
+}
+
+func TestMainCheckModeUnsupported(t *testing.T) {
+	module := greeter{config: &ModuleConfig{SupportsCheckMode: false}}
+	_, result := runMain(t, module, `{"name": "world", "_ansible_check_mode": true}`)
+	if result["skipped"] != true {
+		t.Errorf("Expected module to be skipped in check mode: %v", result)
+	}
+}
+
+func TestMainInvalidInput(t *testing.T) {
+	code, result := runMain(t, greeter{}, `{not json`)
+	if result["failed"] != true || !strings.Contains(result["msg"].(string), "failed to parse") || code == -1 {
+		t.Errorf("Expected parse failure to be reported: %d %v", code, result)
+	}
+}
@@ -0,0 +1,300 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// MountInfo describes a mounted filesystem
+type MountInfo struct {
+	MountID       int
+	ParentID      int
+	Device        string // major:minor
+	Root          string
+	MountPoint    string
+	Options       []string
+	FSType        string
+	Source        string
+	SuperOptions  []string
+	SizeTotal     uint64
+	SizeAvailable uint64
+	SizeUsed      uint64
+	BlockSize     uint64
+	InodesTotal   uint64
+	InodesUsed    uint64
+}
+
+// mountInfoPath is the location of the mount table
+var mountInfoPath = "/proc/self/mountinfo"
+
+// ListMounts returns all mounted filesystems with usage statistics
+func (m *AnsibleModule) ListMounts() ([]MountInfo, error) {
+	mounts, err := readMountTable()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range mounts {
+		// Usage is best effort, pseudo filesystems and restricted mounts may fail
+		fillMountUsage(&mounts[i])
+	}
+
+	return mounts, nil
+}
+
+// readMountTable parses the mount table without statting any mount, so a hung
+// network filesystem does not block lookups that only need the table
+func readMountTable() ([]MountInfo, error) {
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mount table: %w", err)
+	}
+	defer file.Close()
+
+	return parseMountInfo(file)
+}
+
+// FindMount returns the mount containing the given path. Usage statistics are
+// not filled in, use ListMounts for those.
+func (m *AnsibleModule) FindMount(path string) (*MountInfo, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolved
+	}
+
+	mounts, err := readMountTable()
+	if err != nil {
+		return nil, err
+	}
+
+	// The last matching entry wins because later mounts shadow earlier ones
+	var found *MountInfo
+	for i := range mounts {
+		mp := mounts[i].MountPoint
+		if absPath == mp || mp == "/" || strings.HasPrefix(absPath, mp+"/") {
+			if found == nil || len(mp) >= len(found.MountPoint) {
+				found = &mounts[i]
+			}
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no mount found for %s", path)
+	}
+	return found, nil
+}
+
+// IsMountPoint checks if a path is the root of a mounted filesystem
+func (m *AnsibleModule) IsMountPoint(path string) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir() {
+		return false, nil
+	}
+
+	parentInfo, err := os.Lstat(filepath.Join(path, ".."))
+	if err != nil {
+		return false, err
+	}
+
+	// A different device than the parent means a mount boundary, and the
+	// same inode as the parent only happens at the filesystem root
+	dev, ino, ok := fileDeviceInode(info)
+	parentDev, parentIno, parentOk := fileDeviceInode(parentInfo)
+	if ok && parentOk {
+		if dev != parentDev || ino == parentIno {
+			return true, nil
+		}
+	}
+
+	// Bind mounts share the device, so fall back to the mount table
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	mounts, err := readMountTable()
+	if err != nil {
+		return false, nil
+	}
+	for _, mount := range mounts {
+		if mount.MountPoint == absPath {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isMounted reports whether path is a mount point, treating a missing path as unmounted
+func (m *AnsibleModule) isMounted(path string) (bool, error) {
+	mounted, err := m.IsMountPoint(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return mounted, err
+}
+
+// runMount runs mount or umount with the given arguments
+func (m *AnsibleModule) runMount(tool string, args ...string) error {
+	path, err := m.GetBinPath(tool, true)
+	if err != nil {
+		return err
+	}
+	result, err := m.RunCommand(path, args, nil, "")
+	if err != nil {
+		return commandFailure(err, result, "%s %s failed", tool, strings.Join(args, " "))
+	}
+	return nil
+}
+
+// Mount mounts a filesystem at entry.Path unless something is already mounted
+// there, creating the mount point if needed. With an empty Source the
+// filesystem is looked up in fstab by mount(8).
+func (m *AnsibleModule) Mount(entry FstabEntry) (bool, error) {
+	mounted, err := m.isMounted(entry.Path)
+	if err != nil || mounted {
+		return false, err
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+	if err := os.MkdirAll(entry.Path, 0755); err != nil {
+		return false, fmt.Errorf("failed to create mount point %s: %w", entry.Path, err)
+	}
+
+	var args []string
+	if entry.FSType != "" {
+		args = append(args, "-t", entry.FSType)
+	}
+	if entry.Options != "" && entry.Options != "defaults" {
+		args = append(args, "-o", entry.Options)
+	}
+	if entry.Source != "" {
+		args = append(args, entry.Source)
+	}
+	if err := m.runMount("mount", append(args, entry.Path)...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Unmount unmounts the filesystem at path if one is mounted
+func (m *AnsibleModule) Unmount(path string) (bool, error) {
+	mounted, err := m.isMounted(path)
+	if err != nil || !mounted {
+		return false, err
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+	if err := m.runMount("umount", path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Remount remounts the filesystem at path, applying options if given. A remount
+// always counts as a change.
+func (m *AnsibleModule) Remount(path, options string) (bool, error) {
+	mounted, err := m.isMounted(path)
+	if err != nil {
+		return false, err
+	}
+	if !mounted {
+		return false, fmt.Errorf("%s is not mounted", path)
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+
+	// BSD and macOS update mounts with -u instead of the remount option
+	var args []string
+	if runtime.GOOS == "linux" {
+		remount := "remount"
+		if options != "" {
+			remount += "," + options
+		}
+		args = []string{"-o", remount}
+	} else {
+		args = []string{"-u"}
+		if options != "" {
+			args = append(args, "-o", options)
+		}
+	}
+	if err := m.runMount("mount", append(args, path)...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// parseMountInfo parses the mountinfo format described in proc(5)
+func parseMountInfo(r io.Reader) ([]MountInfo, error) {
+	var mounts []MountInfo
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		// Optional fields are terminated by a single hyphen
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep == -1 || len(fields) < sep+3 {
+			return nil, fmt.Errorf("malformed mountinfo line: %s", scanner.Text())
+		}
+
+		mountID, _ := strconv.Atoi(fields[0])
+		parentID, _ := strconv.Atoi(fields[1])
+		mount := MountInfo{
+			MountID:    mountID,
+			ParentID:   parentID,
+			Device:     fields[2],
+			Root:       unescapeMountField(fields[3]),
+			MountPoint: unescapeMountField(fields[4]),
+			Options:    strings.Split(fields[5], ","),
+			FSType:     fields[sep+1],
+			Source:     unescapeMountField(fields[sep+2]),
+		}
+		if len(fields) > sep+3 {
+			mount.SuperOptions = strings.Split(fields[sep+3], ",")
+		}
+		mounts = append(mounts, mount)
+	}
+
+	return mounts, scanner.Err()
+}
+
+// unescapeMountField decodes the octal escapes used for whitespace in mount paths
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, "\\") {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if code, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
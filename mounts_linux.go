@@ -0,0 +1,20 @@
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// fillMountUsage populates size and inode statistics for a mount
+func fillMountUsage(mount *MountInfo) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(mount.MountPoint, &stat); err != nil {
+		return
+	}
+	blockSize := uint64(stat.Bsize)
+	mount.BlockSize = blockSize
+	mount.SizeTotal = stat.Blocks * blockSize
+	mount.SizeAvailable = stat.Bavail * blockSize
+	mount.SizeUsed = (stat.Blocks - stat.Bfree) * blockSize
+	mount.InodesTotal = stat.Files
+	mount.InodesUsed = stat.Files - stat.Ffree
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package ansiblemodule
+
+import (
+	"os"
+)
+
+// fileDeviceInode is not available here, so mount points are found from the mount table
+func fileDeviceInode(info os.FileInfo) (uint64, uint64, bool) {
+	return 0, 0, false
+}
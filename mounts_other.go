@@ -0,0 +1,6 @@
+//go:build !linux
+
+package ansiblemodule
+
+// fillMountUsage is a no-op where mountinfo is not available
+func fillMountUsage(mount *MountInfo) {}
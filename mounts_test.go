@@ -0,0 +1,148 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMountInfo(t *testing.T) {
+	content := `23 28 0:22 / /proc rw,relatime - proc proc rw
+36 35 98:0 /mnt1 /mnt/with\040space rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+`
+	mounts, err := parseMountInfo(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Failed to parse mountinfo: %v", err)
+	}
+	if len(mounts) != 2 {
+		t.Fatalf("Expected 2 mounts, got %d", len(mounts))
+	}
+
+	mount := mounts[1]
+	if mount.MountPoint != "/mnt/with space" {
+		t.Errorf("Expected unescaped mount point, got '%s'", mount.MountPoint)
+	}
+	if mount.FSType != "ext3" || mount.Source != "/dev/root" || mount.Device != "98:0" {
+		t.Errorf("Unexpected mount fields: %+v", mount)
+	}
+	if mount.Root != "/mnt1" || mount.ParentID != 35 {
+		t.Errorf("Unexpected mount root or parent: %+v", mount)
+	}
+	if len(mount.Options) != 2 || mount.Options[1] != "noatime" {
+		t.Errorf("Unexpected mount options: %v", mount.Options)
+	}
+	if len(mount.SuperOptions) != 2 || mount.SuperOptions[1] != "errors=continue" {
+		t.Errorf("Unexpected super options: %v", mount.SuperOptions)
+	}
+
+	// Test malformed line
+	if _, err := parseMountInfo(strings.NewReader("1 2 3 4 5 6 7 8 9 10\n")); err == nil {
+		t.Error("Expected error for malformed line")
+	}
+}
+
+func TestListMounts(t *testing.T) {
+	module := &AnsibleModule{}
+
+	if _, err := os.Stat(mountInfoPath); err != nil {
+		t.Skip("mountinfo not available")
+	}
+
+	mounts, err := module.ListMounts()
+	if err != nil {
+		t.Fatalf("Failed to list mounts: %v", err)
+	}
+	if len(mounts) == 0 {
+		t.Fatal("Expected at least one mount")
+	}
+
+	mount, err := module.FindMount(os.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to find mount: %v", err)
+	}
+	if mount.MountPoint == "" {
+		t.Error("Expected mount point to be set")
+	}
+}
+
+func TestFindMountSkipsUsage(t *testing.T) {
+	module := &AnsibleModule{}
+	tmpDir := t.TempDir()
+	table := filepath.Join(tmpDir, "mountinfo")
+	content := "1 0 8:1 / / rw - ext4 /dev/sda1 rw\n"
+	if err := os.WriteFile(table, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := mountInfoPath
+	mountInfoPath = table
+	defer func() { mountInfoPath = old }()
+
+	mount, err := module.FindMount(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to find mount: %v", err)
+	}
+	if mount.MountPoint != "/" || mount.SizeTotal != 0 || mount.BlockSize != 0 {
+		t.Errorf("Expected the root mount without usage statistics, got %+v", mount)
+	}
+}
+
+func TestIsMountPoint(t *testing.T) {
+	module := &AnsibleModule{}
+
+	isMount, err := module.IsMountPoint("/")
+	if err != nil {
+		t.Fatalf("Failed to check mount point: %v", err)
+	}
+	if !isMount {
+		t.Error("Expected / to be a mount point")
+	}
+
+	tmpDir := t.TempDir()
+	subDir := filepath.Join(tmpDir, "sub")
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	isMount, err = module.IsMountPoint(subDir)
+	if err != nil {
+		t.Fatalf("Failed to check mount point: %v", err)
+	}
+	if isMount {
+		t.Error("Expected plain directory not to be a mount point")
+	}
+
+	if _, err := module.IsMountPoint(filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("Expected error for missing path")
+	}
+}
+
+func TestMountCommands(t *testing.T) {
+	runner := &stubRunner{}
+	module := &AnsibleModule{Runner: runner}
+	target := filepath.Join(t.TempDir(), "mnt")
+
+	changed, err := module.Mount(FstabEntry{Source: "/dev/sdb1", Path: target, FSType: "xfs", Options: "noatime"})
+	if err != nil || !changed {
+		t.Fatalf("Expected mount, got %v (%v)", changed, err)
+	}
+	if got := strings.Join(runner.argv, " "); got != "/stub/mount -t xfs -o noatime /dev/sdb1 "+target {
+		t.Errorf("Unexpected mount command: %s", got)
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		t.Error("Expected mount point to be created")
+	}
+
+	runner.argv = nil
+	changed, err = module.Unmount(target)
+	if err != nil || changed || runner.argv != nil {
+		t.Errorf("Expected unmounted path to be unchanged, got %v (%v)", changed, err)
+	}
+
+	if _, err := module.Remount(target, ""); err == nil {
+		t.Error("Expected remount of unmounted path to fail")
+	}
+	changed, err = module.Remount("/", "ro")
+	if err != nil || !changed || !strings.Contains(strings.Join(runner.argv, " "), "ro") {
+		t.Errorf("Expected remount of /, got %v (%v) %v", changed, err, runner.argv)
+	}
+}
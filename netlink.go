@@ -0,0 +1,262 @@
+package ansiblemodule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// errNetlinkUnsupported is returned by the netlink queries outside Linux
+var errNetlinkUnsupported = errors.New("netlink is not supported on this platform")
+
+// NetLink describes a network link as reported by netlink or ip link
+type NetLink struct {
+	Index     int
+	Name      string
+	Type      string // Link type such as ether or loopback
+	MAC       string
+	MTU       int
+	Up        bool   // Administratively up
+	OperState string // Operational state such as up, down or unknown
+}
+
+// NetAddress describes an address assigned to a link
+type NetAddress struct {
+	Index     int
+	Interface string
+	Family    string // ipv4 or ipv6
+	Address   string
+	PrefixLen int
+	Scope     string // global, site, link or host
+}
+
+// NetRoute describes a route of any routing table
+type NetRoute struct {
+	Family      string // ipv4 or ipv6
+	Destination string // Network in CIDR notation, or default
+	Gateway     string
+	Interface   string
+	Index       int
+	Metric      int
+	Table       int // 254 is the main table, 255 the local table
+	Protocol    string
+	Scope       string
+	Type        string // unicast, local, broadcast, blackhole, unreachable...
+}
+
+// Routing table numbers named by ip route
+var routeTableNumbers = map[string]int{"default": 253, "main": 254, "local": 255}
+
+// NetLinks lists the network links through netlink, falling back to ip -json
+// where netlink is not permitted, for instance under a restrictive seccomp policy
+func (m *AnsibleModule) NetLinks() ([]NetLink, error) {
+	links, err := netlinkLinks()
+	if err == nil {
+		return links, nil
+	}
+	m.writeDebugLog("TRACE", fmt.Sprintf("netlink link query failed, using ip: %v", err))
+	links, ipErr := m.ipLinks()
+	if ipErr != nil {
+		return nil, fmt.Errorf("failed to list links: netlink: %v, ip: %v", err, ipErr)
+	}
+	return links, nil
+}
+
+// ipLinks lists the network links with ip -json link
+func (m *AnsibleModule) ipLinks() ([]NetLink, error) {
+	var entries []ipLinkJSON
+	if err := m.runIPJSON(&entries, "link", "show"); err != nil {
+		return nil, err
+	}
+	links := make([]NetLink, 0, len(entries))
+	for _, entry := range entries {
+		links = append(links, entry.link())
+	}
+	return links, nil
+}
+
+// NetAddresses lists the addresses of all links through netlink, falling back to ip -json
+func (m *AnsibleModule) NetAddresses() ([]NetAddress, error) {
+	addresses, err := netlinkAddresses()
+	if err == nil {
+		return addresses, nil
+	}
+	m.writeDebugLog("TRACE", fmt.Sprintf("netlink address query failed, using ip: %v", err))
+	addresses, ipErr := m.ipAddresses()
+	if ipErr != nil {
+		return nil, fmt.Errorf("failed to list addresses: netlink: %v, ip: %v", err, ipErr)
+	}
+	return addresses, nil
+}
+
+// ipAddresses lists the addresses of all links with ip -json address
+func (m *AnsibleModule) ipAddresses() ([]NetAddress, error) {
+	var entries []ipLinkJSON
+	if err := m.runIPJSON(&entries, "address", "show"); err != nil {
+		return nil, err
+	}
+	addresses := []NetAddress{}
+	for _, entry := range entries {
+		for _, info := range entry.AddrInfo {
+			address := NetAddress{
+				Index:     entry.Index,
+				Interface: entry.Name,
+				Family:    ipFamilyNames[info.Family],
+				Address:   info.Local,
+				PrefixLen: info.PrefixLen,
+				Scope:     info.Scope,
+			}
+			if address.Family != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// NetRoutes lists the IPv4 and IPv6 routes of every table through netlink,
+// falling back to ip -json
+func (m *AnsibleModule) NetRoutes() ([]NetRoute, error) {
+	routes, err := netlinkRoutes()
+	if err == nil {
+		return routes, nil
+	}
+	m.writeDebugLog("TRACE", fmt.Sprintf("netlink route query failed, using ip: %v", err))
+	routes, ipErr := m.ipRoutes()
+	if ipErr != nil {
+		return nil, fmt.Errorf("failed to list routes: netlink: %v, ip: %v", err, ipErr)
+	}
+	return routes, nil
+}
+
+// ipRoutes lists the routes of every table with ip -json route
+func (m *AnsibleModule) ipRoutes() ([]NetRoute, error) {
+	routes := []NetRoute{}
+	for _, family := range []string{"ipv4", "ipv6"} {
+		flag := "-4"
+		if family == "ipv6" {
+			flag = "-6"
+		}
+		var entries []ipRouteJSON
+		if err := m.runIPJSON(&entries, flag, "route", "show", "table", "all"); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			route := entry.route(family)
+			if iface, err := net.InterfaceByName(route.Interface); err == nil {
+				route.Index = iface.Index
+			}
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+// runIPJSON runs ip -json and decodes its output
+func (m *AnsibleModule) runIPJSON(target interface{}, args ...string) error {
+	ip, err := m.GetBinPath("ip", true)
+	if err != nil {
+		return err
+	}
+	result, err := m.RunCommand(ip, append([]string{"-json"}, args...), nil, "")
+	if err != nil {
+		return commandFailure(err, result, "ip %s failed", strings.Join(args, " "))
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), target); err != nil {
+		return fmt.Errorf("failed to parse ip output: %w", err)
+	}
+	return nil
+}
+
+// ipFamilyNames maps the address families of ip -json to NetAddress families
+var ipFamilyNames = map[string]string{"inet": "ipv4", "inet6": "ipv6"}
+
+// ipLinkJSON is a link of ip -json link and address output
+type ipLinkJSON struct {
+	Index     int      `json:"ifindex"`
+	Name      string   `json:"ifname"`
+	Flags     []string `json:"flags"`
+	MTU       int      `json:"mtu"`
+	OperState string   `json:"operstate"`
+	LinkType  string   `json:"link_type"`
+	Address   string   `json:"address"`
+	AddrInfo  []struct {
+		Family    string `json:"family"`
+		Local     string `json:"local"`
+		PrefixLen int    `json:"prefixlen"`
+		Scope     string `json:"scope"`
+	} `json:"addr_info"`
+}
+
+func (l ipLinkJSON) link() NetLink {
+	link := NetLink{
+		Index:     l.Index,
+		Name:      l.Name,
+		Type:      l.LinkType,
+		MAC:       strings.ToLower(l.Address),
+		MTU:       l.MTU,
+		OperState: strings.ToLower(l.OperState),
+	}
+	for _, flag := range l.Flags {
+		if flag == "UP" {
+			link.Up = true
+		}
+	}
+	return link
+}
+
+// ipRouteJSON is a route of ip -json route output
+type ipRouteJSON struct {
+	Type     string      `json:"type"`
+	Dst      string      `json:"dst"`
+	Gateway  string      `json:"gateway"`
+	Dev      string      `json:"dev"`
+	Protocol string      `json:"protocol"`
+	Scope    string      `json:"scope"`
+	Metric   int         `json:"metric"`
+	Table    interface{} `json:"table"` // Name or number
+}
+
+func (r ipRouteJSON) route(family string) NetRoute {
+	route := NetRoute{
+		Family:      family,
+		Destination: r.Dst,
+		Gateway:     r.Gateway,
+		Interface:   r.Dev,
+		Metric:      r.Metric,
+		Table:       routeTableNumbers["main"],
+		Protocol:    r.Protocol,
+		Scope:       r.Scope,
+		Type:        r.Type,
+	}
+	if route.Destination != "default" && !strings.Contains(route.Destination, "/") {
+		if family == "ipv4" {
+			route.Destination += "/32"
+		} else {
+			route.Destination += "/128"
+		}
+	}
+	switch table := r.Table.(type) {
+	case string:
+		if number, ok := routeTableNumbers[table]; ok {
+			route.Table = number
+		} else if number, err := strconv.Atoi(table); err == nil {
+			route.Table = number
+		}
+	case float64:
+		route.Table = int(table)
+	}
+	if route.Protocol == "" {
+		route.Protocol = "boot"
+	}
+	if route.Scope == "" {
+		route.Scope = "global"
+	}
+	if route.Type == "" {
+		route.Type = "unicast"
+	}
+	return route
+}
@@ -0,0 +1,211 @@
+package ansiblemodule
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// linkTypeNames maps ARPHRD link types to the names ip link uses
+var linkTypeNames = map[uint16]string{1: "ether", 512: "ppp", 768: "ipip", 769: "tunnel6", 772: "loopback", 776: "sit", 778: "gre", 65534: "none"}
+
+// operStateNames maps IFLA_OPERSTATE values to the names ip link uses
+var operStateNames = []string{"unknown", "notpresent", "down", "lowerlayerdown", "testing", "dormant", "up"}
+
+// routeProtocolNames maps route protocols to the names ip route uses
+var routeProtocolNames = map[uint8]string{1: "redirect", 2: "kernel", 3: "boot", 4: "static", 9: "ra", 16: "dhcp", 186: "bgp", 188: "ospf"}
+
+// routeTypeNames maps route types to the names ip route uses
+var routeTypeNames = map[uint8]string{1: "unicast", 2: "local", 3: "broadcast", 4: "anycast", 5: "multicast", 6: "blackhole", 7: "unreachable", 8: "prohibit", 9: "throw", 10: "nat"}
+
+// scopeName names an address or route scope
+func scopeName(scope uint8) string {
+	switch scope {
+	case 0:
+		return "global"
+	case 200:
+		return "site"
+	case 253:
+		return "link"
+	case 254:
+		return "host"
+	case 255:
+		return "nowhere"
+	}
+	return strconv.Itoa(int(scope))
+}
+
+// familyName names an address family
+func familyName(family uint8) string {
+	if family == syscall.AF_INET6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// netlinkDump sends a dump request and returns the messages of the given type
+// with their attributes
+func netlinkDump(request, family, reply int, headerSize int) ([]syscall.NetlinkMessage, [][]syscall.NetlinkRouteAttr, error) {
+	data, err := syscall.NetlinkRIB(request, family)
+	if err != nil {
+		return nil, nil, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var found []syscall.NetlinkMessage
+	var attrs [][]syscall.NetlinkRouteAttr
+	for _, msg := range msgs {
+		if int(msg.Header.Type) != reply || len(msg.Data) < headerSize {
+			continue
+		}
+		msgAttrs, err := syscall.ParseNetlinkRouteAttr(&msg)
+		if err != nil {
+			return nil, nil, err
+		}
+		found = append(found, msg)
+		attrs = append(attrs, msgAttrs)
+	}
+	return found, attrs, nil
+}
+
+// netlinkLinks lists links with an RTM_GETLINK dump
+func netlinkLinks() ([]NetLink, error) {
+	msgs, attrs, err := netlinkDump(syscall.RTM_GETLINK, syscall.AF_UNSPEC, syscall.RTM_NEWLINK, syscall.SizeofIfInfomsg)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]NetLink, 0, len(msgs))
+	for i, msg := range msgs {
+		linkType := binary.NativeEndian.Uint16(msg.Data[2:])
+		link := NetLink{
+			Index: int(int32(binary.NativeEndian.Uint32(msg.Data[4:]))),
+			Type:  linkTypeNames[linkType],
+			Up:    binary.NativeEndian.Uint32(msg.Data[8:])&syscall.IFF_UP != 0,
+		}
+		if link.Type == "" {
+			link.Type = strconv.Itoa(int(linkType))
+		}
+		for _, attr := range attrs[i] {
+			switch attr.Attr.Type {
+			case syscall.IFLA_IFNAME:
+				link.Name = strings.TrimRight(string(attr.Value), "\x00")
+			case syscall.IFLA_ADDRESS:
+				link.MAC = net.HardwareAddr(attr.Value).String()
+			case syscall.IFLA_MTU:
+				if len(attr.Value) >= 4 {
+					link.MTU = int(binary.NativeEndian.Uint32(attr.Value))
+				}
+			case syscall.IFLA_OPERSTATE:
+				if len(attr.Value) > 0 && int(attr.Value[0]) < len(operStateNames) {
+					link.OperState = operStateNames[attr.Value[0]]
+				}
+			}
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// netlinkAddresses lists addresses with an RTM_GETADDR dump
+func netlinkAddresses() ([]NetAddress, error) {
+	msgs, attrs, err := netlinkDump(syscall.RTM_GETADDR, syscall.AF_UNSPEC, syscall.RTM_NEWADDR, syscall.SizeofIfAddrmsg)
+	if err != nil {
+		return nil, err
+	}
+	names := map[int]string{}
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			names[iface.Index] = iface.Name
+		}
+	}
+
+	addresses := make([]NetAddress, 0, len(msgs))
+	for i, msg := range msgs {
+		family := msg.Data[0]
+		if family != syscall.AF_INET && family != syscall.AF_INET6 {
+			continue
+		}
+		address := NetAddress{
+			Index:     int(binary.NativeEndian.Uint32(msg.Data[4:])),
+			Family:    familyName(family),
+			PrefixLen: int(msg.Data[1]),
+			Scope:     scopeName(msg.Data[3]),
+		}
+		address.Interface = names[address.Index]
+		// IFA_LOCAL is the address itself, IFA_ADDRESS the peer on point-to-point links
+		for _, attr := range attrs[i] {
+			switch attr.Attr.Type {
+			case syscall.IFA_LOCAL:
+				address.Address = net.IP(attr.Value).String()
+			case syscall.IFA_ADDRESS:
+				if address.Address == "" {
+					address.Address = net.IP(attr.Value).String()
+				}
+			}
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// netlinkRoutes lists the routes of every table with RTM_GETROUTE dumps
+func netlinkRoutes() ([]NetRoute, error) {
+	names := map[int]string{}
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			names[iface.Index] = iface.Name
+		}
+	}
+
+	var routes []NetRoute
+	for _, family := range []int{syscall.AF_INET, syscall.AF_INET6} {
+		msgs, attrs, err := netlinkDump(syscall.RTM_GETROUTE, family, syscall.RTM_NEWROUTE, syscall.SizeofRtMsg)
+		if err != nil {
+			return nil, err
+		}
+		for i, msg := range msgs {
+			route := NetRoute{
+				Family:   familyName(msg.Data[0]),
+				Table:    int(msg.Data[4]),
+				Protocol: routeProtocolNames[msg.Data[5]],
+				Scope:    scopeName(msg.Data[6]),
+				Type:     routeTypeNames[msg.Data[7]],
+			}
+			if route.Protocol == "" {
+				route.Protocol = strconv.Itoa(int(msg.Data[5]))
+			}
+			prefixLen := int(msg.Data[1])
+			for _, attr := range attrs[i] {
+				switch attr.Attr.Type {
+				case syscall.RTA_DST:
+					route.Destination = fmt.Sprintf("%s/%d", net.IP(attr.Value), prefixLen)
+				case syscall.RTA_GATEWAY:
+					route.Gateway = net.IP(attr.Value).String()
+				case syscall.RTA_OIF:
+					if len(attr.Value) >= 4 {
+						route.Index = int(binary.NativeEndian.Uint32(attr.Value))
+					}
+				case syscall.RTA_PRIORITY:
+					if len(attr.Value) >= 4 {
+						route.Metric = int(binary.NativeEndian.Uint32(attr.Value))
+					}
+				case syscall.RTA_TABLE:
+					if len(attr.Value) >= 4 {
+						route.Table = int(binary.NativeEndian.Uint32(attr.Value))
+					}
+				}
+			}
+			if route.Destination == "" {
+				route.Destination = "default"
+			}
+			route.Interface = names[route.Index]
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package ansiblemodule
+
+// netlinkLinks is not available outside Linux
+func netlinkLinks() ([]NetLink, error) {
+	return nil, errNetlinkUnsupported
+}
+
+// netlinkAddresses is not available outside Linux
+func netlinkAddresses() ([]NetAddress, error) {
+	return nil, errNetlinkUnsupported
+}
+
+// netlinkRoutes is not available outside Linux
+func netlinkRoutes() ([]NetRoute, error) {
+	return nil, errNetlinkUnsupported
+}
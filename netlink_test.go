@@ -0,0 +1,97 @@
+package ansiblemodule
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// ipRunner answers ip -json commands with canned output
+type ipRunner struct {
+	outputs map[string]string
+}
+
+func (r *ipRunner) Run(ctx context.Context, cmd string, args []string, env []string, data string) (CommandResult, error) {
+	if output, ok := r.outputs[strings.Join(args, " ")]; ok {
+		return CommandResult{Stdout: output}, nil
+	}
+	return CommandResult{Stderr: "unexpected command", Rc: 1}, nil
+}
+
+func (r *ipRunner) LookPath(name string) (string, error) {
+	return "/sbin/" + name, nil
+}
+
+func TestNetlinkQueries(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("netlink is Linux only")
+	}
+	module := &AnsibleModule{}
+	links, err := module.NetLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, link := range links {
+		if link.Type == "loopback" {
+			found = link.Name != "" && link.Index > 0
+		}
+	}
+	if !found {
+		t.Errorf("Expected the loopback link, got %+v", links)
+	}
+	addresses, err := module.NetAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, address := range addresses {
+		if address.Address == "127.0.0.1" && (address.PrefixLen != 8 || address.Scope != "host") {
+			t.Errorf("Unexpected loopback address %+v", address)
+		}
+	}
+	if _, err := module.NetRoutes(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIPCommandFallback(t *testing.T) {
+	module := &AnsibleModule{Runner: &ipRunner{outputs: map[string]string{
+		"-json link show": `[{"ifindex":1,"ifname":"lo","flags":["LOOPBACK","UP","LOWER_UP"],"mtu":65536,"operstate":"UNKNOWN","link_type":"loopback","address":"00:00:00:00:00:00"},
+			{"ifindex":2,"ifname":"eth0","flags":["BROADCAST","MULTICAST"],"mtu":1500,"operstate":"DOWN","link_type":"ether","address":"02:FC:00:00:00:01"}]`,
+		"-json address show": `[{"ifindex":2,"ifname":"eth0","addr_info":[{"family":"inet","local":"192.0.2.2","prefixlen":24,"scope":"global"},{"family":"inet6","local":"fe80::1","prefixlen":64,"scope":"link"}]}]`,
+		"-json -4 route show table all": `[{"dst":"default","gateway":"192.0.2.1","dev":"eth0","flags":[]},
+			{"type":"local","dst":"192.0.2.2","table":"local","dev":"eth0","protocol":"kernel","scope":"host","prefsrc":"192.0.2.2"}]`,
+		"-json -6 route show table all": `[{"dst":"fd00::/64","dev":"eth0","protocol":"kernel","metric":256,"table":"100"}]`,
+	}}}
+
+	links, err := module.ipLinks()
+	if err != nil || len(links) != 2 {
+		t.Fatalf("Expected two links, got %v (%v)", links, err)
+	}
+	if expected := (NetLink{Index: 2, Name: "eth0", Type: "ether", MAC: "02:fc:00:00:00:01", MTU: 1500, OperState: "down"}); links[1] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, links[1])
+	}
+	if !links[0].Up {
+		t.Error("Expected lo to be up")
+	}
+
+	addresses, err := module.ipAddresses()
+	if err != nil || len(addresses) != 2 || addresses[1].Family != "ipv6" || addresses[0].Address != "192.0.2.2" || addresses[0].Interface != "eth0" {
+		t.Errorf("Unexpected addresses %+v (%v)", addresses, err)
+	}
+
+	routes, err := module.ipRoutes()
+	if err != nil || len(routes) != 3 {
+		t.Fatalf("Expected three routes, got %+v (%v)", routes, err)
+	}
+	if routes[0].Destination != "default" || routes[0].Gateway != "192.0.2.1" || routes[0].Table != 254 || routes[0].Protocol != "boot" || routes[0].Type != "unicast" {
+		t.Errorf("Unexpected default route %+v", routes[0])
+	}
+	if routes[1].Destination != "192.0.2.2/32" || routes[1].Table != 255 || routes[1].Type != "local" || routes[1].Scope != "host" {
+		t.Errorf("Unexpected local route %+v", routes[1])
+	}
+	if routes[2].Family != "ipv6" || routes[2].Table != 100 || routes[2].Metric != 256 {
+		t.Errorf("Unexpected IPv6 route %+v", routes[2])
+	}
+}
@@ -0,0 +1,72 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// normalizeNetworkValue validates and normalizes ipaddr, cidr, macaddr and port arguments
+func normalizeNetworkValue(argType string, value interface{}) (interface{}, error) {
+	if argType == "port" {
+		return normalizePort(value)
+	}
+
+	strVal, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("must be a string")
+	}
+	strVal = strings.TrimSpace(strVal)
+
+	switch argType {
+	case "ipaddr":
+		addr, err := netip.ParseAddr(strVal)
+		if err != nil {
+			return nil, fmt.Errorf("must be a valid IP address: %s", strVal)
+		}
+		return addr.Unmap().String(), nil
+	case "cidr":
+		prefix, err := netip.ParsePrefix(strVal)
+		if err != nil {
+			return nil, fmt.Errorf("must be a valid CIDR network: %s", strVal)
+		}
+		return prefix.Masked().String(), nil
+	case "macaddr":
+		hw, err := net.ParseMAC(strVal)
+		if err != nil || len(hw) != 6 {
+			return nil, fmt.Errorf("must be a valid MAC address: %s", strVal)
+		}
+		return strings.ToLower(hw.String()), nil
+	}
+
+	return nil, fmt.Errorf("unknown network type %s", argType)
+}
+
+// normalizePort converts a port value to an int between 1 and 65535
+func normalizePort(value interface{}) (int, error) {
+	var port int
+	switch v := value.(type) {
+	case int:
+		port = v
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("must be a valid port number")
+		}
+		port = int(v)
+	case string:
+		parsed, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("must be a valid port number: %w", err)
+		}
+		port = parsed
+	default:
+		return 0, fmt.Errorf("must be a valid port number")
+	}
+
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("must be a port number between 1 and 65535, got %d", port)
+	}
+	return port, nil
+}
@@ -0,0 +1,97 @@
+package ansiblemodule
+
+import (
+	"testing"
+)
+
+func TestNormalizeNetworkValue(t *testing.T) {
+	tests := []struct {
+		argType  string
+		value    interface{}
+		expected interface{}
+		hasError bool
+	}{
+		{"ipaddr", "192.168.1.1", "192.168.1.1", false},
+		{"ipaddr", "2001:DB8:0:0:0:0:0:1", "2001:db8::1", false},
+		{"ipaddr", "::ffff:10.0.0.1", "10.0.0.1", false},
+		{"ipaddr", "300.1.1.1", nil, true},
+		{"ipaddr", 123, nil, true},
+		{"cidr", "10.1.2.3/8", "10.0.0.0/8", false},
+		{"cidr", "2001:DB8::1/64", "2001:db8::/64", false},
+		{"cidr", "10.0.0.0/33", nil, true},
+		{"macaddr", "AA:BB:CC:DD:EE:FF", "aa:bb:cc:dd:ee:ff", false},
+		{"macaddr", "aa-bb-cc-dd-ee-ff", "aa:bb:cc:dd:ee:ff", false},
+		{"macaddr", "aa:bb:cc", nil, true},
+		{"port", "8080", 8080, false},
+		{"port", float64(443), 443, false},
+		{"port", 22, 22, false},
+		{"port", 0, nil, true},
+		{"port", "70000", nil, true},
+		{"port", "http", nil, true},
+	}
+
+	for _, test := range tests {
+		result, err := normalizeNetworkValue(test.argType, test.value)
+		if test.hasError {
+			if err == nil {
+				t.Errorf("Expected error for %s %v", test.argType, test.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %s %v: %v", test.argType, test.value, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("Expected %v for %s %v, got %v", test.expected, test.argType, test.value, result)
+		}
+	}
+
+	// Test normalization through argument validation
+	module := &AnsibleModule{
+		ArgSpec: ArgSpecMap{
+			"mac":  ArgumentSpec{Type: "macaddr"},
+			"port": ArgumentSpec{Type: "port"},
+		},
+		Params: ModuleParams{
+			"mac":  "00:1A:2B:3C:4D:5E",
+			"port": "8080",
+		},
+	}
+	if err := module.validateArguments(); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	if module.Params["mac"] != "00:1a:2b:3c:4d:5e" {
+		t.Errorf("Expected normalized MAC, got %v", module.Params["mac"])
+	}
+	if module.Params["port"] != 8080 {
+		t.Errorf("Expected port 8080, got %v", module.Params["port"])
+	}
+}
+
+func TestNestedNetworkArguments(t *testing.T) {
+	module := &AnsibleModule{
+		ArgSpec: ArgSpecMap{
+			"servers":  {Type: "list", Elements: "ipaddr"},
+			"listener": {Type: "dict", Options: ArgSpecMap{"port": {Type: "port", Choices: []string{"80", "443"}}}},
+		},
+		Params: ModuleParams{
+			"servers":  []interface{}{"2001:DB8::1", "::ffff:10.0.0.1"},
+			"listener": map[string]interface{}{"port": " 443"},
+		},
+	}
+	if err := module.validateArguments(); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	if servers := module.Params["servers"].([]interface{}); servers[0] != "2001:db8::1" || servers[1] != "10.0.0.1" {
+		t.Errorf("Expected normalized list elements, got %v", servers)
+	}
+	if port := module.Params["listener"].(map[string]interface{})["port"]; port != 443 {
+		t.Errorf("Expected normalized nested port, got %#v", port)
+	}
+	for name := range module.Params {
+		if name != "servers" && name != "listener" {
+			t.Errorf("Unexpected parameter %s", name)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// noLogReplacement replaces no_log values in output
+const noLogReplacement = "********"
+
+// noLogValues returns the string forms of all parameter values marked no_log
+func (m *AnsibleModule) noLogValues() []string {
+	var values []string
+	for _, name := range m.NoLog {
+		if value, exists := m.Params[name]; exists {
+			values = appendNoLogValues(values, value)
+		}
+	}
+	for name, value := range m.Params {
+		if option, ok := m.ArgSpec[name]; ok && !option.NoLog {
+			values = appendNestedNoLogValues(values, option, value)
+		}
+	}
+	m.noLogMu.Lock()
+	values = append(values, m.runtimeNoLog...)
+	m.noLogMu.Unlock()
+
+	// Replace longer values first so substrings of other secrets don't leak parts
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return values
+}
+
+// NoLogValue registers a secret discovered at runtime, such as a token returned by
+// a login call or a generated password, so it is censored from every warning, log
+// line and result produced afterwards, including the final result
+func (m *AnsibleModule) NoLogValue(value interface{}) {
+	m.noLogMu.Lock()
+	defer m.noLogMu.Unlock()
+	m.runtimeNoLog = appendNoLogValues(m.runtimeNoLog, value)
+}
+
+// appendNoLogValues collects the scalar values contained in a parameter value
+func appendNoLogValues(values []string, value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return values
+	case map[string]interface{}:
+		for _, item := range v {
+			values = appendNoLogValues(values, item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			values = appendNoLogValues(values, item)
+		}
+	case []string:
+		for _, item := range v {
+			values = appendNoLogValues(values, item)
+		}
+	case bool:
+		// Booleans are too common to scrub meaningfully
+		return values
+	default:
+		if str := fmt.Sprintf("%v", v); str != "" {
+			values = append(values, str)
+		}
+	}
+	return values
+}
+
+// scrubString replaces all no_log values in a string
+func (m *AnsibleModule) scrubString(text string) string {
+	for _, value := range m.noLogValues() {
+		text = strings.ReplaceAll(text, value, noLogReplacement)
+	}
+	return text
+}
+
+// noLogPlaceholder replaces the values of no_log options in the invocation
+const noLogPlaceholder = "VALUE_SPECIFIED_IN_NO_LOG_PARAMETER"
+
+// nestedSpec returns the suboptions of a dict or list option
+func nestedSpec(option ArgumentSpec) ArgSpecMap {
+	if len(option.Options) > 0 {
+		return option.Options
+	}
+	return option.SubOptions
+}
+
+// lookupOption finds the spec of an option by name or alias
+func lookupOption(spec ArgSpecMap, key string) (ArgumentSpec, bool) {
+	if option, ok := spec[key]; ok {
+		return option, true
+	}
+	for _, option := range spec {
+		if containsString(option.Aliases, key) {
+			return option, true
+		}
+	}
+	return ArgumentSpec{}, false
+}
+
+// nestedValues returns the dicts held by a dict option or a list of dicts
+func nestedValues(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		var dicts []map[string]interface{}
+		for _, item := range v {
+			if dict, ok := item.(map[string]interface{}); ok {
+				dicts = append(dicts, dict)
+			}
+		}
+		return dicts
+	}
+	return nil
+}
+
+// resolveNestedAliases renames suboption aliases to their option names in place
+func resolveNestedAliases(option ArgumentSpec, value interface{}) {
+	spec := nestedSpec(option)
+	if len(spec) == 0 {
+		return
+	}
+	for _, dict := range nestedValues(value) {
+		for name, sub := range spec {
+			for _, alias := range sub.Aliases {
+				if aliasValue, exists := dict[alias]; exists {
+					if _, mainExists := dict[name]; !mainExists {
+						dict[name] = aliasValue
+					}
+					delete(dict, alias)
+				}
+			}
+		}
+		for name, sub := range spec {
+			if subValue, exists := dict[name]; exists {
+				resolveNestedAliases(sub, subValue)
+			}
+		}
+	}
+}
+
+// appendNestedNoLogValues collects the values of no_log suboptions of an option value
+func appendNestedNoLogValues(values []string, option ArgumentSpec, value interface{}) []string {
+	spec := nestedSpec(option)
+	if len(spec) == 0 {
+		return values
+	}
+	for _, dict := range nestedValues(value) {
+		for key, subValue := range dict {
+			sub, ok := lookupOption(spec, key)
+			if !ok {
+				continue
+			}
+			if sub.NoLog {
+				values = appendNoLogValues(values, subValue)
+			} else {
+				values = appendNestedNoLogValues(values, sub, subValue)
+			}
+		}
+	}
+	return values
+}
+
+// censorNoLog returns a copy of an option value with its no_log suboptions replaced
+func censorNoLog(option ArgumentSpec, value interface{}) interface{} {
+	spec := nestedSpec(option)
+	if len(spec) == 0 {
+		return value
+	}
+	censorDict := func(dict map[string]interface{}) map[string]interface{} {
+		censored := make(map[string]interface{}, len(dict))
+		for key, subValue := range dict {
+			sub, ok := lookupOption(spec, key)
+			switch {
+			case ok && sub.NoLog:
+				censored[key] = noLogPlaceholder
+			case ok:
+				censored[key] = censorNoLog(sub, subValue)
+			default:
+				censored[key] = subValue
+			}
+		}
+		return censored
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return censorDict(v)
+	case []interface{}:
+		censored := make([]interface{}, len(v))
+		for i, item := range v {
+			if dict, ok := item.(map[string]interface{}); ok {
+				censored[i] = censorDict(dict)
+			} else {
+				censored[i] = item
+			}
+		}
+		return censored
+	}
+	return value
+}
+
+// scrubResult returns a copy of a result with no_log values replaced in every string
+func (m *AnsibleModule) scrubResult(result map[string]interface{}) map[string]interface{} {
+	values := m.noLogValues()
+	if len(values) == 0 {
+		return result
+	}
+	scrubbed, _ := scrubValue(result, values).(map[string]interface{})
+	return scrubbed
+}
+
+// scrubValue replaces no_log values in the strings of a value, copying containers
+func scrubValue(value interface{}, values []string) interface{} {
+	switch v := value.(type) {
+	case string:
+		for _, secret := range values {
+			v = strings.ReplaceAll(v, secret, noLogReplacement)
+		}
+		return v
+	case map[string]interface{}:
+		scrubbed := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			scrubbed[key] = scrubValue(item, values)
+		}
+		return scrubbed
+	case []interface{}:
+		scrubbed := make([]interface{}, len(v))
+		for i, item := range v {
+			scrubbed[i] = scrubValue(item, values)
+		}
+		return scrubbed
+	case []string:
+		scrubbed := make([]string, len(v))
+		for i, item := range v {
+			scrubbed[i] = scrubValue(item, values).(string)
+		}
+		return scrubbed
+	case map[string]string:
+		scrubbed := make(map[string]string, len(v))
+		for key, item := range v {
+			scrubbed[key] = scrubValue(item, values).(string)
+		}
+		return scrubbed
+	case []map[string]interface{}:
+		scrubbed := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			scrubbed[i] = scrubValue(item, values).(map[string]interface{})
+		}
+		return scrubbed
+	case []map[string]string:
+		scrubbed := make([]map[string]string, len(v))
+		for i, item := range v {
+			scrubbed[i] = scrubValue(item, values).(map[string]string)
+		}
+		return scrubbed
+	}
+	return value
+}
@@ -0,0 +1,151 @@
+package ansiblemodule
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// noLogSpec has secrets behind an alias, in a dict option and in a list of dicts
+var noLogSpec = ArgSpecMap{
+	"password": {Type: "str", NoLog: true, Aliases: []string{"pass"}},
+	"auth": {Type: "dict", Options: ArgSpecMap{
+		"user":  {Type: "str"},
+		"token": {Type: "str", NoLog: true, Aliases: []string{"api_token"}},
+	}},
+	"users": {Type: "list", Elements: "dict", SubOptions: ArgSpecMap{
+		"name":   {Type: "str"},
+		"secret": {Type: "str", NoLog: true},
+	}},
+}
+
+func TestNoLogAliasesAndNestedOptions(t *testing.T) {
+	t.Setenv("ANSIBLE_MODULE_ARGS", `{
+		"pass": "alias-secret",
+		"auth": {"user": "admin", "api_token": "nested-secret"},
+		"users": [{"name": "alice", "secret": "list-secret"}]
+	}`)
+	module := &AnsibleModule{ArgSpec: noLogSpec, Params: ModuleParams{}, Aliases: map[string]string{"pass": "password"}}
+	if err := module.parseInput(); err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	if err := module.validateArguments(); err != nil {
+		t.Fatalf("Failed to validate arguments: %v", err)
+	}
+	module.NoLog = []string{"password"}
+
+	// Suboption aliases are resolved like top-level ones
+	if auth := module.Params["auth"].(map[string]interface{}); auth["token"] != "nested-secret" {
+		t.Errorf("Expected api_token alias to be resolved, got %v", auth)
+	}
+
+	module.AddWarning("login with list-secret failed")
+	parsed := exitOutput(t, module, map[string]interface{}{
+		"msg":    "token nested-secret accepted",
+		"nested": map[string]interface{}{"lines": []interface{}{"password=alias-secret"}},
+	})
+
+	output, _ := json.Marshal(parsed)
+	for _, secret := range []string{"alias-secret", "nested-secret", "list-secret"} {
+		if strings.Contains(string(output), secret) {
+			t.Errorf("Expected %s to be censored, got %s", secret, output)
+		}
+	}
+
+	invocation := parsed["invocation"].(map[string]interface{})
+	if invocation["password"] != noLogPlaceholder {
+		t.Errorf("Expected password to be hidden, got %v", invocation["password"])
+	}
+	auth := invocation["auth"].(map[string]interface{})
+	if auth["token"] != noLogPlaceholder || auth["user"] != "admin" {
+		t.Errorf("Unexpected auth invocation: %v", auth)
+	}
+	users := invocation["users"].([]interface{})
+	if user := users[0].(map[string]interface{}); user["secret"] != noLogPlaceholder || user["name"] != "alice" {
+		t.Errorf("Unexpected users invocation: %v", user)
+	}
+	if parsed["msg"] != "token ******** accepted" {
+		t.Errorf("Expected msg to be scrubbed, got %v", parsed["msg"])
+	}
+
+	// The module parameters themselves keep the real values
+	if module.Params["password"] != "alias-secret" {
+		t.Errorf("Expected params to be unchanged, got %v", module.Params["password"])
+	}
+}
+
+func TestAliasOverridesDefault(t *testing.T) {
+	t.Setenv("ANSIBLE_MODULE_ARGS", `{"dest": "/tmp/file"}`)
+	module := &AnsibleModule{
+		ArgSpec: ArgSpecMap{"path": {Type: "path", Default: "/default", Aliases: []string{"dest"}}},
+		Params:  ModuleParams{},
+		Aliases: map[string]string{"dest": "path"},
+	}
+	if err := module.parseInput(); err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	if module.Params["path"] != "/tmp/file" {
+		t.Errorf("Expected alias value to win over the default, got %v", module.Params["path"])
+	}
+}
+
+func TestNoLogValue(t *testing.T) {
+	module := &AnsibleModule{Params: ModuleParams{}, NoTargetSyslog: true}
+	logFile := filepath.Join(t.TempDir(), "module.log")
+	t.Setenv(DebugLogEnv, logFile)
+
+	module.DebugMsg("before login")
+	module.NoLogValue("session-token-1234")
+	module.NoLogValue(map[string]interface{}{"password": "generated-pw"})
+	module.AddWarning("login returned session-token-1234")
+	module.DebugMsg("using session-token-1234")
+
+	parsed := exitOutput(t, module, map[string]interface{}{
+		"token":  "session-token-1234",
+		"config": map[string]interface{}{"password": "generated-pw"},
+	})
+	output, _ := json.Marshal(parsed)
+	for _, secret := range []string{"session-token-1234", "generated-pw"} {
+		if strings.Contains(string(output), secret) {
+			t.Errorf("Expected %q to be censored, got %s", secret, output)
+		}
+	}
+
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read debug log: %v", err)
+	}
+	if strings.Contains(string(content), "session-token-1234") {
+		t.Errorf("Expected runtime secret to be scrubbed from debug log: %s", content)
+	}
+	if !strings.Contains(string(content), "before login") {
+		t.Errorf("Expected earlier messages to be logged: %s", content)
+	}
+}
+
+func TestDiffRedaction(t *testing.T) {
+	module := &AnsibleModule{
+		Params:  ModuleParams{"password": "hunter2"},
+		ArgSpec: ArgSpecMap{"password": {Type: "str", NoLog: true}},
+		NoLog:   []string{"password"},
+	}
+
+	diff := module.CreateDiff("user=admin\npassword=old\n", "user=admin\npassword=hunter2\n", "", "")
+	if after := diff["after"].(string); strings.Contains(after, "hunter2") || !strings.Contains(after, "user=admin") {
+		t.Errorf("Expected password masked in diff, got %q", after)
+	}
+	prepared := module.CreatePreparedDiff("-password=old\n+password=hunter2\n")
+	if strings.Contains(prepared["prepared"].(string), "hunter2") {
+		t.Errorf("Expected password masked in prepared diff, got %q", prepared["prepared"])
+	}
+
+	// Secrets registered after the diff was made are masked on exit
+	diffs := []map[string]interface{}{module.CreateDiff("", "token=abc-789\n", "", "")}
+	module.NoLogValue("abc-789")
+	output, _ := json.Marshal(exitOutput(t, module, map[string]interface{}{"diff": diffs}))
+	if strings.Contains(string(output), "abc-789") {
+		t.Errorf("Expected late secret masked in diff list, got %s", output)
+	}
+}
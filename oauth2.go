@@ -0,0 +1,206 @@
+package ansiblemodule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2Config defines how to obtain OAuth2 access tokens
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RefreshToken string            // If set, the refresh_token grant is used instead of client_credentials
+	AuthInParams bool              // Send client credentials in the form body instead of basic auth
+	ExtraParams  map[string]string // Additional form parameters sent to the token endpoint
+}
+
+// OAuth2Token is an access token returned by a token endpoint
+type OAuth2Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Valid reports whether the token is set and not about to expire
+func (t *OAuth2Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	if t.Expiry.IsZero() {
+		return true
+	}
+	return time.Now().Add(oauth2ExpiryDelta).Before(t.Expiry)
+}
+
+// oauth2ExpiryDelta is how early a token is considered expired
+const oauth2ExpiryDelta = 30 * time.Second
+
+// OAuth2TokenSource fetches and caches OAuth2 tokens for the duration of a run
+type OAuth2TokenSource struct {
+	config OAuth2Config
+	client *http.Client
+	ctx    func() context.Context // Module context, so token requests stop on cancellation
+	mu     sync.Mutex
+	token  *OAuth2Token
+}
+
+// NewOAuth2TokenSource creates a token source using the module HTTP client
+func (m *AnsibleModule) NewOAuth2TokenSource(config OAuth2Config) *OAuth2TokenSource {
+	// Use a copy of the client so token requests never pass through
+	// transports that are later wrapped around the module client
+	client := *m.httpClient()
+	return &OAuth2TokenSource{
+		config: config,
+		client: &client,
+		ctx:    m.Context,
+	}
+}
+
+// Token returns a cached token, fetching or refreshing it when needed
+func (s *OAuth2TokenSource) Token() (*OAuth2Token, error) {
+	return s.TokenContext(s.ctx())
+}
+
+// TokenContext is Token with the token request bound to ctx
+func (s *OAuth2TokenSource) TokenContext(ctx context.Context) (*OAuth2Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	refreshToken := s.config.RefreshToken
+	if s.token != nil && s.token.RefreshToken != "" {
+		refreshToken = s.token.RefreshToken
+	}
+
+	token, err := s.fetch(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	s.token = token
+	return token, nil
+}
+
+// fetch requests a new token from the token endpoint
+func (s *OAuth2TokenSource) fetch(ctx context.Context, refreshToken string) (*OAuth2Token, error) {
+	form := url.Values{}
+	if refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(s.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.config.Scopes, " "))
+	}
+	if s.config.AuthInParams {
+		form.Set("client_id", s.config.ClientID)
+		form.Set("client_secret", s.config.ClientSecret)
+	}
+	for k, v := range s.config.ExtraParams {
+		form.Set(k, v)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !s.config.AuthInParams {
+		req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(s.config.ClientSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		RefreshToken     string      `json:"refresh_token"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse token response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || body.Error != "" {
+		msg := body.Error
+		if body.ErrorDescription != "" {
+			msg = fmt.Sprintf("%s: %s", msg, body.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, msg)
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("token response did not contain an access_token")
+	}
+
+	token := &OAuth2Token{
+		AccessToken:  body.AccessToken,
+		TokenType:    body.TokenType,
+		RefreshToken: body.RefreshToken,
+	}
+	if token.TokenType == "" {
+		token.TokenType = "Bearer"
+	}
+	if seconds, err := body.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		token.Expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+
+	return token, nil
+}
+
+// oauth2Transport adds an Authorization header to outgoing requests
+type oauth2Transport struct {
+	source *OAuth2TokenSource
+	base   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	token, err := t.source.TokenContext(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the caller's request
+	authReq := req.Clone(req.Context())
+	tokenType := token.TokenType
+	if strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	authReq.Header.Set("Authorization", tokenType+" "+token.AccessToken)
+	return t.base.RoundTrip(authReq)
+}
+
+// UseOAuth2 configures the module HTTP client to authenticate all requests with OAuth2
+func (m *AnsibleModule) UseOAuth2(config OAuth2Config) *OAuth2TokenSource {
+	source := m.NewOAuth2TokenSource(config)
+	m.wrapTransport(func(base http.RoundTripper) http.RoundTripper {
+		return &oauth2Transport{source: source, base: base}
+	})
+	return source
+}
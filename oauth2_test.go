@@ -0,0 +1,125 @@
+package ansiblemodule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuth2TokenSource(t *testing.T) {
+	module := &AnsibleModule{}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "client" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+		r.ParseForm()
+		switch r.Form.Get("grant_type") {
+		case "client_credentials":
+			fmt.Fprint(w, `{"access_token":"token1","token_type":"bearer","expires_in":3600}`)
+		case "refresh_token":
+			fmt.Fprintf(w, `{"access_token":"refreshed-%s","expires_in":3600}`, r.Form.Get("refresh_token"))
+		}
+	}))
+	defer server.Close()
+
+	// Test client credentials flow with caching
+	source := module.NewOAuth2TokenSource(OAuth2Config{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	})
+	token, err := source.Token()
+	if err != nil {
+		t.Fatalf("Failed to fetch token: %v", err)
+	}
+	if token.AccessToken != "token1" {
+		t.Errorf("Expected access token 'token1', got '%s'", token.AccessToken)
+	}
+	if _, err := source.Token(); err != nil {
+		t.Fatalf("Failed to fetch cached token: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected token to be cached, got %d requests", requests)
+	}
+
+	// Test refresh token flow
+	source = module.NewOAuth2TokenSource(OAuth2Config{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RefreshToken: "abc",
+	})
+	token, err = source.Token()
+	if err != nil {
+		t.Fatalf("Failed to refresh token: %v", err)
+	}
+	if token.AccessToken != "refreshed-abc" {
+		t.Errorf("Expected access token 'refreshed-abc', got '%s'", token.AccessToken)
+	}
+
+	// Test token endpoint error
+	source = module.NewOAuth2TokenSource(OAuth2Config{
+		TokenURL: server.URL,
+		ClientID: "wrong",
+	})
+	if _, err := source.Token(); err == nil {
+		t.Error("Expected error for invalid client")
+	}
+}
+
+func TestUseOAuth2(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token":"abc123","token_type":"Bearer"}`)
+	}))
+	defer tokenServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer apiServer.Close()
+
+	module.UseOAuth2(OAuth2Config{TokenURL: tokenServer.URL})
+
+	resp, err := module.FetchURL("GET", apiServer.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if string(resp.Body) != "Bearer abc123" {
+		t.Errorf("Expected Authorization 'Bearer abc123', got '%s'", resp.Body)
+	}
+
+	// Test explicit Authorization header is preserved
+	resp, err = module.FetchURL("GET", apiServer.URL, nil, map[string]string{"Authorization": "Basic xyz"})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if string(resp.Body) != "Basic xyz" {
+		t.Errorf("Expected Authorization 'Basic xyz', got '%s'", resp.Body)
+	}
+}
+
+func TestOAuth2TokenCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no token request after cancellation")
+	}))
+	defer server.Close()
+
+	module := &AnsibleModule{}
+	source := module.NewOAuth2TokenSource(OAuth2Config{TokenURL: server.URL, ClientID: "client"})
+	module.Context()
+	module.cancel()
+	if _, err := source.Token(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the token request to be cancelled, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+)
+
+// captureStdout returns what fn writes to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	output := make(chan string, 1)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		output <- buf.String()
+	}()
+
+	oldStdout := os.Stdout
+	os.Stdout = w
+	func() {
+		defer func() {
+			w.Close()
+			os.Stdout = oldStdout
+		}()
+		fn()
+	}()
+	return <-output
+}
+
+// captureResult returns the JSON result fn writes to stdout
+func captureResult(t *testing.T, fn func()) map[string]interface{} {
+	t.Helper()
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(captureStdout(t, fn)), &parsed); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	return parsed
+}
+
+// exitOutput runs ExitJson and returns the parsed output
+func exitOutput(t *testing.T, module *AnsibleModule, result map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	module.ExitFunc = func(code int) {}
+	return captureResult(t, func() { module.ExitJson(result) })
+}
@@ -0,0 +1,92 @@
+package ansiblemodule
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ParallelTask identifies the item a RunParallel callback is working on
+type ParallelTask struct {
+	Index    int
+	warnings []string
+}
+
+// Warn records a warning that is added to the module once all items are done
+func (t *ParallelTask) Warn(msg string) {
+	t.warnings = append(t.warnings, msg)
+}
+
+// ParallelResult aggregates the outcome of RunParallel
+type ParallelResult struct {
+	Changed bool
+	Failed  int
+	Errors  []error // Error for each item by index, nil for items that succeeded
+}
+
+// Err summarizes the item failures, or returns nil if every item succeeded
+func (r *ParallelResult) Err() error {
+	if r.Failed == 0 {
+		return nil
+	}
+	for i, err := range r.Errors {
+		if err != nil {
+			return fmt.Errorf("%d of %d items failed, first error (item %d): %w", r.Failed, len(r.Errors), i, err)
+		}
+	}
+	return nil
+}
+
+// RunParallel calls fn for every item using at most workers goroutines. Items not
+// yet started when the module context is cancelled fail with the context error.
+// Warnings recorded through the task are added to the module in item order.
+func RunParallel[T any](m *AnsibleModule, items []T, workers int,
+	fn func(ctx context.Context, task *ParallelTask, item T) (bool, error)) *ParallelResult {
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	ctx := m.Context()
+	tasks := make([]*ParallelTask, len(items))
+	changed := make([]bool, len(items))
+	result := &ParallelResult{Errors: make([]error, len(items))}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				tasks[i] = &ParallelTask{Index: i}
+				if err := ctx.Err(); err != nil {
+					result.Errors[i] = err
+					continue
+				}
+				changed[i], result.Errors[i] = fn(ctx, tasks[i], items[i])
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, task := range tasks {
+		if result.Errors[i] != nil {
+			result.Failed++
+		} else if changed[i] {
+			result.Changed = true
+		}
+		for _, warning := range task.warnings {
+			m.AddWarning(warning)
+		}
+	}
+	return result
+}
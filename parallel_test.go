@@ -0,0 +1,81 @@
+package ansiblemodule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunParallel(t *testing.T) {
+	module := &AnsibleModule{}
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+
+	var running, maxRunning int32
+	result := RunParallel(module, items, 4, func(ctx context.Context, task *ParallelTask, item int) (bool, error) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		if task.Index != item {
+			t.Errorf("Expected task index %d, got %d", item, task.Index)
+		}
+		if item%10 == 0 {
+			task.Warn(fmt.Sprintf("item %d is special", item))
+		}
+		if item == 7 || item == 13 {
+			return false, fmt.Errorf("item %d broken", item)
+		}
+		return item == 42, nil
+	})
+
+	if maxRunning > 4 {
+		t.Errorf("Expected at most 4 workers, got %d", maxRunning)
+	}
+	if !result.Changed {
+		t.Error("Expected result to be changed")
+	}
+	if result.Failed != 2 || result.Errors[7] == nil || result.Errors[13] == nil {
+		t.Errorf("Expected items 7 and 13 to fail, got %d failures", result.Failed)
+	}
+	if err := result.Err(); err == nil || !strings.Contains(err.Error(), "2 of 50 items failed") ||
+		!strings.Contains(err.Error(), "item 7 broken") {
+		t.Errorf("Unexpected summary error: %v", err)
+	}
+	if len(module.Warnings) != 5 || module.Warnings[0] != "item 0 is special" || module.Warnings[4] != "item 40 is special" {
+		t.Errorf("Expected warnings merged in item order, got %v", module.Warnings)
+	}
+}
+
+func TestRunParallelCancelled(t *testing.T) {
+	module := &AnsibleModule{}
+	module.Context()
+	module.cancel()
+
+	calls := 0
+	result := RunParallel(module, []string{"a", "b", "c"}, 2, func(ctx context.Context, task *ParallelTask, item string) (bool, error) {
+		calls++
+		return true, nil
+	})
+
+	if calls != 0 {
+		t.Errorf("Expected no items to run after cancellation, got %d", calls)
+	}
+	if result.Failed != 3 || result.Changed {
+		t.Errorf("Expected all items to fail unchanged, got failed=%d changed=%v", result.Failed, result.Changed)
+	}
+	if result.Errors[0] != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", result.Errors[0])
+	}
+}
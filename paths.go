@@ -0,0 +1,201 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// windowsPaths selects Windows path rules for the path helpers
+var windowsPaths = runtime.GOOS == "windows"
+
+// Prefixes of Windows long and UNC paths, in slash form
+const (
+	longPathPrefix = "//?/"
+	longUNCPrefix  = "//?/UNC/"
+)
+
+// splitWindowsVolume splits a slash-form Windows path into its volume (drive,
+// UNC share or long path prefix) and the rest of the path
+func splitWindowsVolume(p string) (string, string) {
+	switch {
+	case strings.HasPrefix(p, longUNCPrefix):
+		server, share, rest := splitUNC(p[len(longUNCPrefix):])
+		return longUNCPrefix + server + "/" + share, rest
+	case strings.HasPrefix(p, longPathPrefix):
+		return longPathPrefix, p[len(longPathPrefix):]
+	case strings.HasPrefix(p, "//"):
+		server, share, rest := splitUNC(p[2:])
+		return "//" + server + "/" + share, rest
+	case len(p) >= 2 && p[1] == ':' && unicode.IsLetter(rune(p[0])):
+		return strings.ToUpper(p[:1]) + ":", p[2:]
+	}
+	return "", p
+}
+
+// splitUNC splits "server/share/rest" into its parts, keeping the leading slash of rest
+func splitUNC(p string) (string, string, string) {
+	server, rest, _ := strings.Cut(p, "/")
+	share, rest, found := strings.Cut(rest, "/")
+	if found {
+		rest = "/" + rest
+	}
+	return server, share, rest
+}
+
+// NormalizePath cleans a path and converts its separators to the platform form.
+// On Windows both / and \ are accepted, drive letters are upper-cased and
+// \\?\ long paths are left as they are, since Windows does not parse them.
+func NormalizePath(p string) string {
+	if !windowsPaths {
+		return filepath.Clean(p)
+	}
+	slashed := strings.ReplaceAll(p, `\`, "/")
+	volume, rest := splitWindowsVolume(slashed)
+	if volume == longPathPrefix || strings.HasPrefix(volume, longUNCPrefix) {
+		return strings.ReplaceAll(volume+rest, "/", `\`)
+	}
+	if rest != "" {
+		rest = path.Clean(rest)
+	} else if strings.HasPrefix(volume, "//") {
+		rest = "/"
+	} else if volume == "" {
+		rest = "."
+	}
+	return strings.ReplaceAll(volume+rest, "/", `\`)
+}
+
+// IsUNCPath reports whether a path names a network share such as \\server\share
+func IsUNCPath(p string) bool {
+	if !windowsPaths {
+		return false
+	}
+	slashed := strings.ReplaceAll(p, `\`, "/")
+	return strings.HasPrefix(slashed, longUNCPrefix) ||
+		(strings.HasPrefix(slashed, "//") && !strings.HasPrefix(slashed, longPathPrefix))
+}
+
+// LongPath returns an absolute Windows path with the \\?\ prefix that lifts the
+// MAX_PATH limit, converting \\server\share to \\?\UNC\server\share. Relative
+// paths and paths on other platforms are returned normalized.
+func LongPath(p string) string {
+	normalized := NormalizePath(p)
+	if !windowsPaths {
+		return normalized
+	}
+	slashed := strings.ReplaceAll(normalized, `\`, "/")
+	volume, rest := splitWindowsVolume(slashed)
+	switch {
+	case strings.HasPrefix(slashed, longPathPrefix):
+		return normalized
+	case strings.HasPrefix(volume, "//"):
+		return strings.ReplaceAll(longUNCPrefix+slashed[2:], "/", `\`)
+	case volume != "" && strings.HasPrefix(rest, "/"):
+		return strings.ReplaceAll(longPathPrefix+slashed, "/", `\`)
+	}
+	return normalized
+}
+
+// StripLongPath removes the \\?\ prefix added by LongPath
+func StripLongPath(p string) string {
+	if !windowsPaths {
+		return p
+	}
+	slashed := strings.ReplaceAll(p, `\`, "/")
+	switch {
+	case strings.HasPrefix(slashed, longUNCPrefix):
+		return `\\` + strings.ReplaceAll(slashed[len(longUNCPrefix):], "/", `\`)
+	case strings.HasPrefix(slashed, longPathPrefix):
+		return strings.ReplaceAll(slashed[len(longPathPrefix):], "/", `\`)
+	}
+	return p
+}
+
+// SafeJoin joins user-supplied path fragments to base, rejecting fragments that
+// are absolute, carry a drive or share, or climb out of base with ..
+func SafeJoin(base string, elems ...string) (string, error) {
+	joined := ""
+	for _, elem := range elems {
+		if strings.ContainsRune(elem, 0) {
+			return "", fmt.Errorf("path %q contains a NUL byte", elem)
+		}
+		slashed := elem
+		if windowsPaths {
+			slashed = strings.ReplaceAll(elem, `\`, "/")
+			if volume, _ := splitWindowsVolume(slashed); volume != "" {
+				return "", fmt.Errorf("path %q must be relative", elem)
+			}
+		}
+		if strings.HasPrefix(slashed, "/") {
+			return "", fmt.Errorf("path %q must be relative", elem)
+		}
+		joined = path.Join(joined, slashed)
+		if joined == ".." || strings.HasPrefix(joined, "../") {
+			return "", fmt.Errorf("path %q escapes %s", elem, base)
+		}
+	}
+	if joined == "" || joined == "." {
+		return NormalizePath(base), nil
+	}
+	return NormalizePath(base + "/" + joined), nil
+}
+
+// PathsEqual reports whether two paths name the same file. Paths differing only
+// in case are equal when they resolve to the same file or the filesystem holding
+// them is case-insensitive, as on Windows and default macOS volumes.
+func (m *AnsibleModule) PathsEqual(a, b string) bool {
+	a, b = NormalizePath(a), NormalizePath(b)
+	if a == b {
+		return true
+	}
+	if !strings.EqualFold(a, b) {
+		return false
+	}
+
+	infoA, errA := m.fs().Stat(a)
+	infoB, errB := m.fs().Stat(b)
+	if errA == nil && errB == nil {
+		return os.SameFile(infoA, infoB)
+	}
+	insensitive, err := m.IsCaseInsensitive(filepath.Dir(a))
+	return err == nil && insensitive
+}
+
+// IsCaseInsensitive reports whether the filesystem holding dir ignores case in names
+func (m *AnsibleModule) IsCaseInsensitive(dir string) (bool, error) {
+	info, err := m.fs().Stat(dir)
+	if err != nil {
+		return false, err
+	}
+
+	// Look the directory up with the case of its name swapped
+	base := filepath.Base(dir)
+	if swapped := swapCase(base); swapped != base {
+		other, err := m.fs().Stat(filepath.Join(filepath.Dir(dir), swapped))
+		return err == nil && os.SameFile(info, other), nil
+	}
+
+	// The name has no letters, so probe with a temporary file
+	probe, err := m.fs().CreateTemp(dir, "ansible-case-probe-")
+	if err != nil {
+		return false, fmt.Errorf("failed to probe %s: %w", dir, err)
+	}
+	probe.Close()
+	defer m.fs().Remove(probe.Name())
+	_, err = m.fs().Stat(filepath.Join(dir, strings.ToUpper(filepath.Base(probe.Name()))))
+	return err == nil, nil
+}
+
+// swapCase swaps upper and lower case letters
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
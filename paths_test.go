@@ -0,0 +1,134 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// withWindowsPaths runs fn with Windows path rules selected
+func withWindowsPaths(t *testing.T, enabled bool, fn func()) {
+	t.Helper()
+	old := windowsPaths
+	windowsPaths = enabled
+	defer func() { windowsPaths = old }()
+	fn()
+}
+
+func TestNormalizePathWindows(t *testing.T) {
+	withWindowsPaths(t, true, func() {
+		tests := map[string]string{
+			`c:/Users/./admin/../Public/`: `C:\Users\Public`,
+			`C:\`:                         `C:\`,
+			`C:`:                          `C:`,
+			`\\server\share\dir\..\file`:  `\\server\share\file`,
+			`//server/share`:              `\\server\share\`,
+			`\\?\C:\very\..\literal`:      `\\?\C:\very\..\literal`,
+			`\\?\UNC\server\share\a/b`:    `\\?\UNC\server\share\a\b`,
+			`relative/../../up`:           `..\up`,
+			``:                            `.`,
+		}
+		for input, expected := range tests {
+			if result := NormalizePath(input); result != expected {
+				t.Errorf("NormalizePath(%q): expected %q, got %q", input, expected, result)
+			}
+		}
+	})
+}
+
+func TestLongPath(t *testing.T) {
+	withWindowsPaths(t, true, func() {
+		tests := map[string]string{
+			`C:\data\file.txt`:        `\\?\C:\data\file.txt`,
+			`\\server\share\file.txt`: `\\?\UNC\server\share\file.txt`,
+			`\\?\C:\already`:          `\\?\C:\already`,
+			`relative\file.txt`:       `relative\file.txt`,
+			`C:relative`:              `C:relative`,
+		}
+		for input, expected := range tests {
+			result := LongPath(input)
+			if result != expected {
+				t.Errorf("LongPath(%q): expected %q, got %q", input, expected, result)
+			}
+			expected = StripLongPath(NormalizePath(input))
+			if stripped := StripLongPath(result); stripped != expected {
+				t.Errorf("StripLongPath(%q): expected %q, got %q", result, expected, stripped)
+			}
+		}
+		if !IsUNCPath(`\\server\share`) || !IsUNCPath(`\\?\UNC\server\share`) || IsUNCPath(`\\?\C:\x`) {
+			t.Error("Unexpected IsUNCPath result")
+		}
+	})
+
+	withWindowsPaths(t, false, func() {
+		if result := LongPath("/data/../file"); result != "/file" {
+			t.Errorf("Expected cleaned path, got %q", result)
+		}
+		if IsUNCPath("//server/share") {
+			t.Error("Expected no UNC paths outside Windows")
+		}
+	})
+}
+
+func TestSafeJoin(t *testing.T) {
+	withWindowsPaths(t, false, func() {
+		result, err := SafeJoin("/srv/www", "static", "css/../js/app.js")
+		if err != nil || result != "/srv/www/static/js/app.js" {
+			t.Errorf("Expected joined path, got %q (%v)", result, err)
+		}
+		for _, elem := range []string{"../etc/passwd", "a/../../b", "/etc/passwd", "bad\x00name"} {
+			if _, err := SafeJoin("/srv/www", elem); err == nil {
+				t.Errorf("Expected error for %q", elem)
+			}
+		}
+	})
+
+	withWindowsPaths(t, true, func() {
+		result, err := SafeJoin(`C:\inetpub`, `wwwroot\index.html`)
+		if err != nil || result != `C:\inetpub\wwwroot\index.html` {
+			t.Errorf("Expected joined path, got %q (%v)", result, err)
+		}
+		for _, elem := range []string{`..\Windows`, `C:\Windows`, `D:file`, `\\server\share`, `\root`} {
+			if _, err := SafeJoin(`C:\inetpub`, elem); err == nil {
+				t.Errorf("Expected error for %q", elem)
+			}
+		}
+	})
+}
+
+func TestPathsEqual(t *testing.T) {
+	tmpDir := t.TempDir()
+	dir := filepath.Join(tmpDir, "Data")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	module := &AnsibleModule{}
+
+	if !module.PathsEqual(dir, filepath.Join(tmpDir, "x", "..", "Data")) {
+		t.Error("Expected equal paths after cleaning")
+	}
+	if module.PathsEqual(dir, filepath.Join(tmpDir, "Other")) {
+		t.Error("Expected different paths not to be equal")
+	}
+
+	insensitive, err := module.IsCaseInsensitive(dir)
+	if err != nil {
+		t.Fatalf("Failed to probe case sensitivity: %v", err)
+	}
+	if runtime.GOOS == "linux" && insensitive {
+		t.Error("Expected a case-sensitive filesystem on Linux")
+	}
+	if module.PathsEqual(dir, filepath.Join(tmpDir, "data")) != insensitive {
+		t.Errorf("Expected case-only difference to be equal only on case-insensitive filesystems")
+	}
+
+	// Names without letters are probed with a temporary file
+	numeric := filepath.Join(tmpDir, "123")
+	if err := os.Mkdir(numeric, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if result, err := module.IsCaseInsensitive(numeric); err != nil || result != insensitive {
+		t.Errorf("Expected probe result %v, got %v (%v)", insensitive, result, err)
+	}
+}
@@ -0,0 +1,306 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PackageLockTimeout is how long package operations wait for another package
+// manager process holding the package database lock
+var PackageLockTimeout = 5 * time.Minute
+
+// PackageManager installs and removes packages with a system package manager.
+// Install and Remove only act on packages whose state differs, report whether
+// anything changed, and make no changes in check mode.
+type PackageManager interface {
+	// Name returns the package manager name, such as apt or dnf
+	Name() string
+	// IsInstalled reports whether a package is installed
+	IsInstalled(name string) (bool, error)
+	// Versions returns the installed version of each installed package among names
+	Versions(names ...string) (map[string]string, error)
+	// Install installs the packages that are not installed
+	Install(names ...string) (bool, error)
+	// Remove removes the packages that are installed
+	Remove(names ...string) (bool, error)
+}
+
+// packageBackend describes how to drive one package manager
+type packageBackend struct {
+	name     string
+	binary   string
+	install  func() []string
+	remove   func() []string
+	env      func() map[string]string
+	versions func(m *AnsibleModule, names []string) (map[string]string, error)
+	lockFile string // Lock the manager fails on instead of waiting, waited for before running
+}
+
+// packageBackends are the supported package managers in detection order
+var packageBackends = []packageBackend{
+	{
+		name:   "apt",
+		binary: "apt-get",
+		install: func() []string {
+			return []string{"-o", "DPkg::Lock::Timeout=" + lockSeconds(), "-y", "-q", "install"}
+		},
+		remove: func() []string {
+			return []string{"-o", "DPkg::Lock::Timeout=" + lockSeconds(), "-y", "-q", "remove"}
+		},
+		env: func() map[string]string {
+			return map[string]string{"DEBIAN_FRONTEND": "noninteractive", "DEBCONF_NONINTERACTIVE_SEEN": "true"}
+		},
+		versions: dpkgVersions,
+	},
+	{
+		name:     "dnf",
+		binary:   "dnf",
+		install:  func() []string { return []string{"-y", "install"} },
+		remove:   func() []string { return []string{"-y", "remove"} },
+		versions: rpmVersions,
+	},
+	{
+		name:     "yum",
+		binary:   "yum",
+		install:  func() []string { return []string{"-y", "install"} },
+		remove:   func() []string { return []string{"-y", "remove"} },
+		versions: rpmVersions,
+	},
+	{
+		name:    "zypper",
+		binary:  "zypper",
+		install: func() []string { return []string{"--non-interactive", "install", "--auto-agree-with-licenses"} },
+		remove:  func() []string { return []string{"--non-interactive", "remove"} },
+		env: func() map[string]string {
+			return map[string]string{"ZYPP_LOCK_TIMEOUT": lockSeconds()}
+		},
+		versions: rpmVersions,
+	},
+	{
+		name:     "apk",
+		binary:   "apk",
+		install:  func() []string { return []string{"add", "--wait", lockSeconds()} },
+		remove:   func() []string { return []string{"del", "--wait", lockSeconds()} },
+		versions: apkVersions,
+	},
+	{
+		name:     "pacman",
+		binary:   "pacman",
+		install:  func() []string { return []string{"-S", "--noconfirm", "--needed"} },
+		remove:   func() []string { return []string{"-R", "--noconfirm"} },
+		versions: pacmanVersions,
+		lockFile: "/var/lib/pacman/db.lck",
+	},
+}
+
+// lockSeconds returns PackageLockTimeout in whole seconds
+func lockSeconds() string {
+	return strconv.Itoa(int(PackageLockTimeout.Seconds()))
+}
+
+// commandPackageManager implements PackageManager by running a package manager
+type commandPackageManager struct {
+	m       *AnsibleModule
+	backend packageBackend
+	path    string
+}
+
+// PackageManager returns the named package manager (apt, dnf, yum, zypper, apk
+// or pacman), or the first one found on the system if name is empty
+func (m *AnsibleModule) PackageManager(name string) (PackageManager, error) {
+	for _, backend := range packageBackends {
+		if name != "" && backend.name != name {
+			continue
+		}
+		path, err := m.GetBinPath(backend.binary, name != "")
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			return &commandPackageManager{m: m, backend: backend, path: path}, nil
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("unsupported package manager %s", name)
+	}
+	return nil, fmt.Errorf("no supported package manager found")
+}
+
+// Name returns the package manager name
+func (p *commandPackageManager) Name() string {
+	return p.backend.name
+}
+
+// IsInstalled reports whether a package is installed
+func (p *commandPackageManager) IsInstalled(name string) (bool, error) {
+	versions, err := p.Versions(name)
+	if err != nil {
+		return false, err
+	}
+	_, ok := versions[name]
+	return ok, nil
+}
+
+// Versions returns the installed versions of the packages that are installed
+func (p *commandPackageManager) Versions(names ...string) (map[string]string, error) {
+	if len(names) == 0 {
+		return map[string]string{}, nil
+	}
+	return p.backend.versions(p.m, names)
+}
+
+// Install installs the packages that are not installed
+func (p *commandPackageManager) Install(names ...string) (bool, error) {
+	versions, err := p.Versions(names...)
+	if err != nil {
+		return false, err
+	}
+	var missing []string
+	for _, name := range names {
+		if _, ok := versions[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return p.run(p.backend.install(), missing)
+}
+
+// Remove removes the packages that are installed
+func (p *commandPackageManager) Remove(names ...string) (bool, error) {
+	versions, err := p.Versions(names...)
+	if err != nil {
+		return false, err
+	}
+	var present []string
+	for _, name := range names {
+		if _, ok := versions[name]; ok {
+			present = append(present, name)
+		}
+	}
+	return p.run(p.backend.remove(), present)
+}
+
+// run runs the package manager for the packages that need changing
+func (p *commandPackageManager) run(args []string, names []string) (bool, error) {
+	if len(names) == 0 {
+		return false, nil
+	}
+	if p.m.CheckMode {
+		return true, nil
+	}
+	if err := p.waitForLock(); err != nil {
+		return false, err
+	}
+
+	var env map[string]string
+	if p.backend.env != nil {
+		env = p.backend.env()
+	}
+	args = append(append(args, "--"), names...)
+	if _, err := p.m.RunCommand(p.path, args, env, ""); err != nil {
+		return false, fmt.Errorf("%s failed for %s: %w", p.backend.name, strings.Join(names, ", "), err)
+	}
+	return true, nil
+}
+
+// waitForLock waits for the lock file of managers that fail instead of waiting
+func (p *commandPackageManager) waitForLock() error {
+	if p.backend.lockFile == "" {
+		return nil
+	}
+	deadline := time.Now().Add(PackageLockTimeout)
+	for {
+		if _, err := os.Stat(p.backend.lockFile); os.IsNotExist(err) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s lock %s", p.backend.name, p.backend.lockFile)
+		}
+		select {
+		case <-p.m.Context().Done():
+			return p.m.Context().Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// queryPackages runs a query command, returning its output. Query tools exit
+// non-zero when some packages are missing, which is only an error if they
+// printed nothing at all.
+func queryPackages(m *AnsibleModule, binary string, args []string) (string, error) {
+	path, err := m.GetBinPath(binary, true)
+	if err != nil {
+		return "", err
+	}
+	result, err := m.RunCommand(path, args, nil, "")
+	if err != nil && (result.Rc <= 0 || result.Stdout == "" && result.Stderr == "") {
+		return "", fmt.Errorf("%s failed: %w", binary, err)
+	}
+	return result.Stdout, nil
+}
+
+// dpkgVersions reads installed versions from dpkg
+func dpkgVersions(m *AnsibleModule, names []string) (map[string]string, error) {
+	output, err := queryPackages(m, "dpkg-query", append([]string{"-W", "-f", "${Package}\t${Status}\t${Version}\n", "--"}, names...))
+	if err != nil {
+		return nil, err
+	}
+	versions := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) == 3 && strings.HasSuffix(fields[1], " installed") {
+			versions[strings.SplitN(fields[0], ":", 2)[0]] = fields[2]
+		}
+	}
+	return versions, nil
+}
+
+// rpmVersions reads installed versions from the rpm database
+func rpmVersions(m *AnsibleModule, names []string) (map[string]string, error) {
+	output, err := queryPackages(m, "rpm", append([]string{"-q", "--qf", "%{NAME}\t%{VERSION}-%{RELEASE}\n", "--"}, names...))
+	if err != nil {
+		return nil, err
+	}
+	versions := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		if name, version, ok := strings.Cut(line, "\t"); ok {
+			versions[name] = version
+		}
+	}
+	return versions, nil
+}
+
+// pacmanVersions reads installed versions from pacman
+func pacmanVersions(m *AnsibleModule, names []string) (map[string]string, error) {
+	output, err := queryPackages(m, "pacman", append([]string{"-Q", "--"}, names...))
+	if err != nil {
+		return nil, err
+	}
+	versions := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			versions[fields[0]] = fields[1]
+		}
+	}
+	return versions, nil
+}
+
+// apkVersions reads installed versions from apk, which lists them as name-version
+func apkVersions(m *AnsibleModule, names []string) (map[string]string, error) {
+	output, err := queryPackages(m, "apk", []string{"info", "-v"})
+	if err != nil {
+		return nil, err
+	}
+	versions := map[string]string{}
+	for _, line := range strings.Fields(output) {
+		for _, name := range names {
+			version, found := strings.CutPrefix(line, name+"-")
+			if found && version != "" && version[0] >= '0' && version[0] <= '9' {
+				versions[name] = version
+			}
+		}
+	}
+	return versions, nil
+}
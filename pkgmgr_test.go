@@ -0,0 +1,104 @@
+package ansiblemodule
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// packageRunner answers package queries from a fixed output and records other commands
+type packageRunner struct {
+	query    CommandResult
+	commands [][]string
+	env      []string
+}
+
+func (p *packageRunner) Run(ctx context.Context, cmd string, args []string, env []string, data string) (CommandResult, error) {
+	switch cmd {
+	case "/stub/dpkg-query", "/stub/rpm", "/stub/pacman", "/stub/apk":
+		if len(args) > 0 && (args[0] == "-W" || args[0] == "-q" || args[0] == "-Q" || args[0] == "info") {
+			return p.query, nil
+		}
+	}
+	p.commands = append(p.commands, append([]string{cmd}, args...))
+	p.env = env
+	return CommandResult{}, nil
+}
+
+func (p *packageRunner) LookPath(name string) (string, error) {
+	return "/stub/" + name, nil
+}
+
+func TestPackageManagerVersions(t *testing.T) {
+	tests := []struct {
+		manager string
+		output  string
+	}{
+		{"apt", "curl\tinstall ok installed\t7.88.1-10\ngit\tdeinstall ok config-files\t1:2.39\n"},
+		{"dnf", "curl\t7.76.1-26.el9\n"},
+		{"zypper", "curl\t7.76.1-26.el9\npackage git is not installed\n"},
+		{"pacman", "curl 8.5.0-1\n"},
+		{"apk", "musl-1.2.4-r2\ncurl-8.5.0-r0\ncurl-doc-8.5.0-r0\n"},
+	}
+	for _, test := range tests {
+		runner := &packageRunner{query: CommandResult{Stdout: test.output, Rc: 1}}
+		module := &AnsibleModule{Runner: runner}
+		manager, err := module.PackageManager(test.manager)
+		if err != nil {
+			t.Fatalf("%s: %v", test.manager, err)
+		}
+
+		versions, err := manager.Versions("curl", "git")
+		if err != nil {
+			t.Fatalf("%s: %v", test.manager, err)
+		}
+		if len(versions) != 1 || versions["curl"] == "" || strings.Contains(versions["curl"], "\t") {
+			t.Errorf("%s: expected only curl installed, got %v", test.manager, versions)
+		}
+	}
+}
+
+func TestPackageManagerInstallRemove(t *testing.T) {
+	runner := &packageRunner{query: CommandResult{Stdout: "curl\tinstall ok installed\t7.88.1-10\n", Rc: 1}}
+	module := &AnsibleModule{Runner: runner}
+	manager, err := module.PackageManager("")
+	if err != nil || manager.Name() != "apt" {
+		t.Fatalf("Expected apt to be detected first, got %v (%v)", manager, err)
+	}
+
+	changed, err := manager.Install("curl")
+	if err != nil || changed || len(runner.commands) != 0 {
+		t.Errorf("Expected installed package to be unchanged, got %v (%v) %v", changed, err, runner.commands)
+	}
+
+	changed, err = manager.Install("curl", "git")
+	if err != nil || !changed {
+		t.Fatalf("Expected install to change, got %v (%v)", changed, err)
+	}
+	expected := []string{"/stub/apt-get", "-o", "DPkg::Lock::Timeout=300", "-y", "-q", "install", "--", "git"}
+	if len(runner.commands) != 1 || !reflect.DeepEqual(runner.commands[0], expected) {
+		t.Errorf("Expected %v, got %v", expected, runner.commands)
+	}
+	if !strings.Contains(strings.Join(runner.env, "\n"), "DEBIAN_FRONTEND=noninteractive") {
+		t.Error("Expected apt to run non-interactively")
+	}
+
+	changed, err = manager.Remove("git")
+	if err != nil || changed || len(runner.commands) != 1 {
+		t.Errorf("Expected missing package removal to be unchanged, got %v (%v)", changed, err)
+	}
+
+	module.CheckMode = true
+	changed, err = manager.Remove("curl")
+	if err != nil || !changed || len(runner.commands) != 1 {
+		t.Errorf("Expected check mode to report a change without running, got %v (%v) %v", changed, err, runner.commands)
+	}
+}
+
+func TestPackageManagerUnsupported(t *testing.T) {
+	module := &AnsibleModule{Runner: &packageRunner{}}
+	if _, err := module.PackageManager("portage"); err == nil {
+		t.Error("Expected unsupported package manager to fail")
+	}
+}
@@ -0,0 +1,187 @@
+package ansiblemodule
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+// PowerShellOptions controls how RunPowerShell starts PowerShell
+type PowerShellOptions struct {
+	Executable      string            // powershell or pwsh, found in PATH if empty
+	ExecutionPolicy string            // Policy for the session, defaults to Bypass
+	ErrorAction     string            // $ErrorActionPreference, defaults to Stop
+	Environment     map[string]string // Extra environment variables
+}
+
+// PowerShellError is an error record raised by a PowerShell script
+type PowerShellError struct {
+	Message          string `json:"message"`
+	Category         string `json:"category"`
+	ErrorID          string `json:"error_id"`
+	Target           string `json:"target,omitempty"`
+	ScriptStackTrace string `json:"script_stack_trace,omitempty"`
+}
+
+// PowerShellResult is the result of a PowerShell script with its error records
+type PowerShellResult struct {
+	CommandResult
+	Errors []PowerShellError
+}
+
+// powerShellErrorMarker prefixes the JSON error records the wrapper writes to stderr
+const powerShellErrorMarker = "ANSIGO_PS_ERRORS:"
+
+// maxEncodedCommand is the longest -EncodedCommand argument that fits in a Windows command line
+const maxEncodedCommand = 30000
+
+// powerShellWrapper runs the script and reports its error records as JSON on stderr
+const powerShellWrapper = `$ErrorActionPreference = '%s'
+$ProgressPreference = 'SilentlyContinue'
+$Error.Clear()
+$ansigoRc = 0
+try {
+    & {
+%s
+    }
+    if (-not $?) { $ansigoRc = 1 }
+    if ($LASTEXITCODE) { $ansigoRc = $LASTEXITCODE }
+} catch {
+    $ansigoRc = 1
+}
+$ansigoErrors = @($Error | ForEach-Object {
+    if ($_ -is [System.Management.Automation.ErrorRecord]) {
+        [ordered]@{
+            message = $_.Exception.Message
+            category = $_.CategoryInfo.Category.ToString()
+            error_id = $_.FullyQualifiedErrorId
+            target = [string]$_.TargetObject
+            script_stack_trace = $_.ScriptStackTrace
+        }
+    }
+})
+[Array]::Reverse($ansigoErrors)
+[Console]::Error.WriteLine('` + powerShellErrorMarker + `' + (ConvertTo-Json -InputObject $ansigoErrors -Compress -Depth 3))
+exit $ansigoRc
+`
+
+// clixmlErrorPattern matches the error stream strings of CLIXML output
+var clixmlErrorPattern = regexp.MustCompile(`<S S="Error">([^<]*)</S>`)
+
+// clixmlEscapePattern matches the _xHHHH_ escapes of CLIXML strings
+var clixmlEscapePattern = regexp.MustCompile(`_x([0-9A-Fa-f]{4})_`)
+
+// encodePowerShell encodes a script for -EncodedCommand as base64 UTF-16LE
+func encodePowerShell(script string) string {
+	units := utf16.Encode([]rune(script))
+	data := make([]byte, len(units)*2)
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(data[i*2:], unit)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// powerShellExecutable finds PowerShell, preferring Windows PowerShell on Windows
+func (m *AnsibleModule) powerShellExecutable(name string) (string, error) {
+	if name != "" {
+		return m.GetBinPath(name, true)
+	}
+	candidates := []string{"pwsh", "powershell"}
+	if runtime.GOOS == "windows" {
+		candidates = []string{"powershell", "pwsh"}
+	}
+	for _, candidate := range candidates {
+		if path, err := m.commandRunner().LookPath(candidate); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find PowerShell in PATH")
+}
+
+// RunPowerShell runs a PowerShell script non-interactively and parses its error
+// records. The script runs with $ErrorActionPreference set to Stop unless
+// opts.ErrorAction says otherwise, so any error fails the script with rc 1.
+func (m *AnsibleModule) RunPowerShell(script string, opts PowerShellOptions) (*PowerShellResult, error) {
+	executable, err := m.powerShellExecutable(opts.Executable)
+	if err != nil {
+		return nil, err
+	}
+	policy := opts.ExecutionPolicy
+	if policy == "" {
+		policy = "Bypass"
+	}
+	errorAction := opts.ErrorAction
+	if errorAction == "" {
+		errorAction = "Stop"
+	}
+
+	wrapped := fmt.Sprintf(powerShellWrapper, errorAction, script)
+	args := []string{"-NoProfile", "-NonInteractive", "-NoLogo", "-ExecutionPolicy", policy,
+		"-InputFormat", "Text", "-OutputFormat", "Text"}
+
+	// Scripts too long for the command line are run from a file, which the
+	// execution policy allows because it is passed with -ExecutionPolicy
+	if encoded := encodePowerShell(wrapped); len(encoded) <= maxEncodedCommand {
+		args = append(args, "-EncodedCommand", encoded)
+	} else {
+		file, err := m.createTemp("ansible-script-*.ps1")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create script file: %w", err)
+		}
+		// A byte order mark makes Windows PowerShell read the script as UTF-8
+		_, err = file.Write(append([]byte("\ufeff"), wrapped...))
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to write script file: %w", err)
+		}
+		defer m.fs().Remove(file.Name())
+		args = append(args, "-File", file.Name())
+	}
+
+	commandResult, err := m.RunCommand(executable, args, opts.Environment, "")
+	result := &PowerShellResult{CommandResult: commandResult}
+	result.Stderr, result.Errors = parsePowerShellErrors(commandResult.Stderr)
+	if err != nil && len(result.Errors) > 0 {
+		return result, fmt.Errorf("powershell failed: %s", result.Errors[0].Message)
+	}
+	return result, err
+}
+
+// parsePowerShellErrors extracts the error records written by the wrapper and
+// decodes CLIXML error output, returning the remaining stderr text
+func parsePowerShellErrors(stderr string) (string, []PowerShellError) {
+	var records []PowerShellError
+	var lines []string
+	for _, line := range strings.SplitAfter(stderr, "\n") {
+		trimmed := strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(trimmed, powerShellErrorMarker) {
+			json.Unmarshal([]byte(strings.TrimPrefix(trimmed, powerShellErrorMarker)), &records)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return decodeCLIXML(strings.Join(lines, "")), records
+}
+
+// decodeCLIXML converts the CLIXML error stream PowerShell writes to stderr into text
+func decodeCLIXML(stderr string) string {
+	if !strings.HasPrefix(stderr, "#< CLIXML") {
+		return stderr
+	}
+	var b strings.Builder
+	for _, match := range clixmlErrorPattern.FindAllStringSubmatch(stderr, -1) {
+		text := clixmlEscapePattern.ReplaceAllStringFunc(match[1], func(escape string) string {
+			var r rune
+			fmt.Sscanf(escape[2:6], "%x", &r)
+			return string(r)
+		})
+		b.WriteString(html.UnescapeString(text))
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package ansiblemodule
+
+import (
+	"encoding/base64"
+	"os/exec"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// decodePowerShell reverses encodePowerShell
+func decodePowerShell(t *testing.T, encoded string) string {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode command: %v", err)
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[i*2]) | uint16(data[i*2+1])<<8
+	}
+	return string(utf16.Decode(units))
+}
+
+func TestRunPowerShellArguments(t *testing.T) {
+	stderr := "warning text\n" + powerShellErrorMarker +
+		`[{"message":"Cannot find path 'C:\\missing'","category":"ObjectNotFound","error_id":"PathNotFound,Microsoft.PowerShell.Commands.GetItemCommand","target":"C:\\missing"}]` + "\r\n"
+	runner := &stubRunner{result: CommandResult{Stderr: stderr, Rc: 1}}
+	module := &AnsibleModule{Runner: runner}
+
+	result, err := module.RunPowerShell("Get-Item C:\\missing # ünïcode", PowerShellOptions{ExecutionPolicy: "RemoteSigned"})
+	if err == nil || !strings.Contains(err.Error(), "Cannot find path") {
+		t.Errorf("Expected error from the error record, got %v", err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Category != "ObjectNotFound" {
+		t.Fatalf("Unexpected error records: %+v", result.Errors)
+	}
+	if result.Stderr != "warning text\n" {
+		t.Errorf("Expected marker line to be removed from stderr, got %q", result.Stderr)
+	}
+
+	argv := strings.Join(runner.argv, " ")
+	if runner.argv[0] != "/stub/pwsh" || !strings.Contains(argv, "-ExecutionPolicy RemoteSigned") {
+		t.Errorf("Unexpected command line: %s", argv)
+	}
+	encoded := runner.argv[len(runner.argv)-1]
+	if script := decodePowerShell(t, encoded); !strings.Contains(script, "Get-Item C:\\missing # ünïcode") ||
+		!strings.Contains(script, "$ErrorActionPreference = 'Stop'") {
+		t.Errorf("Unexpected encoded script: %s", script)
+	}
+}
+
+func TestRunPowerShellLongScript(t *testing.T) {
+	runner := &stubRunner{}
+	module := &AnsibleModule{Runner: runner}
+	defer module.Cleanup()
+
+	script := strings.Repeat("Write-Output 'padding'\n", 2000)
+	if _, err := module.RunPowerShell(script, PowerShellOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if runner.argv[len(runner.argv)-2] != "-File" || !strings.HasSuffix(runner.argv[len(runner.argv)-1], ".ps1") {
+		t.Errorf("Expected long script to run from a file, got %v", runner.argv[len(runner.argv)-2:])
+	}
+}
+
+func TestDecodeCLIXML(t *testing.T) {
+	stderr := "#< CLIXML\r\n<Objs Version=\"1.1.0.1\" xmlns=\"http://schemas.microsoft.com/powershell/2004/04\">" +
+		"<S S=\"Error\">access denied &amp; more_x000D__x000A_</S><S S=\"Error\">second_x000D__x000A_</S></Objs>"
+	if text := decodeCLIXML(stderr); text != "access denied & more\r\nsecond\r\n" {
+		t.Errorf("Unexpected decoded text: %q", text)
+	}
+	if text := decodeCLIXML("plain"); text != "plain" {
+		t.Errorf("Expected plain text to be unchanged, got %q", text)
+	}
+}
+
+func TestRunPowerShell(t *testing.T) {
+	if _, err := exec.LookPath("pwsh"); err != nil {
+		t.Skip("pwsh not available")
+	}
+	module := &AnsibleModule{}
+	defer module.Cleanup()
+
+	result, err := module.RunPowerShell("Write-Output 'hello'", PowerShellOptions{})
+	if err != nil || strings.TrimSpace(result.Stdout) != "hello" {
+		t.Errorf("Expected hello, got %+v (%v)", result, err)
+	}
+
+	result, err = module.RunPowerShell("Get-Item '/nonexistent/path'", PowerShellOptions{})
+	if err == nil || len(result.Errors) == 0 || result.Errors[0].Category != "ObjectNotFound" {
+		t.Errorf("Expected ObjectNotFound error record, got %+v (%v)", result, err)
+	}
+}
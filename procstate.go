@@ -0,0 +1,89 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ProcessState is a snapshot of the process-wide state that helpers tend to
+// change: the environment, the umask and the working directory
+type ProcessState struct {
+	Environ []string
+	Umask   os.FileMode
+	Dir     string
+}
+
+// SnapshotProcessState captures the environment, umask and working directory
+func SnapshotProcessState() (*ProcessState, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read working directory: %w", err)
+	}
+	umaskMu.Lock()
+	mask := setUmask(0)
+	setUmask(mask)
+	umaskMu.Unlock()
+	return &ProcessState{Environ: os.Environ(), Umask: os.FileMode(mask), Dir: dir}, nil
+}
+
+// Restore puts the process back into the captured state. Only variables that
+// differ are changed, so the environment is never seen empty.
+func (s *ProcessState) Restore() error {
+	saved := environMap(s.Environ)
+	for key := range environMap(os.Environ()) {
+		if _, ok := saved[key]; !ok {
+			os.Unsetenv(key)
+		}
+	}
+	var errs []error
+	for key, value := range saved {
+		if current, ok := os.LookupEnv(key); !ok || current != value {
+			if err := os.Setenv(key, value); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore %s: %w", key, err))
+			}
+		}
+	}
+
+	umaskMu.Lock()
+	setUmask(int(s.Umask))
+	umaskMu.Unlock()
+
+	if dir, err := os.Getwd(); err != nil || dir != s.Dir {
+		if err := os.Chdir(s.Dir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore working directory: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// environMap splits KEY=value entries into a map
+func environMap(environ []string) map[string]string {
+	env := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		// Windows keeps per-drive directories in variables starting with =
+		if key, value, ok := strings.Cut(entry, "="); ok && key != "" {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// WithProcessState runs fn, which may change the environment, umask and working
+// directory freely, and restores all three afterwards, even if fn panics
+func (m *AnsibleModule) WithProcessState(fn func() error) (err error) {
+	state, err := SnapshotProcessState()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if restoreErr := state.Restore(); restoreErr != nil {
+			m.writeDebugLog("TRACE", fmt.Sprintf("restoring process state: %v", restoreErr))
+			if err == nil {
+				err = restoreErr
+			}
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,57 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWithProcessState(t *testing.T) {
+	t.Setenv("ANSIGO_KEPT", "original")
+	t.Setenv("ANSIGO_REMOVED", "present")
+	os.Unsetenv("ANSIGO_ADDED")
+	before, err := SnapshotProcessState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := &AnsibleModule{}
+	dir := t.TempDir()
+
+	err = module.WithProcessState(func() error {
+		os.Setenv("ANSIGO_KEPT", "changed")
+		os.Setenv("ANSIGO_ADDED", "new")
+		os.Unsetenv("ANSIGO_REMOVED")
+		module.SetUmask(0077)
+		return os.Chdir(dir)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if os.Getenv("ANSIGO_KEPT") != "original" || os.Getenv("ANSIGO_REMOVED") != "present" {
+		t.Error("Expected changed and removed variables to be restored")
+	}
+	if _, ok := os.LookupEnv("ANSIGO_ADDED"); ok {
+		t.Error("Expected added variable to be removed")
+	}
+	if wd, _ := os.Getwd(); wd != before.Dir {
+		t.Errorf("Expected working directory %s, got %s", before.Dir, wd)
+	}
+	after, _ := SnapshotProcessState()
+	if runtime.GOOS != "windows" && after.Umask != before.Umask {
+		t.Errorf("Expected umask %o, got %o", before.Umask, after.Umask)
+	}
+	module.restoreUmask()
+
+	func() {
+		defer func() { recover() }()
+		module.WithProcessState(func() error {
+			os.Chdir(filepath.Dir(dir))
+			panic("helper failed")
+		})
+	}()
+	if wd, _ := os.Getwd(); wd != before.Dir {
+		t.Errorf("Expected working directory to be restored after a panic, got %s", wd)
+	}
+}
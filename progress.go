@@ -0,0 +1,147 @@
+package ansiblemodule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ProgressInterval is the minimum time between progress log messages
+var ProgressInterval = 10 * time.Second
+
+// Progress reports the status of a long running operation to a file in TmpDir
+type Progress struct {
+	module  *AnsibleModule
+	name    string
+	path    string
+	total   int64
+	mu      sync.Mutex
+	lastLog time.Time
+}
+
+// progressStatus is the content of the progress file
+type progressStatus struct {
+	Name     string  `json:"name"`
+	Done     int64   `json:"done"`
+	Total    int64   `json:"total"`
+	Percent  float64 `json:"percent"`
+	Message  string  `json:"message"`
+	Finished bool    `json:"finished"`
+	Updated  string  `json:"updated"`
+}
+
+// unsafeFileChars matches characters not allowed in progress file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// StartProgress begins progress reporting for an operation of total units, or 0 if unknown
+func (m *AnsibleModule) StartProgress(name string, total int64) (*Progress, error) {
+	dir, err := m.tmpDir()
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &Progress{
+		module: m,
+		name:   name,
+		path:   filepath.Join(dir, "progress-"+unsafeFileChars.ReplaceAllString(name, "_")+".json"),
+		total:  total,
+	}
+	if err := progress.write(0, "started", false); err != nil {
+		return nil, err
+	}
+	m.Log(fmt.Sprintf("%s: started, status in %s", name, progress.path))
+	progress.lastLog = time.Now()
+
+	return progress, nil
+}
+
+// Path returns the location of the progress file
+func (p *Progress) Path() string {
+	return p.path
+}
+
+// Update records that done units are complete
+func (p *Progress) Update(done int64, message string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.write(done, message, false); err != nil {
+		return err
+	}
+
+	// The file always reflects the latest state, but logging is rate limited
+	if time.Since(p.lastLog) >= ProgressInterval {
+		p.module.Log(p.describe(done, message))
+		p.lastLog = time.Now()
+	}
+	return nil
+}
+
+// Finish marks the operation as complete
+func (p *Progress) Finish(message string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	done := p.total
+	if err := p.write(done, message, true); err != nil {
+		return err
+	}
+	p.module.Log(p.describe(done, message))
+	return nil
+}
+
+// percent returns the completion percentage, or -1 if the total is unknown
+func (p *Progress) percent(done int64) float64 {
+	if p.total <= 0 {
+		return -1
+	}
+	return float64(done) * 100 / float64(p.total)
+}
+
+// describe formats a progress log message
+func (p *Progress) describe(done int64, message string) string {
+	if p.total <= 0 {
+		return fmt.Sprintf("%s: %d done, %s", p.name, done, message)
+	}
+	return fmt.Sprintf("%s: %.1f%% (%d/%d), %s", p.name, p.percent(done), done, p.total, message)
+}
+
+// write atomically replaces the progress file
+func (p *Progress) write(done int64, message string, finished bool) error {
+	data, err := json.Marshal(progressStatus{
+		Name:     p.name,
+		Done:     done,
+		Total:    p.total,
+		Percent:  p.percent(done),
+		Message:  p.module.scrubString(message),
+		Finished: finished,
+		Updated:  time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Readers polling the file must never see a partial write
+	fsys := p.module.fs()
+	tmpPath := p.path + ".tmp"
+	file, err := fsys.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fsys.Rename(tmpPath, p.path)
+	}
+	if err != nil {
+		fsys.Remove(tmpPath)
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	module := &AnsibleModule{}
+	defer module.Cleanup()
+
+	var logs bytes.Buffer
+	module.SetLogHandler(slog.NewTextHandler(&logs, nil))
+
+	oldInterval := ProgressInterval
+	ProgressInterval = time.Hour
+	defer func() { ProgressInterval = oldInterval }()
+
+	progress, err := module.StartProgress("copy files", 200)
+	if err != nil {
+		t.Fatalf("Failed to start progress: %v", err)
+	}
+	if !strings.HasPrefix(progress.Path(), module.TmpDir) {
+		t.Errorf("Expected progress file in TmpDir, got %s", progress.Path())
+	}
+
+	if err := progress.Update(50, "copying"); err != nil {
+		t.Fatalf("Failed to update progress: %v", err)
+	}
+
+	var status map[string]interface{}
+	content, err := os.ReadFile(progress.Path())
+	if err != nil {
+		t.Fatalf("Failed to read progress file: %v", err)
+	}
+	if err := json.Unmarshal(content, &status); err != nil {
+		t.Fatalf("Failed to parse progress file: %v", err)
+	}
+	if status["percent"] != float64(25) || status["message"] != "copying" || status["finished"] != false {
+		t.Errorf("Unexpected progress status: %v", status)
+	}
+
+	// Updates within the interval are not logged
+	if strings.Contains(logs.String(), "copying") {
+		t.Error("Expected update not to be logged within the interval")
+	}
+
+	if err := progress.Finish("done"); err != nil {
+		t.Fatalf("Failed to finish progress: %v", err)
+	}
+	content, _ = os.ReadFile(progress.Path())
+	json.Unmarshal(content, &status)
+	if status["percent"] != float64(100) || status["finished"] != true {
+		t.Errorf("Unexpected final status: %v", status)
+	}
+	if !strings.Contains(logs.String(), "copy files: 100.0% (200/200), done") {
+		t.Errorf("Expected completion to be logged, got %s", logs.String())
+	}
+}
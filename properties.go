@@ -0,0 +1,372 @@
+package ansiblemodule
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PropertiesFormat selects the syntax of a properties file
+type PropertiesFormat int
+
+const (
+	// PropertiesShell is KEY=VALUE with shell quoting, as in sysconfig and .env files
+	PropertiesShell PropertiesFormat = iota
+	// PropertiesJava is the Java properties syntax with backslash escapes and continuations
+	PropertiesJava
+)
+
+// PropertiesFile holds the content of a key-value file being edited. Lines that
+// are not touched keep their formatting, and changed values keep the quoting
+// style of the line they replace where possible.
+type PropertiesFile struct {
+	File     string
+	Format   PropertiesFormat
+	original string
+	lines    []string
+}
+
+// property is a parsed assignment spanning lines[start:end]
+type property struct {
+	start, end int
+	key, value string
+	prefix     string // "export " in shell files
+	quote      byte   // Quote character of a shell value, or 0
+	separator  string // Separator of a Java property, such as "=" or " : "
+}
+
+// LoadProperties reads a properties file. A missing file loads as an empty file.
+func (m *AnsibleModule) LoadProperties(path string, format PropertiesFormat) (*PropertiesFile, error) {
+	props := &PropertiesFile{File: path, Format: format}
+	if m.FileExists(path) {
+		content, err := m.ReadTextFile(path)
+		if err != nil {
+			return nil, err
+		}
+		props.original = content
+	}
+	if props.original != "" {
+		props.lines = strings.Split(strings.TrimSuffix(props.original, "\n"), "\n")
+	}
+	return props, nil
+}
+
+// parse returns the assignments in the file in order
+func (p *PropertiesFile) parse() []property {
+	var props []property
+	for i := 0; i < len(p.lines); i++ {
+		start := i
+		line := p.lines[i]
+		if p.Format == PropertiesJava {
+			// A line ending in an odd number of backslashes continues on the next line
+			for continuesJavaLine(line) && i+1 < len(p.lines) {
+				i++
+				line = line[:len(line)-1] + strings.TrimLeft(p.lines[i], " \t\f")
+			}
+			if prop, ok := parseJavaProperty(line); ok {
+				prop.start, prop.end = start, i+1
+				props = append(props, prop)
+			}
+		} else if prop, ok := parseShellProperty(line); ok {
+			prop.start, prop.end = start, i+1
+			props = append(props, prop)
+		}
+	}
+	return props
+}
+
+// find returns the effective, last, assignment of a key
+func (p *PropertiesFile) find(key string) (property, bool) {
+	props := p.parse()
+	for i := len(props) - 1; i >= 0; i-- {
+		if props[i].key == key {
+			return props[i], true
+		}
+	}
+	return property{}, false
+}
+
+// Keys returns the assigned keys in order of first assignment
+func (p *PropertiesFile) Keys() []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, prop := range p.parse() {
+		if !seen[prop.key] {
+			seen[prop.key] = true
+			keys = append(keys, prop.key)
+		}
+	}
+	return keys
+}
+
+// Get returns the value of a key, taken from its last assignment
+func (p *PropertiesFile) Get(key string) (string, bool) {
+	prop, ok := p.find(key)
+	return prop.value, ok
+}
+
+// Set assigns a value to a key, reporting whether the file changed. The last
+// assignment of an existing key is rewritten and new keys are appended.
+func (p *PropertiesFile) Set(key, value string) bool {
+	prop, ok := p.find(key)
+	if ok && prop.value == value {
+		return false
+	}
+	if !ok {
+		prop = property{key: key, separator: "=", start: len(p.lines), end: len(p.lines)}
+	}
+	prop.value = value
+
+	var line string
+	if p.Format == PropertiesJava {
+		line = escapeJavaProperty(prop.key, true) + prop.separator + escapeJavaProperty(prop.value, false)
+	} else {
+		line = prop.prefix + prop.key + "=" + quoteShellValue(prop.value, prop.quote)
+	}
+	p.lines = append(p.lines[:prop.start], append([]string{line}, p.lines[prop.end:]...)...)
+	return true
+}
+
+// Unset removes every assignment of a key, reporting whether the file changed
+func (p *PropertiesFile) Unset(key string) bool {
+	changed := false
+	props := p.parse()
+	for i := len(props) - 1; i >= 0; i-- {
+		if props[i].key == key {
+			p.lines = append(p.lines[:props[i].start], p.lines[props[i].end:]...)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Render returns the file content
+func (p *PropertiesFile) Render() string {
+	if len(p.lines) == 0 {
+		return ""
+	}
+	return strings.Join(p.lines, "\n") + "\n"
+}
+
+// Changed reports whether the content differs from what was loaded
+func (p *PropertiesFile) Changed() bool {
+	return p.Render() != p.original
+}
+
+// Diff returns a diff of the loaded and current content
+func (p *PropertiesFile) Diff(m *AnsibleModule) map[string]interface{} {
+	return m.CreateDiff(p.original, p.Render(), p.File+" (before)", p.File+" (after)")
+}
+
+// SaveProperties atomically writes the file if it changed, keeping the mode of
+// an existing file, and returns the backup path if requested
+func (m *AnsibleModule) SaveProperties(props *PropertiesFile, backup bool) (bool, string, error) {
+	if !props.Changed() {
+		return false, "", nil
+	}
+	if m.CheckMode {
+		return true, "", nil
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := m.fs().Stat(props.File); err == nil {
+		mode = info.Mode().Perm()
+	}
+	backupPath := ""
+	if backup && props.original != "" {
+		path, err := m.BackupFile(props.File)
+		if err != nil {
+			return false, "", err
+		}
+		backupPath = path
+	}
+
+	content := props.Render()
+	if _, err := m.WriteTextFile(props.File, content, mode); err != nil {
+		return false, backupPath, err
+	}
+	props.original = content
+	return true, backupPath, nil
+}
+
+// parseShellProperty parses a KEY=VALUE line with an optional export prefix
+func parseShellProperty(line string) (property, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if trimmed == "" || trimmed[0] == '#' {
+		return property{}, false
+	}
+	var prop property
+	if rest, found := strings.CutPrefix(trimmed, "export "); found {
+		prop.prefix = "export "
+		trimmed = strings.TrimLeft(rest, " \t")
+	}
+	key, raw, found := strings.Cut(trimmed, "=")
+	if !found || key == "" || strings.ContainsAny(key, " \t") {
+		return property{}, false
+	}
+	prop.key = key
+
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		prop.quote = '\''
+		value, _, _ := strings.Cut(raw[1:], "'")
+		prop.value = value
+	case strings.HasPrefix(raw, `"`):
+		prop.quote = '"'
+		var b strings.Builder
+		for i := 1; i < len(raw) && raw[i] != '"'; i++ {
+			if raw[i] == '\\' && i+1 < len(raw) && strings.IndexByte("\\\"$`", raw[i+1]) >= 0 {
+				i++
+			}
+			b.WriteByte(raw[i])
+		}
+		prop.value = b.String()
+	default:
+		// An unquoted value ends at a comment
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		prop.value = strings.TrimRight(raw, " \t")
+	}
+	return prop, true
+}
+
+// quoteShellValue quotes a value with the preferred quote character, switching to
+// double quotes when the value cannot be written with it
+func quoteShellValue(value string, quote byte) string {
+	if quote == 0 && strings.ContainsAny(value, " \t\n'\"\\$`#;&|<>()*?[]~!{}") {
+		quote = '"'
+	}
+	if quote == '\'' && strings.Contains(value, "'") {
+		quote = '"'
+	}
+	switch quote {
+	case '\'':
+		return "'" + value + "'"
+	case '"':
+		var b strings.Builder
+		b.WriteByte('"')
+		for i := 0; i < len(value); i++ {
+			if strings.IndexByte("\\\"$`", value[i]) >= 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(value[i])
+		}
+		b.WriteByte('"')
+		return b.String()
+	}
+	return value
+}
+
+// continuesJavaLine reports whether a line ends in an unescaped backslash
+func continuesJavaLine(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+// parseJavaProperty parses a logical line of a Java properties file
+func parseJavaProperty(line string) (property, bool) {
+	trimmed := strings.TrimLeft(line, " \t\f")
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+		return property{}, false
+	}
+
+	// The key ends at the first unescaped separator or whitespace
+	end := 0
+	for end < len(trimmed) {
+		c := trimmed[end]
+		if c == '\\' {
+			end += 2
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+		end++
+	}
+	end = min(end, len(trimmed))
+	rest := trimmed[end:]
+	valueStart := strings.TrimLeft(rest, " \t\f")
+	if valueStart != "" && (valueStart[0] == '=' || valueStart[0] == ':') {
+		valueStart = strings.TrimLeft(valueStart[1:], " \t\f")
+	}
+
+	return property{
+		key:       unescapeJavaProperty(trimmed[:end]),
+		value:     unescapeJavaProperty(valueStart),
+		separator: rest[:len(rest)-len(valueStart)],
+	}, true
+}
+
+// unescapeJavaProperty decodes the backslash escapes of a Java properties key or value
+func unescapeJavaProperty(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 <= len(s) {
+				if code, err := strconv.ParseUint(s[i+1:i+5], 16, 16); err == nil {
+					b.WriteRune(rune(code))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// escapeJavaProperty encodes a key or value for a Java properties file. Keys
+// also escape separators and whitespace, values only their leading space.
+func escapeJavaProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case ' ':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(' ')
+		case '=', ':', '#', '!':
+			if key {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
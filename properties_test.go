@@ -0,0 +1,94 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestShellProperties(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sysconfig")
+	content := "# Network settings\nexport HOST=web1\nNAME='my host'\nOPTS=\"-v \\\"x\\\"\" # debug\nPORT=80 # http\nPORT=8080\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	module := &AnsibleModule{}
+
+	props, err := module.LoadProperties(path, PropertiesShell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := props.Keys(); !reflect.DeepEqual(keys, []string{"HOST", "NAME", "OPTS", "PORT"}) {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+	for key, expected := range map[string]string{"HOST": "web1", "NAME": "my host", "OPTS": `-v "x"`, "PORT": "8080"} {
+		if value, ok := props.Get(key); !ok || value != expected {
+			t.Errorf("Expected %s=%q, got %q", key, expected, value)
+		}
+	}
+
+	if props.Set("PORT", "8080") {
+		t.Error("Expected same value to be unchanged")
+	}
+	props.Set("HOST", "web2")
+	props.Set("NAME", "it's")
+	props.Set("PATH_EXTRA", "/opt/bin:$PATH")
+	if !props.Unset("PORT") || props.Unset("MISSING") {
+		t.Error("Expected unset to report only existing keys")
+	}
+
+	changed, _, err := module.SaveProperties(props, false)
+	if err != nil || !changed {
+		t.Fatalf("Expected save, got %v (%v)", changed, err)
+	}
+	saved, _ := os.ReadFile(path)
+	expected := "# Network settings\nexport HOST=web2\nNAME=\"it's\"\nOPTS=\"-v \\\"x\\\"\" # debug\nPATH_EXTRA=\"/opt/bin:\\$PATH\"\n"
+	if string(saved) != expected {
+		t.Errorf("Unexpected content:\n%s", saved)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode to be kept, got %v", info.Mode())
+	}
+}
+
+func TestJavaProperties(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.properties")
+	content := "! comment\nserver.port : 8080\ngreeting = hello \\\n    world\nkey\\ with\\ spaces=x\npath=C:\\\\app\nunicode=caf\\u00e9\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	module := &AnsibleModule{CheckMode: true}
+
+	props, err := module.LoadProperties(path, PropertiesJava)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"server.port":     "8080",
+		"greeting":        "hello world",
+		"key with spaces": "x",
+		"path":            `C:\app`,
+		"unicode":         "café",
+	} {
+		if value, ok := props.Get(key); !ok || value != expected {
+			t.Errorf("Expected %s=%q, got %q", key, expected, value)
+		}
+	}
+
+	props.Set("server.port", "9090")
+	props.Set("greeting", " hi")
+	props.Set("new:key", "a=b")
+	expected := "! comment\nserver.port : 9090\ngreeting = \\ hi\nkey\\ with\\ spaces=x\npath=C:\\\\app\nunicode=caf\\u00e9\nnew\\:key=a=b\n"
+	if props.Render() != expected {
+		t.Errorf("Unexpected content:\n%s", props.Render())
+	}
+
+	changed, _, err := module.SaveProperties(props, false)
+	if err != nil || !changed {
+		t.Errorf("Expected check mode change, got %v (%v)", changed, err)
+	}
+	if saved, _ := os.ReadFile(path); string(saved) != content {
+		t.Error("Expected check mode not to write")
+	}
+}
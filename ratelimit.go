@@ -0,0 +1,258 @@
+package ansiblemodule
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig defines client-side throttling for the module HTTP client
+type RateLimitConfig struct {
+	RequestsPerSecond    float64       // Sustained request rate, 0 for unlimited
+	Burst                int           // Number of requests allowed above the sustained rate
+	MaxConcurrent        int           // Maximum in-flight requests, 0 for unlimited
+	MaxRetries           int           // Number of times a 429 response is retried
+	MinRequestsPerSecond float64       // Lower bound for the rate after 429 slowdowns
+	MaxRetryDelay        time.Duration // Longest Retry-After delay waited for, 0 for DefaultMaxRetryDelay
+}
+
+// DefaultMaxRetryDelay caps the delay a server can ask for with Retry-After
+var DefaultMaxRetryDelay = time.Minute
+
+// RateLimiter is a token bucket limiter that slows down when the server throttles
+type RateLimiter struct {
+	mu       sync.Mutex
+	rate     float64
+	maxRate  float64
+	minRate  float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	inflight chan struct{}
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+// NewRateLimiter creates a rate limiter from the given configuration
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	burst := float64(config.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	minRate := config.MinRequestsPerSecond
+	if minRate <= 0 || minRate > config.RequestsPerSecond {
+		minRate = config.RequestsPerSecond / 16
+	}
+
+	limiter := &RateLimiter{
+		rate:    config.RequestsPerSecond,
+		maxRate: config.RequestsPerSecond,
+		minRate: minRate,
+		burst:   burst,
+		tokens:  burst,
+		last:    time.Now(),
+		sleep:   sleepContext,
+	}
+	if config.MaxConcurrent > 0 {
+		limiter.inflight = make(chan struct{}, config.MaxConcurrent)
+	}
+	return limiter
+}
+
+// Rate returns the current request rate in requests per second
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Wait blocks until a request may be sent
+func (l *RateLimiter) Wait() {
+	l.WaitContext(context.Background())
+}
+
+// WaitContext blocks until a request may be sent or ctx is done
+func (l *RateLimiter) WaitContext(ctx context.Context) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	// Reserve a token, going into debt if none are available
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay > 0 {
+		return l.sleep(ctx, delay)
+	}
+	return nil
+}
+
+// acquire takes a concurrency slot unless ctx is done first
+func (l *RateLimiter) acquire(ctx context.Context) error {
+	if l.inflight == nil {
+		return nil
+	}
+	select {
+	case l.inflight <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a concurrency slot
+func (l *RateLimiter) release() {
+	if l.inflight != nil {
+		<-l.inflight
+	}
+}
+
+// Throttled halves the request rate after the server responded with 429
+func (l *RateLimiter) Throttled() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return
+	}
+	l.rate /= 2
+	if l.rate < l.minRate {
+		l.rate = l.minRate
+	}
+}
+
+// Succeeded slowly restores the request rate after a successful response
+func (l *RateLimiter) Succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 || l.rate >= l.maxRate {
+		return
+	}
+	l.rate *= 1.1
+	if l.rate > l.maxRate {
+		l.rate = l.maxRate
+	}
+}
+
+// sleepContext waits for d unless ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimitTransport applies a RateLimiter to outgoing requests
+type rateLimitTransport struct {
+	limiter       *RateLimiter
+	maxRetries    int
+	maxRetryDelay time.Duration
+	base          http.RoundTripper
+	ctx           func() context.Context // Module context, waits also end with it
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	if t.ctx != nil {
+		defer context.AfterFunc(t.ctx(), cancel)()
+	}
+	req = req.WithContext(ctx)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.acquire(ctx); err != nil {
+			return nil, err
+		}
+		if err := t.limiter.WaitContext(ctx); err != nil {
+			t.limiter.release()
+			return nil, err
+		}
+		resp, err := t.base.RoundTrip(req)
+		t.limiter.release()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.limiter.Succeeded()
+			return resp, nil
+		}
+
+		t.limiter.Throttled()
+		if attempt >= t.maxRetries {
+			return resp, nil
+		}
+
+		// Requests with a body can only be retried if it can be rewound
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, nil
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		delay := min(retryAfter(resp.Header.Get("Retry-After")), t.maxRetryDelay)
+		resp.Body.Close()
+		if delay > 0 {
+			if err := t.limiter.sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// retryAfter parses a Retry-After header value
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
+
+// SetRateLimit throttles all requests made through the module HTTP client
+func (m *AnsibleModule) SetRateLimit(config RateLimitConfig) *RateLimiter {
+	limiter := NewRateLimiter(config)
+	maxRetryDelay := config.MaxRetryDelay
+	if maxRetryDelay <= 0 {
+		maxRetryDelay = DefaultMaxRetryDelay
+	}
+	m.wrapTransport(func(base http.RoundTripper) http.RoundTripper {
+		return &rateLimitTransport{
+			limiter:       limiter,
+			maxRetries:    config.MaxRetries,
+			maxRetryDelay: maxRetryDelay,
+			base:          base,
+			ctx:           m.Context,
+		}
+	})
+	return limiter
+}
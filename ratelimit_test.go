@@ -0,0 +1,138 @@
+package ansiblemodule
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{
+		RequestsPerSecond: 10,
+		Burst:             2,
+	})
+
+	var slept time.Duration
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		slept += d
+		return nil
+	}
+
+	// Burst requests should not wait
+	limiter.Wait()
+	limiter.Wait()
+	if slept != 0 {
+		t.Errorf("Expected no delay within burst, got %v", slept)
+	}
+
+	// The next request should wait for a token
+	limiter.Wait()
+	if slept <= 0 {
+		t.Error("Expected delay after burst was used")
+	}
+
+	// Test adaptive slowdown and recovery
+	limiter.Throttled()
+	if limiter.Rate() != 5 {
+		t.Errorf("Expected rate 5 after throttling, got %v", limiter.Rate())
+	}
+	for i := 0; i < 20; i++ {
+		limiter.Succeeded()
+	}
+	if limiter.Rate() != 10 {
+		t.Errorf("Expected rate to recover to 10, got %v", limiter.Rate())
+	}
+
+	// Test rate never drops below the minimum
+	for i := 0; i < 20; i++ {
+		limiter.Throttled()
+	}
+	if limiter.Rate() != 10.0/16 {
+		t.Errorf("Expected minimum rate %v, got %v", 10.0/16, limiter.Rate())
+	}
+}
+
+func TestSetRateLimit(t *testing.T) {
+	module := &AnsibleModule{}
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	limiter := module.SetRateLimit(RateLimitConfig{
+		RequestsPerSecond: 100,
+		Burst:             10,
+		MaxConcurrent:     2,
+		MaxRetries:        1,
+	})
+
+	// Test 429 response is retried with a rewound body
+	resp, err := module.FetchURL("POST", server.URL, strings.NewReader("data"), nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.Status != http.StatusOK {
+		t.Errorf("Expected status 200 after retry, got %d", resp.Status)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+	if limiter.Rate() >= 100 {
+		t.Errorf("Expected rate to be reduced after 429, got %v", limiter.Rate())
+	}
+}
+
+func TestRateLimitWaitsEnd(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// A server asking for an hour is only waited for up to MaxRetryDelay
+	module := &AnsibleModule{}
+	module.SetRateLimit(RateLimitConfig{MaxRetries: 1, MaxRetryDelay: 10 * time.Millisecond})
+	start := time.Now()
+	if resp, err := module.FetchURL("GET", server.URL, nil, nil); err != nil || resp.Status != http.StatusTooManyRequests {
+		t.Errorf("Expected the retried 429 response, got %v (%v)", resp, err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Expected the Retry-After delay to be capped, took %v", time.Since(start))
+	}
+
+	// Waiting for a token ends with the module context, even for requests
+	// made without it
+	module = &AnsibleModule{}
+	module.SetRateLimit(RateLimitConfig{RequestsPerSecond: 0.001})
+	module.Context()
+	client := module.httpClient()
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Get(server.URL)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	module.cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the wait to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a cancelled module not to wait for the rate limit")
+	}
+}
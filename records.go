@@ -0,0 +1,156 @@
+package ansiblemodule
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Records holds the entries of a list-of-dicts parameter, such as firewall
+// rules or users, decoded into T and indexed by their key field
+type Records[T any] struct {
+	Items []T
+	Keys  []string // Key field of each item, empty without a key field
+	index map[string]int
+}
+
+// Get returns the record whose key field is key
+func (r *Records[T]) Get(key string) (T, bool) {
+	if i, ok := r.index[key]; ok {
+		return r.Items[i], true
+	}
+	var zero T
+	return zero, false
+}
+
+// ParamRecords validates the list-of-dicts parameter name against its
+// suboptions and decodes every entry into T through JSON, so T can be a struct
+// with json tags or a map. Unknown fields are rejected, aliases resolved,
+// defaults applied and values converted as for top-level options. With a key
+// field, each entry must set it and two entries may not share a value.
+func ParamRecords[T any](m *AnsibleModule, name, key string) (*Records[T], error) {
+	entries, keys, err := m.validateRecords(name, key)
+	if err != nil {
+		return nil, err
+	}
+	records := &Records[T]{Items: make([]T, len(entries)), Keys: keys, index: make(map[string]int, len(keys))}
+	for i, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d] %w", name, i, err)
+		}
+		if err := json.Unmarshal(data, &records.Items[i]); err != nil {
+			return nil, fmt.Errorf("%s[%d] cannot be decoded: %w", name, i, err)
+		}
+	}
+	for i, k := range keys {
+		records.index[k] = i
+	}
+	return records, nil
+}
+
+// validateRecords returns the validated entries of a list-of-dicts parameter
+// and the value of the key field of each
+func (m *AnsibleModule) validateRecords(name, key string) ([]map[string]interface{}, []string, error) {
+	spec, ok := m.ArgSpec[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("parameter %s is not in the argument spec", name)
+	}
+	options := spec.SubOptions
+	if options == nil {
+		options = spec.Options
+	}
+	if key != "" {
+		if _, ok := options[key]; !ok {
+			return nil, nil, fmt.Errorf("key field %s is not a suboption of %s", key, name)
+		}
+	}
+
+	value := m.Params[name]
+	if value == nil {
+		return nil, nil, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, nil, invalidParam(name, CodeInvalidType, name, "a list of dictionaries")
+	}
+
+	aliases := make(map[string]string)
+	for option, optionSpec := range options {
+		for _, alias := range optionSpec.Aliases {
+			aliases[alias] = option
+		}
+	}
+
+	entries := make([]map[string]interface{}, len(list))
+	var keys []string
+	seen := make(map[string]int)
+	for i, item := range list {
+		path := fmt.Sprintf("%s[%d]", name, i)
+		raw, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, nil, invalidParam(path, CodeInvalidType, path, "a dictionary")
+		}
+		entry, err := m.validateRecord(path, raw, options, aliases)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries[i] = entry
+
+		if key == "" {
+			continue
+		}
+		if entry[key] == nil {
+			return nil, nil, invalidParam(path+"."+key, CodeMissingSuboption, path+"."+key)
+		}
+		k := fmt.Sprint(entry[key])
+		if first, ok := seen[k]; ok {
+			return nil, nil, invalidParam(path, CodeDuplicateKey, path, key, k, name, first)
+		}
+		seen[k] = i
+		keys = append(keys, k)
+	}
+	return entries, keys, nil
+}
+
+// validateRecord checks one entry against the suboptions, returning a copy
+// with aliases resolved, defaults applied and values converted
+func (m *AnsibleModule) validateRecord(path string, raw map[string]interface{}, options ArgSpecMap,
+	aliases map[string]string) (map[string]interface{}, error) {
+
+	entry := make(map[string]interface{}, len(options))
+	fields := make([]string, 0, len(raw))
+	for field := range raw {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		option := field
+		if _, ok := options[field]; !ok {
+			if option, ok = aliases[field]; !ok {
+				return nil, invalidParam(path, CodeUnsupportedParameter, path, field)
+			}
+			if _, dup := raw[option]; dup {
+				return nil, invalidParam(path, CodeAliasConflict, path, option, field)
+			}
+		}
+		entry[option] = raw[field]
+	}
+
+	for option, spec := range options {
+		value, ok := entry[option]
+		if !ok || value == nil {
+			if spec.Required {
+				return nil, invalidParam(path+"."+option, CodeMissingSuboption, path+"."+option)
+			}
+			entry[option] = spec.Default
+			continue
+		}
+		normalized, err := m.validateArgument(path+"."+option, value, spec)
+		if err != nil {
+			return nil, err
+		}
+		entry[option] = normalized
+	}
+	return entry, nil
+}
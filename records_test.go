@@ -0,0 +1,57 @@
+package ansiblemodule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParamRecords(t *testing.T) {
+	type rule struct {
+		Name    string `json:"name"`
+		Port    int    `json:"port"`
+		Proto   string `json:"proto"`
+		Enabled bool   `json:"enabled"`
+	}
+	module := &AnsibleModule{ArgSpec: ArgSpecMap{
+		"rules": {Type: "list", Elements: "dict", SubOptions: ArgSpecMap{
+			"name":    {Type: "str", Required: true},
+			"port":    {Type: "port", Required: true},
+			"proto":   {Type: "str", Default: "tcp", Choices: []string{"tcp", "udp"}, Aliases: []string{"protocol"}},
+			"enabled": {Type: "bool", Default: true},
+		}},
+	}}
+	module.Params = ModuleParams{"rules": []interface{}{
+		map[string]interface{}{"name": "web", "port": "443"},
+		map[string]interface{}{"name": "dns", "port": 53, "protocol": "udp", "enabled": "no"},
+	}}
+
+	records, err := ParamRecords[rule](module, "rules", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if web, ok := records.Get("web"); !ok || web != (rule{"web", 443, "tcp", true}) {
+		t.Errorf("Expected converted record with defaults, got %+v", web)
+	}
+	if dns, _ := records.Get("dns"); dns.Proto != "udp" || dns.Enabled {
+		t.Errorf("Expected alias and boolean conversion, got %+v", dns)
+	}
+	if len(records.Keys) != 2 || records.Keys[1] != "dns" {
+		t.Errorf("Unexpected keys %v", records.Keys)
+	}
+
+	for _, entries := range []struct {
+		rules []interface{}
+		err   string
+	}{
+		{[]interface{}{map[string]interface{}{"name": "a", "port": 1}, map[string]interface{}{"name": "a", "port": 2}}, "same name"},
+		{[]interface{}{map[string]interface{}{"name": "a", "port": 1, "extra": true}}, "unsupported parameter extra"},
+		{[]interface{}{map[string]interface{}{"name": "a"}}, "rules[0].port is required"},
+		{[]interface{}{map[string]interface{}{"name": "a", "port": 1, "proto": "icmp"}}, "must be one of"},
+		{[]interface{}{"web"}, "must be a dictionary"},
+	} {
+		module.Params["rules"] = entries.rules
+		if _, err := ParamRecords[map[string]interface{}](module, "rules", "name"); err == nil || !strings.Contains(err.Error(), entries.err) {
+			t.Errorf("Expected error containing %q, got %v", entries.err, err)
+		}
+	}
+}
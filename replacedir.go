@@ -0,0 +1,158 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ReplaceDirectory replaces target with the tree prepared in stagingDir, so that
+// readers see either the old or the new tree, never a mix. Between moving the
+// old tree aside and renaming the new one into place target briefly does not
+// exist. The staging directory must be on the same filesystem as target,
+// ideally next to it. The old tree is put back if the new one cannot be renamed
+// into place.
+//
+// When the trees already match, the staging directory is removed and nothing
+// changes. In check mode the staging directory is left for the caller.
+func (m *AnsibleModule) ReplaceDirectory(stagingDir, target string) (bool, error) {
+	if info, err := m.fs().Stat(stagingDir); err != nil || !info.IsDir() {
+		return false, fmt.Errorf("staging directory %s is not a directory", stagingDir)
+	}
+	info, err := m.fs().Lstat(target)
+	targetExists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if targetExists && !info.IsDir() {
+		return false, fmt.Errorf("%s exists and is not a directory", target)
+	}
+
+	if targetExists {
+		same, err := m.sameTree(stagingDir, target)
+		if err != nil {
+			return false, err
+		}
+		if same {
+			if !m.CheckMode {
+				m.fs().RemoveAll(stagingDir)
+			}
+			return false, nil
+		}
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+	if err := m.Context().Err(); err != nil {
+		return false, err
+	}
+
+	if !targetExists {
+		if err := m.fs().Rename(stagingDir, target); err != nil {
+			return false, fmt.Errorf("failed to move %s into place: %w", stagingDir, err)
+		}
+		m.RecordAudit("replace_directory", target, map[string]interface{}{"state": "absent"}, map[string]interface{}{"state": "directory"})
+		return true, nil
+	}
+
+	// Move the old tree aside under a free name next to it
+	old, err := m.fs().MkdirTemp(filepath.Dir(target), "."+filepath.Base(target)+".old-")
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve a name for the old %s: %w", target, err)
+	}
+	m.fs().Remove(old)
+	if err := m.fs().Rename(target, old); err != nil {
+		return false, fmt.Errorf("failed to move %s aside: %w", target, err)
+	}
+	if err := m.fs().Rename(stagingDir, target); err != nil {
+		if rollbackErr := m.fs().Rename(old, target); rollbackErr != nil {
+			return false, fmt.Errorf("failed to move %s into place: %v, and failed to restore the old tree from %s: %v",
+				stagingDir, err, old, rollbackErr)
+		}
+		return false, fmt.Errorf("failed to move %s into place: %w", stagingDir, err)
+	}
+	if err := m.fs().RemoveAll(old); err != nil {
+		m.AddWarning(fmt.Sprintf("failed to remove old tree %s: %v", old, err))
+	}
+	m.RecordAudit("replace_directory", target, map[string]interface{}{"state": "directory"}, map[string]interface{}{"state": "directory"})
+	return true, nil
+}
+
+// treeEntry describes a path within a tree for comparison
+type treeEntry struct {
+	mode   fs.FileMode
+	size   int64
+	target string // Symlink target
+}
+
+// sameTree reports whether two directory trees hold the same paths, types,
+// permissions, symlink targets and file content
+func (m *AnsibleModule) sameTree(a, b string) (bool, error) {
+	entriesA, err := m.readTree(a)
+	if err != nil {
+		return false, err
+	}
+	entriesB, err := m.readTree(b)
+	if err != nil {
+		return false, err
+	}
+	if len(entriesA) != len(entriesB) {
+		return false, nil
+	}
+	for path, entry := range entriesA {
+		if other, ok := entriesB[path]; !ok || other != entry {
+			return false, nil
+		}
+	}
+	for path, entry := range entriesA {
+		if !entry.mode.IsRegular() {
+			continue
+		}
+		same, err := m.sameFileContent(filepath.Join(a, path), filepath.Join(b, path), m.ComparisonHash)
+		if err != nil || !same {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// readTree lists root and the entries below it by relative path
+func (m *AnsibleModule) readTree(root string) (map[string]treeEntry, error) {
+	entries := make(map[string]treeEntry)
+	if err := m.addTreeEntries(entries, root, "."); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// addTreeEntries adds the entry at rel below root to entries, descending into
+// directories
+func (m *AnsibleModule) addTreeEntries(entries map[string]treeEntry, root, rel string) error {
+	path := filepath.Join(root, rel)
+	info, err := m.fs().Lstat(path)
+	if err != nil {
+		return err
+	}
+	entry := treeEntry{mode: info.Mode()}
+	switch {
+	case info.Mode().IsRegular():
+		entry.size = info.Size()
+	case info.Mode()&fs.ModeSymlink != 0:
+		if entry.target, err = m.fs().Readlink(path); err != nil {
+			return err
+		}
+	case info.IsDir():
+		children, err := m.fs().ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if err := m.addTreeEntries(entries, root, filepath.Join(rel, child.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	entries[rel] = entry
+	return nil
+}
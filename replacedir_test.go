@@ -0,0 +1,92 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTree creates files below root from a map of relative paths to content
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Chmod(root, 0755)
+}
+
+func TestReplaceDirectory(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "conf.d")
+	module := &AnsibleModule{}
+
+	staging := filepath.Join(dir, "staging1")
+	writeTree(t, staging, map[string]string{"a.conf": "a", "sub/b.conf": "b"})
+	changed, err := module.ReplaceDirectory(staging, target)
+	if err != nil || !changed {
+		t.Fatalf("Expected new directory, got %v (%v)", changed, err)
+	}
+
+	staging = filepath.Join(dir, "staging2")
+	writeTree(t, staging, map[string]string{"a.conf": "a", "sub/b.conf": "b"})
+	changed, err = module.ReplaceDirectory(staging, target)
+	if err != nil || changed {
+		t.Errorf("Expected identical tree to be unchanged, got %v (%v)", changed, err)
+	}
+	if module.FileExists(staging) {
+		t.Error("Expected unused staging directory to be removed")
+	}
+
+	staging = filepath.Join(dir, "staging3")
+	writeTree(t, staging, map[string]string{"a.conf": "A"})
+	module.CheckMode = true
+	if changed, err := module.ReplaceDirectory(staging, target); err != nil || !changed || !module.FileExists(filepath.Join(target, "sub")) {
+		t.Errorf("Expected check mode to report a change without swapping, got %v (%v)", changed, err)
+	}
+
+	module.CheckMode = false
+	changed, err = module.ReplaceDirectory(staging, target)
+	if err != nil || !changed {
+		t.Fatalf("Expected swap, got %v (%v)", changed, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(target, "a.conf")); string(content) != "A" || module.FileExists(filepath.Join(target, "sub")) {
+		t.Error("Expected the target to hold exactly the new tree")
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, ".conf.d.old-*")); len(leftovers) != 0 {
+		t.Errorf("Expected the old tree to be removed, found %v", leftovers)
+	}
+}
+
+func TestReplaceDirectoryRollback(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "certs")
+	writeTree(t, target, map[string]string{"ca.pem": "old"})
+	staging := filepath.Join(dir, "staging")
+	writeTree(t, staging, map[string]string{"ca.pem": "new"})
+
+	// Fail the rename of the staging directory into place
+	module := &AnsibleModule{FileSystem: failingRenameFS{OSFileSystem{}, staging}}
+	if _, err := module.ReplaceDirectory(staging, target); err == nil {
+		t.Fatal("Expected the failed rename to be reported")
+	}
+	if content, _ := os.ReadFile(filepath.Join(target, "ca.pem")); string(content) != "old" {
+		t.Errorf("Expected the old tree to be restored, got %q", content)
+	}
+}
+
+// failingRenameFS fails renames of one path
+type failingRenameFS struct {
+	OSFileSystem
+	fail string
+}
+
+func (f failingRenameFS) Rename(oldpath, newpath string) error {
+	if oldpath == f.fail {
+		return os.ErrPermission
+	}
+	return f.OSFileSystem.Rename(oldpath, newpath)
+}
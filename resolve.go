@@ -0,0 +1,135 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ResolveTimeout is how long ResolveHost waits when the options set no timeout
+var ResolveTimeout = 5 * time.Second
+
+// hostsFilePath is the static host table consulted before DNS
+var hostsFilePath = defaultHostsFile()
+
+// defaultHostsFile returns the location of the hosts file on this platform
+func defaultHostsFile() string {
+	if runtime.GOOS == "windows" {
+		root := os.Getenv("SystemRoot")
+		if root == "" {
+			root = `C:\Windows`
+		}
+		return filepath.Join(root, "System32", "drivers", "etc", "hosts")
+	}
+	return "/etc/hosts"
+}
+
+// ResolveOptions controls how ResolveHost looks up a name
+type ResolveOptions struct {
+	Timeout       time.Duration // ResolveTimeout if zero
+	Family        string        // Only return "ipv4" or "ipv6" addresses, both if empty
+	Prefer        string        // List "ipv4" or "ipv6" addresses first, keeping resolver order if empty
+	SkipHostsFile bool          // Do not answer from the hosts file before asking the resolver
+}
+
+// ResolveHost returns the addresses of a host name. Names in the hosts file are
+// answered from it, so they resolve even when DNS is broken, and resolver
+// lookups give up after the timeout instead of blocking the module.
+func (m *AnsibleModule) ResolveHost(name string, opts ResolveOptions) ([]string, error) {
+	var network string
+	switch opts.Family {
+	case "":
+		network = "ip"
+	case "ipv4":
+		network = "ip4"
+	case "ipv6":
+		network = "ip6"
+	default:
+		return nil, fmt.Errorf("invalid address family %s", opts.Family)
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(strings.Trim(name, "[]")); ip != nil {
+		ips = []net.IP{ip}
+	} else if !opts.SkipHostsFile {
+		ips = lookupHostsFile(name)
+	}
+
+	if ips == nil {
+		timeout := opts.Timeout
+		if timeout == 0 {
+			timeout = ResolveTimeout
+		}
+		ctx, cancel := context.WithTimeout(m.Context(), timeout)
+		defer cancel()
+		found, err := net.DefaultResolver.LookupIP(ctx, network, name)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out resolving %s after %v", name, timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		ips = found
+	}
+
+	var addresses []string
+	for _, ip := range ips {
+		if isV4 := ip.To4() != nil; (network == "ip4" && !isV4) || (network == "ip6" && isV4) {
+			continue
+		}
+		addresses = append(addresses, ip.String())
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no %s addresses found for %s", opts.Family, name)
+	}
+
+	if opts.Prefer != "" {
+		preferV4 := opts.Prefer == "ipv4"
+		sort.SliceStable(addresses, func(i, j int) bool {
+			iV4 := !strings.Contains(addresses[i], ":")
+			jV4 := !strings.Contains(addresses[j], ":")
+			return iV4 != jV4 && iV4 == preferV4
+		})
+	}
+	return addresses, nil
+}
+
+// lookupHostsFile returns the addresses the hosts file gives for a name
+func lookupHostsFile(name string) []net.IP {
+	file, err := os.Open(hostsFilePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var ips []net.IP
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// Zone suffixes such as fe80::1%lo0 are dropped
+		address, _, _ := strings.Cut(fields[0], "%")
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+		for _, host := range fields[1:] {
+			if strings.EqualFold(strings.TrimSuffix(host, "."), strings.TrimSuffix(name, ".")) {
+				ips = append(ips, ip)
+				break
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,47 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveHostFromHostsFile(t *testing.T) {
+	hosts := filepath.Join(t.TempDir(), "hosts")
+	os.WriteFile(hosts, []byte("# static\n10.0.0.5 db.internal db\nfe80::5%eth0 db.internal\n2001:db8::5 DB.internal # v6\n"), 0644)
+	old := hostsFilePath
+	hostsFilePath = hosts
+	defer func() { hostsFilePath = old }()
+	module := &AnsibleModule{}
+
+	addresses, err := module.ResolveHost("db.internal", ResolveOptions{Prefer: "ipv6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"fe80::5", "2001:db8::5", "10.0.0.5"}; !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("Expected %v, got %v", expected, addresses)
+	}
+
+	addresses, err = module.ResolveHost("db", ResolveOptions{Family: "ipv4"})
+	if err != nil || !reflect.DeepEqual(addresses, []string{"10.0.0.5"}) {
+		t.Errorf("Expected the IPv4 address only, got %v (%v)", addresses, err)
+	}
+	if _, err := module.ResolveHost("db", ResolveOptions{Family: "ipv6"}); err == nil {
+		t.Error("Expected no IPv6 addresses for db")
+	}
+	if _, err := module.ResolveHost("db", ResolveOptions{Family: "ipx"}); err == nil {
+		t.Error("Expected an invalid family to fail")
+	}
+}
+
+func TestResolveHostLiteral(t *testing.T) {
+	module := &AnsibleModule{}
+	addresses, err := module.ResolveHost("[::1]", ResolveOptions{})
+	if err != nil || !reflect.DeepEqual(addresses, []string{"::1"}) {
+		t.Errorf("Expected the literal address, got %v (%v)", addresses, err)
+	}
+	if _, err := module.ResolveHost("invalid..name.test", ResolveOptions{SkipHostsFile: true, Timeout: 1}); err == nil {
+		t.Error("Expected a lookup that cannot finish in time to fail")
+	}
+}
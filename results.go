@@ -0,0 +1,122 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// resultBooleans are the result fields Ansible reads as task status flags
+var resultBooleans = []string{"changed", "failed", "skipped", "unreachable"}
+
+// normalizeResultBooleans returns a copy of result with its status flags
+// converted to real booleans. Booleans of any named type, nil and the numbers 0
+// and 1 are converted; strings such as "yes" are rejected, since the controller
+// would treat any non-empty string as true.
+func normalizeResultBooleans(result map[string]interface{}) (map[string]interface{}, error) {
+	result = maps.Clone(result)
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	for _, key := range resultBooleans {
+		value, ok := result[key]
+		if !ok {
+			continue
+		}
+		if value == nil {
+			result[key] = false
+			continue
+		}
+		v := reflect.ValueOf(value)
+		switch {
+		case v.Kind() == reflect.Bool:
+			result[key] = v.Bool()
+		case v.CanInt() && (v.Int() == 0 || v.Int() == 1):
+			result[key] = v.Int() == 1
+		case v.CanUint() && v.Uint() <= 1:
+			result[key] = v.Uint() == 1
+		case v.CanFloat() && (v.Float() == 0 || v.Float() == 1):
+			result[key] = v.Float() == 1
+		default:
+			return nil, fmt.Errorf("result field %s must be a boolean, got %T %v", key, value, value)
+		}
+	}
+	return result, nil
+}
+
+// failureResult builds the result of a failed module from msg and args
+func failureResult(msg string, args map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{"failed": true, "msg": msg}
+	maps.Copy(result, args)
+	return result
+}
+
+// invalidResultFailure is the failure replacing a result that cannot be output
+func invalidResultFailure(detail string) map[string]interface{} {
+	return failureResult(formatMessage(CodeInvalidResult, detail), map[string]interface{}{"error_code": string(CodeInvalidResult)})
+}
+
+// resultProtectedKeys are result fields filled in by ExitJson itself
+var resultProtectedKeys = []string{"invocation"}
+
+// reservedFactNames are facts the controller refuses to set, since they would
+// change how it connects to the host
+var reservedFactNames = map[string]bool{
+	"ansible_connection": true, "ansible_host": true, "ansible_port": true, "ansible_user": true,
+	"ansible_password": true, "ansible_ssh_pass": true, "ansible_ssh_private_key_file": true,
+	"ansible_become": true, "ansible_become_method": true, "ansible_become_user": true,
+	"ansible_become_password": true, "ansible_become_pass": true, "ansible_shell_type": true,
+	"ansible_shell_executable": true, "ansible_python_interpreter": true,
+}
+
+// reservedResultKeys removes the fields of a result that collide with keys
+// reserved by Ansible and returns a description of each: fields ExitJson fills
+// in, _ansible_ fields the controller strips, an ansible_facts that is not a
+// dictionary and facts naming connection variables.
+func reservedResultKeys(result map[string]interface{}) []string {
+	var findings []string
+	for _, key := range resultProtectedKeys {
+		if _, ok := result[key]; ok {
+			findings = append(findings, fmt.Sprintf("result field %s is set by the module library", key))
+			delete(result, key)
+		}
+	}
+	keys := make([]string, 0, len(result))
+	for key := range result {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.HasPrefix(key, "_ansible_") {
+			findings = append(findings, fmt.Sprintf("result field %s is reserved for the controller", key))
+			delete(result, key)
+		}
+	}
+
+	facts, ok := result["ansible_facts"]
+	if !ok {
+		return findings
+	}
+	factMap, ok := facts.(map[string]interface{})
+	if !ok {
+		findings = append(findings, fmt.Sprintf("result field ansible_facts must be a dictionary, got %T", facts))
+		delete(result, "ansible_facts")
+		return findings
+	}
+	factMap = maps.Clone(factMap)
+	result["ansible_facts"] = factMap
+	names := make([]string, 0, len(factMap))
+	for name := range factMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if reservedFactNames[name] {
+			findings = append(findings, fmt.Sprintf("fact %s is a connection variable and cannot be set", name))
+			delete(factMap, name)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,73 @@
+package ansiblemodule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeResultBooleans(t *testing.T) {
+	type flag bool
+	result := map[string]interface{}{"changed": flag(true), "failed": 0, "skipped": nil, "unreachable": 1.0, "msg": "yes"}
+	normalized, err := normalizeResultBooleans(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["changed"] != flag(true) || result["failed"] != 0 {
+		t.Errorf("Expected the original result to be left alone, got %v", result)
+	}
+	result = normalized
+	if result["changed"] != true || result["failed"] != false || result["skipped"] != false || result["unreachable"] != true {
+		t.Errorf("Expected real booleans, got %v", result)
+	}
+	if result["msg"] != "yes" {
+		t.Error("Expected other fields to be left alone")
+	}
+
+	for _, value := range []interface{}{"yes", "true", 2, []bool{true}} {
+		if _, err := normalizeResultBooleans(map[string]interface{}{"changed": value}); err == nil {
+			t.Errorf("Expected %#v to be rejected", value)
+		}
+	}
+}
+
+func TestExitJsonRejectsStringBooleans(t *testing.T) {
+	module := &AnsibleModule{Params: ModuleParams{}}
+	result := exitOutput(t, module, map[string]interface{}{"changed": "yes"})
+	if result["failed"] != true || !strings.Contains(result["msg"].(string), "changed must be a boolean") {
+		t.Errorf("Expected string changed to fail the module, got %v", result)
+	}
+}
+
+func TestReservedResultKeys(t *testing.T) {
+	module := &AnsibleModule{Params: ModuleParams{"name": "web"}}
+	facts := map[string]interface{}{"app_version": "1.2", "ansible_host": "10.0.0.1"}
+	original := map[string]interface{}{
+		"changed":         false,
+		"invocation":      "mine",
+		"_ansible_no_log": true,
+		"ansible_facts":   facts,
+	}
+	result := exitOutput(t, module, original)
+	if len(original) != 4 || original["invocation"] != "mine" || len(facts) != 2 {
+		t.Errorf("Expected the caller's result to be left alone, got %v", original)
+	}
+	if invocation, ok := result["invocation"].(map[string]interface{}); !ok || invocation["name"] != "web" {
+		t.Errorf("Expected the library invocation, got %v", result["invocation"])
+	}
+	if _, ok := result["_ansible_no_log"]; ok {
+		t.Error("Expected controller fields to be dropped")
+	}
+	if facts := result["ansible_facts"].(map[string]interface{}); facts["ansible_host"] != nil || facts["app_version"] != "1.2" {
+		t.Errorf("Expected only the connection variable to be dropped, got %v", facts)
+	}
+	if warnings, _ := result["warnings"].([]interface{}); len(warnings) != 3 {
+		t.Errorf("Expected a warning per reserved key, got %v", result["warnings"])
+	}
+
+	module = &AnsibleModule{Params: ModuleParams{}, StrictResults: true}
+	result = exitOutput(t, module, map[string]interface{}{"ansible_facts": []string{"not", "a", "dict"}})
+	if result["failed"] != true || result["error_code"] != string(CodeInvalidResult) ||
+		!strings.Contains(result["msg"].(string), "ansible_facts must be a dictionary") {
+		t.Errorf("Expected strict mode to fail the module, got %v", result)
+	}
+}
@@ -0,0 +1,81 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetrySummary describes how a RetryUntil loop ended
+type RetrySummary struct {
+	Attempts  int
+	Succeeded bool
+	LastError error
+}
+
+// AsResult returns the summary in the format attached to module results
+func (s *RetrySummary) AsResult() map[string]interface{} {
+	result := map[string]interface{}{
+		"attempts":  s.Attempts,
+		"succeeded": s.Succeeded,
+	}
+	if s.LastError != nil {
+		result["last_error"] = s.LastError.Error()
+	}
+	return result
+}
+
+// RetryUntil calls fn until it reports done without error, up to attempts times.
+// The delay between attempts is multiplied by backoff after each attempt (values
+// below 1 keep it constant). Waiting stops early if the module context is
+// cancelled. The summary is added to the module results under "retries".
+func (m *AnsibleModule) RetryUntil(attempts int, delay time.Duration, backoff float64,
+	fn func(attempt int) (bool, error)) (*RetrySummary, error) {
+
+	if attempts < 1 {
+		attempts = 1
+	}
+	if backoff < 1 {
+		backoff = 1
+	}
+
+	summary := &RetrySummary{}
+	defer m.recordRetry(summary)
+
+	ctx := m.Context()
+	for attempt := 1; attempt <= attempts; attempt++ {
+		summary.Attempts = attempt
+
+		done, err := fn(attempt)
+		summary.LastError = err
+		if done && err == nil {
+			summary.Succeeded = true
+			return summary, nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		m.writeDebugLog("DEBUG", fmt.Sprintf("attempt %d of %d not done, retrying in %s", attempt, attempts, delay))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			summary.LastError = ctx.Err()
+			return summary, fmt.Errorf("retry cancelled after %d attempts: %w", attempt, ctx.Err())
+		case <-timer.C:
+		}
+		delay = time.Duration(float64(delay) * backoff)
+	}
+
+	if summary.LastError != nil {
+		return summary, fmt.Errorf("condition not met after %d attempts: %w", summary.Attempts, summary.LastError)
+	}
+	return summary, fmt.Errorf("condition not met after %d attempts", summary.Attempts)
+}
+
+// recordRetry keeps a retry summary for the module results
+func (m *AnsibleModule) recordRetry(summary *RetrySummary) {
+	m.partialMu.Lock()
+	defer m.partialMu.Unlock()
+	m.retries = append(m.retries, summary.AsResult())
+}
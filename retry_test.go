@@ -0,0 +1,71 @@
+package ansiblemodule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryUntil(t *testing.T) {
+	module := &AnsibleModule{}
+
+	// Test success after retries with backoff
+	var calls []time.Time
+	summary, err := module.RetryUntil(5, 10*time.Millisecond, 2, func(attempt int) (bool, error) {
+		calls = append(calls, time.Now())
+		if attempt < 2 {
+			return false, fmt.Errorf("not ready")
+		}
+		return attempt == 3, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+	if summary.Attempts != 3 || !summary.Succeeded || summary.LastError != nil {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if gap := calls[2].Sub(calls[1]); gap < 20*time.Millisecond {
+		t.Errorf("Expected delay to back off to 20ms, got %v", gap)
+	}
+
+	// Test exhausted attempts
+	broken := fmt.Errorf("still broken")
+	summary, err = module.RetryUntil(2, time.Millisecond, 1, func(attempt int) (bool, error) {
+		return false, broken
+	})
+	if !errors.Is(err, broken) {
+		t.Errorf("Expected last error in failure, got %v", err)
+	}
+	if summary.Attempts != 2 || summary.Succeeded {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+
+	if len(module.retries) != 2 {
+		t.Fatalf("Expected 2 recorded retry summaries, got %d", len(module.retries))
+	}
+	if module.retries[1]["last_error"] != "still broken" || module.retries[1]["attempts"] != 2 {
+		t.Errorf("Unexpected recorded summary: %v", module.retries[1])
+	}
+}
+
+func TestRetryUntilCancelled(t *testing.T) {
+	module := &AnsibleModule{}
+	module.SetDeadline(time.Now().Add(20 * time.Millisecond))
+
+	start := time.Now()
+	summary, err := module.RetryUntil(10, time.Hour, 1, func(attempt int) (bool, error) {
+		return false, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+	if summary.Attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", summary.Attempts)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Expected retry delay to stop at the deadline")
+	}
+}
@@ -0,0 +1,67 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+)
+
+// runAsMu serializes RunAsUser, since effective ids belong to the whole process
+var runAsMu sync.Mutex
+
+// RunAsUser runs fn with the effective uid, gid and supplementary groups of the
+// named user, so files and directories it creates are owned by that user rather
+// than root. The ids are restored when fn returns. Module temp files created by
+// fn are placed in a temp directory owned by the user for the duration of the
+// call. Switching users requires root; running as the current user calls fn directly.
+func (m *AnsibleModule) RunAsUser(name string, fn func() error) error {
+	u, err := user.Lookup(name)
+	if err != nil {
+		if u, err = user.LookupId(name); err != nil {
+			return fmt.Errorf("failed to look up user %s: %w", name, err)
+		}
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return fmt.Errorf("user %s has no numeric uid", name)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return fmt.Errorf("user %s has no numeric gid", name)
+	}
+	var groups []int
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			if group, err := strconv.Atoi(id); err == nil {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	runAsMu.Lock()
+	defer runAsMu.Unlock()
+	if os.Geteuid() == uid {
+		return fn()
+	}
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("running as user %s requires root", name)
+	}
+
+	// The module temp directory is private to root, so give fn one of its own
+	userTmp, err := os.MkdirTemp("", "ansible-go-"+u.Username+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir for %s: %w", name, err)
+	}
+	defer os.RemoveAll(userTmp)
+	if err := os.Chown(userTmp, uid, gid); err != nil {
+		return fmt.Errorf("failed to create temp dir for %s: %w", name, err)
+	}
+	savedTmp := m.TmpDir
+	m.TmpDir = userTmp
+	defer func() { m.TmpDir = savedTmp }()
+
+	m.DebugMsg(fmt.Sprintf("running as user %s (uid %d, gid %d)", name, uid, gid))
+	return withCredentials(uid, gid, groups, fn)
+}
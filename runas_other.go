@@ -0,0 +1,12 @@
+//go:build !unix
+
+package ansiblemodule
+
+import (
+	"fmt"
+)
+
+// withCredentials is not supported where there are no effective user ids
+func withCredentials(uid, gid int, groups []int, fn func() error) error {
+	return fmt.Errorf("running as another user is not supported on this platform")
+}
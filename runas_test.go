@@ -0,0 +1,73 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestRunAsCurrentUser(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("Cannot look up current user: %v", err)
+	}
+	module := &AnsibleModule{Params: ModuleParams{}}
+	called := false
+	if err := module.RunAsUser(current.Username, func() error {
+		called = true
+		return nil
+	}); err != nil {
+		t.Fatalf("RunAsUser failed: %v", err)
+	}
+	if !called {
+		t.Error("Expected function to be called")
+	}
+
+	if err := module.RunAsUser("ansigo-no-such-user", func() error { return nil }); err == nil {
+		t.Error("Expected error for unknown user")
+	}
+}
+
+func TestRunAsUserDropsPrivileges(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Requires root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("No nobody user")
+	}
+	uid, _ := strconv.Atoi(nobody.Uid)
+
+	// The test temp directories must be reachable by nobody
+	dir := t.TempDir()
+	os.Chmod(filepath.Dir(dir), 0755)
+	os.Chmod(dir, 0777)
+	dest := filepath.Join(dir, "owned.txt")
+	module := &AnsibleModule{Params: ModuleParams{}, TmpDir: t.TempDir()}
+	err = module.RunAsUser("nobody", func() error {
+		if os.Geteuid() != uid {
+			t.Errorf("Expected euid %d, got %d", uid, os.Geteuid())
+		}
+		_, err := module.WriteTextFile(dest, "hello\n", 0644)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("RunAsUser failed: %v", err)
+	}
+
+	if os.Geteuid() != 0 {
+		t.Fatalf("Expected euid to be restored, got %d", os.Geteuid())
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if owner := info.Sys().(*syscall.Stat_t).Uid; int(owner) != uid {
+		t.Errorf("Expected file owned by %d, got %d", uid, owner)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// withCredentials runs fn with effective ids switched from root to uid and gid.
+// The group ids are changed first, since they cannot be changed once uid is not root.
+func withCredentials(uid, gid int, groups []int, fn func() error) (err error) {
+	savedGid := os.Getegid()
+	savedGroups, err := os.Getgroups()
+	if err != nil {
+		return fmt.Errorf("failed to read groups: %w", err)
+	}
+	if len(groups) == 0 {
+		groups = []int{gid}
+	}
+
+	if err := syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("failed to set groups: %w", err)
+	}
+	if err := syscall.Setegid(gid); err != nil {
+		syscall.Setgroups(savedGroups)
+		return fmt.Errorf("failed to set effective gid %d: %w", gid, err)
+	}
+	if err := syscall.Seteuid(uid); err != nil {
+		syscall.Setegid(savedGid)
+		syscall.Setgroups(savedGroups)
+		return fmt.Errorf("failed to set effective uid %d: %w", uid, err)
+	}
+
+	defer func() {
+		restoreErr := syscall.Seteuid(0)
+		if restoreErr == nil {
+			restoreErr = syscall.Setegid(savedGid)
+		}
+		if restoreErr == nil {
+			restoreErr = syscall.Setgroups(savedGroups)
+		}
+		if restoreErr != nil && err == nil {
+			err = fmt.Errorf("failed to restore credentials: %v", restoreErr)
+		}
+	}()
+	return fn()
+}
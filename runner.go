@@ -0,0 +1,67 @@
+package ansiblemodule
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// CommandRunner executes and locates commands for RunCommand and GetBinPath.
+// Tests can replace the module Runner to return canned results.
+type CommandRunner interface {
+	// Run executes cmd with args, returning its output and exit code. The error is
+	// only set when the command could not be run at all; a non-zero exit code is
+	// not an error. env is nil to inherit the module environment.
+	Run(ctx context.Context, cmd string, args []string, env []string, data string) (CommandResult, error)
+	// LookPath searches for an executable the way exec.LookPath does
+	LookPath(name string) (string, error)
+}
+
+// ExecRunner runs commands as child processes using os/exec
+type ExecRunner struct{}
+
+// Run executes the command as a child process killed when ctx is cancelled
+func (ExecRunner) Run(ctx context.Context, cmd string, args []string, env []string, data string) (CommandResult, error) {
+	result := CommandResult{Cmd: cmd}
+
+	command := exec.CommandContext(ctx, cmd, args...)
+	command.Env = env
+	if data != "" {
+		command.Stdin = strings.NewReader(data)
+	}
+
+	// Keep only the end of the output when the caller asked for a limit
+	limit := OutputLimitFrom(ctx)
+	stdout, stderr := newTailBuffer(limit), newTailBuffer(limit)
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	err := command.Run()
+	result.Stdout, result.StdoutBytes = stdout.String(), stdout.total
+	result.Stderr, result.StderrBytes = stderr.String(), stderr.total
+
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		result.Rc = exitCode(exitError)
+		return result, nil
+	}
+	if err != nil {
+		result.Rc = 1
+		return result, err
+	}
+	return result, nil
+}
+
+// LookPath searches PATH for the executable
+func (ExecRunner) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// commandRunner returns the module command runner, defaulting to ExecRunner
+func (m *AnsibleModule) commandRunner() CommandRunner {
+	if m.Runner == nil {
+		return ExecRunner{}
+	}
+	return m.Runner
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package ansiblemodule
+
+import (
+	"os/exec"
+)
+
+// exitCode returns the exit status of a finished command. On Windows this is the
+// full 32-bit process exit code, such as 0xC000013A for a console interrupt.
+func exitCode(exitError *exec.ExitError) int {
+	return exitError.ExitCode()
+}
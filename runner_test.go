@@ -0,0 +1,58 @@
+package ansiblemodule
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExecRunner(t *testing.T) {
+	runner := ExecRunner{}
+
+	result, err := runner.Run(context.Background(), "sh", []string{"-c", "cat; echo err >&2; exit 3"}, nil, "input")
+	if err != nil {
+		t.Fatalf("Expected non-zero exit not to be an error, got %v", err)
+	}
+	if result.Rc != 3 || result.Stdout != "input" || result.Stderr != "err\n" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if _, err := runner.Run(context.Background(), "/nonexistent/command", nil, nil, ""); err == nil {
+		t.Error("Expected error for missing command")
+	}
+}
+
+// stubRunner returns a fixed result for every command
+type stubRunner struct {
+	result CommandResult
+	argv   []string
+}
+
+func (s *stubRunner) Run(ctx context.Context, cmd string, args []string, env []string, data string) (CommandResult, error) {
+	s.argv = append([]string{cmd}, args...)
+	return s.result, nil
+}
+
+func (s *stubRunner) LookPath(name string) (string, error) {
+	return "/stub/" + name, nil
+}
+
+func TestModuleRunner(t *testing.T) {
+	runner := &stubRunner{result: CommandResult{Stdout: "stubbed", Rc: 2}}
+	module := &AnsibleModule{Runner: runner}
+
+	path, err := module.GetBinPath("tool", true)
+	if err != nil || path != "/stub/tool" {
+		t.Errorf("Expected stubbed path, got %q (%v)", path, err)
+	}
+
+	result, err := module.RunCommand(path, []string{"--flag"}, nil, "")
+	if err == nil {
+		t.Error("Expected non-zero rc to return an error")
+	}
+	if result.Rc != 2 || result.Stdout != "stubbed" || result.Cmd != "/stub/tool" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(runner.argv) != 2 || runner.argv[1] != "--flag" {
+		t.Errorf("Unexpected command line: %v", runner.argv)
+	}
+}
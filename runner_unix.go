@@ -0,0 +1,16 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// exitCode returns the exit status of a finished command, -1 if it was killed by a signal
+func exitCode(exitError *exec.ExitError) int {
+	if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
+		return status.ExitStatus()
+	}
+	return exitError.ExitCode()
+}
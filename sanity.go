@@ -0,0 +1,233 @@
+package ansiblemodule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SanityEnv names the environment variable that makes NewModule report sanity
+// findings for its argument spec instead of running the module
+const SanityEnv = "ANSIBLE_GO_SANITY"
+
+// argumentTypes are the argument types understood by validateArgument
+var argumentTypes = map[string]bool{
+	"str": true, "string": true, "bool": true, "boolean": true, "int": true, "integer": true,
+	"float": true, "list": true, "array": true, "dict": true, "map": true, "path": true,
+	"ipaddr": true, "cidr": true, "macaddr": true, "port": true, "datetime": true, "raw": true,
+}
+
+// noLogNameHints are option name fragments that usually hold secrets
+var noLogNameHints = []string{"pass", "secret", "token", "key", "credential", "pwd"}
+
+// noLogNameExceptions are option names containing a hint that are not secrets
+var noLogNameExceptions = []string{"key_file", "keyfile", "key_path", "public_key", "pubkey", "key_type",
+	"key_size", "passphrase_file", "token_file", "token_url", "update_password", "primary_key", "passive", "client_key"}
+
+// SanityFinding is a problem found in a module definition, using the codes of ansible-test validate-modules
+type SanityFinding struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Path     string `json:"path"`
+	Msg      string `json:"msg"`
+}
+
+// SanityDefinition is the module definition inspected by SanityCheck
+type SanityDefinition struct {
+	ArgSpec           ArgSpecMap
+	MutuallyExclusive [][]string
+	RequiredTogether  [][]string
+	RequiredOne       [][]string
+	RequiredIf        []RequiredIfSpec
+	RequiredBy        map[string][]string
+	SupportsCheckMode bool
+}
+
+// SanityCheck inspects a module definition the way ansible-test sanity does and
+// returns the findings sorted by path and code
+func SanityCheck(def SanityDefinition) []SanityFinding {
+	var findings []SanityFinding
+	add := func(code, severity, path, format string, args ...interface{}) {
+		findings = append(findings, SanityFinding{Code: code, Severity: severity, Path: path, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	checkArgSpec(def.ArgSpec, "argument_spec", add)
+
+	// Parameter groups must only reference known parameters
+	known := func(name string) bool {
+		_, ok := def.ArgSpec[name]
+		return ok
+	}
+	checkGroups := func(code string, groups [][]string) {
+		for i, group := range groups {
+			for _, name := range group {
+				if !known(name) {
+					add(code, "error", fmt.Sprintf("%s[%d]", strings.Split(code, "-")[0], i),
+						"%s references unknown parameter %q", strings.Split(code, "-")[0], name)
+				}
+			}
+		}
+	}
+	checkGroups("mutually_exclusive-unknown", def.MutuallyExclusive)
+	checkGroups("required_together-unknown", def.RequiredTogether)
+	checkGroups("required_one_of-unknown", def.RequiredOne)
+	for i, condition := range def.RequiredIf {
+		for _, name := range append([]string{condition.Key}, condition.Requirements...) {
+			if !known(name) {
+				add("required_if-unknown", "error", fmt.Sprintf("required_if[%d]", i),
+					"required_if references unknown parameter %q", name)
+			}
+		}
+	}
+	for key, names := range def.RequiredBy {
+		for _, name := range append([]string{key}, names...) {
+			if !known(name) {
+				add("required_by-unknown", "error", "required_by."+key,
+					"required_by references unknown parameter %q", name)
+			}
+		}
+	}
+
+	if !def.SupportsCheckMode {
+		add("check-mode-not-supported", "warning", "supports_check_mode",
+			"module does not declare check mode support")
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Code < findings[j].Code
+	})
+	return findings
+}
+
+// checkArgSpec inspects each option of an argument spec, recursing into suboptions
+func checkArgSpec(spec ArgSpecMap, path string,
+	add func(code, severity, path, format string, args ...interface{})) {
+
+	aliases := make(map[string]string)
+	for name, option := range spec {
+		optionPath := path + "." + name
+
+		if strings.HasPrefix(name, "_ansible_") {
+			add("invalid-argument-name", "error", optionPath, "option names must not start with _ansible_")
+		}
+		if option.Type != "" && !argumentTypes[option.Type] {
+			add("invalid-argument-spec", "error", optionPath, "unknown type %q", option.Type)
+		}
+		if option.Required && option.Default != nil {
+			add("no-default-for-required-parameter", "error", optionPath,
+				"option is required but has a default value")
+		}
+		isList := option.Type == "list" || option.Type == "array"
+		if isList && option.Elements == "" {
+			add("parameter-list-no-elements", "error", optionPath,
+				"option has type list but elements is not defined")
+		}
+		if option.Elements != "" && !isList {
+			add("parameter-invalid-elements", "error", optionPath,
+				"elements is only valid for options of type list")
+		}
+		if len(option.Choices) > 0 && option.Default != nil {
+			if !containsString(option.Choices, fmt.Sprint(option.Default)) {
+				add("doc-default-not-in-choices", "error", optionPath,
+					"default %v is not one of the choices", option.Default)
+			}
+		}
+		if !option.NoLog && looksSecret(name) {
+			add("no-log-needed", "error", optionPath,
+				"option name suggests it holds a secret, set NoLog or rename it")
+		}
+		if option.Description == "" {
+			add("undocumented-parameter", "warning", optionPath, "option has no description")
+		}
+
+		for _, alias := range option.Aliases {
+			if alias == name {
+				add("parameter-alias-self", "error", optionPath, "option is an alias of itself")
+				continue
+			}
+			if other, ok := aliases[alias]; ok {
+				add("parameter-alias-repeated", "error", optionPath, "alias %q is also used by %s", alias, other)
+			}
+			if _, ok := spec[alias]; ok {
+				add("parameter-alias-repeated", "error", optionPath, "alias %q is also an option name", alias)
+			}
+			aliases[alias] = name
+		}
+
+		if len(option.Options) > 0 {
+			checkArgSpec(option.Options, optionPath+".options", add)
+		}
+		if len(option.SubOptions) > 0 {
+			checkArgSpec(option.SubOptions, optionPath+".suboptions", add)
+		}
+	}
+}
+
+// looksSecret reports whether an option name suggests it holds a secret
+func looksSecret(name string) bool {
+	lower := strings.ToLower(name)
+	for _, exception := range noLogNameExceptions {
+		if strings.Contains(lower, exception) {
+			return false
+		}
+	}
+	for _, hint := range noLogNameHints {
+		if strings.Contains(lower, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// runSanity prints the sanity findings of the module definition as JSON and exits,
+// with status 1 if any finding is an error
+func (m *AnsibleModule) runSanity(supportsCheckMode bool) {
+	findings := SanityCheck(SanityDefinition{
+		ArgSpec:           m.ArgSpec,
+		MutuallyExclusive: m.MutuallyExclusive,
+		RequiredTogether:  m.RequiredTogether,
+		RequiredOne:       m.RequiredOne,
+		RequiredIf:        m.RequiredIf,
+		RequiredBy:        m.RequiredBy,
+		SupportsCheckMode: supportsCheckMode,
+	})
+
+	code := 0
+	errors := 0
+	for _, finding := range findings {
+		if finding.Severity == "error" {
+			errors++
+			code = 1
+		}
+	}
+	if findings == nil {
+		findings = []SanityFinding{}
+	}
+
+	output, _ := json.Marshal(map[string]interface{}{
+		"module":   m.moduleName(),
+		"findings": findings,
+		"errors":   errors,
+	})
+	fmt.Println(string(output))
+	if m.ExitFunc != nil {
+		m.ExitFunc(code)
+	} else {
+		os.Exit(code)
+	}
+}
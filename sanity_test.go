@@ -0,0 +1,69 @@
+package ansiblemodule
+
+import (
+	"testing"
+)
+
+func TestSanityCheck(t *testing.T) {
+	findings := SanityCheck(SanityDefinition{
+		ArgSpec: ArgSpecMap{
+			"name":     {Type: "str", Required: true, Default: "x", Description: "Name."},
+			"password": {Type: "str", Description: "Password."},
+			"api_key":  {Type: "str", NoLog: true, Description: "Key."},
+			"packages": {Type: "list", Description: "Packages."},
+			"state":    {Type: "str", Default: "on", Choices: []string{"present", "absent"}, Description: "State."},
+			"mode":     {Type: "octal", Aliases: []string{"state"}},
+			"settings": {Type: "dict", Description: "Settings.", Options: ArgSpecMap{
+				"secret": {Type: "str", Description: "Secret."},
+			}},
+		},
+		MutuallyExclusive: [][]string{{"name", "missing"}},
+		RequiredIf:        []RequiredIfSpec{{Key: "state", Value: "present", Requirements: []string{"gone"}}},
+		SupportsCheckMode: false,
+	})
+
+	expected := map[string]string{
+		"argument_spec.name":                    "no-default-for-required-parameter",
+		"argument_spec.password":                "no-log-needed",
+		"argument_spec.packages":                "parameter-list-no-elements",
+		"argument_spec.state":                   "doc-default-not-in-choices",
+		"argument_spec.settings.options.secret": "no-log-needed",
+		"mutually_exclusive[0]":                 "mutually_exclusive-unknown",
+		"required_if[0]":                        "required_if-unknown",
+		"supports_check_mode":                   "check-mode-not-supported",
+	}
+	found := make(map[string]map[string]bool)
+	for _, finding := range findings {
+		if found[finding.Path] == nil {
+			found[finding.Path] = make(map[string]bool)
+		}
+		found[finding.Path][finding.Code] = true
+	}
+	for path, code := range expected {
+		if !found[path][code] {
+			t.Errorf("Expected %s finding for %s, got %v", code, path, found[path])
+		}
+	}
+	for _, code := range []string{"invalid-argument-spec", "parameter-alias-repeated", "undocumented-parameter"} {
+		if !found["argument_spec.mode"][code] {
+			t.Errorf("Expected %s finding for mode, got %v", code, found["argument_spec.mode"])
+		}
+	}
+	if len(found["argument_spec.api_key"]) != 0 {
+		t.Errorf("Expected no findings for api_key, got %v", found["argument_spec.api_key"])
+	}
+}
+
+func TestRunSanity(t *testing.T) {
+	module := &AnsibleModule{ArgSpec: ArgSpecMap{"name": {Type: "str", Description: "Name."}}}
+	exitCode := -1
+	module.ExitFunc = func(code int) { exitCode = code }
+
+	parsed := captureResult(t, func() { module.runSanity(true) })
+	if parsed["errors"] != float64(0) || len(parsed["findings"].([]interface{})) != 0 {
+		t.Errorf("Expected clean sanity result, got %v", parsed)
+	}
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d", exitCode)
+	}
+}
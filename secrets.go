@@ -0,0 +1,87 @@
+package ansiblemodule
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SecretHashIterations is the PBKDF2 iteration count used by HashSecret
+var SecretHashIterations = 600000
+
+// secretHashPrefix identifies hashes produced by HashSecret
+const secretHashPrefix = "pbkdf2-sha256"
+
+// SecretsEqual compares two secrets in constant time. Both are hashed first,
+// so the time taken does not reveal their lengths either.
+func SecretsEqual(a, b string) bool {
+	hashA := sha256.Sum256([]byte(a))
+	hashB := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(hashA[:], hashB[:]) == 1
+}
+
+// HashSecret returns a salted PBKDF2-SHA256 hash of a secret, in the form
+// pbkdf2-sha256$iterations$salt$hash, suitable for storing and comparing later
+func HashSecret(secret string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return hashSecretWithSalt(secret, salt, SecretHashIterations)
+}
+
+// hashSecretWithSalt derives the hash string for a salt and iteration count
+func hashSecretWithSalt(secret string, salt []byte, iterations int) (string, error) {
+	key, err := pbkdf2.Key(sha256.New, secret, salt, iterations, sha256.Size)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash secret: %w", err)
+	}
+	encoding := base64.RawStdEncoding
+	return fmt.Sprintf("%s$%d$%s$%s", secretHashPrefix, iterations,
+		encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+// VerifySecret reports whether a secret matches a hash from HashSecret, comparing in constant time
+func VerifySecret(secret, hashed string) (bool, error) {
+	fields := strings.Split(hashed, "$")
+	if len(fields) != 4 || fields[0] != secretHashPrefix {
+		return false, fmt.Errorf("unsupported secret hash format")
+	}
+	iterations, err := strconv.Atoi(fields[1])
+	if err != nil || iterations < 1 {
+		return false, fmt.Errorf("invalid iteration count %q", fields[1])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return false, fmt.Errorf("invalid salt: %w", err)
+	}
+	expected, err := hashSecretWithSalt(secret, salt, iterations)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(hashed)) == 1, nil
+}
+
+// SecretDigest returns a fingerprint of a secret that can be shown in results
+// and diffs in place of the value. Digests are keyed with a random key held by
+// the module, so equal secrets give equal digests within one run but the
+// digest cannot be used to guess the secret offline.
+func (m *AnsibleModule) SecretDigest(secret string) string {
+	m.noLogMu.Lock()
+	if m.digestKey == nil {
+		m.digestKey = make([]byte, 32)
+		rand.Read(m.digestKey)
+	}
+	key := m.digestKey
+	m.noLogMu.Unlock()
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(secret))
+	return fmt.Sprintf("hmac-sha256:%x", mac.Sum(nil)[:16])
+}
@@ -0,0 +1,57 @@
+package ansiblemodule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSecretsEqual(t *testing.T) {
+	if !SecretsEqual("hunter2", "hunter2") {
+		t.Error("Expected equal secrets to match")
+	}
+	if SecretsEqual("hunter2", "hunter3") || SecretsEqual("hunter2", "hunter22") {
+		t.Error("Expected different secrets not to match")
+	}
+}
+
+func TestHashSecret(t *testing.T) {
+	oldIterations := SecretHashIterations
+	SecretHashIterations = 1000
+	defer func() { SecretHashIterations = oldIterations }()
+
+	first, err := HashSecret("s3cret")
+	if err != nil {
+		t.Fatalf("HashSecret failed: %v", err)
+	}
+	second, _ := HashSecret("s3cret")
+	if first == second {
+		t.Error("Expected salted hashes of the same secret to differ")
+	}
+	if !strings.HasPrefix(first, "pbkdf2-sha256$1000$") || strings.Contains(first, "s3cret") {
+		t.Errorf("Unexpected hash format: %s", first)
+	}
+
+	if ok, err := VerifySecret("s3cret", first); err != nil || !ok {
+		t.Errorf("Expected secret to verify, got %v, %v", ok, err)
+	}
+	if ok, _ := VerifySecret("wrong", first); ok {
+		t.Error("Expected wrong secret not to verify")
+	}
+	if _, err := VerifySecret("s3cret", "$6$salt$hash"); err == nil {
+		t.Error("Expected error for unsupported hash format")
+	}
+}
+
+func TestSecretDigest(t *testing.T) {
+	module := &AnsibleModule{}
+	digest := module.SecretDigest("s3cret")
+	if digest != module.SecretDigest("s3cret") {
+		t.Error("Expected stable digest within a module")
+	}
+	if digest == module.SecretDigest("other") {
+		t.Error("Expected different secrets to have different digests")
+	}
+	if (&AnsibleModule{}).SecretDigest("s3cret") == digest {
+		t.Error("Expected digests to be keyed per module")
+	}
+}
@@ -0,0 +1,105 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SELinuxStatus describes the SELinux state of the host
+type SELinuxStatus struct {
+	Enabled       bool
+	Mode          string // enforcing, permissive or disabled
+	ConfigMode    string // Mode configured in /etc/selinux/config
+	Policy        string // Policy type, e.g. targeted
+	PolicyVersion string
+}
+
+// AppArmorStatus describes the AppArmor state of the host
+type AppArmorStatus struct {
+	Enabled        bool
+	ProfilesLoaded int
+	EnforceCount   int
+	ComplainCount  int
+}
+
+// Locations of the security module interfaces
+var (
+	selinuxFSPath        = "/sys/fs/selinux"
+	selinuxConfigPath    = "/etc/selinux/config"
+	apparmorEnabledPath  = "/sys/module/apparmor/parameters/enabled"
+	apparmorProfilesPath = "/sys/kernel/security/apparmor/profiles"
+)
+
+// SELinuxStatus returns whether SELinux is enabled and its current mode and policy
+func (m *AnsibleModule) SELinuxStatus() (*SELinuxStatus, error) {
+	status := &SELinuxStatus{Mode: "disabled"}
+
+	// Configured values are reported even when SELinux is disabled at runtime
+	if config, err := parseKeyValueFile(selinuxConfigPath); err == nil {
+		status.ConfigMode = strings.ToLower(config["SELINUX"])
+		status.Policy = config["SELINUXTYPE"]
+	}
+
+	enforce, err := os.ReadFile(filepath.Join(selinuxFSPath, "enforce"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return status, nil
+		}
+		return nil, err
+	}
+
+	status.Enabled = true
+	if strings.TrimSpace(string(enforce)) == "1" {
+		status.Mode = "enforcing"
+	} else {
+		status.Mode = "permissive"
+	}
+	if version, err := os.ReadFile(filepath.Join(selinuxFSPath, "policyvers")); err == nil {
+		status.PolicyVersion = strings.TrimSpace(string(version))
+	}
+
+	return status, nil
+}
+
+// AppArmorStatus returns whether AppArmor is enabled and how many profiles are loaded
+func (m *AnsibleModule) AppArmorStatus() (*AppArmorStatus, error) {
+	status := &AppArmorStatus{}
+
+	enabled, err := os.ReadFile(apparmorEnabledPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return status, nil
+		}
+		return nil, err
+	}
+	status.Enabled = strings.TrimSpace(string(enabled)) == "Y"
+	if !status.Enabled {
+		return status, nil
+	}
+
+	// The profile list is only readable by root, so counts are best effort
+	file, err := os.Open(apparmorProfilesPath)
+	if err != nil {
+		return status, nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		status.ProfilesLoaded++
+		switch {
+		case strings.HasSuffix(line, "(enforce)"):
+			status.EnforceCount++
+		case strings.HasSuffix(line, "(complain)"):
+			status.ComplainCount++
+		}
+	}
+
+	return status, scanner.Err()
+}
@@ -0,0 +1,82 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSELinuxStatus(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldFS, oldConfig := selinuxFSPath, selinuxConfigPath
+	selinuxFSPath = filepath.Join(tmpDir, "selinux")
+	selinuxConfigPath = filepath.Join(tmpDir, "config")
+	defer func() { selinuxFSPath, selinuxConfigPath = oldFS, oldConfig }()
+
+	os.WriteFile(selinuxConfigPath, []byte("SELINUX=enforcing\nSELINUXTYPE=targeted\n"), 0644)
+
+	// Test disabled at runtime
+	status, err := module.SELinuxStatus()
+	if err != nil {
+		t.Fatalf("Failed to get SELinux status: %v", err)
+	}
+	if status.Enabled || status.Mode != "disabled" {
+		t.Errorf("Expected SELinux to be disabled, got %+v", status)
+	}
+	if status.ConfigMode != "enforcing" || status.Policy != "targeted" {
+		t.Errorf("Expected configured values to be read, got %+v", status)
+	}
+
+	// Test permissive mode
+	os.MkdirAll(selinuxFSPath, 0755)
+	os.WriteFile(filepath.Join(selinuxFSPath, "enforce"), []byte("0"), 0644)
+	os.WriteFile(filepath.Join(selinuxFSPath, "policyvers"), []byte("33\n"), 0644)
+	status, err = module.SELinuxStatus()
+	if err != nil {
+		t.Fatalf("Failed to get SELinux status: %v", err)
+	}
+	if !status.Enabled || status.Mode != "permissive" || status.PolicyVersion != "33" {
+		t.Errorf("Expected SELinux to be permissive, got %+v", status)
+	}
+}
+
+func TestAppArmorStatus(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldEnabled, oldProfiles := apparmorEnabledPath, apparmorProfilesPath
+	apparmorEnabledPath = filepath.Join(tmpDir, "enabled")
+	apparmorProfilesPath = filepath.Join(tmpDir, "profiles")
+	defer func() { apparmorEnabledPath, apparmorProfilesPath = oldEnabled, oldProfiles }()
+
+	// Test AppArmor not available
+	status, err := module.AppArmorStatus()
+	if err != nil {
+		t.Fatalf("Failed to get AppArmor status: %v", err)
+	}
+	if status.Enabled {
+		t.Error("Expected AppArmor to be disabled")
+	}
+
+	os.WriteFile(apparmorEnabledPath, []byte("Y\n"), 0644)
+	os.WriteFile(apparmorProfilesPath, []byte("/usr/sbin/ntpd (enforce)\nnvidia_modprobe (complain)\n/usr/bin/man (enforce)\n"), 0644)
+	status, err = module.AppArmorStatus()
+	if err != nil {
+		t.Fatalf("Failed to get AppArmor status: %v", err)
+	}
+	if !status.Enabled || status.ProfilesLoaded != 3 || status.EnforceCount != 2 || status.ComplainCount != 1 {
+		t.Errorf("Unexpected AppArmor status: %+v", status)
+	}
+}
@@ -0,0 +1,240 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Service states accepted by EnsureService
+const (
+	ServiceStarted   = "started"
+	ServiceStopped   = "stopped"
+	ServiceRestarted = "restarted"
+	ServiceReloaded  = "reloaded"
+)
+
+// serviceManagerMarkers are paths whose presence identifies the running service
+// manager, checked in order
+var serviceManagerMarkers = []struct {
+	path    string
+	manager string
+}{
+	{"/run/systemd/system", "systemd"},
+	{"/run/openrc", "openrc"},
+	{"/sbin/openrc-run", "openrc"},
+	{"/etc/init.d", "sysvinit"},
+}
+
+// sysvRunlevelDirs are the globs of runlevel directories holding sysvinit start links
+var sysvRunlevelDirs = []string{"/etc/rc[2345].d", "/etc/rc.d/rc[2345].d"}
+
+// ServiceManager detects the service manager of the host: systemd, openrc or sysvinit
+func (m *AnsibleModule) ServiceManager() (string, error) {
+	for _, marker := range serviceManagerMarkers {
+		if _, err := m.fs().Stat(marker.path); err == nil {
+			return marker.manager, nil
+		}
+	}
+	return "", fmt.Errorf("no supported service manager found")
+}
+
+// serviceBackend drives one service manager
+type serviceBackend interface {
+	isRunning(name string) (bool, error)
+	isEnabled(name string) (enabled, toggleable bool, err error)
+	control(name, action string) error
+	setEnabled(name string, enabled bool) error
+}
+
+// EnsureService brings a service to a state (started, stopped, restarted or
+// reloaded, or empty to leave it) and, unless enabled is nil, enables or
+// disables it at boot. It reports whether anything changed and makes no
+// changes in check mode.
+func (m *AnsibleModule) EnsureService(name, state string, enabled *bool) (bool, error) {
+	manager, err := m.ServiceManager()
+	if err != nil {
+		return false, err
+	}
+	var backend serviceBackend
+	switch manager {
+	case "systemd":
+		backend = systemdService{m}
+	case "openrc":
+		backend = openrcService{m}
+	default:
+		backend = sysvService{m}
+	}
+
+	changed := false
+	var action string
+	switch state {
+	case "":
+	case ServiceStarted, ServiceStopped:
+		running, err := backend.isRunning(name)
+		if err != nil {
+			return false, err
+		}
+		if running && state == ServiceStopped {
+			action = "stop"
+		} else if !running && state == ServiceStarted {
+			action = "start"
+		}
+	case ServiceRestarted:
+		action = "restart"
+	case ServiceReloaded:
+		action = "reload"
+	default:
+		return false, fmt.Errorf("invalid service state %s", state)
+	}
+	if action != "" {
+		changed = true
+		if !m.CheckMode {
+			if err := backend.control(name, action); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if enabled != nil {
+		// Units that cannot be toggled, such as static systemd units, are left alone
+		current, toggleable, err := backend.isEnabled(name)
+		if err != nil {
+			return changed, err
+		}
+		if toggleable && current != *enabled {
+			changed = true
+			if !m.CheckMode {
+				if err := backend.setEnabled(name, *enabled); err != nil {
+					return changed, err
+				}
+			}
+		}
+	}
+	return changed, nil
+}
+
+// runService runs a service tool, returning an error with its output if it fails
+func (m *AnsibleModule) runService(tool string, args ...string) error {
+	path, err := m.GetBinPath(tool, true)
+	if err != nil {
+		return err
+	}
+	result, err := m.RunCommand(path, args, nil, "")
+	if err != nil {
+		return commandFailure(err, result, "%s %s failed", tool, strings.Join(args, " "))
+	}
+	return nil
+}
+
+// systemdService manages services with systemctl
+type systemdService struct{ m *AnsibleModule }
+
+func (s systemdService) isRunning(name string) (bool, error) {
+	return s.m.SystemdIsActive(name)
+}
+
+func (s systemdService) isEnabled(name string) (bool, bool, error) {
+	state, err := s.m.SystemdEnabledState(name)
+	if err != nil {
+		return false, false, err
+	}
+	return systemdEnabled(state), !slices.Contains(systemdFixedStates, state), nil
+}
+
+func (s systemdService) control(name, action string) error {
+	return s.m.systemdChange(action, name)
+}
+
+func (s systemdService) setEnabled(name string, enabled bool) error {
+	if enabled {
+		return s.m.systemdChange("enable", name)
+	}
+	return s.m.systemdChange("disable", name)
+}
+
+// openrcService manages services with rc-service and rc-update in the default runlevel
+type openrcService struct{ m *AnsibleModule }
+
+func (s openrcService) isRunning(name string) (bool, error) {
+	path, err := s.m.GetBinPath("rc-service", true)
+	if err != nil {
+		return false, err
+	}
+	// status exits non-zero for stopped services
+	result, _ := s.m.RunCommand(path, []string{name, "status"}, nil, "")
+	return result.Rc == 0, nil
+}
+
+func (s openrcService) isEnabled(name string) (bool, bool, error) {
+	path, err := s.m.GetBinPath("rc-update", true)
+	if err != nil {
+		return false, false, err
+	}
+	result, err := s.m.RunCommand(path, []string{"show", "default"}, nil, "")
+	if err != nil {
+		return false, false, commandFailure(err, result, "rc-update show failed")
+	}
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		service, _, _ := strings.Cut(line, "|")
+		if strings.TrimSpace(service) == name {
+			return true, true, nil
+		}
+	}
+	return false, true, nil
+}
+
+func (s openrcService) control(name, action string) error {
+	return s.m.runService("rc-service", name, action)
+}
+
+func (s openrcService) setEnabled(name string, enabled bool) error {
+	if enabled {
+		return s.m.runService("rc-update", "add", name, "default")
+	}
+	return s.m.runService("rc-update", "del", name, "default")
+}
+
+// sysvService manages init scripts with service and update-rc.d or chkconfig
+type sysvService struct{ m *AnsibleModule }
+
+func (s sysvService) isRunning(name string) (bool, error) {
+	path, err := s.m.GetBinPath("service", true)
+	if err != nil {
+		return false, err
+	}
+	// status exits non-zero for stopped services
+	result, _ := s.m.RunCommand(path, []string{name, "status"}, nil, "")
+	return result.Rc == 0, nil
+}
+
+func (s sysvService) isEnabled(name string) (bool, bool, error) {
+	for _, dirs := range sysvRunlevelDirs {
+		links, err := filepath.Glob(filepath.Join(dirs, "S[0-9][0-9]"+name))
+		if err != nil {
+			return false, false, err
+		}
+		if len(links) > 0 {
+			return true, true, nil
+		}
+	}
+	return false, true, nil
+}
+
+func (s sysvService) control(name, action string) error {
+	return s.m.runService("service", name, action)
+}
+
+func (s sysvService) setEnabled(name string, enabled bool) error {
+	if path, _ := s.m.GetBinPath("update-rc.d", false); path != "" {
+		if enabled {
+			return s.m.runService("update-rc.d", name, "defaults")
+		}
+		return s.m.runService("update-rc.d", "-f", name, "remove")
+	}
+	if enabled {
+		return s.m.runService("chkconfig", name, "on")
+	}
+	return s.m.runService("chkconfig", name, "off")
+}
@@ -0,0 +1,95 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeServiceManager makes the service manager detection find manager
+func fakeServiceManager(t *testing.T, manager string) {
+	marker := t.TempDir()
+	old := serviceManagerMarkers
+	serviceManagerMarkers = append(serviceManagerMarkers[:0:0], struct {
+		path    string
+		manager string
+	}{marker, manager})
+	t.Cleanup(func() { serviceManagerMarkers = old })
+}
+
+func TestEnsureServiceSystemd(t *testing.T) {
+	log := fakeSystemctl(t)
+	fakeServiceManager(t, "systemd")
+	module := &AnsibleModule{}
+
+	enabled := true
+	changed, err := module.EnsureService("running.service", ServiceStarted, &enabled)
+	if err != nil || !changed {
+		t.Fatalf("Expected enabling to change, got %v (%v)", changed, err)
+	}
+	calls, _ := os.ReadFile(log)
+	if strings.Contains(string(calls), "start running.service") || !strings.Contains(string(calls), "enable running.service") {
+		t.Errorf("Expected only enable to run, got:\n%s", calls)
+	}
+
+	changed, err = module.EnsureService("enabled.service", "", &enabled)
+	if err != nil || changed {
+		t.Errorf("Expected enabled service to be unchanged, got %v (%v)", changed, err)
+	}
+
+	// Static units cannot be toggled, so neither setting changes them
+	disabled := false
+	os.Remove(log)
+	for _, want := range []*bool{&enabled, &disabled} {
+		changed, err = module.EnsureService("static.service", "", want)
+		if err != nil || changed {
+			t.Errorf("Expected static service to be unchanged, got %v (%v)", changed, err)
+		}
+	}
+	if calls, _ := os.ReadFile(log); strings.Contains(string(calls), "able static.service") {
+		t.Errorf("Expected static service not to be toggled, got:\n%s", calls)
+	}
+
+	module.CheckMode = true
+	os.Remove(log)
+	changed, err = module.EnsureService("stopped.service", ServiceStarted, nil)
+	if err != nil || !changed {
+		t.Errorf("Expected check mode start to report a change, got %v (%v)", changed, err)
+	}
+	if calls, _ := os.ReadFile(log); strings.Contains(string(calls), "start") {
+		t.Errorf("Expected check mode not to start, got:\n%s", calls)
+	}
+
+	if _, err := module.EnsureService("stopped.service", "paused", nil); err == nil {
+		t.Error("Expected invalid state to fail")
+	}
+}
+
+func TestEnsureServiceSysv(t *testing.T) {
+	fakeServiceManager(t, "sysvinit")
+	rcDir := filepath.Join(t.TempDir(), "rc3.d")
+	os.Mkdir(rcDir, 0755)
+	os.WriteFile(filepath.Join(rcDir, "S20ssh"), nil, 0644)
+	old := sysvRunlevelDirs
+	sysvRunlevelDirs = []string{rcDir}
+	defer func() { sysvRunlevelDirs = old }()
+
+	runner := &stubRunner{}
+	module := &AnsibleModule{Runner: runner}
+
+	enabled := true
+	changed, err := module.EnsureService("ssh", "", &enabled)
+	if err != nil || changed {
+		t.Errorf("Expected linked service to be enabled, got %v (%v)", changed, err)
+	}
+
+	enabled = false
+	changed, err = module.EnsureService("ssh", ServiceRestarted, &enabled)
+	if err != nil || !changed {
+		t.Fatalf("Expected restart and disable to change, got %v (%v)", changed, err)
+	}
+	if strings.Join(runner.argv, " ") != "/stub/update-rc.d -f ssh remove" {
+		t.Errorf("Expected update-rc.d removal, got %v", runner.argv)
+	}
+}
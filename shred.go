@@ -0,0 +1,59 @@
+package ansiblemodule
+
+import (
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ShredFile overwrites a file with random data, flushes it to disk and removes
+// it, for files that held secrets. This is best effort: copy-on-write
+// filesystems, journals and SSD wear levelling may keep the old content.
+func (m *AnsibleModule) ShredFile(path string) error {
+	defer m.auditFile("remove_file", path)()
+	m.overwriteFile(path)
+	return m.fs().Remove(path)
+}
+
+// overwriteFile replaces the content of a regular file with random data in
+// place, ignoring errors
+func (m *AnsibleModule) overwriteFile(path string) {
+	info, err := m.fs().Lstat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+		return
+	}
+	file, err := m.fs().OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if _, err := io.CopyN(file, rand.Reader, info.Size()); err == nil {
+		if syncer, ok := file.(interface{ Sync() error }); ok {
+			syncer.Sync()
+		}
+	}
+}
+
+// shredTree overwrites every regular file below dir, ignoring errors
+func (m *AnsibleModule) shredTree(dir string) {
+	entries, _ := m.fs().ReadDir(dir)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.IsDir():
+			m.shredTree(path)
+		case entry.Type().IsRegular():
+			m.overwriteFile(path)
+		}
+	}
+}
+
+// removeTmpTree removes a temp directory from the module file system, first
+// overwriting the files in it when ShredTmp is set
+func (m *AnsibleModule) removeTmpTree(dir string) error {
+	if m.ShredTmp {
+		m.shredTree(dir)
+	}
+	return m.fs().RemoveAll(dir)
+}
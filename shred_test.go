@@ -0,0 +1,34 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShredTmp(t *testing.T) {
+	module := &AnsibleModule{TmpDir: filepath.Join(t.TempDir(), "tmp"), ShredTmp: true}
+	os.MkdirAll(module.TmpDir, 0700)
+	secret := filepath.Join(module.TmpDir, "token")
+	os.WriteFile(secret, []byte("s3cret-value"), 0600)
+
+	// A second link to the file shows what was left in it
+	witness := filepath.Join(t.TempDir(), "witness")
+	if err := os.Link(secret, witness); err != nil {
+		t.Skipf("Hard links not supported: %v", err)
+	}
+	module.Cleanup()
+
+	if _, err := os.Stat(module.TmpDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the temp directory to be removed, got %v", err)
+	}
+	content, _ := os.ReadFile(witness)
+	if len(content) != len("s3cret-value") || string(content) == "s3cret-value" {
+		t.Errorf("Expected the content to be overwritten, got %q", content)
+	}
+
+	os.WriteFile(witness, []byte("key"), 0600)
+	if err := module.ShredFile(witness); err != nil || module.FileExists(witness) {
+		t.Errorf("Expected ShredFile to remove the file: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package ansiblemodule
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleSignals controls whether NewModule installs SIGINT/SIGTERM handling
+var HandleSignals = true
+
+// interruptSignals are the signals that abort a running module
+var interruptSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// handleSignals cancels the module context and fails cleanly when the module is interrupted
+func (m *AnsibleModule) handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, interruptSignals...)
+
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		m.interrupt(sig)
+	}()
+}
+
+// interrupt stops pending work and reports the failure. Temporary files are
+// removed on exit, not while the module may still be writing to them.
+func (m *AnsibleModule) interrupt(sig os.Signal) {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.writeDebugLog("DEBUG", "module interrupted by "+sig.String())
+	m.FailJson("module interrupted", map[string]interface{}{"signal": sig.String()})
+}
@@ -0,0 +1,48 @@
+package ansiblemodule
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestInterrupt(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	module := &AnsibleModule{Params: ModuleParams{}, remoteTmp: tmpDir}
+	module.ctx, module.cancel = context.WithCancel(context.Background())
+	moduleTmp, err := module.GetTmpDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exitCode := -1
+	module.ExitFunc = func(code int) { exitCode = code }
+
+	parsed := captureResult(t, func() { module.interrupt(syscall.SIGTERM) })
+	if parsed["failed"] != true || parsed["msg"] != "module interrupted" {
+		t.Errorf("Expected module interrupted failure, got %v", parsed)
+	}
+	if parsed["signal"] != syscall.SIGTERM.String() {
+		t.Errorf("Expected signal %s, got %v", syscall.SIGTERM, parsed["signal"])
+	}
+	if exitCode != 0 {
+		t.Errorf("Expected exit function to be called, got %d", exitCode)
+	}
+	if module.ctx.Err() == nil {
+		t.Error("Expected module context to be cancelled")
+	}
+	if _, err := os.Stat(moduleTmp); !os.IsNotExist(err) {
+		t.Error("Expected the module temp directory to be removed on exit")
+	}
+
+	// The module finishing after the interrupt does not write a second result
+	if output := captureStdout(t, func() { module.ExitJson(map[string]interface{}{"changed": true}) }); output != "" {
+		t.Errorf("Expected no output after the module exited, got %q", output)
+	}
+}
@@ -0,0 +1,188 @@
+package ansiblemodule
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// VerifySignature checks the OpenPGP signature of a file against the keys of a
+// keyring with gpgv, or gpg when gpgv is not installed, and returns the
+// fingerprint of the signing key. sigFile is the detached signature, or empty
+// when file carries its own signature. The keyring must be a binary (not
+// ASCII-armored) keyring or keybox, such as one written by gpg --dearmor.
+func (m *AnsibleModule) VerifySignature(file, sigFile, keyring string) (string, error) {
+	keyring, err := filepath.Abs(keyring)
+	if err != nil {
+		return "", err
+	}
+	if !m.FileExists(keyring) {
+		return "", notFound(fmt.Errorf("keyring %s does not exist", keyring))
+	}
+
+	var args []string
+	tool, err := m.GetBinPath("gpgv", false)
+	if err != nil || tool == "" {
+		if tool, err = m.GetBinPath("gpg", true); err != nil {
+			return "", err
+		}
+		args = []string{"--batch", "--no-default-keyring", "--no-auto-key-retrieve", "--verify"}
+	}
+	args = append([]string{"--status-fd", "1", "--keyring", keyring}, args...)
+	if sigFile != "" {
+		args = append(args, sigFile)
+	}
+	args = append(args, file)
+
+	result, runErr := m.RunCommand(tool, args, map[string]string{"LC_ALL": "C"}, "")
+	fingerprint, status := parseGPGStatus(result.Stdout)
+	if runErr != nil || fingerprint == "" {
+		if status == "" {
+			status = strings.TrimSpace(result.Stderr)
+		}
+		return "", fmt.Errorf("signature verification of %s failed: %s", file, status)
+	}
+	return fingerprint, nil
+}
+
+// parseGPGStatus reads gpg --status-fd output, returning the fingerprint of a
+// valid signature, or else the status describing why verification failed
+func parseGPGStatus(output string) (string, string) {
+	fingerprint, status := "", ""
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(strings.TrimPrefix(scanner.Text(), "[GNUPG:] "))
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "VALIDSIG":
+			// The primary key fingerprint follows the signing subkey and signature fields
+			if len(fields) > 10 {
+				fingerprint = fields[10]
+			} else if len(fields) > 1 {
+				fingerprint = fields[1]
+			}
+		case "BADSIG":
+			status = "bad signature"
+		case "ERRSIG", "NO_PUBKEY":
+			status = "signing key not found in keyring"
+		case "EXPKEYSIG":
+			status = "signing key has expired"
+		case "REVKEYSIG":
+			status = "signing key has been revoked"
+		case "NODATA":
+			status = "no signature found"
+		}
+	}
+	if status != "" {
+		fingerprint = ""
+	}
+	return fingerprint, status
+}
+
+// bsdChecksumLine matches the BSD style lines written by sha256sum --tag
+var bsdChecksumLine = regexp.MustCompile(`^([A-Za-z0-9-]+) ?\((.*)\) ?= ?([0-9a-fA-F]+)$`)
+
+// ChecksumEntry is a line of a checksum file
+type ChecksumEntry struct {
+	Name      string
+	Digest    string // Lowercase hex
+	Algorithm string // Set for BSD style lines, empty otherwise
+}
+
+// ParseChecksumFile parses checksum files such as SHA256SUMS, in the GNU format
+// of sha256sum ("digest  name", or "digest *name" in binary mode) or the BSD
+// format of sha256sum --tag ("SHA256 (name) = digest"). Blank lines and
+// comments are skipped.
+func ParseChecksumFile(content string) ([]ChecksumEntry, error) {
+	var entries []ChecksumEntry
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match := bsdChecksumLine.FindStringSubmatch(line); match != nil {
+			entries = append(entries, ChecksumEntry{
+				Name:      match[2],
+				Digest:    strings.ToLower(match[3]),
+				Algorithm: strings.ReplaceAll(strings.ToLower(match[1]), "-", ""),
+			})
+			continue
+		}
+
+		// A leading backslash marks a name with escaped newlines or backslashes
+		escaped := strings.HasPrefix(line, "\\")
+		line = strings.TrimPrefix(line, "\\")
+		digest, name, ok := strings.Cut(line, " ")
+		if !ok || !isHexDigest(digest) || name == "" {
+			return nil, fmt.Errorf("invalid checksum line %d: %s", i+1, line)
+		}
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+		if escaped {
+			name = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r").Replace(name)
+		}
+		entries = append(entries, ChecksumEntry{Name: name, Digest: strings.ToLower(digest)})
+	}
+	return entries, nil
+}
+
+// isHexDigest reports whether s is a non-empty hex string
+func isHexDigest(s string) bool {
+	if s == "" || len(s)%2 != 0 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// checksumAlgorithmsByLength guesses the algorithm of a GNU style checksum line
+var checksumAlgorithmsByLength = map[int]string{32: "md5", 40: "sha1", 56: "sha224", 64: "sha256", 96: "sha384", 128: "sha512"}
+
+// VerifyChecksumFile checks a file against its entry in a checksum file such as
+// SHA256SUMS. The entry is found by the name of the file, with or without its
+// directory. algorithm may be empty to use the one named by a BSD style line or
+// implied by the digest length.
+func (m *AnsibleModule) VerifyChecksumFile(path, sumsFile, algorithm string) error {
+	content, err := m.ReadTextFile(sumsFile)
+	if err != nil {
+		return err
+	}
+	entries, err := ParseChecksumFile(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sumsFile, err)
+	}
+
+	base := filepath.Base(path)
+	for _, entry := range entries {
+		name := strings.TrimPrefix(filepath.ToSlash(entry.Name), "./")
+		if name != filepath.ToSlash(path) && name != base {
+			continue
+		}
+		if entry.Algorithm != "" && algorithm != "" && entry.Algorithm != algorithm {
+			continue
+		}
+		entryAlgorithm := algorithm
+		if entryAlgorithm == "" {
+			entryAlgorithm = entry.Algorithm
+		}
+		if entryAlgorithm == "" {
+			entryAlgorithm = checksumAlgorithmsByLength[len(entry.Digest)]
+		}
+		digest, err := m.Checksum(path, entryAlgorithm)
+		if err != nil {
+			return err
+		}
+		if digest != entry.Digest {
+			return fmt.Errorf("%s checksum mismatch for %s: expected %s, got %s", entryAlgorithm, path, entry.Digest, digest)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s has no checksum for %s", sumsFile, base)
+}
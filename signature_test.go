@@ -0,0 +1,82 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	dir := t.TempDir()
+	keyring := filepath.Join(dir, "vendor.gpg")
+	os.WriteFile(keyring, []byte("keyring"), 0644)
+	runner := &stubRunner{result: CommandResult{Stdout: "[GNUPG:] NEWSIG\n" +
+		"[GNUPG:] GOODSIG 1122334455667788 Vendor <release@example.com>\n" +
+		"[GNUPG:] VALIDSIG AAAA1111 2024-01-01 1704067200 0 4 0 1 10 00 BBBB2222\n"}}
+	module := &AnsibleModule{Runner: runner}
+
+	fingerprint, err := module.VerifySignature("app.tar.gz", "app.tar.gz.sig", keyring)
+	if err != nil || fingerprint != "BBBB2222" {
+		t.Errorf("Expected the primary key fingerprint, got %q (%v)", fingerprint, err)
+	}
+	expected := "/stub/gpgv --status-fd 1 --keyring " + keyring + " app.tar.gz.sig app.tar.gz"
+	if strings.Join(runner.argv, " ") != expected {
+		t.Errorf("Unexpected command line: %v", runner.argv)
+	}
+
+	runner.result = CommandResult{Stdout: "[GNUPG:] BADSIG 1122334455667788 Vendor\n", Rc: 1}
+	if _, err := module.VerifySignature("app.tar.gz", "", keyring); err == nil || !strings.Contains(err.Error(), "bad signature") {
+		t.Errorf("Expected a bad signature to fail: %v", err)
+	}
+	if _, err := module.VerifySignature("app.tar.gz", "", filepath.Join(dir, "missing.gpg")); err == nil {
+		t.Error("Expected a missing keyring to fail")
+	}
+}
+
+func TestParseChecksumFile(t *testing.T) {
+	entries, err := ParseChecksumFile("# release checksums\n" +
+		"E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855  empty.txt\n" +
+		"d41d8cd98f00b204e9800998ecf8427e *bin/tool.exe\n" +
+		"\\d41d8cd98f00b204e9800998ecf8427e  odd\\nname\r\n" +
+		"SHA512 (tagged.bin) = cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %v", entries)
+	}
+	if entries[0].Name != "empty.txt" || !strings.HasPrefix(entries[0].Digest, "e3b0c442") {
+		t.Errorf("Unexpected GNU entry: %+v", entries[0])
+	}
+	if entries[1].Name != "bin/tool.exe" || entries[2].Name != "odd\nname" {
+		t.Errorf("Unexpected binary or escaped entries: %+v %+v", entries[1], entries[2])
+	}
+	if entries[3].Name != "tagged.bin" || entries[3].Algorithm != "sha512" {
+		t.Errorf("Unexpected BSD entry: %+v", entries[3])
+	}
+	if _, err := ParseChecksumFile("not a checksum line\n"); err == nil {
+		t.Error("Expected an invalid line to fail")
+	}
+}
+
+func TestVerifyChecksumFile(t *testing.T) {
+	dir := t.TempDir()
+	artifact := filepath.Join(dir, "app.tar.gz")
+	os.WriteFile(artifact, []byte("release"), 0644)
+	module := &AnsibleModule{}
+	digest, _ := module.Checksum(artifact, "sha256")
+	sums := filepath.Join(dir, "SHA256SUMS")
+	os.WriteFile(sums, []byte(digest+"  ./app.tar.gz\n"+strings.Repeat("0", 64)+"  other.tar.gz\n"), 0644)
+
+	if err := module.VerifyChecksumFile(artifact, sums, ""); err != nil {
+		t.Errorf("Expected checksum to match: %v", err)
+	}
+	os.WriteFile(artifact, []byte("tampered"), 0644)
+	if err := module.VerifyChecksumFile(artifact, sums, "sha256"); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("Expected tampered file to fail: %v", err)
+	}
+	if err := module.VerifyChecksumFile(filepath.Join(dir, "unlisted.tar.gz"), sums, ""); err == nil {
+		t.Error("Expected an unlisted file to fail")
+	}
+}
@@ -0,0 +1,399 @@
+package ansiblemodule
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AuthorizedKey is a line of an OpenSSH authorized_keys file
+type AuthorizedKey struct {
+	Options []string // Such as from="10.0.0.0/8" or no-pty, quoting kept
+	Type    string
+	Key     string // Base64 encoded public key
+	Comment string
+}
+
+// KnownHost is a line of an OpenSSH known_hosts file
+type KnownHost struct {
+	Marker  string   // @cert-authority, @revoked or empty
+	Hosts   []string // Host patterns, or a single |1|salt|hash hashed entry
+	Type    string
+	Key     string // Base64 encoded public key
+	Comment string
+}
+
+// isSSHKeyType reports whether a field names a public key algorithm
+func isSSHKeyType(field string) bool {
+	return strings.HasPrefix(field, "ssh-") || strings.HasPrefix(field, "ecdsa-sha2-") ||
+		strings.HasPrefix(field, "sk-") || strings.HasSuffix(field, "@openssh.com")
+}
+
+// parseSSHPublicKey parses the "type key [comment]" part shared by both files
+func parseSSHPublicKey(text string) (keyType, key, comment string, err error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 || !isSSHKeyType(fields[0]) {
+		return "", "", "", fmt.Errorf("no public key found")
+	}
+	if _, err := base64.StdEncoding.DecodeString(fields[1]); err != nil {
+		return "", "", "", fmt.Errorf("invalid %s key: %w", fields[0], err)
+	}
+	if len(fields) > 2 {
+		comment = strings.Join(fields[2:], " ")
+	}
+	return fields[0], fields[1], comment, nil
+}
+
+// splitSSHOptions splits an option list at unquoted commas
+func splitSSHOptions(text string) []string {
+	var options []string
+	quoted, start := false, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				options = append(options, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(options, text[start:])
+}
+
+// ParseAuthorizedKey parses an authorized_keys line. Options are kept exactly as
+// written, including quoted values holding commas or spaces.
+func ParseAuthorizedKey(line string) (AuthorizedKey, error) {
+	line = strings.TrimSpace(line)
+	if keyType, key, comment, err := parseSSHPublicKey(line); err == nil {
+		return AuthorizedKey{Type: keyType, Key: key, Comment: comment}, nil
+	}
+
+	// The options end at the first unquoted whitespace
+	quoted, end := false, len(line)
+	for i := 0; i < len(line) && end == len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ' ', '\t':
+			if !quoted {
+				end = i
+			}
+		}
+	}
+	if quoted {
+		return AuthorizedKey{}, fmt.Errorf("unterminated quote in key options")
+	}
+	keyType, key, comment, err := parseSSHPublicKey(line[end:])
+	if err != nil {
+		return AuthorizedKey{}, err
+	}
+	return AuthorizedKey{Options: splitSSHOptions(line[:end]), Type: keyType, Key: key, Comment: comment}, nil
+}
+
+// String renders the key as an authorized_keys line
+func (k AuthorizedKey) String() string {
+	line := k.Type + " " + k.Key
+	if len(k.Options) > 0 {
+		line = strings.Join(k.Options, ",") + " " + line
+	}
+	if k.Comment != "" {
+		line += " " + k.Comment
+	}
+	return line
+}
+
+// ParseKnownHost parses a known_hosts line
+func ParseKnownHost(line string) (KnownHost, error) {
+	var entry KnownHost
+	fields := strings.Fields(line)
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+		if fields[0] != "@cert-authority" && fields[0] != "@revoked" {
+			return entry, fmt.Errorf("unknown marker %s", fields[0])
+		}
+		entry.Marker, fields = fields[0], fields[1:]
+	}
+	if len(fields) < 3 {
+		return entry, fmt.Errorf("expected hosts, key type and key")
+	}
+	entry.Hosts = strings.Split(fields[0], ",")
+	var err error
+	entry.Type, entry.Key, entry.Comment, err = parseSSHPublicKey(strings.Join(fields[1:], " "))
+	return entry, err
+}
+
+// String renders the entry as a known_hosts line
+func (h KnownHost) String() string {
+	line := strings.Join(h.Hosts, ",") + " " + h.Type + " " + h.Key
+	if h.Marker != "" {
+		line = h.Marker + " " + line
+	}
+	if h.Comment != "" {
+		line += " " + h.Comment
+	}
+	return line
+}
+
+// Hashed reports whether the host names of the entry are hashed
+func (h KnownHost) Hashed() bool {
+	return len(h.Hosts) == 1 && strings.HasPrefix(h.Hosts[0], "|1|")
+}
+
+// knownHostName returns the name ssh looks up for a host and port
+func knownHostName(host string, port int) string {
+	host = strings.ToLower(host)
+	if port != 0 && port != 22 {
+		return "[" + host + "]:" + strconv.Itoa(port)
+	}
+	return host
+}
+
+// HashKnownHost returns the hashed form of a host name as ssh-keygen -H writes
+// it, for a port other than 0 or 22 the [host]:port name
+func HashKnownHost(host string, port int) (string, error) {
+	salt := make([]byte, sha1.Size)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hashKnownHostName(knownHostName(host, port), salt), nil
+}
+
+func hashKnownHostName(name string, salt []byte) string {
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(name))
+	return "|1|" + base64.StdEncoding.EncodeToString(salt) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Matches reports whether the entry applies to a host and port, checking hashed
+// names, wildcards and negated patterns like ssh does
+func (h KnownHost) Matches(host string, port int) bool {
+	name := knownHostName(host, port)
+	if h.Hashed() {
+		parts := strings.Split(h.Hosts[0], "|")
+		if len(parts) != 4 {
+			return false
+		}
+		salt, err := base64.StdEncoding.DecodeString(parts[2])
+		return err == nil && hmac.Equal([]byte(hashKnownHostName(name, salt)), []byte(h.Hosts[0]))
+	}
+	matched := false
+	for _, pattern := range h.Hosts {
+		negated := strings.HasPrefix(pattern, "!")
+		if matchHostPattern(strings.ToLower(strings.TrimPrefix(pattern, "!")), name) {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchHostPattern matches a name against a pattern with * and ? wildcards
+func matchHostPattern(pattern, name string) bool {
+	for pattern != "" {
+		switch pattern[0] {
+		case '*':
+			for i := len(name); i >= 0; i-- {
+				if matchHostPattern(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if name == "" {
+				return false
+			}
+		default:
+			if name == "" || name[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return name == ""
+}
+
+// AuthorizedKeys reads the keys of an authorized_keys file, skipping comments
+// and lines that are not keys. A missing file has no keys.
+func (m *AnsibleModule) AuthorizedKeys(path string) ([]AuthorizedKey, error) {
+	lines, err := m.sshEntryLines(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []AuthorizedKey
+	for _, line := range lines {
+		if key, err := ParseAuthorizedKey(line); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// authorizedKeyIdentity identifies an authorized_keys line by its public key
+func authorizedKeyIdentity(line string) string {
+	if key, err := ParseAuthorizedKey(line); err == nil {
+		return key.Type + " " + key.Key
+	}
+	return strings.TrimSpace(line)
+}
+
+// SetAuthorizedKeys makes an authorized_keys file hold the given keys. A key
+// already present is updated in place; when the desired key has no options the
+// options it has in the file are preserved. With exclusive, other keys are
+// removed. New files are created with mode 0600.
+func (m *AnsibleModule) SetAuthorizedKeys(path string, keys []AuthorizedKey, exclusive bool) (EntryListResult, error) {
+	existing, err := m.AuthorizedKeys(path)
+	if err != nil {
+		return EntryListResult{}, err
+	}
+	desired := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.Options == nil {
+			for _, current := range existing {
+				if current.Type == key.Type && current.Key == key.Key {
+					key.Options = current.Options
+				}
+			}
+		}
+		desired = append(desired, key.String())
+	}
+	return m.ReconcileEntries(path, desired, EntryListOptions{Key: authorizedKeyIdentity, Exclusive: exclusive, Mode: 0600})
+}
+
+// RemoveAuthorizedKeys removes the given keys from an authorized_keys file,
+// whatever their options and comments
+func (m *AnsibleModule) RemoveAuthorizedKeys(path string, keys []AuthorizedKey) (EntryListResult, error) {
+	unwanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		unwanted[key.Type+" "+key.Key] = true
+	}
+	return m.removeSSHEntries(path, func(line string) bool {
+		return unwanted[authorizedKeyIdentity(line)]
+	})
+}
+
+// KnownHosts reads the entries of a known_hosts file, skipping comments and
+// lines that are not entries. A missing file has no entries.
+func (m *AnsibleModule) KnownHosts(path string) ([]KnownHost, error) {
+	lines, err := m.sshEntryLines(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []KnownHost
+	for _, line := range lines {
+		if entry, err := ParseKnownHost(line); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// LookupKnownHost returns the entries of a known_hosts file that apply to a
+// host and port, including hashed ones
+func (m *AnsibleModule) LookupKnownHost(path, host string, port int) ([]KnownHost, error) {
+	entries, err := m.KnownHosts(path)
+	if err != nil {
+		return nil, err
+	}
+	var found []KnownHost
+	for _, entry := range entries {
+		if entry.Matches(host, port) {
+			found = append(found, entry)
+		}
+	}
+	return found, nil
+}
+
+// SetKnownHost makes a known_hosts file hold the key of a host, replacing any
+// other key of the same type and marker for that host, hashed or not. With hash,
+// a new entry is written with the host name hashed. An entry that already holds
+// the key is left alone, so hashed entries do not change on every run.
+func (m *AnsibleModule) SetKnownHost(path, host string, port int, key KnownHost, hash bool) (EntryListResult, error) {
+	existing, err := m.KnownHosts(path)
+	if err != nil {
+		return EntryListResult{}, err
+	}
+	identity := func(line string) string {
+		entry, err := ParseKnownHost(line)
+		if err == nil && entry.Type == key.Type && entry.Marker == key.Marker && entry.Matches(host, port) {
+			return "\x00target"
+		}
+		return strings.TrimSpace(line)
+	}
+
+	var desired string
+	for _, entry := range existing {
+		if entry.Key == key.Key && entry.Hashed() == hash && identity(entry.String()) == "\x00target" {
+			desired = entry.String()
+			break
+		}
+	}
+	if desired == "" {
+		key.Hosts = []string{knownHostName(host, port)}
+		if hash {
+			hashed, err := HashKnownHost(host, port)
+			if err != nil {
+				return EntryListResult{}, err
+			}
+			key.Hosts = []string{hashed}
+		}
+		desired = key.String()
+	}
+	return m.ReconcileEntries(path, []string{desired}, EntryListOptions{Key: identity})
+}
+
+// RemoveKnownHost removes the entries of a host and port from a known_hosts
+// file, only those of keyType unless it is empty
+func (m *AnsibleModule) RemoveKnownHost(path, host string, port int, keyType string) (EntryListResult, error) {
+	return m.removeSSHEntries(path, func(line string) bool {
+		entry, err := ParseKnownHost(line)
+		return err == nil && (keyType == "" || entry.Type == keyType) && entry.Matches(host, port)
+	})
+}
+
+// sshEntryLines returns the lines of a file that are not blank or comments
+func (m *AnsibleModule) sshEntryLines(path string) ([]string, error) {
+	if !m.FileExists(path) {
+		return nil, nil
+	}
+	content, err := m.ReadTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if isEntry(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// removeSSHEntries drops the entry lines matched by unwanted, keeping comments
+// and the order of the rest of the file
+func (m *AnsibleModule) removeSSHEntries(path string, unwanted func(line string) bool) (EntryListResult, error) {
+	lines, err := m.sshEntryLines(path)
+	if err != nil {
+		return EntryListResult{}, err
+	}
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !unwanted(line) {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == len(lines) {
+		return EntryListResult{}, nil
+	}
+	return m.ReconcileEntries(path, kept, EntryListOptions{Exclusive: true})
+}
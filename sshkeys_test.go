@@ -0,0 +1,109 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSSHKey = "AAAAC3NzaC1lZDI1NTE5AAAAIK454JswMHk7W4kxnmBb+7boMb9ARe819Dew+e6f4oyy"
+
+func TestParseAuthorizedKey(t *testing.T) {
+	line := `from="10.0.0.0/8,192.168.0.0/16",command="echo a b",no-pty ssh-ed25519 ` + testSSHKey + ` deploy key`
+	key, err := ParseAuthorizedKey(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key.Options) != 3 || key.Options[1] != `command="echo a b"` || key.Type != "ssh-ed25519" || key.Comment != "deploy key" {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+	if key.String() != line {
+		t.Errorf("Expected the line to render unchanged, got %s", key.String())
+	}
+	if _, err := ParseAuthorizedKey("ssh-ed25519 not-base64!"); err == nil {
+		t.Error("Expected an invalid key to fail")
+	}
+}
+
+func TestKnownHostMatches(t *testing.T) {
+	// Hashed by ssh-keygen -H
+	for _, test := range []struct {
+		line string
+		host string
+		port int
+	}{
+		{"|1|6JwcuaFbKbm0CImjLYi79LE6ZnU=|/Uec8TcC9aRv9m4XpMqphvRDWWk= ssh-ed25519 " + testSSHKey, "web.example.com", 22},
+		{"|1|pHXDkYlzcEGgTSVLwlwLcXR01+g=|MMF03H8pOh4mZZIE3SLqp9CL5UY= ssh-ed25519 " + testSSHKey, "DB.example.com", 2222},
+		{"*.example.com,!bastion.example.com ssh-ed25519 " + testSSHKey, "web.example.com", 0},
+	} {
+		entry, err := ParseKnownHost(test.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !entry.Matches(test.host, test.port) {
+			t.Errorf("Expected %s to match %s:%d", test.line, test.host, test.port)
+		}
+		if entry.Matches("bastion.example.com", 22) || entry.Matches(test.host, 2200) {
+			t.Errorf("Expected %s not to match other hosts", test.line)
+		}
+	}
+	hashed, _ := HashKnownHost("web.example.com", 22)
+	if entry := (KnownHost{Hosts: []string{hashed}}); !entry.Hashed() || !entry.Matches("web.example.com", 22) {
+		t.Errorf("Expected %s to match its host", hashed)
+	}
+}
+
+func TestSetAuthorizedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+	os.WriteFile(path, []byte("# managed\nno-pty ssh-ed25519 "+testSSHKey+" old comment\nssh-rsa AAAAB3NzaC1yc2E= other\n"), 0600)
+	module := &AnsibleModule{}
+
+	keys := []AuthorizedKey{{Type: "ssh-ed25519", Key: testSSHKey, Comment: "deploy"}}
+	result, err := module.SetAuthorizedKeys(path, keys, true)
+	if err != nil || !result.Changed {
+		t.Fatalf("Expected change, got %+v (%v)", result, err)
+	}
+	content, _ := os.ReadFile(path)
+	if expected := "# managed\nno-pty ssh-ed25519 " + testSSHKey + " deploy\n"; string(content) != expected {
+		t.Errorf("Expected options to be kept and the other key removed, got:\n%s", content)
+	}
+	if result, _ := module.SetAuthorizedKeys(path, keys, true); result.Changed {
+		t.Error("Expected no change the second time")
+	}
+
+	result, err = module.RemoveAuthorizedKeys(path, keys)
+	if err != nil || !result.Changed {
+		t.Errorf("Expected the key to be removed, got %+v (%v)", result, err)
+	}
+	if keys, _ := module.AuthorizedKeys(path); len(keys) != 0 {
+		t.Errorf("Expected no keys left, got %v", keys)
+	}
+}
+
+func TestSetKnownHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(path, []byte("web.example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOld\nother.example.com ssh-rsa AAAAB3NzaC1yc2E=\n"), 0644)
+	module := &AnsibleModule{}
+	key := KnownHost{Type: "ssh-ed25519", Key: testSSHKey}
+
+	result, err := module.SetKnownHost(path, "web.example.com", 22, key, true)
+	if err != nil || len(result.Removed) != 1 || len(result.Added) != 1 {
+		t.Fatalf("Expected the old key to be replaced, got %+v (%v)", result, err)
+	}
+	entries, _ := module.LookupKnownHost(path, "web.example.com", 22)
+	if len(entries) != 1 || !entries[0].Hashed() || entries[0].Key != testSSHKey {
+		t.Errorf("Expected a single hashed entry, got %+v", entries)
+	}
+	if result, _ := module.SetKnownHost(path, "web.example.com", 22, key, true); result.Changed {
+		t.Error("Expected a hashed entry holding the key to be kept")
+	}
+
+	if result, _ := module.RemoveKnownHost(path, "web.example.com", 22, ""); !result.Changed {
+		t.Error("Expected the hashed entry to be removed")
+	}
+	content, _ := os.ReadFile(path)
+	if strings.TrimSpace(string(content)) != "other.example.com ssh-rsa AAAAB3NzaC1yc2E=" {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+}
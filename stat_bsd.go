@@ -0,0 +1,16 @@
+//go:build darwin || freebsd || netbsd
+
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// statExtraFields adds the times, file flags (chflags) and generation number
+func statExtraFields(stat *syscall.Stat_t, result map[string]interface{}) {
+	result["atime"], _ = stat.Atimespec.Unix()
+	result["ctime"], _ = stat.Ctimespec.Unix()
+	result["birthtime"], _ = stat.Birthtimespec.Unix()
+	result["flags"] = uint64(stat.Flags)
+	result["gen"] = uint64(stat.Gen)
+}
@@ -0,0 +1,13 @@
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// statExtraFields adds the times, file flags (chflags) and generation number
+func statExtraFields(stat *syscall.Stat_t, result map[string]interface{}) {
+	result["atime"], _ = stat.Atim.Unix()
+	result["ctime"], _ = stat.Ctim.Unix()
+	result["flags"] = uint64(stat.Flags)
+	result["gen"] = uint64(stat.Gen)
+}
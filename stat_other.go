@@ -0,0 +1,20 @@
+//go:build !unix
+
+package ansiblemodule
+
+import (
+	"os"
+)
+
+// platformStat has no extra fields where there is no stat data
+func platformStat(info os.FileInfo, result map[string]interface{}) {}
+
+// executeMask accepts any execute bit where there is no ownership information
+func executeMask(info os.FileInfo) os.FileMode {
+	return 0111
+}
+
+// ownedByCurrentUser accepts any file where there is no ownership information
+func ownedByCurrentUser(info os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,13 @@
+//go:build linux || solaris || illumos || aix
+
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// statExtraFields adds the access and change times
+func statExtraFields(stat *syscall.Stat_t, result map[string]interface{}) {
+	result["atime"], _ = stat.Atim.Unix()
+	result["ctime"], _ = stat.Ctim.Unix()
+}
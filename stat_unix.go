@@ -0,0 +1,86 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// platformStat adds ownership, inode and time fields from the system stat data
+func platformStat(info os.FileInfo, result map[string]interface{}) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	result["uid"] = int64(stat.Uid)
+	result["gid"] = int64(stat.Gid)
+	result["pw_name"] = userName(stat.Uid)
+	result["gr_name"] = groupName(stat.Gid)
+	result["inode"] = uint64(stat.Ino)
+	result["dev"] = uint64(stat.Dev)
+	result["nlink"] = uint64(stat.Nlink)
+	result["isuid"] = info.Mode()&os.ModeSetuid != 0
+	result["isgid"] = info.Mode()&os.ModeSetgid != 0
+	statExtraFields(stat, result)
+}
+
+// ownedByCurrentUser reports whether the effective user owns a file
+func ownedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Geteuid()
+}
+
+// userName returns the name of a user ID, or the ID itself if it has no name
+func userName(uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return id
+}
+
+// groupName returns the name of a group ID, or the ID itself if it has no name
+func groupName(gid uint32) string {
+	id := strconv.FormatUint(uint64(gid), 10)
+	if g, err := user.LookupGroupId(id); err == nil {
+		return g.Name
+	}
+	return id
+}
+
+// executeMask returns the execute bit that applies to the current user: any bit
+// for root, otherwise the owner, group or other bit like access(2) with X_OK
+func executeMask(info os.FileInfo) os.FileMode {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0111
+	}
+	uid := os.Geteuid()
+	if uid == 0 {
+		return 0111
+	}
+	if uint32(uid) == stat.Uid {
+		return 0100
+	}
+	if inGroup(stat.Gid) {
+		return 0010
+	}
+	return 0001
+}
+
+// inGroup reports whether the current process is a member of the group
+func inGroup(gid uint32) bool {
+	if uint32(os.Getegid()) == gid {
+		return true
+	}
+	groups, _ := os.Getgroups()
+	for _, group := range groups {
+		if uint32(group) == gid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileStatPlatformFields(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "file.txt")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	module := &AnsibleModule{}
+	stat, err := module.FileStat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if stat["uid"] != int64(os.Getuid()) {
+		t.Errorf("Expected uid %d, got %v", os.Getuid(), stat["uid"])
+	}
+	if name, ok := stat["pw_name"].(string); !ok || name == "" {
+		t.Errorf("Expected owner name, got %v", stat["pw_name"])
+	}
+	for _, key := range []string{"gid", "gr_name", "inode", "dev", "nlink", "atime", "ctime"} {
+		if _, ok := stat[key]; !ok {
+			t.Errorf("Expected %s in stat result", key)
+		}
+	}
+
+	_, hasBirthtime := stat["birthtime"]
+	switch runtime.GOOS {
+	case "darwin", "ios", "freebsd", "netbsd", "openbsd":
+		if !hasBirthtime {
+			t.Error("Expected birthtime in stat result")
+		}
+	case "linux":
+		if hasBirthtime {
+			t.Error("Expected no birthtime in stat result on Linux")
+		}
+	}
+}
+
+func TestExecuteMask(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "script.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// Only others may execute the file, which does not include its owner
+	if err := os.Chmod(path, 0641); err != nil {
+		t.Fatalf("Failed to chmod file: %v", err)
+	}
+
+	module := &AnsibleModule{}
+	expected := os.Geteuid() == 0
+	if module.IsExecutable(path) != expected {
+		t.Errorf("Expected IsExecutable to be %v for mode 0641 owned by the current user", expected)
+	}
+
+	if err := os.Chmod(path, 0744); err != nil {
+		t.Fatalf("Failed to chmod file: %v", err)
+	}
+	if !module.IsExecutable(path) {
+		t.Error("Expected owner-executable file to be executable")
+	}
+}
@@ -0,0 +1,233 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArgSpecFromStruct derives an argument spec from the fields of a struct, or a
+// pointer to one, so a module declares its parameters once as a typed struct.
+// Each exported field is an option described by its ansible tag:
+//
+//	Port  int      `ansible:"name=port,type=port,required,aliases=listen_port"`
+//	State string   `ansible:"default=present,choices=present|absent"`
+//	Token string   `ansible:"no_log"`
+//	Hosts []string `ansible:"elements=str"`
+//
+// Lists in choices, aliases and list defaults are separated by |. Without a
+// name the json name or the lower-cased field name is used, and without a type
+// it follows the Go type. Nested structs become dict options, slices list
+// options whose elements follow the element type, with suboptions for structs.
+// Embedded structs contribute their fields, and fields tagged "-" are skipped.
+// Recursive types have no finite spec and are refused.
+func ArgSpecFromStruct(v interface{}) (ArgSpecMap, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("argument spec requires a struct, got %T", v)
+	}
+	spec := ArgSpecMap{}
+	if err := addStructOptions(spec, t, map[reflect.Type]bool{}); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// addStructOptions adds an option for every field of t to spec. The types
+// being expanded are tracked in visiting to refuse recursive types.
+func addStructOptions(spec ArgSpecMap, t reflect.Type, visiting map[reflect.Type]bool) error {
+	if visiting[t] {
+		return fmt.Errorf("recursive type %s", t)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, tagged := field.Tag.Lookup("ansible")
+		if tag == "-" {
+			continue
+		}
+		if embedded := field.Type; field.Anonymous && !tagged {
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := addStructOptions(spec, embedded, visiting); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, option, err := structFieldOption(field, tag, visiting)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if _, dup := spec[name]; dup {
+			return fmt.Errorf("field %s: option %s is declared twice", field.Name, name)
+		}
+		spec[name] = option
+	}
+	return nil
+}
+
+// structFieldOption returns the option name and spec of a struct field
+func structFieldOption(field reflect.StructField, tag string, visiting map[reflect.Type]bool) (string, ArgumentSpec, error) {
+	name := ""
+	if jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonName != "" && jsonName != "-" {
+		name = jsonName
+	}
+	var option ArgumentSpec
+	var defaultValue string
+	hasDefault := false
+	for _, part := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "":
+		case "name":
+			name = value
+		case "type":
+			option.Type = value
+		case "elements":
+			option.Elements = value
+		case "required":
+			option.Required = true
+		case "no_log":
+			option.NoLog = true
+		case "default":
+			defaultValue, hasDefault = value, true
+		case "choices":
+			option.Choices = strings.Split(value, "|")
+		case "aliases":
+			option.Aliases = strings.Split(value, "|")
+		case "version_added":
+			option.VersionAdded = value
+		default:
+			return "", option, fmt.Errorf("unknown ansible tag key %s", key)
+		}
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	t := field.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if option.Type == "" {
+		option.Type = goArgumentType(t)
+		if option.Type == "" {
+			return "", option, fmt.Errorf("unsupported type %s", field.Type)
+		}
+	}
+	switch {
+	case t.Kind() == reflect.Struct && t != timeType:
+		option.Options = ArgSpecMap{}
+		if err := addStructOptions(option.Options, t, visiting); err != nil {
+			return "", option, err
+		}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		elem := t.Elem()
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		if option.Elements == "" {
+			option.Elements = goArgumentType(elem)
+		}
+		if elem.Kind() == reflect.Struct && elem != timeType {
+			option.SubOptions = ArgSpecMap{}
+			if err := addStructOptions(option.SubOptions, elem, visiting); err != nil {
+				return "", option, err
+			}
+		}
+	}
+
+	if hasDefault {
+		value, err := parseTagDefault(option, defaultValue)
+		if err != nil {
+			return "", option, err
+		}
+		option.Default = value
+	}
+	return name, option, nil
+}
+
+// timeType is time.Time, which maps to datetime rather than dict
+var timeType = reflect.TypeOf(time.Time{})
+
+// goArgumentType returns the argument type matching a Go type, or an empty
+// string for types without one
+func goArgumentType(t reflect.Type) string {
+	if t == timeType {
+		return "datetime"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "str"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "dict"
+	case reflect.Interface:
+		return "raw"
+	case reflect.Pointer:
+		return goArgumentType(t.Elem())
+	}
+	return ""
+}
+
+// parseTagDefault converts the default of a tag to the option type
+func parseTagDefault(option ArgumentSpec, value string) (interface{}, error) {
+	switch option.Type {
+	case "bool", "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("default %q is not a boolean", value)
+		}
+		return b, nil
+	case "int", "integer", "port":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("default %q is not an integer", value)
+		}
+		return n, nil
+	case "float":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("default %q is not a float", value)
+		}
+		return f, nil
+	case "list", "array":
+		items := []interface{}{}
+		if value == "" {
+			return items, nil
+		}
+		for _, item := range strings.Split(value, "|") {
+			converted, err := parseTagDefault(ArgumentSpec{Type: option.Elements}, item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, converted)
+		}
+		return items, nil
+	case "dict", "map":
+		return nil, fmt.Errorf("dict options cannot have a default in the tag")
+	}
+	return value, nil
+}
@@ -0,0 +1,110 @@
+package ansiblemodule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type specCommon struct {
+	State string `ansible:"default=present,choices=present|absent"`
+}
+
+type specListener struct {
+	Port     int    `ansible:"type=port,required"`
+	Protocol string `json:"proto" ansible:"default=tcp"`
+}
+
+type specNode struct {
+	Name     string
+	Children []specNode
+}
+
+type specLinked struct {
+	Next *specLinked
+}
+
+type specParams struct {
+	specCommon
+	Name      string         `ansible:"name=name,required,aliases=server|host"`
+	Password  string         `ansible:"no_log"`
+	Retries   *int           `ansible:"default=3"`
+	Enabled   bool           `ansible:"default=true"`
+	Weights   []int          `ansible:"default=1|2"`
+	Tags      []string       `json:"tags,omitempty"`
+	Expires   time.Time      `ansible:"version_added=1.2.0"`
+	Labels    map[string]any `json:"labels"`
+	TLS       specListener   `ansible:"name=tls"`
+	Listeners []specListener
+	Extra     interface{}
+	Internal  string `ansible:"-"`
+	hidden    string
+}
+
+func TestArgSpecFromStruct(t *testing.T) {
+	spec, err := ArgSpecFromStruct(&specParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := ArgSpecMap{
+		"port":  {Type: "port", Required: true},
+		"proto": {Type: "str", Default: "tcp"},
+	}
+	expected := ArgSpecMap{
+		"state":     {Type: "str", Default: "present", Choices: []string{"present", "absent"}},
+		"name":      {Type: "str", Required: true, Aliases: []string{"server", "host"}},
+		"password":  {Type: "str", NoLog: true},
+		"retries":   {Type: "int", Default: 3},
+		"enabled":   {Type: "bool", Default: true},
+		"weights":   {Type: "list", Elements: "int", Default: []interface{}{1, 2}},
+		"tags":      {Type: "list", Elements: "str"},
+		"expires":   {Type: "datetime", VersionAdded: "1.2.0"},
+		"labels":    {Type: "dict"},
+		"tls":       {Type: "dict", Options: listener},
+		"listeners": {Type: "list", Elements: "dict", SubOptions: listener},
+		"extra":     {Type: "raw"},
+	}
+	if !reflect.DeepEqual(spec, expected) {
+		t.Errorf("Unexpected spec:\n%#v\n%#v", spec, expected)
+	}
+	for _, finding := range SanityCheck(SanityDefinition{ArgSpec: spec, SupportsCheckMode: true}) {
+		if finding.Severity == "error" {
+			t.Errorf("%s %s", finding.Path, finding.Msg)
+		}
+	}
+
+	module := &AnsibleModule{ArgSpec: spec, Params: ModuleParams{"name": "web", "tls": map[string]interface{}{"proto": "udp"}}}
+	if err := module.validateArguments(); err == nil || !strings.Contains(err.Error(), "tls.port is required") {
+		t.Errorf("Expected the nested required option to be enforced, got %v", err)
+	}
+	module.Params = ModuleParams{
+		"name":      "web",
+		"tls":       map[string]interface{}{"port": 443},
+		"listeners": []interface{}{map[string]interface{}{"proto": "udp"}},
+	}
+	if err := module.validateArguments(); err == nil || !strings.Contains(err.Error(), "listeners[0].port is required") {
+		t.Errorf("Expected the list element suboptions to be enforced, got %v", err)
+	}
+
+	for _, bad := range []interface{}{
+		"not a struct",
+		struct{ C chan int }{},
+		struct {
+			N int `ansible:"default=many"`
+		}{},
+		struct {
+			N int `ansible:"requird"`
+		}{},
+		struct {
+			A string `ansible:"name=x"`
+			B string `ansible:"name=x"`
+		}{},
+		specNode{},
+		specLinked{},
+	} {
+		if _, err := ArgSpecFromStruct(bad); err == nil {
+			t.Errorf("Expected an error for %T", bad)
+		}
+	}
+}
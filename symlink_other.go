@@ -0,0 +1,31 @@
+//go:build !windows
+
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+)
+
+// linkType reports whether a file is a symlink; junctions only exist on Windows
+func linkType(path string, info os.FileInfo) string {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return "symlink"
+	}
+	return ""
+}
+
+// symlinkNotPermitted is always false, any user can create symlinks
+func symlinkNotPermitted(err error) bool {
+	return false
+}
+
+// canCreateSymlinks is always true, any user can create symlinks
+func canCreateSymlinks() bool {
+	return true
+}
+
+// createJunction is only available on Windows
+func (m *AnsibleModule) createJunction(target, link string) error {
+	return fmt.Errorf("junctions are only supported on Windows")
+}
@@ -0,0 +1,57 @@
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCreateSymlinkDangling(t *testing.T) {
+	module := &AnsibleModule{}
+	if !module.CanCreateSymlinks() {
+		t.Skip("symlinks are not permitted")
+	}
+	tmpDir := t.TempDir()
+	link := filepath.Join(tmpDir, "link")
+
+	// A link to a missing target must be replaced, not reported as a conflict
+	if err := os.Symlink(filepath.Join(tmpDir, "missing"), link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	target := filepath.Join(tmpDir, "target")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	changed, err := module.CreateSymlink(target, link)
+	if err != nil || !changed {
+		t.Fatalf("Expected dangling link to be replaced, got changed=%v err=%v", changed, err)
+	}
+	if !module.IsSymlink(link) {
+		t.Error("Expected IsSymlink to be true")
+	}
+
+	stat, err := module.FileStat(link)
+	if err != nil {
+		t.Fatalf("Failed to stat link: %v", err)
+	}
+	if stat["islnk"] != true || stat["lnk_target"] != target {
+		t.Errorf("Unexpected link stat: %v", stat)
+	}
+	if _, ok := stat["isjunction"]; ok {
+		t.Error("Expected symlink not to be reported as a junction")
+	}
+}
+
+func TestCanCreateSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("depends on Developer Mode and privileges")
+	}
+	if !(&AnsibleModule{}).CanCreateSymlinks() {
+		t.Error("Expected symlinks to be permitted")
+	}
+	if symlinkNotPermitted(os.ErrPermission) {
+		t.Error("Expected no junction fallback outside Windows")
+	}
+}
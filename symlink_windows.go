@@ -0,0 +1,128 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// ioReparseTagMountPoint is the reparse tag of junctions
+const ioReparseTagMountPoint = 0xA0000003
+
+// developerModeKey holds the AllowDevelopmentWithoutDevLicense value set by Developer Mode
+const developerModeKey = `SOFTWARE\Microsoft\Windows\CurrentVersion\AppModelUnlock`
+
+var procLookupPrivilegeValue = advapi32.NewProc("LookupPrivilegeValueW")
+
+// linkType reports whether a file is a symlink or a junction. Since Go 1.23
+// Lstat reports junctions as irregular files, so the reparse tag is checked.
+func linkType(path string, info os.FileInfo) string {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return "symlink"
+	}
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok || data.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT == 0 {
+		return ""
+	}
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return ""
+	}
+	var find syscall.Win32finddata
+	handle, err := syscall.FindFirstFile(name, &find)
+	if err != nil {
+		return ""
+	}
+	syscall.FindClose(handle)
+	if find.Reserved0 == ioReparseTagMountPoint {
+		return "junction"
+	}
+	return ""
+}
+
+// symlinkNotPermitted reports whether a symlink failed for lack of privilege
+func symlinkNotPermitted(err error) bool {
+	return errors.Is(err, syscall.ERROR_PRIVILEGE_NOT_HELD)
+}
+
+// canCreateSymlinks reports whether Developer Mode is enabled or the process
+// holds SeCreateSymbolicLinkPrivilege
+func canCreateSymlinks() bool {
+	return developerModeEnabled() || hasPrivilege("SeCreateSymbolicLinkPrivilege")
+}
+
+// developerModeEnabled reads the Developer Mode registry value
+func developerModeEnabled() bool {
+	subkey, err := syscall.UTF16PtrFromString(developerModeKey)
+	if err != nil {
+		return false
+	}
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(syscall.HKEY_LOCAL_MACHINE, subkey, 0, syscall.KEY_READ, &key); err != nil {
+		return false
+	}
+	defer syscall.RegCloseKey(key)
+
+	name, _ := syscall.UTF16PtrFromString("AllowDevelopmentWithoutDevLicense")
+	var valueType, value uint32
+	size := uint32(unsafe.Sizeof(value))
+	if err := syscall.RegQueryValueEx(key, name, nil, &valueType, (*byte)(unsafe.Pointer(&value)), &size); err != nil {
+		return false
+	}
+	return valueType == syscall.REG_DWORD && value == 1
+}
+
+// hasPrivilege reports whether the process token holds a privilege, enabled or not
+func hasPrivilege(privilege string) bool {
+	name, err := syscall.UTF16PtrFromString(privilege)
+	if err != nil {
+		return false
+	}
+	var luid [2]uint32
+	if r, _, _ := procLookupPrivilegeValue.Call(0, uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&luid))); r == 0 {
+		return false
+	}
+
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return false
+	}
+	var token syscall.Token
+	if err := syscall.OpenProcessToken(process, syscall.TOKEN_QUERY, &token); err != nil {
+		return false
+	}
+	defer token.Close()
+
+	// TOKEN_PRIVILEGES is a count followed by 12 byte LUID_AND_ATTRIBUTES entries
+	var size uint32
+	syscall.GetTokenInformation(token, syscall.TokenPrivileges, nil, 0, &size)
+	if size < 4 {
+		return false
+	}
+	buf := make([]byte, size)
+	if err := syscall.GetTokenInformation(token, syscall.TokenPrivileges, &buf[0], size, &size); err != nil {
+		return false
+	}
+	count := *(*uint32)(unsafe.Pointer(&buf[0]))
+	for i := uint32(0); i < count && 4+(i+1)*12 <= size; i++ {
+		entry := (*[2]uint32)(unsafe.Pointer(&buf[4+i*12]))
+		if *entry == luid {
+			return true
+		}
+	}
+	return false
+}
+
+// createJunction links a directory with a junction, which needs no privilege
+func (m *AnsibleModule) createJunction(target, link string) error {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	if _, err := m.RunCommand("cmd.exe", []string{"/c", "mklink", "/J", link, target}, nil, ""); err != nil {
+		return fmt.Errorf("failed to create junction %s: %w", link, err)
+	}
+	return nil
+}
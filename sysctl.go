@@ -0,0 +1,137 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SysctlOptions controls how SetSysctl applies and persists a value
+type SysctlOptions struct {
+	Persist bool   // Write the value to a file in the sysctl.d directory
+	File    string // File name in the sysctl.d directory, defaults to 99-ansible.conf
+	NoApply bool   // Only persist the value without changing the running kernel
+}
+
+// Locations of the runtime and persistent sysctl settings
+var (
+	sysctlProcPath = "/proc/sys"
+	sysctlDir      = "/etc/sysctl.d"
+)
+
+// sysctlPath converts a dotted sysctl name to its path under /proc/sys
+func sysctlPath(name string) string {
+	// As with sysctl(8), names containing slashes use them in place of dots
+	if strings.Contains(name, "/") {
+		name = strings.Map(func(r rune) rune {
+			switch r {
+			case '.':
+				return '/'
+			case '/':
+				return '.'
+			}
+			return r
+		}, name)
+	} else {
+		name = strings.ReplaceAll(name, ".", "/")
+	}
+	return filepath.Join(sysctlProcPath, name)
+}
+
+// normalizeSysctlValue collapses whitespace so tab separated values compare equal
+func normalizeSysctlValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// GetSysctl reads the current value of a kernel parameter
+func (m *AnsibleModule) GetSysctl(name string) (string, error) {
+	content, err := os.ReadFile(sysctlPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", notFound(fmt.Errorf("unknown sysctl key: %s", name))
+		}
+		return "", fmt.Errorf("failed to read sysctl %s: %w", name, err)
+	}
+	return normalizeSysctlValue(string(content)), nil
+}
+
+// SetSysctl sets a kernel parameter and optionally persists it
+func (m *AnsibleModule) SetSysctl(name, value string, opts SysctlOptions) (bool, error) {
+	value = normalizeSysctlValue(value)
+	changed := false
+
+	if !opts.NoApply {
+		current, err := m.GetSysctl(name)
+		if err != nil {
+			return false, err
+		}
+		if current != value {
+			changed = true
+			if !m.CheckMode {
+				if err := os.WriteFile(sysctlPath(name), []byte(value), 0644); err != nil {
+					return false, fmt.Errorf("failed to set sysctl %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	if opts.Persist {
+		persisted, err := m.persistSysctl(name, value, opts.File)
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || persisted
+	}
+
+	return changed, nil
+}
+
+// persistSysctl writes a key to a sysctl.d file, replacing any existing entry
+func (m *AnsibleModule) persistSysctl(name, value, file string) (bool, error) {
+	if file == "" {
+		file = "99-ansible.conf"
+	}
+	path := filepath.Join(sysctlDir, file)
+
+	var lines []string
+	if m.FileExists(path) {
+		content, err := m.ReadTextFile(path)
+		if err != nil {
+			return false, err
+		}
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	entry := fmt.Sprintf("%s = %s", name, value)
+	found := false
+	result := make([]string, 0, len(lines)+1)
+	for _, line := range lines {
+		key, _, isSetting := strings.Cut(line, "=")
+		trimmed := strings.TrimSpace(line)
+		if isSetting && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, ";") &&
+			sysctlPath(strings.TrimPrefix(strings.TrimSpace(key), "-")) == sysctlPath(name) {
+			// Keep only the first occurrence, later ones would override it
+			if !found {
+				result = append(result, entry)
+				found = true
+			}
+			continue
+		}
+		result = append(result, line)
+	}
+	if !found {
+		result = append(result, entry)
+	}
+
+	content := strings.Join(result, "\n") + "\n"
+	if m.CheckMode {
+		existing, _ := m.ReadTextFile(path)
+		return existing != content, nil
+	}
+
+	if err := os.MkdirAll(sysctlDir, 0755); err != nil {
+		return false, err
+	}
+	return m.WriteTextFile(path, content, 0644)
+}
@@ -0,0 +1,77 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSysctl(t *testing.T) {
+	module := &AnsibleModule{}
+
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldProc, oldDir := sysctlProcPath, sysctlDir
+	sysctlProcPath = filepath.Join(tmpDir, "proc")
+	sysctlDir = filepath.Join(tmpDir, "sysctl.d")
+	defer func() { sysctlProcPath, sysctlDir = oldProc, oldDir }()
+
+	os.MkdirAll(filepath.Join(sysctlProcPath, "net", "ipv4"), 0755)
+	os.WriteFile(filepath.Join(sysctlProcPath, "net", "ipv4", "ip_forward"), []byte("0\n"), 0644)
+	os.WriteFile(filepath.Join(sysctlProcPath, "net", "ipv4", "ip_local_port_range"), []byte("32768\t60999\n"), 0644)
+
+	// Test reading values
+	value, err := module.GetSysctl("net.ipv4.ip_local_port_range")
+	if err != nil {
+		t.Fatalf("Failed to read sysctl: %v", err)
+	}
+	if value != "32768 60999" {
+		t.Errorf("Expected normalized value '32768 60999', got '%s'", value)
+	}
+	if _, err := module.GetSysctl("net.ipv4.missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected an unknown key to match ErrNotFound, got %v", err)
+	}
+
+	// Test check mode
+	module.CheckMode = true
+	changed, err := module.SetSysctl("net.ipv4.ip_forward", "1", SysctlOptions{Persist: true})
+	if err != nil || !changed {
+		t.Fatalf("Expected change in check mode, got %v (%v)", changed, err)
+	}
+	if value, _ := module.GetSysctl("net.ipv4.ip_forward"); value != "0" {
+		t.Error("Expected value to be unchanged in check mode")
+	}
+	if module.FileExists(filepath.Join(sysctlDir, "99-ansible.conf")) {
+		t.Error("Expected no file to be written in check mode")
+	}
+	module.CheckMode = false
+
+	// Test setting and persisting
+	os.MkdirAll(sysctlDir, 0755)
+	persistFile := filepath.Join(sysctlDir, "99-ansible.conf")
+	os.WriteFile(persistFile, []byte("# managed\nnet.ipv4.ip_forward=0\nvm.swappiness = 10\n"), 0644)
+
+	changed, err = module.SetSysctl("net.ipv4.ip_forward", "1", SysctlOptions{Persist: true})
+	if err != nil || !changed {
+		t.Fatalf("Expected change, got %v (%v)", changed, err)
+	}
+	if value, _ := module.GetSysctl("net.ipv4.ip_forward"); value != "1" {
+		t.Errorf("Expected value 1, got %s", value)
+	}
+	content, _ := os.ReadFile(persistFile)
+	expected := "# managed\nnet.ipv4.ip_forward = 1\nvm.swappiness = 10\n"
+	if string(content) != expected {
+		t.Errorf("Expected persisted file %q, got %q", expected, content)
+	}
+
+	// Test idempotency
+	changed, err = module.SetSysctl("net.ipv4.ip_forward", "1", SysctlOptions{Persist: true})
+	if err != nil || changed {
+		t.Errorf("Expected no change, got %v (%v)", changed, err)
+	}
+}
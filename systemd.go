@@ -0,0 +1,154 @@
+package ansiblemodule
+
+import (
+	"slices"
+	"strings"
+)
+
+// systemctl runs systemctl with the given arguments
+func (m *AnsibleModule) systemctl(args ...string) (CommandResult, error) {
+	path, err := m.GetBinPath("systemctl", true)
+	if err != nil {
+		return CommandResult{}, err
+	}
+	return m.RunCommand(path, args, nil, "")
+}
+
+// systemctlQuery runs a query such as is-active, whose exit code is part of
+// the answer. It fails only when systemctl did not run or printed no state.
+func (m *AnsibleModule) systemctlQuery(args ...string) (CommandResult, error) {
+	result, err := m.systemctl(args...)
+	if err != nil && (result.Rc <= 0 || strings.TrimSpace(result.Stdout) == "") {
+		return result, err
+	}
+	return result, nil
+}
+
+// SystemdIsActive checks if a systemd unit is active
+func (m *AnsibleModule) SystemdIsActive(unit string) (bool, error) {
+	result, err := m.systemctlQuery("is-active", unit)
+	if err != nil {
+		return false, commandFailure(err, result, "failed to get active state of %s", unit)
+	}
+	return strings.TrimSpace(result.Stdout) == "active", nil
+}
+
+// systemdFixedStates are is-enabled states that enable and disable cannot change
+var systemdFixedStates = []string{"static", "indirect", "generated", "transient", "alias"}
+
+// SystemdEnabledState returns the unit file state reported by systemctl
+// is-enabled, such as enabled, disabled, static or masked
+func (m *AnsibleModule) SystemdEnabledState(unit string) (string, error) {
+	result, err := m.systemctlQuery("is-enabled", unit)
+	if err != nil {
+		return "", commandFailure(err, result, "failed to get enabled state of %s", unit)
+	}
+	state, _, _ := strings.Cut(strings.TrimSpace(result.Stdout), "\n")
+	return state, nil
+}
+
+// SystemdIsEnabled checks if a systemd unit is enabled to start at boot. Units
+// that are static, generated or only pulled in by others are not enabled.
+func (m *AnsibleModule) SystemdIsEnabled(unit string) (bool, error) {
+	state, err := m.SystemdEnabledState(unit)
+	if err != nil {
+		return false, err
+	}
+	return systemdEnabled(state), nil
+}
+
+// systemdEnabled reports whether an is-enabled state means enabled at boot
+func systemdEnabled(state string) bool {
+	return state == "enabled" || state == "enabled-runtime"
+}
+
+// systemdChange runs a state-changing systemctl action unless in check mode
+func (m *AnsibleModule) systemdChange(action, unit string) error {
+	if m.CheckMode {
+		return nil
+	}
+	if result, err := m.systemctl(action, unit); err != nil {
+		return commandFailure(err, result, "failed to %s %s", action, unit)
+	}
+	return nil
+}
+
+// SystemdStart starts a unit if it is not active
+func (m *AnsibleModule) SystemdStart(unit string) (bool, error) {
+	active, err := m.SystemdIsActive(unit)
+	if err != nil {
+		return false, err
+	}
+	if active {
+		return false, nil
+	}
+	if err := m.systemdChange("start", unit); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SystemdStop stops a unit if it is active
+func (m *AnsibleModule) SystemdStop(unit string) (bool, error) {
+	active, err := m.SystemdIsActive(unit)
+	if err != nil {
+		return false, err
+	}
+	if !active {
+		return false, nil
+	}
+	if err := m.systemdChange("stop", unit); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SystemdRestart restarts a unit, which always counts as a change
+func (m *AnsibleModule) SystemdRestart(unit string) (bool, error) {
+	if err := m.systemdChange("restart", unit); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SystemdEnable enables a unit if it is not enabled. Units whose state enable
+// cannot change, such as static units, are left alone.
+func (m *AnsibleModule) SystemdEnable(unit string) (bool, error) {
+	state, err := m.SystemdEnabledState(unit)
+	if err != nil {
+		return false, err
+	}
+	if systemdEnabled(state) || slices.Contains(systemdFixedStates, state) {
+		return false, nil
+	}
+	if err := m.systemdChange("enable", unit); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SystemdDisable disables a unit if it is enabled
+func (m *AnsibleModule) SystemdDisable(unit string) (bool, error) {
+	enabled, err := m.SystemdIsEnabled(unit)
+	if err != nil {
+		return false, err
+	}
+	if !enabled {
+		return false, nil
+	}
+	if err := m.systemdChange("disable", unit); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SystemdDaemonReload reloads the systemd manager configuration
+func (m *AnsibleModule) SystemdDaemonReload() error {
+	if m.CheckMode {
+		return nil
+	}
+	if result, err := m.systemctl("daemon-reload"); err != nil {
+		return commandFailure(err, result, "failed to reload systemd")
+	}
+	return nil
+}
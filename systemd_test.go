@@ -0,0 +1,126 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSystemctl installs a systemctl script that records its calls
+func fakeSystemctl(t *testing.T) string {
+	tmpDir := t.TempDir()
+	log := filepath.Join(tmpDir, "calls.log")
+	script := `#!/bin/sh
+echo "$@" >> ` + log + `
+case "$1 $2" in
+  "is-active running.service") echo active ;;
+  "is-active nobus.service") echo "Failed to connect to bus" >&2; exit 1 ;;
+  "is-active"*) echo inactive; exit 3 ;;
+  "is-enabled enabled.service") echo enabled ;;
+  "is-enabled static.service") echo static ;;
+  "is-enabled runtime.service") echo enabled-runtime ;;
+  "is-enabled missing.service") echo "Failed to get unit file state" >&2; exit 1 ;;
+  "is-enabled"*) echo disabled; exit 1 ;;
+  "start broken.service") echo "Job failed" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "systemctl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake systemctl: %v", err)
+	}
+	t.Setenv("PATH", tmpDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+func TestSystemdState(t *testing.T) {
+	module := &AnsibleModule{}
+	fakeSystemctl(t)
+
+	active, err := module.SystemdIsActive("running.service")
+	if err != nil || !active {
+		t.Errorf("Expected running.service to be active, got %v (%v)", active, err)
+	}
+	active, err = module.SystemdIsActive("stopped.service")
+	if err != nil || active {
+		t.Errorf("Expected stopped.service to be inactive, got %v (%v)", active, err)
+	}
+
+	// A systemctl that fails without reporting a state is an error, not an inactive unit
+	var command *CommandError
+	if _, err := module.SystemdIsActive("nobus.service"); !errors.As(err, &command) || command.Result.Rc != 1 {
+		t.Errorf("Expected the failed query to be reported, got %v", err)
+	}
+
+	enabled, err := module.SystemdIsEnabled("enabled.service")
+	if err != nil || !enabled {
+		t.Errorf("Expected enabled.service to be enabled, got %v (%v)", enabled, err)
+	}
+	enabled, err = module.SystemdIsEnabled("other.service")
+	if err != nil || enabled {
+		t.Errorf("Expected other.service to be disabled, got %v (%v)", enabled, err)
+	}
+	enabled, err = module.SystemdIsEnabled("runtime.service")
+	if err != nil || !enabled {
+		t.Errorf("Expected runtime.service to be enabled, got %v (%v)", enabled, err)
+	}
+
+	// is-enabled exits 0 for static units, which are not enabled at boot
+	enabled, err = module.SystemdIsEnabled("static.service")
+	if err != nil || enabled {
+		t.Errorf("Expected static.service not to be enabled, got %v (%v)", enabled, err)
+	}
+	if state, err := module.SystemdEnabledState("static.service"); err != nil || state != "static" {
+		t.Errorf("Expected static state, got %q (%v)", state, err)
+	}
+	if _, err := module.SystemdIsEnabled("missing.service"); err == nil {
+		t.Error("Expected error for missing unit")
+	}
+}
+
+func TestSystemdChanges(t *testing.T) {
+	module := &AnsibleModule{}
+	log := fakeSystemctl(t)
+
+	// Test start of an already running unit
+	changed, err := module.SystemdStart("running.service")
+	if err != nil || changed {
+		t.Errorf("Expected no change starting running unit, got %v (%v)", changed, err)
+	}
+
+	// Test start of a stopped unit
+	changed, err = module.SystemdStart("stopped.service")
+	if err != nil || !changed {
+		t.Errorf("Expected change starting stopped unit, got %v (%v)", changed, err)
+	}
+
+	// Test failure is reported
+	if _, err := module.SystemdStart("broken.service"); err == nil {
+		t.Error("Expected error starting broken unit")
+	}
+
+	// Test check mode does not run state-changing commands
+	module.CheckMode = true
+	changed, err = module.SystemdEnable("other.service")
+	if err != nil || !changed {
+		t.Errorf("Expected change enabling unit in check mode, got %v (%v)", changed, err)
+	}
+	changed, err = module.SystemdEnable("static.service")
+	if err != nil || changed {
+		t.Errorf("Expected no change enabling a static unit, got %v (%v)", changed, err)
+	}
+	if err := module.SystemdDaemonReload(); err != nil {
+		t.Errorf("Unexpected error reloading in check mode: %v", err)
+	}
+
+	calls, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("Failed to read call log: %v", err)
+	}
+	if !strings.Contains(string(calls), "start stopped.service") {
+		t.Error("Expected stopped.service to be started")
+	}
+	if strings.Contains(string(calls), "enable other.service") || strings.Contains(string(calls), "daemon-reload") {
+		t.Error("Expected no state changes in check mode")
+	}
+}
@@ -0,0 +1,64 @@
+package ansiblemodule
+
+import (
+	"time"
+)
+
+// Span measures the duration of a named phase of module execution
+type Span struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	ended    bool
+	module   *AnsibleModule
+}
+
+// Span starts timing a named phase; call End on the returned span when the phase completes
+func (m *AnsibleModule) Span(name string) *Span {
+	span := &Span{
+		Name:   name,
+		Start:  time.Now(),
+		module: m,
+	}
+
+	m.timingsMu.Lock()
+	m.timings = append(m.timings, span)
+	m.timingsMu.Unlock()
+
+	return span
+}
+
+// End stops the span and returns its duration, later calls return the first duration
+func (s *Span) End() time.Duration {
+	s.module.timingsMu.Lock()
+	defer s.module.timingsMu.Unlock()
+
+	if !s.ended {
+		s.Duration = time.Since(s.Start)
+		s.ended = true
+	}
+	return s.Duration
+}
+
+// Timings returns the recorded spans in the format attached to results
+func (m *AnsibleModule) Timings() []map[string]interface{} {
+	m.timingsMu.Lock()
+	defer m.timingsMu.Unlock()
+
+	timings := make([]map[string]interface{}, 0, len(m.timings))
+	for _, span := range m.timings {
+		timing := map[string]interface{}{
+			"name":  span.Name,
+			"start": span.Start.UTC().Format(time.RFC3339Nano),
+		}
+		if span.ended {
+			timing["duration"] = span.Duration.Seconds()
+		} else {
+			// Spans still open at exit are reported with their elapsed time
+			timing["duration"] = time.Since(span.Start).Seconds()
+			timing["incomplete"] = true
+		}
+		timings = append(timings, timing)
+	}
+	return timings
+}
@@ -0,0 +1,47 @@
+package ansiblemodule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpan(t *testing.T) {
+	module := &AnsibleModule{}
+
+	span := module.Span("fetch config")
+	time.Sleep(5 * time.Millisecond)
+	duration := span.End()
+	if duration < 5*time.Millisecond {
+		t.Errorf("Expected duration of at least 5ms, got %v", duration)
+	}
+	if span.End() != duration {
+		t.Error("Expected repeated End to return the first duration")
+	}
+
+	module.Span("apply")
+
+	timings := module.Timings()
+	if len(timings) != 2 {
+		t.Fatalf("Expected 2 timings, got %d", len(timings))
+	}
+	if timings[0]["name"] != "fetch config" || timings[0]["duration"].(float64) < 0.005 {
+		t.Errorf("Unexpected timing: %v", timings[0])
+	}
+	if timings[1]["incomplete"] != true {
+		t.Errorf("Expected open span to be incomplete: %v", timings[1])
+	}
+}
+
+func TestTimingsInResult(t *testing.T) {
+	module := &AnsibleModule{
+		Params:    ModuleParams{},
+		Verbosity: 2,
+	}
+	module.Span("phase").End()
+
+	parsed := exitOutput(t, module, map[string]interface{}{"changed": false})
+	timings, ok := parsed["timings"].([]interface{})
+	if !ok || len(timings) != 1 {
+		t.Errorf("Expected timings in result, got %v", parsed["timings"])
+	}
+}
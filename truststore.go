@@ -0,0 +1,443 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// CABundle holds the content of a PEM CA bundle being edited. Text before a
+// certificate, such as a label comment, is kept with it.
+type CABundle struct {
+	File     string
+	original string
+	entries  []caBundleEntry
+	trailer  string
+}
+
+// caBundleEntry is a certificate of a bundle and the text preceding it
+type caBundleEntry struct {
+	header string
+	block  string
+	cert   *x509.Certificate
+}
+
+// LoadCABundle reads a PEM CA bundle. A missing file loads as an empty bundle.
+func (m *AnsibleModule) LoadCABundle(file string) (*CABundle, error) {
+	bundle := &CABundle{File: file}
+	if m.FileExists(file) {
+		content, err := m.ReadTextFile(file)
+		if err != nil {
+			return nil, err
+		}
+		bundle.original = content
+	}
+
+	rest := []byte(bundle.original)
+	for {
+		start := bytes.Index(rest, []byte("-----BEGIN"))
+		if start < 0 {
+			break
+		}
+		block, remaining := pem.Decode(rest[start:])
+		if block == nil {
+			break
+		}
+		end := len(rest) - len(remaining)
+		if block.Type == "CERTIFICATE" || block.Type == "TRUSTED CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to parse certificate %d: %w", file, len(bundle.entries)+1, err)
+			}
+			bundle.entries = append(bundle.entries, caBundleEntry{
+				header: string(rest[:start]),
+				block:  strings.TrimRight(string(rest[start:end]), "\n") + "\n",
+				cert:   cert,
+			})
+		} else {
+			bundle.trailer += string(rest[:end])
+		}
+		rest = remaining
+	}
+	bundle.trailer += string(rest)
+	return bundle, nil
+}
+
+// Certificates returns the certificates of the bundle
+func (b *CABundle) Certificates() []*x509.Certificate {
+	certs := make([]*x509.Certificate, len(b.entries))
+	for i, entry := range b.entries {
+		certs[i] = entry.cert
+	}
+	return certs
+}
+
+// Add appends a certificate, with an optional label comment, unless the bundle
+// already contains it. It reports whether the bundle changed.
+func (b *CABundle) Add(cert *x509.Certificate, label string) bool {
+	fingerprint := CertificateFingerprint(cert)
+	for _, entry := range b.entries {
+		if CertificateFingerprint(entry.cert) == fingerprint {
+			return false
+		}
+	}
+	header := ""
+	if label != "" {
+		header = "# " + label + "\n"
+	}
+	if len(b.entries) > 0 || strings.TrimSpace(b.trailer) != "" {
+		header = "\n" + header
+	}
+	block := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	b.entries = append(b.entries, caBundleEntry{header: strings.TrimRight(b.trailer, "\n") + header, block: block, cert: cert})
+	b.trailer = ""
+	return true
+}
+
+// Remove deletes the certificates with a SHA-256 or SHA-1 fingerprint, given
+// in hex with or without colons, reporting whether the bundle changed
+func (b *CABundle) Remove(fingerprint string) bool {
+	fingerprint = normalizeFingerprint(fingerprint)
+	kept := b.entries[:0]
+	for _, entry := range b.entries {
+		if certificateHasFingerprint(entry.cert, fingerprint) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	changed := len(kept) != len(b.entries)
+	b.entries = kept
+	return changed
+}
+
+// Render returns the bundle content
+func (b *CABundle) Render() string {
+	var out strings.Builder
+	for _, entry := range b.entries {
+		out.WriteString(entry.header)
+		out.WriteString(entry.block)
+	}
+	out.WriteString(b.trailer)
+	return out.String()
+}
+
+// Changed reports whether the bundle differs from what was loaded
+func (b *CABundle) Changed() bool {
+	return b.Render() != b.original
+}
+
+// Diff returns a diff of the loaded and current bundle content
+func (b *CABundle) Diff(m *AnsibleModule) map[string]interface{} {
+	return m.CreateDiff(b.original, b.Render(), b.File+" (before)", b.File+" (after)")
+}
+
+// SaveCABundle writes the bundle if it changed, returning the backup path if requested
+func (m *AnsibleModule) SaveCABundle(bundle *CABundle, backup bool) (bool, string, error) {
+	if !bundle.Changed() {
+		return false, "", nil
+	}
+	if m.CheckMode {
+		return true, "", nil
+	}
+
+	backupPath := ""
+	if backup && bundle.original != "" {
+		path, err := m.BackupFile(bundle.File)
+		if err != nil {
+			return false, "", err
+		}
+		backupPath = path
+	}
+
+	content := bundle.Render()
+	if _, err := m.WriteTextFile(bundle.File, content, 0644); err != nil {
+		return false, backupPath, err
+	}
+	bundle.original = content
+	return true, backupPath, nil
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of a certificate in lowercase hex
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint lowercases a fingerprint and drops colons and spaces
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(fingerprint))
+}
+
+// certificateHasFingerprint matches a normalized SHA-256 or SHA-1 fingerprint
+func certificateHasFingerprint(cert *x509.Certificate, fingerprint string) bool {
+	if len(fingerprint) == sha1.Size*2 {
+		sum := sha1.Sum(cert.Raw)
+		return hex.EncodeToString(sum[:]) == fingerprint
+	}
+	return CertificateFingerprint(cert) == fingerprint
+}
+
+// CertificateSubjectHash returns the OpenSSL subject hash of a certificate, the
+// name of its links in a hashed certificate directory, as printed by
+// openssl x509 -subject_hash
+func CertificateSubjectHash(cert *x509.Certificate) (string, error) {
+	canonical, err := canonicalName(cert.RawSubject)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse certificate subject: %w", err)
+	}
+	sum := sha1.Sum(canonical)
+	return fmt.Sprintf("%08x", binary.LittleEndian.Uint32(sum[:4])), nil
+}
+
+// attributeTypeAndValue is an entry of a relative distinguished name
+type attributeTypeAndValue struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// canonicalName encodes a distinguished name the way OpenSSL does before hashing
+// it: string values become lowercased UTF8Strings with whitespace collapsed, and
+// the sets of the name are concatenated without the outer sequence
+func canonicalName(raw []byte) ([]byte, error) {
+	var name asn1.RawValue
+	if _, err := asn1.Unmarshal(raw, &name); err != nil {
+		return nil, err
+	}
+	var canonical []byte
+	for rest := name.Bytes; len(rest) > 0; {
+		var set asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &set); err != nil {
+			return nil, err
+		}
+		var entries [][]byte
+		for values := set.Bytes; len(values) > 0; {
+			var atv attributeTypeAndValue
+			if values, err = asn1.Unmarshal(values, &atv); err != nil {
+				return nil, err
+			}
+			if text, ok := decodeASN1String(atv.Value); ok {
+				atv.Value = asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte(canonicalString(text))}
+			}
+			entry, err := asn1.Marshal(atv)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i], entries[j]) < 0 })
+		encoded, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: bytes.Join(entries, nil)})
+		if err != nil {
+			return nil, err
+		}
+		canonical = append(canonical, encoded...)
+	}
+	return canonical, nil
+}
+
+// decodeASN1String decodes the string types OpenSSL canonicalizes
+func decodeASN1String(value asn1.RawValue) (string, bool) {
+	if value.Class != asn1.ClassUniversal {
+		return "", false
+	}
+	switch value.Tag {
+	case asn1.TagUTF8String, asn1.TagPrintableString, asn1.TagIA5String, 26: // VisibleString
+		return string(value.Bytes), true
+	case asn1.TagT61String:
+		runes := make([]rune, len(value.Bytes))
+		for i, c := range value.Bytes {
+			runes[i] = rune(c)
+		}
+		return string(runes), true
+	case asn1.TagBMPString:
+		units := make([]uint16, len(value.Bytes)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(value.Bytes[2*i:])
+		}
+		return string(utf16.Decode(units)), true
+	case 28: // UniversalString
+		runes := make([]rune, len(value.Bytes)/4)
+		for i := range runes {
+			runes[i] = rune(binary.BigEndian.Uint32(value.Bytes[4*i:]))
+		}
+		return string(runes), true
+	}
+	return "", false
+}
+
+// canonicalString trims and collapses whitespace and lowercases ASCII letters
+func canonicalString(s string) string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\v' || r == '\f' || r == '\r'
+	})
+	joined := []byte(strings.Join(fields, " "))
+	for i, c := range joined {
+		if c >= 'A' && c <= 'Z' {
+			joined[i] = c + 'a' - 'A'
+		}
+	}
+	return string(joined)
+}
+
+// hashLinkPattern matches the links of a hashed certificate directory
+var hashLinkPattern = regexp.MustCompile(`^[0-9a-f]{8}\.[0-9]+$`)
+
+// certificateFileExtensions are the files RehashDirectory links
+var certificateFileExtensions = []string{".pem", ".crt", ".cer"}
+
+// RehashDirectory brings the subject hash links of a certificate directory up to
+// date, as c_rehash and openssl rehash do: each .pem, .crt or .cer file gets a
+// HASH.N symlink, duplicates are linked once and stale links are removed. It
+// reports whether any link changed.
+func (m *AnsibleModule) RehashDirectory(dir string) (bool, error) {
+	entries, err := m.fs().ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+
+	existing := map[string]string{}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if hashLinkPattern.MatchString(name) && entry.Type()&os.ModeSymlink != 0 {
+			target, err := m.fs().Readlink(filepath.Join(dir, name))
+			if err != nil {
+				return false, err
+			}
+			existing[name] = target
+			continue
+		}
+		if entry.Type().IsRegular() && hasCertificateExtension(name) {
+			files = append(files, name)
+		}
+	}
+
+	desired := map[string]string{}
+	seen := map[string]bool{}
+	counts := map[string]int{}
+	for _, name := range files {
+		data, err := m.fs().ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return false, err
+		}
+		certs, err := ParseCertificates(data)
+		if err != nil || len(certs) != 1 {
+			m.AddWarning(fmt.Sprintf("skipping %s in %s: it does not hold exactly one certificate", name, dir))
+			continue
+		}
+		fingerprint := CertificateFingerprint(certs[0])
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+		hash, err := CertificateSubjectHash(certs[0])
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		desired[fmt.Sprintf("%s.%d", hash, counts[hash])] = name
+		counts[hash]++
+	}
+
+	changed := false
+	for link, target := range existing {
+		if desired[link] == target {
+			continue
+		}
+		changed = true
+		if m.CheckMode {
+			continue
+		}
+		if err := m.fs().Remove(filepath.Join(dir, link)); err != nil {
+			return false, err
+		}
+	}
+	for link, target := range desired {
+		if current, ok := existing[link]; ok && current == target {
+			continue
+		}
+		changed = true
+		if m.CheckMode {
+			continue
+		}
+		if _, err := m.CreateSymlink(target, filepath.Join(dir, link)); err != nil {
+			return false, err
+		}
+	}
+	return changed, nil
+}
+
+// hasCertificateExtension reports whether a file name looks like a certificate
+func hasCertificateExtension(name string) bool {
+	for _, ext := range certificateFileExtensions {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// InstallCertificate writes a certificate into a hashed certificate directory as
+// name and rehashes the directory, reporting whether anything changed
+func (m *AnsibleModule) InstallCertificate(dir, name string, cert *x509.Certificate) (bool, error) {
+	path := filepath.Join(dir, name)
+	content := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	changed := false
+	if current, err := m.ReadTextFile(path); err != nil || current != content {
+		changed = true
+		if m.CheckMode {
+			return true, nil
+		}
+		if _, err := m.WriteTextFile(path, content, 0644); err != nil {
+			return false, err
+		}
+	}
+	rehashed, err := m.RehashDirectory(dir)
+	return changed || rehashed, err
+}
+
+// RemoveCertificate deletes the certificate files of a hashed certificate
+// directory that match a SHA-256 or SHA-1 fingerprint and rehashes the
+// directory, reporting whether anything changed
+func (m *AnsibleModule) RemoveCertificate(dir, fingerprint string) (bool, error) {
+	fingerprint = normalizeFingerprint(fingerprint)
+	entries, err := m.fs().ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !hasCertificateExtension(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := m.fs().ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		certs, err := ParseCertificates(data)
+		if err != nil || len(certs) != 1 || !certificateHasFingerprint(certs[0], fingerprint) {
+			continue
+		}
+		changed = true
+		if m.CheckMode {
+			continue
+		}
+		if err := m.fs().Remove(path); err != nil {
+			return false, err
+		}
+	}
+	rehashed, err := m.RehashDirectory(dir)
+	return changed || rehashed, err
+}
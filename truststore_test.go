@@ -0,0 +1,143 @@
+package ansiblemodule
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseTestCertificate creates a certificate with newTestCertificate and parses it
+func parseTestCertificate(t *testing.T, name string) *x509.Certificate {
+	t.Helper()
+	certPEM, _ := newTestCertificate(t, name, time.Hour)
+	certs, err := ParseCertificates(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certs[0]
+}
+
+func TestCertificateSubjectHash(t *testing.T) {
+	cert := parseTestCertificate(t, "web.example.com")
+	if hash, err := CertificateSubjectHash(cert); err != nil || hash != "3c3589d6" {
+		t.Errorf("Expected openssl subject hash 3c3589d6, got %s (%v)", hash, err)
+	}
+
+	// OpenSSL encodes these as UTF8String and Go as PrintableString, and both
+	// hash alike once the case and whitespace are canonicalized
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Country: []string{"US"}, Organization: []string{"Example  Corp"}, CommonName: "Internal Root CA"},
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	cert, _ = x509.ParseCertificate(der)
+	if hash, err := CertificateSubjectHash(cert); err != nil || hash != "2baa84e3" {
+		t.Errorf("Expected openssl subject hash 2baa84e3, got %s (%v)", hash, err)
+	}
+}
+
+func TestCABundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca-bundle.crt")
+	first, _ := newTestCertificate(t, "first", time.Hour)
+	os.WriteFile(path, append([]byte("# First CA\n"), first...), 0644)
+	module := &AnsibleModule{}
+
+	bundle, err := module.LoadCABundle(path)
+	if err != nil || len(bundle.Certificates()) != 1 {
+		t.Fatalf("Expected one certificate, got %v", err)
+	}
+	existing := bundle.Certificates()[0]
+	if bundle.Add(existing, "") || bundle.Changed() {
+		t.Error("Expected adding a present certificate to change nothing")
+	}
+
+	second := parseTestCertificate(t, "second")
+	if !bundle.Add(second, "Second CA") {
+		t.Fatal("Expected certificate to be added")
+	}
+	changed, _, err := module.SaveCABundle(bundle, false)
+	if err != nil || !changed {
+		t.Fatalf("Expected bundle to be saved: %v", err)
+	}
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "# First CA\n-----BEGIN") || !strings.Contains(string(content), "\n\n# Second CA\n-----BEGIN") {
+		t.Errorf("Unexpected bundle content:\n%s", content)
+	}
+
+	bundle, _ = module.LoadCABundle(path)
+	fingerprint := CertificateFingerprint(existing)
+	var colons []string
+	for i := 0; i < len(fingerprint); i += 2 {
+		colons = append(colons, strings.ToUpper(fingerprint[i:i+2]))
+	}
+	if !bundle.Remove(strings.Join(colons, ":")) || len(bundle.Certificates()) != 1 || bundle.Certificates()[0].Subject.CommonName != "second" {
+		t.Errorf("Expected the first certificate to be removed by fingerprint")
+	}
+	if bundle.Remove(fingerprint) {
+		t.Error("Expected a second removal to change nothing")
+	}
+}
+
+func TestRehashDirectory(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, _ := newTestCertificate(t, "web.example.com", time.Hour)
+	os.WriteFile(filepath.Join(dir, "web.pem"), certPEM, 0644)
+	os.WriteFile(filepath.Join(dir, "web-copy.crt"), certPEM, 0644)
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a certificate"), 0644)
+	os.Symlink("gone.pem", filepath.Join(dir, "deadbeef.0"))
+	module := &AnsibleModule{}
+
+	module.CheckMode = true
+	if changed, err := module.RehashDirectory(dir); err != nil || !changed {
+		t.Fatalf("Expected check mode to report changes: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "deadbeef.0")); err != nil {
+		t.Error("Expected check mode to leave links alone")
+	}
+
+	module.CheckMode = false
+	if changed, err := module.RehashDirectory(dir); err != nil || !changed {
+		t.Fatalf("Expected links to be created: %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(dir, "3c3589d6.0")); err != nil || target != "web-copy.crt" {
+		t.Errorf("Expected one link to the first file, got %s (%v)", target, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	if strings.Join(names, " ") != "3c3589d6.0 README web-copy.crt web.pem" {
+		t.Errorf("Unexpected directory content: %v", names)
+	}
+	if changed, _ := module.RehashDirectory(dir); changed {
+		t.Error("Expected a second rehash to change nothing")
+	}
+
+	other := parseTestCertificate(t, "other.example.com")
+	if changed, err := module.InstallCertificate(dir, "other.pem", other); err != nil || !changed {
+		t.Fatalf("Expected certificate to be installed: %v", err)
+	}
+	if changed, _ := module.InstallCertificate(dir, "other.pem", other); changed {
+		t.Error("Expected installing again to change nothing")
+	}
+	hash, _ := CertificateSubjectHash(other)
+	if changed, err := module.RemoveCertificate(dir, CertificateFingerprint(other)); err != nil || !changed {
+		t.Fatalf("Expected certificate to be removed: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, hash+".0")); !os.IsNotExist(err) {
+		t.Error("Expected the hash link of the removed certificate to be gone")
+	}
+}
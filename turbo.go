@@ -0,0 +1,395 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Environment variables controlling turbo mode
+const (
+	TurboEnv       = "ANSIBLE_GO_TURBO" // Set to 0 to run without the turbo server
+	turboSocketEnv = "ANSIBLE_GO_TURBO_SOCKET"
+)
+
+// TurboTTL is how long the turbo server waits for another invocation before exiting
+var TurboTTL = 15 * time.Second
+
+// turboStartTimeout is how long a client waits for a new turbo server to listen
+var turboStartTimeout = 5 * time.Second
+
+// TurboCache holds values that survive between invocations served by the same
+// turbo server, such as API sessions and warmed caches. It is empty outside turbo mode.
+var TurboCache sync.Map
+
+// defaultOutput is the result destination given to modules created by NewModule
+var defaultOutput io.Writer
+
+// turboExitGrace is how long the server waits for a module to unwind after it
+// was failed from another goroutine, such as its watchdog
+var turboExitGrace = time.Second
+
+// turboRequest carries the module arguments and environment of one invocation to the server
+type turboRequest struct {
+	Args string   `json:"args"`
+	Env  []string `json:"env"`
+}
+
+// turboResponse carries the module output and exit code back to the client
+type turboResponse struct {
+	Output   string `json:"output"`
+	ExitCode int    `json:"exit_code"`
+	stuck    bool   // The module did not unwind after exiting, so the server must stop
+}
+
+// turboRun is the invocation the turbo server is running. Modules created
+// during it write their result to it and exit through it.
+type turboRun struct {
+	mu      sync.Mutex
+	output  bytes.Buffer
+	code    int
+	exited  chan struct{}
+	modules []*AnsibleModule
+}
+
+// turboCurrent is the invocation being served, nil outside the turbo server
+var turboCurrent atomic.Pointer[turboRun]
+
+// adopt makes a new module write to and exit through the invocation
+func (r *turboRun) adopt(m *AnsibleModule) {
+	m.ExitFunc = r.exit
+	m.Output = r
+	r.mu.Lock()
+	r.modules = append(r.modules, m)
+	r.mu.Unlock()
+}
+
+// Write collects the module output until the invocation has ended, dropping
+// what late exits write afterwards
+func (r *turboRun) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case <-r.exited:
+	default:
+		r.output.Write(p)
+	}
+	return len(p), nil
+}
+
+// end ends the invocation with code unless it has already ended
+func (r *turboRun) end(code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case <-r.exited:
+	default:
+		r.code = code
+		close(r.exited)
+	}
+}
+
+// fail replaces the output with a failure unless the invocation has ended
+func (r *turboRun) fail(msg string, replace bool) {
+	r.mu.Lock()
+	select {
+	case <-r.exited:
+	default:
+		if replace || r.output.Len() == 0 {
+			r.output.Reset()
+			data, _ := json.Marshal(map[string]interface{}{"failed": true, "msg": msg})
+			r.output.Write(append(data, '\n'))
+		}
+	}
+	r.mu.Unlock()
+	r.end(1)
+}
+
+// exit is the exit function of adopted modules. It ends the invocation and
+// stops the calling goroutine, which is the module itself or one of its timer
+// or signal goroutines, running its deferred calls as os.Exit would not.
+func (r *turboRun) exit(code int) {
+	r.end(code)
+	runtime.Goexit()
+}
+
+// Turbo runs a module through a persistent server process, so repeated
+// invocations of the same binary during a play skip process startup and can
+// reuse authenticated clients kept in TurboCache. The first invocation starts
+// the server, listening on a unix socket in a private directory of the user
+// below the controller's remote_tmp, which exits once idle for TurboTTL. Both
+// ends check that the other runs as the same user before exchanging anything,
+// so turbo mode is only used where peer credentials can be read (Linux). newModule creates the module, usually by calling
+// NewModule, and run does the work. Invocations are served one at a time, each
+// with the environment of its client.
+//
+// Turbo must be called at the start of main. If the server cannot be reached the
+// module runs in the current process, as it does when ANSIBLE_GO_TURBO=0.
+func Turbo(newModule func() (*AnsibleModule, error), run func(m *AnsibleModule)) {
+	if socket := os.Getenv(turboSocketEnv); socket != "" {
+		if err := serveTurbo(socket, newModule, run); err != nil {
+			fmt.Fprintf(os.Stderr, "turbo server failed: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	input, err := readModuleInput()
+	if err == nil && os.Getenv(TurboEnv) != "0" && turboSupported() {
+		if socket, err := turboSocketPath(input); err == nil {
+			if response, err := turboInvoke(socket, input); err == nil {
+				os.Stdout.WriteString(response.Output)
+				os.Exit(response.ExitCode)
+			}
+		}
+	}
+
+	// Run in this process, handing over the arguments already read from stdin
+	if err == nil {
+		os.Setenv("ANSIBLE_MODULE_ARGS", string(input))
+	}
+	if m, err := newModule(); err == nil {
+		run(m)
+	}
+}
+
+// readModuleInput reads the module arguments the way parseInput does
+func readModuleInput() ([]byte, error) {
+	if moduleArgs := os.Getenv("ANSIBLE_MODULE_ARGS"); moduleArgs != "" {
+		return []byte(moduleArgs), nil
+	}
+	return readLimitedInput(os.Stdin)
+}
+
+// turboSocketPath returns the socket of the server for this binary, in a
+// private directory below the controller's remote_tmp if it is given and
+// exists, or the system temp directory
+func turboSocketPath(input []byte) (string, error) {
+	executable, _ := os.Executable()
+	var modTime int64
+	if info, err := os.Stat(executable); err == nil {
+		modTime = info.ModTime().UnixNano()
+	}
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", executable, modTime)))
+	name := fmt.Sprintf("%x.sock", key[:8])
+
+	var args map[string]interface{}
+	json.Unmarshal(input, &args)
+	if remoteTmp, ok := args["_ansible_remote_tmp"].(string); ok && remoteTmp != "" {
+		if strings.HasPrefix(remoteTmp, "~") {
+			if home, err := os.UserHomeDir(); err == nil {
+				remoteTmp = home + remoteTmp[1:]
+			}
+		}
+		// Socket paths are limited to about 100 bytes
+		dir := filepath.Join(remoteTmp, turboDirName())
+		if info, err := os.Stat(remoteTmp); err == nil && info.IsDir() && len(filepath.Join(dir, name)) < 100 {
+			if err := ensurePrivateDir(dir); err == nil {
+				return filepath.Join(dir, name), nil
+			}
+		}
+	}
+	dir := filepath.Join(os.TempDir(), turboDirName())
+	if err := ensurePrivateDir(dir); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// turboDirName is the name of the private socket directory of the user
+func turboDirName() string {
+	return fmt.Sprintf("ansigo-turbo-%d", os.Geteuid())
+}
+
+// checkTurboPeer fails unless the other end of conn runs as the current user
+func checkTurboPeer(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("turbo connection is not a unix socket")
+	}
+	uid, err := turboPeerUID(unixConn)
+	if err != nil {
+		return fmt.Errorf("failed to read turbo peer credentials: %w", err)
+	}
+	if uid != os.Geteuid() {
+		return fmt.Errorf("turbo peer runs as uid %d, not %d", uid, os.Geteuid())
+	}
+	return nil
+}
+
+// turboInvoke sends the arguments to the server, starting it if it is not running
+func turboInvoke(socket string, input []byte) (*turboResponse, error) {
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		if err := startTurboServer(socket); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(turboStartTimeout)
+		for {
+			if conn, err = net.DialTimeout("unix", socket, time.Second); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("turbo server did not start: %w", err)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	defer conn.Close()
+
+	// Arguments and environment may hold secrets, only hand them to our own server
+	if err := checkTurboPeer(conn); err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(conn).Encode(turboRequest{Args: string(input), Env: os.Environ()}); err != nil {
+		return nil, fmt.Errorf("failed to send module arguments: %w", err)
+	}
+	var response turboResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to read module result: %w", err)
+	}
+	return &response, nil
+}
+
+// startTurboServer starts the current binary as a detached turbo server
+func startTurboServer(socket string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate module executable: %w", err)
+	}
+	cmd := exec.Command(executable)
+	cmd.Env = append(os.Environ(), turboSocketEnv+"="+socket)
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start turbo server: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+// serveTurbo listens on the socket until no invocation arrives for TurboTTL.
+// The socket must be in a private directory of the user, so nobody else can
+// replace it between removing a stale socket and listening.
+func serveTurbo(socket string, newModule func() (*AnsibleModule, error), run func(m *AnsibleModule)) error {
+	if err := ensurePrivateDir(filepath.Dir(socket)); err != nil {
+		return err
+	}
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	return serveTurboListener(listener.(*net.UnixListener), newModule, run)
+}
+
+// serveTurboListener serves invocations one at a time, since modules change
+// process-wide state such as the environment and umask
+func serveTurboListener(listener *net.UnixListener, newModule func() (*AnsibleModule, error), run func(m *AnsibleModule)) error {
+	defer listener.Close()
+	for {
+		listener.SetDeadline(time.Now().Add(TurboTTL))
+		conn, err := listener.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil
+			}
+			return err
+		}
+		if err := checkTurboPeer(conn); err != nil {
+			conn.Close()
+			continue
+		}
+
+		var request turboRequest
+		if err := json.NewDecoder(conn).Decode(&request); err != nil {
+			conn.Close()
+			continue
+		}
+		response := runTurboRequest(request, newModule, run)
+		json.NewEncoder(conn).Encode(response)
+		conn.Close()
+		if response.stuck {
+			return fmt.Errorf("module did not stop after exiting")
+		}
+	}
+}
+
+// runTurboRequest runs the module for one invocation in the environment of the
+// client, capturing its output and turning its exit into a return
+func runTurboRequest(request turboRequest, newModule func() (*AnsibleModule, error), run func(m *AnsibleModule)) turboResponse {
+	r := &turboRun{exited: make(chan struct{})}
+	restoreEnv := setTurboEnvironment(request.Env)
+	os.Setenv("ANSIBLE_MODULE_ARGS", request.Args)
+	turboCurrent.Store(r)
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer func() {
+			// Report a panic as a failure rather than taking the server down
+			if p := recover(); p != nil {
+				r.fail(fmt.Sprintf("module panicked: %v", p), true)
+			}
+		}()
+		m, err := newModule()
+		if err != nil {
+			r.fail(err.Error(), false)
+			return
+		}
+		run(m)
+		r.end(0)
+	}()
+
+	response := turboResponse{}
+	select {
+	case <-finished:
+	case <-r.exited:
+		// Failed from another goroutine, give the module time to unwind
+		select {
+		case <-finished:
+		case <-time.After(turboExitGrace):
+			response.stuck = true
+		}
+	}
+	turboCurrent.Store(nil)
+	restoreEnv()
+
+	r.mu.Lock()
+	for _, m := range r.modules {
+		m.stopWatchdog()
+	}
+	response.Output, response.ExitCode = r.output.String(), r.code
+	r.mu.Unlock()
+	return response
+}
+
+// setTurboEnvironment replaces the server environment with env, when given,
+// and returns a function restoring the server's own
+func setTurboEnvironment(env []string) func() {
+	saved := os.Environ()
+	replaceEnvironment := func(env []string) {
+		os.Clearenv()
+		for _, entry := range env {
+			// Windows keeps per-drive directories in variables starting with =
+			if i := strings.IndexByte(entry[min(1, len(entry)):], '=') + 1; i > 0 {
+				os.Setenv(entry[:i], entry[i+1:])
+			}
+		}
+	}
+	if env != nil {
+		replaceEnvironment(env)
+	}
+	return func() { replaceEnvironment(saved) }
+}
@@ -0,0 +1,31 @@
+package ansiblemodule
+
+import (
+	"net"
+	"syscall"
+)
+
+// turboSupported reports whether turbo mode can check who is at the other end
+// of its socket
+func turboSupported() bool {
+	return true
+}
+
+// turboPeerUID returns the user ID of the process at the other end of conn
+func turboPeerUID(conn *net.UnixConn) (int, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return -1, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return -1, err
+	}
+	if credErr != nil {
+		return -1, credErr
+	}
+	return int(cred.Uid), nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package ansiblemodule
+
+import (
+	"fmt"
+	"net"
+)
+
+// turboSupported reports that peer credentials cannot be checked, so modules
+// run in their own process
+func turboSupported() bool {
+	return false
+}
+
+// turboPeerUID reports that peer credentials are not available
+func turboPeerUID(conn *net.UnixConn) (int, error) {
+	return -1, fmt.Errorf("peer credentials are only supported on Linux")
+}
@@ -0,0 +1,163 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// turboTestModule counts invocations in TurboCache and echoes its name argument
+func turboTestModule() (func() (*AnsibleModule, error), func(m *AnsibleModule)) {
+	newModule := func() (*AnsibleModule, error) {
+		return NewModule(ArgSpecMap{"name": {Type: "str", Required: true}}, nil, nil, nil, nil, true)
+	}
+	run := func(m *AnsibleModule) {
+		count, _ := TurboCache.LoadOrStore("count", new(int))
+		*count.(*int)++
+		if m.Params["name"] == "boom" {
+			panic("exploded")
+		}
+		m.ExitJson(map[string]interface{}{"name": m.Params["name"], "count": *count.(*int)})
+	}
+	return newModule, run
+}
+
+func TestRunTurboRequest(t *testing.T) {
+	oldLocale := NormalizeLocale
+	NormalizeLocale = false
+	defer func() { NormalizeLocale = oldLocale }()
+	newModule, run := turboTestModule()
+
+	response := runTurboRequest(turboRequest{Args: `{"name": "web"}`}, newModule, run)
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(response.Output), &result); err != nil || result["name"] != "web" {
+		t.Fatalf("Expected module result, got %q (%v)", response.Output, err)
+	}
+
+	response = runTurboRequest(turboRequest{Args: `{}`}, newModule, run)
+	if !strings.Contains(response.Output, `"failed":true`) {
+		t.Errorf("Expected validation failure, got %q", response.Output)
+	}
+
+	response = runTurboRequest(turboRequest{Args: `{"name": "boom"}`}, newModule, run)
+	if response.ExitCode != 1 || !strings.Contains(response.Output, "module panicked: exploded") {
+		t.Errorf("Expected panic to be reported, got %d %q", response.ExitCode, response.Output)
+	}
+	if turboCurrent.Load() != nil {
+		t.Error("Expected no invocation to be running")
+	}
+}
+
+func TestTurboServer(t *testing.T) {
+	oldLocale, oldTTL := NormalizeLocale, TurboTTL
+	NormalizeLocale, TurboTTL = false, 200*time.Millisecond
+	defer func() { NormalizeLocale, TurboTTL = oldLocale, oldTTL }()
+	TurboCache.Delete("count")
+	newModule, run := turboTestModule()
+	if !turboSupported() {
+		t.Skip("Turbo mode needs peer credentials")
+	}
+
+	socket := filepath.Join(t.TempDir(), "turbo.sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		t.Skipf("Cannot listen on unix socket: %v", err)
+	}
+	done := make(chan error)
+	go func() { done <- serveTurboListener(listener, newModule, run) }()
+
+	// State kept in TurboCache is shared between invocations
+	for i := 1; i <= 2; i++ {
+		response, err := turboInvoke(socket, []byte(`{"name": "db"}`))
+		if err != nil {
+			t.Fatalf("Invocation %d failed: %v", i, err)
+		}
+		var result map[string]interface{}
+		json.Unmarshal([]byte(response.Output), &result)
+		if result["count"] != float64(i) {
+			t.Errorf("Expected count %d, got %v", i, result["count"])
+		}
+	}
+
+	// The server exits once idle
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Server failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected server to exit after TTL")
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Error("Expected socket to be removed")
+	}
+}
+
+func TestTurboSocketPath(t *testing.T) {
+	remoteTmp := t.TempDir()
+	input, _ := json.Marshal(map[string]interface{}{"_ansible_remote_tmp": remoteTmp})
+	socket, err := turboSocketPath(input)
+	if err != nil || filepath.Dir(socket) != filepath.Join(remoteTmp, turboDirName()) {
+		t.Fatalf("Expected socket in a private directory of remote tmp, got %s (%v)", socket, err)
+	}
+	if info, err := os.Lstat(filepath.Dir(socket)); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected a 0700 socket directory, got %v (%v)", info.Mode(), err)
+	}
+
+	// A directory others can write to, or a symlink, is not used
+	os.Chmod(filepath.Dir(socket), 0777)
+	if err := ensurePrivateDir(filepath.Dir(socket)); err == nil {
+		t.Error("Expected a shared directory to be rejected")
+	}
+	os.Remove(filepath.Dir(socket))
+	os.Symlink(t.TempDir(), filepath.Dir(socket))
+	if err := ensurePrivateDir(filepath.Dir(socket)); err == nil {
+		t.Error("Expected a symlinked directory to be rejected")
+	}
+	if other, err := turboSocketPath(input); err != nil || filepath.Dir(filepath.Dir(other)) != filepath.Clean(os.TempDir()) {
+		t.Errorf("Expected socket in system temp dir, got %s (%v)", other, err)
+	}
+}
+
+func TestTurboRequestIsolation(t *testing.T) {
+	oldLocale, oldGrace := NormalizeLocale, turboExitGrace
+	NormalizeLocale, turboExitGrace = false, 100*time.Millisecond
+	defer func() { NormalizeLocale, turboExitGrace = oldLocale, oldGrace }()
+	newModule := func() (*AnsibleModule, error) {
+		spec, _ := MergeArgSpecs(ArgSpecMap{"hang": {Type: "bool"}}, WatchdogSpec())
+		return NewModule(spec, nil, nil, nil, nil, true)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	run := func(m *AnsibleModule) {
+		if m.Params["hang"] == true {
+			m.StartWatchdog(50 * time.Millisecond)
+			<-release
+		}
+		m.ExitJson(map[string]interface{}{"value": os.Getenv("TURBO_TEST_VALUE")})
+	}
+
+	// Each invocation runs with the environment of its client, and a watchdog
+	// still armed when the module exits is disarmed with the invocation
+	env := append(os.Environ(), "TURBO_TEST_VALUE=client")
+	response := runTurboRequest(turboRequest{Args: `{"module_timeout": 0.05}`, Env: env}, newModule, run)
+	if response.ExitCode != 0 || !strings.Contains(response.Output, `"value":"client"`) {
+		t.Errorf("Expected the client environment, got %d %q", response.ExitCode, response.Output)
+	}
+	if os.Getenv("TURBO_TEST_VALUE") != "" {
+		t.Error("Expected the server environment to be restored")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// A module failed by its watchdog is reported and marks the server as stuck
+	response = runTurboRequest(turboRequest{Args: `{"hang": true}`}, newModule, run)
+	if !strings.Contains(response.Output, "module timed out after 50ms") || !response.stuck {
+		t.Errorf("Expected the watchdog failure, got %q", response.Output)
+	}
+}
@@ -0,0 +1,45 @@
+package ansiblemodule
+
+import (
+	"os"
+	"sync"
+)
+
+// ModuleUmask, when not negative, is the umask NewModule sets for the life of
+// the module, so created files get the same permissions however it was started
+var ModuleUmask = -1
+
+// umaskMu serializes umask changes, since the umask belongs to the whole process
+var umaskMu sync.Mutex
+
+// SetUmask sets the process umask until the module exits, returning the previous
+// umask. The umask the module started with is restored by ExitJson and FailJson.
+func (m *AnsibleModule) SetUmask(mask os.FileMode) os.FileMode {
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+	previous := os.FileMode(setUmask(int(mask.Perm())))
+	if m.savedUmask == nil {
+		m.savedUmask = &previous
+	}
+	return previous
+}
+
+// WithUmask runs fn with a different umask, such as 0077 for a file holding
+// secrets, and restores the module umask afterwards
+func (m *AnsibleModule) WithUmask(mask os.FileMode, fn func() error) error {
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+	previous := setUmask(int(mask.Perm()))
+	defer setUmask(previous)
+	return fn()
+}
+
+// restoreUmask puts back the umask replaced by SetUmask
+func (m *AnsibleModule) restoreUmask() {
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+	if m.savedUmask != nil {
+		setUmask(int(*m.savedUmask))
+		m.savedUmask = nil
+	}
+}
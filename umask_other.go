@@ -0,0 +1,8 @@
+//go:build !unix
+
+package ansiblemodule
+
+// setUmask does nothing where there is no umask
+func setUmask(mask int) int {
+	return 0
+}
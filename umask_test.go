@@ -0,0 +1,53 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetUmaskRestoredOnExit(t *testing.T) {
+	original := setUmask(0022)
+	defer setUmask(original)
+
+	module := &AnsibleModule{Params: ModuleParams{}}
+	if previous := module.SetUmask(0077); previous != 0022 {
+		t.Errorf("Expected previous umask 0022, got %04o", previous)
+	}
+	module.SetUmask(0027)
+
+	path := filepath.Join(t.TempDir(), "private")
+	if err := os.WriteFile(path, []byte("x"), 0666); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640, got %04o", info.Mode().Perm())
+	}
+
+	exitOutput(t, module, map[string]interface{}{})
+	if current := setUmask(0022); current != 0022 {
+		t.Errorf("Expected umask 0022 restored, got %04o", current)
+	}
+}
+
+func TestWithUmask(t *testing.T) {
+	original := setUmask(0022)
+	defer setUmask(original)
+
+	module := &AnsibleModule{Params: ModuleParams{}}
+	path := filepath.Join(t.TempDir(), "secret")
+	err := module.WithUmask(0077, func() error {
+		return os.WriteFile(path, []byte("x"), 0666)
+	})
+	if err != nil {
+		t.Fatalf("WithUmask failed: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %04o", info.Mode().Perm())
+	}
+	if current := setUmask(0022); current != 0022 {
+		t.Errorf("Expected umask 0022 after WithUmask, got %04o", current)
+	}
+}
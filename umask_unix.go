@@ -0,0 +1,12 @@
+//go:build unix
+
+package ansiblemodule
+
+import (
+	"syscall"
+)
+
+// setUmask sets the process umask and returns the previous one
+func setUmask(mask int) int {
+	return syscall.Umask(mask)
+}
@@ -0,0 +1,401 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UserSpec is the desired state of a local user account. Empty fields are left
+// as they are, and Groups is only managed when it is not nil.
+type UserSpec struct {
+	Name       string
+	State      string   // present (the default) or absent
+	UID        int      // 0 leaves the uid unmanaged
+	Group      string   // Primary group name or gid
+	Groups     []string // Supplementary groups
+	Append     bool     // Add Groups to the current groups instead of replacing them
+	Comment    string
+	Home       string
+	Shell      string
+	System     bool // Create a system account
+	CreateHome bool // Create the home directory when adding the user
+	RemoveHome bool // Remove the home directory when removing the user
+}
+
+// GroupSpec is the desired state of a local group
+type GroupSpec struct {
+	Name   string
+	State  string // present (the default) or absent
+	GID    int    // 0 leaves the gid unmanaged
+	System bool   // Create a system group
+}
+
+// accountTools identifies the account management commands available:
+// shadow (useradd and friends, also on OpenBSD and NetBSD), pw on FreeBSD and
+// DragonFly, or busybox adduser
+func (m *AnsibleModule) accountTools() (string, error) {
+	for _, tool := range []struct{ binary, name string }{{"useradd", "shadow"}, {"pw", "pw"}, {"adduser", "busybox"}} {
+		if path, _ := m.GetBinPath(tool.binary, false); path != "" {
+			return tool.name, nil
+		}
+	}
+	return "", fmt.Errorf("no user management commands found")
+}
+
+// lookupAccount returns the getent fields of a passwd or group entry, or nil if it does not exist
+func (m *AnsibleModule) lookupAccount(database, name string) ([]string, error) {
+	entries, err := m.Getent(database, name)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entries[name], nil
+}
+
+// userGroups returns the supplementary groups listing a user as a member
+func (m *AnsibleModule) userGroups(name string) ([]string, error) {
+	entries, err := m.Getent("group", "")
+	if err != nil {
+		return nil, err
+	}
+	var groups []string
+	for group, fields := range entries {
+		if len(fields) < 3 {
+			continue
+		}
+		for _, member := range strings.Split(fields[2], ",") {
+			if member == name {
+				groups = append(groups, group)
+				break
+			}
+		}
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
+
+// groupID resolves a group name or gid to a gid
+func (m *AnsibleModule) groupID(group string) (string, error) {
+	if _, err := strconv.Atoi(group); err == nil {
+		return group, nil
+	}
+	fields, err := m.lookupAccount("group", group)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) < 2 {
+		return "", notFound(fmt.Errorf("group %s does not exist", group))
+	}
+	return fields[1], nil
+}
+
+// runAccountCommand runs an account management command
+func (m *AnsibleModule) runAccountCommand(tool string, args ...string) error {
+	path, err := m.GetBinPath(tool, true)
+	if err != nil {
+		return err
+	}
+	result, err := m.RunCommand(path, args, nil, "")
+	if err != nil {
+		return commandFailure(err, result, "%s failed", tool)
+	}
+	return nil
+}
+
+// EnsureUser creates, updates or removes a local user to match spec, reporting
+// whether anything changed. No changes are made in check mode.
+func (m *AnsibleModule) EnsureUser(spec UserSpec) (bool, error) {
+	if spec.State != "" && spec.State != "present" && spec.State != "absent" {
+		return false, fmt.Errorf("invalid user state %s", spec.State)
+	}
+	current, err := m.lookupAccount("passwd", spec.Name)
+	if err != nil {
+		return false, err
+	}
+	if current != nil && len(current) < 6 {
+		return false, fmt.Errorf("malformed passwd entry for %s", spec.Name)
+	}
+
+	if spec.State == "absent" {
+		if current == nil {
+			return false, nil
+		}
+		if m.CheckMode {
+			return true, nil
+		}
+		if err := m.removeUser(spec); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if current == nil {
+		if m.CheckMode {
+			return true, nil
+		}
+		if err := m.addUser(spec); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return m.updateUser(spec, current)
+}
+
+// addUser creates a user
+func (m *AnsibleModule) addUser(spec UserSpec) error {
+	tools, err := m.accountTools()
+	if err != nil {
+		return err
+	}
+	if tools == "busybox" {
+		args := []string{"-D"}
+		args = appendFlag(args, "-u", spec.UID)
+		args = appendFlag(args, "-G", spec.Group)
+		args = appendFlag(args, "-g", spec.Comment)
+		args = appendFlag(args, "-h", spec.Home)
+		args = appendFlag(args, "-s", spec.Shell)
+		if spec.System {
+			args = append(args, "-S")
+		}
+		if !spec.CreateHome {
+			args = append(args, "-H")
+		}
+		if err := m.runAccountCommand("adduser", append(args, spec.Name)...); err != nil {
+			return err
+		}
+		for _, group := range spec.Groups {
+			if err := m.runAccountCommand("addgroup", spec.Name, group); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	args := userFlags(spec, spec.Groups)
+	if spec.CreateHome {
+		args = append(args, "-m")
+	}
+	if tools == "pw" {
+		return m.runAccountCommand("pw", append([]string{"useradd", spec.Name}, args...)...)
+	}
+	if spec.System {
+		args = append(args, "-r")
+	}
+	return m.runAccountCommand("useradd", append(args, spec.Name)...)
+}
+
+// removeUser deletes a user
+func (m *AnsibleModule) removeUser(spec UserSpec) error {
+	tools, err := m.accountTools()
+	if err != nil {
+		return err
+	}
+	switch tools {
+	case "busybox":
+		if spec.RemoveHome {
+			return m.runAccountCommand("deluser", "--remove-home", spec.Name)
+		}
+		return m.runAccountCommand("deluser", spec.Name)
+	case "pw":
+		if spec.RemoveHome {
+			return m.runAccountCommand("pw", "userdel", spec.Name, "-r")
+		}
+		return m.runAccountCommand("pw", "userdel", spec.Name)
+	}
+	if spec.RemoveHome {
+		return m.runAccountCommand("userdel", "-r", spec.Name)
+	}
+	return m.runAccountCommand("userdel", spec.Name)
+}
+
+// updateUser modifies the attributes of an existing user that differ from spec.
+// current holds the getent fields: password, uid, gid, comment, home and shell.
+func (m *AnsibleModule) updateUser(spec UserSpec, current []string) (bool, error) {
+	var desired UserSpec
+	desired.Name = spec.Name
+	if spec.UID != 0 && strconv.Itoa(spec.UID) != current[1] {
+		desired.UID = spec.UID
+	}
+	if spec.Group != "" {
+		gid, err := m.groupID(spec.Group)
+		if err != nil {
+			return false, err
+		}
+		if gid != current[2] {
+			desired.Group = spec.Group
+		}
+	}
+	if spec.Comment != "" && spec.Comment != current[3] {
+		desired.Comment = spec.Comment
+	}
+	if spec.Home != "" && spec.Home != current[4] {
+		desired.Home = spec.Home
+	}
+	if spec.Shell != "" && spec.Shell != current[5] {
+		desired.Shell = spec.Shell
+	}
+
+	var groups, added, removed []string
+	if spec.Groups != nil {
+		have, err := m.userGroups(spec.Name)
+		if err != nil {
+			return false, err
+		}
+		haveSet := make(map[string]bool, len(have))
+		for _, group := range have {
+			haveSet[group] = true
+		}
+		wantSet := make(map[string]bool, len(spec.Groups))
+		for _, group := range spec.Groups {
+			wantSet[group] = true
+			if !haveSet[group] {
+				added = append(added, group)
+			}
+		}
+		if !spec.Append {
+			for _, group := range have {
+				if !wantSet[group] {
+					removed = append(removed, group)
+				}
+			}
+		}
+		if len(added) > 0 || len(removed) > 0 {
+			// Replace the groups with the union when appending
+			if spec.Append {
+				for group := range haveSet {
+					wantSet[group] = true
+				}
+			}
+			groups = []string{}
+			for group := range wantSet {
+				groups = append(groups, group)
+			}
+			sort.Strings(groups)
+		}
+	}
+
+	attributes := userFlags(desired, nil)
+	if len(attributes) == 0 && groups == nil {
+		return false, nil
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+
+	tools, err := m.accountTools()
+	if err != nil {
+		return false, err
+	}
+	switch tools {
+	case "busybox":
+		if len(attributes) > 0 {
+			return false, fmt.Errorf("busybox cannot modify user %s, only its groups", spec.Name)
+		}
+		for _, group := range added {
+			if err := m.runAccountCommand("addgroup", spec.Name, group); err != nil {
+				return false, err
+			}
+		}
+		for _, group := range removed {
+			if err := m.runAccountCommand("delgroup", spec.Name, group); err != nil {
+				return false, err
+			}
+		}
+	case "pw":
+		if err := m.runAccountCommand("pw", append([]string{"usermod", spec.Name}, userFlags(desired, groups)...)...); err != nil {
+			return false, err
+		}
+	default:
+		if err := m.runAccountCommand("usermod", append(userFlags(desired, groups), spec.Name)...); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// userFlags returns the useradd, usermod and pw flags setting the fields of spec
+func userFlags(spec UserSpec, groups []string) []string {
+	var args []string
+	args = appendFlag(args, "-u", spec.UID)
+	args = appendFlag(args, "-g", spec.Group)
+	if groups != nil {
+		args = append(args, "-G", strings.Join(groups, ","))
+	}
+	args = appendFlag(args, "-c", spec.Comment)
+	args = appendFlag(args, "-d", spec.Home)
+	args = appendFlag(args, "-s", spec.Shell)
+	return args
+}
+
+// appendFlag appends a flag and its value unless the value is empty or zero
+func appendFlag[T string | int](args []string, flag string, value T) []string {
+	var zero T
+	if value == zero {
+		return args
+	}
+	return append(args, flag, fmt.Sprint(value))
+}
+
+// EnsureGroup creates, updates or removes a local group to match spec,
+// reporting whether anything changed. No changes are made in check mode.
+func (m *AnsibleModule) EnsureGroup(spec GroupSpec) (bool, error) {
+	if spec.State != "" && spec.State != "present" && spec.State != "absent" {
+		return false, fmt.Errorf("invalid group state %s", spec.State)
+	}
+	current, err := m.lookupAccount("group", spec.Name)
+	if err != nil {
+		return false, err
+	}
+
+	var args []string
+	var command string
+	switch {
+	case spec.State == "absent" && current == nil:
+		return false, nil
+	case spec.State == "absent":
+		command = "del"
+	case current == nil:
+		command = "add"
+		args = appendFlag(args, "-g", spec.GID)
+	case spec.GID != 0 && (len(current) < 2 || current[1] != strconv.Itoa(spec.GID)):
+		command = "mod"
+		args = appendFlag(args, "-g", spec.GID)
+	default:
+		return false, nil
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+
+	tools, err := m.accountTools()
+	if err != nil {
+		return false, err
+	}
+	switch tools {
+	case "busybox":
+		switch command {
+		case "add":
+			if spec.System {
+				args = append(args, "-S")
+			}
+			err = m.runAccountCommand("addgroup", append(args, spec.Name)...)
+		case "del":
+			err = m.runAccountCommand("delgroup", spec.Name)
+		default:
+			err = fmt.Errorf("busybox cannot change the gid of group %s", spec.Name)
+		}
+	case "pw":
+		err = m.runAccountCommand("pw", append([]string{"group" + command, spec.Name}, args...)...)
+	default:
+		if command == "add" && spec.System {
+			args = append(args, "-r")
+		}
+		err = m.runAccountCommand("group"+command, append(args, spec.Name)...)
+	}
+	return err == nil, err
+}
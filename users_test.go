@@ -0,0 +1,139 @@
+package ansiblemodule
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// accountRunner answers getent from fixed databases, provides the listed
+// binaries and records the other commands run, failing those of failing
+type accountRunner struct {
+	databases map[string][]string
+	binaries  []string
+	commands  []string
+	failing   string
+}
+
+func (a *accountRunner) Run(ctx context.Context, cmd string, args []string, env []string, data string) (CommandResult, error) {
+	if cmd == "/stub/getent" {
+		var lines []string
+		for _, line := range a.databases[args[0]] {
+			if len(args) == 1 || strings.HasPrefix(line, args[1]+":") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			return CommandResult{Rc: 2}, nil
+		}
+		return CommandResult{Stdout: strings.Join(lines, "\n") + "\n"}, nil
+	}
+	a.commands = append(a.commands, strings.Join(append([]string{strings.TrimPrefix(cmd, "/stub/")}, args...), " "))
+	if strings.TrimPrefix(cmd, "/stub/") == a.failing {
+		return CommandResult{Stderr: "permission denied\n", Rc: 1}, nil
+	}
+	return CommandResult{}, nil
+}
+
+func (a *accountRunner) LookPath(name string) (string, error) {
+	for _, binary := range append(a.binaries, "getent") {
+		if binary == name {
+			return "/stub/" + name, nil
+		}
+	}
+	return "", context.Canceled
+}
+
+func newAccountRunner(binaries ...string) *accountRunner {
+	return &accountRunner{
+		databases: map[string][]string{
+			"passwd": {"alice:x:1000:1000:Alice:/home/alice:/bin/sh"},
+			"group":  {"alice:x:1000:", "wheel:x:10:alice", "docker:x:999:", "staff:x:50:bob"},
+		},
+		binaries: binaries,
+	}
+}
+
+func TestEnsureUser(t *testing.T) {
+	runner := newAccountRunner("useradd", "usermod", "userdel")
+	module := &AnsibleModule{Runner: runner}
+
+	changed, err := module.EnsureUser(UserSpec{Name: "alice", UID: 1000, Group: "alice", Shell: "/bin/sh", Groups: []string{"wheel"}})
+	if err != nil || changed || len(runner.commands) != 0 {
+		t.Errorf("Expected matching user to be unchanged, got %v (%v) %v", changed, err, runner.commands)
+	}
+
+	changed, err = module.EnsureUser(UserSpec{Name: "alice", Shell: "/bin/bash", Groups: []string{"docker"}, Append: true})
+	if err != nil || !changed {
+		t.Fatalf("Expected modification, got %v (%v)", changed, err)
+	}
+	if expected := []string{"usermod -G docker,wheel -s /bin/bash alice"}; !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("Expected %v, got %v", expected, runner.commands)
+	}
+
+	runner.commands = nil
+	changed, err = module.EnsureUser(UserSpec{Name: "bob", UID: 1001, System: true, CreateHome: true})
+	if err != nil || !changed || !reflect.DeepEqual(runner.commands, []string{"useradd -u 1001 -m -r bob"}) {
+		t.Errorf("Expected bob to be added, got %v (%v) %v", changed, err, runner.commands)
+	}
+
+	runner.commands = nil
+	module.CheckMode = true
+	changed, err = module.EnsureUser(UserSpec{Name: "alice", State: "absent"})
+	if err != nil || !changed || len(runner.commands) != 0 {
+		t.Errorf("Expected check mode removal without commands, got %v (%v) %v", changed, err, runner.commands)
+	}
+
+	module.CheckMode = false
+	for _, spec := range []UserSpec{{Name: "alice", State: "absent"}, {Name: "carol"}} {
+		runner.failing = map[string]string{"absent": "userdel", "": "useradd"}[spec.State]
+		if changed, err := module.EnsureUser(spec); err == nil || changed {
+			t.Errorf("Expected a failed %s to report no change, got %v (%v)", runner.failing, changed, err)
+		}
+	}
+}
+
+func TestEnsureUserVariants(t *testing.T) {
+	runner := newAccountRunner("pw")
+	module := &AnsibleModule{Runner: runner}
+	if _, err := module.EnsureUser(UserSpec{Name: "alice", Groups: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"pw usermod alice -G "}; !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("Expected %v, got %v", expected, runner.commands)
+	}
+
+	runner = newAccountRunner("adduser", "addgroup", "delgroup", "deluser")
+	module = &AnsibleModule{Runner: runner}
+	if _, err := module.EnsureUser(UserSpec{Name: "alice", Groups: []string{"docker"}}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"addgroup alice docker", "delgroup alice wheel"}; !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("Expected %v, got %v", expected, runner.commands)
+	}
+	if _, err := module.EnsureUser(UserSpec{Name: "alice", Shell: "/bin/ash"}); err == nil {
+		t.Error("Expected busybox attribute change to fail")
+	}
+}
+
+func TestEnsureGroup(t *testing.T) {
+	runner := newAccountRunner("useradd", "groupadd", "groupmod", "groupdel")
+	module := &AnsibleModule{Runner: runner}
+
+	for _, spec := range []GroupSpec{
+		{Name: "wheel", GID: 10},
+		{Name: "wheel", GID: 11},
+		{Name: "ops", System: true},
+		{Name: "staff", State: "absent"},
+		{Name: "missing", State: "absent"},
+	} {
+		if _, err := module.EnsureGroup(spec); err != nil {
+			t.Fatalf("%v: %v", spec, err)
+		}
+	}
+	expected := []string{"groupmod -g 11 wheel", "groupadd -r ops", "groupdel staff"}
+	if !reflect.DeepEqual(runner.commands, expected) {
+		t.Errorf("Expected %v, got %v", expected, runner.commands)
+	}
+}
@@ -0,0 +1,133 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// WaitInterval is the pause between checks of WaitForPort and WaitForPath
+var WaitInterval = time.Second
+
+// waitConnectTimeout bounds each connection attempt of WaitForPort
+const waitConnectTimeout = 5 * time.Second
+
+// waitUntil checks a condition after delay and then every WaitInterval until it
+// holds, the timeout passes or the module is cancelled. It returns the time waited.
+func (m *AnsibleModule) waitUntil(what string, timeout, delay time.Duration, check func(deadline time.Time) (bool, error)) (time.Duration, error) {
+	start := time.Now()
+	deadline := start.Add(delay + timeout)
+	ctx := m.Context()
+	select {
+	case <-ctx.Done():
+		return time.Since(start), ctx.Err()
+	case <-time.After(delay):
+	}
+	for {
+		done, err := check(deadline)
+		if err != nil {
+			return time.Since(start), err
+		}
+		if done {
+			return time.Since(start), nil
+		}
+		if !time.Now().Add(WaitInterval).Before(deadline) {
+			return time.Since(start), fmt.Errorf("timeout when waiting for %s", what)
+		}
+		select {
+		case <-ctx.Done():
+			return time.Since(start), ctx.Err()
+		case <-time.After(WaitInterval):
+		}
+	}
+}
+
+// checkPortState validates a WaitForPort state, returning whether it wants the port open
+func checkPortState(state string) (bool, error) {
+	switch state {
+	case "", "started", "present":
+		return true, nil
+	case "stopped", "absent":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid port state %s, expected started or stopped", state)
+}
+
+// WaitForPort waits until a TCP port accepts connections (state started or
+// present) or refuses them (stopped or absent), checking after delay and then
+// every WaitInterval for up to timeout. It returns the time waited.
+func (m *AnsibleModule) WaitForPort(host string, port int, state string, timeout, delay time.Duration) (time.Duration, error) {
+	wantOpen, err := checkPortState(state)
+	if err != nil {
+		return 0, err
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	return m.waitUntil(fmt.Sprintf("%s to be %s", address, portStateName(wantOpen)), timeout, delay, func(deadline time.Time) (bool, error) {
+		dialer := net.Dialer{Timeout: min(waitConnectTimeout, max(time.Until(deadline), time.Millisecond))}
+		conn, err := dialer.DialContext(m.Context(), "tcp", address)
+		if err == nil {
+			conn.Close()
+		}
+		return (err == nil) == wantOpen, nil
+	})
+}
+
+// WaitForUDPPort waits like WaitForPort for a UDP port. Since UDP has no
+// connections, a port counts as closed when a probe is answered with an error
+// such as ICMP port unreachable, and as open when it gets a reply or no answer.
+func (m *AnsibleModule) WaitForUDPPort(host string, port int, state string, timeout, delay time.Duration) (time.Duration, error) {
+	wantOpen, err := checkPortState(state)
+	if err != nil {
+		return 0, err
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	return m.waitUntil(fmt.Sprintf("%s/udp to be %s", address, portStateName(wantOpen)), timeout, delay, func(deadline time.Time) (bool, error) {
+		conn, err := net.DialTimeout("udp", address, waitConnectTimeout)
+		if err != nil {
+			return !wantOpen, nil
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(min(time.Second, max(time.Until(deadline), time.Millisecond))))
+		conn.Write([]byte{})
+		_, err = conn.Read(make([]byte, 1))
+		var netErr net.Error
+		open := err == nil || errors.As(err, &netErr) && netErr.Timeout()
+		return open == wantOpen, nil
+	})
+}
+
+// portStateName describes the awaited port state
+func portStateName(open bool) string {
+	if open {
+		return "started"
+	}
+	return "stopped"
+}
+
+// WaitForPath waits until a path exists (state present) or is gone (absent),
+// checking after delay and then every WaitInterval for up to timeout. It
+// returns the time waited.
+func (m *AnsibleModule) WaitForPath(path, state string, timeout, delay time.Duration) (time.Duration, error) {
+	var wantPresent bool
+	switch state {
+	case "", "present", "started":
+		wantPresent = true
+	case "absent", "stopped":
+	default:
+		return 0, fmt.Errorf("invalid path state %s, expected present or absent", state)
+	}
+	name := "present"
+	if !wantPresent {
+		name = "absent"
+	}
+	return m.waitUntil(fmt.Sprintf("%s to be %s", path, name), timeout, delay, func(time.Time) (bool, error) {
+		_, err := m.fs().Stat(path)
+		if err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		return (err == nil) == wantPresent, nil
+	})
+}
@@ -0,0 +1,57 @@
+package ansiblemodule
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitForPort(t *testing.T) {
+	old := WaitInterval
+	WaitInterval = 10 * time.Millisecond
+	defer func() { WaitInterval = old }()
+	module := &AnsibleModule{}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	if _, err := module.WaitForPort("127.0.0.1", port, "started", time.Second, 0); err != nil {
+		t.Errorf("Expected open port to be found: %v", err)
+	}
+	if _, err := module.WaitForPort("127.0.0.1", port, "stopped", 50*time.Millisecond, 0); err == nil {
+		t.Error("Expected waiting for an open port to stop to time out")
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() { listener.Close() })
+	if _, err := module.WaitForPort("127.0.0.1", port, "stopped", 2*time.Second, 0); err != nil {
+		t.Errorf("Expected closed port to be detected: %v", err)
+	}
+	if _, err := module.WaitForPort("127.0.0.1", port, "drained", time.Second, 0); err == nil {
+		t.Error("Expected an invalid state to fail")
+	}
+}
+
+func TestWaitForPath(t *testing.T) {
+	old := WaitInterval
+	WaitInterval = 10 * time.Millisecond
+	defer func() { WaitInterval = old }()
+	module := &AnsibleModule{}
+	path := filepath.Join(t.TempDir(), "ready")
+
+	time.AfterFunc(50*time.Millisecond, func() { os.WriteFile(path, nil, 0644) })
+	waited, err := module.WaitForPath(path, "present", 2*time.Second, 20*time.Millisecond)
+	if err != nil || waited < 20*time.Millisecond {
+		t.Errorf("Expected path to appear after the delay, waited %v (%v)", waited, err)
+	}
+	if _, err := module.WaitForPath(path, "absent", 50*time.Millisecond, 0); err == nil {
+		t.Error("Expected waiting for an existing path to go away to time out")
+	}
+	os.Remove(path)
+	if _, err := module.WaitForPath(path, "absent", time.Second, 0); err != nil {
+		t.Errorf("Expected missing path to be absent: %v", err)
+	}
+}
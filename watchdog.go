@@ -0,0 +1,94 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// WatchdogEnv names the environment variable holding the overall module timeout in seconds
+const WatchdogEnv = "ANSIBLE_GO_MODULE_TIMEOUT"
+
+// WatchdogOption is the module option of WatchdogSpec holding the timeout in seconds
+const WatchdogOption = "module_timeout"
+
+// watchdogTimeout returns the timeout set by the module_timeout option or, when
+// the option is not given and useEnv is set, by the environment. It is 0 for no
+// timeout.
+func (m *AnsibleModule) watchdogTimeout(useEnv bool) time.Duration {
+	var seconds float64
+	if _, ok := m.ArgSpec[WatchdogOption]; ok && m.Params[WatchdogOption] != nil {
+		seconds, _ = m.Params[WatchdogOption].(float64)
+	} else if useEnv {
+		seconds, _ = strconv.ParseFloat(os.Getenv(WatchdogEnv), 64)
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// StartWatchdog fails the module if it is still running after timeout. The module
+// context expires at the same time so pending operations stop. The returned
+// function disarms the watchdog.
+func (m *AnsibleModule) StartWatchdog(timeout time.Duration) func() {
+	m.SetDeadline(time.Now().Add(timeout))
+	timer := time.AfterFunc(timeout, func() {
+		m.watchdogExpired(timeout)
+	})
+	m.partialMu.Lock()
+	if m.watchdog != nil {
+		m.watchdog.Stop()
+	}
+	m.watchdog = timer
+	m.partialMu.Unlock()
+	return func() { timer.Stop() }
+}
+
+// stopWatchdog disarms the watchdog started last, if any
+func (m *AnsibleModule) stopWatchdog() {
+	m.partialMu.Lock()
+	defer m.partialMu.Unlock()
+	if m.watchdog != nil {
+		m.watchdog.Stop()
+		m.watchdog = nil
+	}
+}
+
+// SetPartialResult records a result value that is reported even if the module times out
+func (m *AnsibleModule) SetPartialResult(key string, value interface{}) {
+	m.partialMu.Lock()
+	defer m.partialMu.Unlock()
+
+	if m.partial == nil {
+		m.partial = make(map[string]interface{})
+	}
+	m.partial[key] = value
+}
+
+// watchdogExpired reports what was completed and fails the module
+func (m *AnsibleModule) watchdogExpired(timeout time.Duration) {
+	msg := fmt.Sprintf("module timed out after %s", timeout)
+
+	m.partialMu.Lock()
+	result := make(map[string]interface{}, len(m.partial)+1)
+	for k, v := range m.partial {
+		result[k] = v
+	}
+	m.partialMu.Unlock()
+
+	// Dump the phases that were still running to help find where the module hung
+	timings := m.Timings()
+	for _, timing := range timings {
+		if timing["incomplete"] == true {
+			m.writeDebugLog("DEBUG", fmt.Sprintf("%s: phase %v still running after %.3fs",
+				msg, timing["name"], timing["duration"]))
+		}
+	}
+	if len(timings) > 0 {
+		result["timings"] = timings
+	}
+
+	m.FailJson(msg, result)
+}
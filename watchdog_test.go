@@ -0,0 +1,105 @@
+package ansiblemodule
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	module := &AnsibleModule{Params: ModuleParams{}, remoteTmp: tmpDir}
+	moduleTmp, err := module.GetTmpDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan int, 1)
+	module.ExitFunc = func(code int) { exited <- code }
+
+	parsed := captureResult(t, func() {
+		module.SetPartialResult("processed", 3)
+		module.Span("slow phase")
+		module.StartWatchdog(50 * time.Millisecond)
+
+		select {
+		case <-exited:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected watchdog to fail the module")
+		}
+	})
+	if parsed["failed"] != true || parsed["msg"] != "module timed out after 50ms" {
+		t.Errorf("Expected timeout failure, got %v", parsed)
+	}
+	if parsed["processed"] != float64(3) {
+		t.Errorf("Expected partial result to be reported, got %v", parsed["processed"])
+	}
+	timings, ok := parsed["timings"].([]interface{})
+	if !ok || len(timings) != 1 || timings[0].(map[string]interface{})["incomplete"] != true {
+		t.Errorf("Expected incomplete phase in timings, got %v", parsed["timings"])
+	}
+	if module.Context().Err() == nil {
+		t.Error("Expected module context to expire")
+	}
+	if _, err := os.Stat(moduleTmp); !os.IsNotExist(err) {
+		t.Error("Expected the module temp directory to be removed on exit")
+	}
+}
+
+func TestWatchdogStop(t *testing.T) {
+	module := &AnsibleModule{}
+	module.ExitFunc = func(code int) { t.Error("Expected stopped watchdog not to exit") }
+
+	stop := module.StartWatchdog(20 * time.Millisecond)
+	stop()
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestWatchdogCheckModeUnsupported(t *testing.T) {
+	oldExit := DefaultExitFunc
+	DefaultExitFunc = func(code int) { t.Error("Expected no watchdog for a module that was refused") }
+	defer func() { DefaultExitFunc = oldExit }()
+
+	t.Setenv(WatchdogEnv, "0.02")
+	t.Setenv("ANSIBLE_MODULE_ARGS", `{"_ansible_check_mode": true}`)
+	if _, err := NewModule(ArgSpecMap{}, nil, nil, nil, nil, false); !errors.Is(err, ErrCheckModeUnsupported) {
+		t.Fatalf("Expected ErrCheckModeUnsupported, got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestWatchdogTimeoutEnv(t *testing.T) {
+	module := &AnsibleModule{}
+	t.Setenv(WatchdogEnv, "1.5")
+	if module.watchdogTimeout(true) != 1500*time.Millisecond {
+		t.Errorf("Expected 1.5s, got %v", module.watchdogTimeout(true))
+	}
+	if module.watchdogTimeout(false) != 0 {
+		t.Errorf("Expected the environment to be ignored, got %v", module.watchdogTimeout(false))
+	}
+
+	t.Setenv(WatchdogEnv, "invalid")
+	if module.watchdogTimeout(true) != 0 {
+		t.Errorf("Expected no timeout for invalid value, got %v", module.watchdogTimeout(true))
+	}
+}
+
+func TestWatchdogOption(t *testing.T) {
+	t.Setenv(WatchdogEnv, "30")
+	module := &AnsibleModule{ArgSpec: WatchdogSpec(), Params: ModuleParams{WatchdogOption: 2}}
+	if err := module.validateArguments(); err != nil {
+		t.Fatal(err)
+	}
+	if module.watchdogTimeout(true) != 2*time.Second || module.watchdogTimeout(false) != 2*time.Second {
+		t.Errorf("Expected the option to set the timeout, got %v", module.watchdogTimeout(true))
+	}
+	module.Params[WatchdogOption] = 0.0
+	if module.watchdogTimeout(true) != 0 {
+		t.Errorf("Expected 0 to disable the watchdog, got %v", module.watchdogTimeout(true))
+	}
+}
@@ -0,0 +1,236 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FileAttribute is a set of Windows file attribute flags
+type FileAttribute uint32
+
+// Windows file attributes that modules can manage
+const (
+	AttributeReadOnly FileAttribute = 0x1
+	AttributeHidden   FileAttribute = 0x2
+	AttributeSystem   FileAttribute = 0x4
+	AttributeArchive  FileAttribute = 0x20
+)
+
+// fileAttributeNames maps attribute names to flags
+var fileAttributeNames = map[string]FileAttribute{
+	"readonly": AttributeReadOnly,
+	"hidden":   AttributeHidden,
+	"system":   AttributeSystem,
+	"archive":  AttributeArchive,
+}
+
+// managedAttributes are the attribute flags changed by SetFileAttributes
+const managedAttributes = AttributeReadOnly | AttributeHidden | AttributeSystem | AttributeArchive
+
+// ParseFileAttributes converts attribute names such as "hidden" and "readonly" into flags
+func ParseFileAttributes(names []string) (FileAttribute, error) {
+	var attrs FileAttribute
+	for _, name := range names {
+		attr, ok := fileAttributeNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("unknown file attribute %q", name)
+		}
+		attrs |= attr
+	}
+	return attrs, nil
+}
+
+// Names returns the sorted names of the managed attributes that are set
+func (a FileAttribute) Names() []string {
+	names := []string{}
+	for name, attr := range fileAttributeNames {
+		if a&attr != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetFileAttributes returns the Windows attributes of a file
+func (m *AnsibleModule) GetFileAttributes(path string) (FileAttribute, error) {
+	attrs, err := getFileAttributes(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get attributes of %s: %w", path, err)
+	}
+	return attrs, nil
+}
+
+// SetFileAttributes sets the attributes in set and clears those in clear, leaving
+// the others unchanged, and reports whether the attributes changed
+func (m *AnsibleModule) SetFileAttributes(path string, set, clear FileAttribute) (bool, error) {
+	current, err := m.GetFileAttributes(path)
+	if err != nil {
+		return false, err
+	}
+	desired := (current | set&managedAttributes) &^ (clear & managedAttributes)
+	if desired == current {
+		return false, nil
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+	if err := setFileAttributes(path, desired); err != nil {
+		return false, fmt.Errorf("failed to set attributes of %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// ACE is an access control entry of a discretionary ACL in SDDL notation
+type ACE struct {
+	Type              string // A (allow), D (deny), OA, OD, ...
+	Flags             string // Inheritance flags such as OICI, or ID when inherited
+	Rights            string // Access rights such as FA, FR, or a hex mask
+	ObjectGUID        string
+	InheritObjectGUID string
+	SID               string // Account SID or alias such as BA, SY, or S-1-5-...
+}
+
+// String returns the entry in SDDL notation
+func (a ACE) String() string {
+	return "(" + strings.Join([]string{a.Type, a.Flags, a.Rights, a.ObjectGUID, a.InheritObjectGUID, a.SID}, ";") + ")"
+}
+
+// Inherited reports whether the entry was inherited from a parent directory
+func (a ACE) Inherited() bool {
+	return strings.Contains(a.Flags, "ID")
+}
+
+// ACL is a discretionary access control list
+type ACL struct {
+	Flags   string // DACL flags: P (protected from inheritance), AI, AR
+	Entries []ACE
+}
+
+// Protected reports whether the ACL blocks inheritance from parent directories
+func (a *ACL) Protected() bool {
+	return strings.Contains(a.Flags, "P")
+}
+
+// String returns the ACL as an SDDL DACL component
+func (a *ACL) String() string {
+	var b strings.Builder
+	b.WriteString("D:")
+	b.WriteString(a.Flags)
+	for _, entry := range a.Entries {
+		b.WriteString(entry.String())
+	}
+	return b.String()
+}
+
+// ParseACL parses the DACL of an SDDL security descriptor such as
+// "O:BAD:P(A;OICI;FA;;;SY)(A;OICI;FA;;;BA)"
+func ParseACL(sddl string) (*ACL, error) {
+	dacl, ok := sddlComponent(sddl, 'D')
+	if !ok {
+		return nil, fmt.Errorf("security descriptor has no DACL")
+	}
+
+	acl := &ACL{}
+	flagsEnd := strings.IndexByte(dacl, '(')
+	if flagsEnd < 0 {
+		flagsEnd = len(dacl)
+	}
+	acl.Flags = dacl[:flagsEnd]
+
+	rest := dacl[flagsEnd:]
+	for rest != "" {
+		end := strings.IndexByte(rest, ')')
+		if rest[0] != '(' || end < 0 {
+			return nil, fmt.Errorf("invalid ACE at %q", rest)
+		}
+		fields := strings.Split(rest[1:end], ";")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("invalid ACE %q: expected 6 fields, got %d", rest[:end+1], len(fields))
+		}
+		acl.Entries = append(acl.Entries, ACE{
+			Type:              fields[0],
+			Flags:             fields[1],
+			Rights:            fields[2],
+			ObjectGUID:        fields[3],
+			InheritObjectGUID: fields[4],
+			SID:               fields[5],
+		})
+		rest = rest[end+1:]
+	}
+	return acl, nil
+}
+
+// sddlComponent returns the value of the O, G, D or S component of an SDDL string
+func sddlComponent(sddl string, component byte) (string, bool) {
+	depth := 0
+	start := -1
+	for i := 0; i < len(sddl); i++ {
+		switch c := sddl[i]; {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && i+1 < len(sddl) && sddl[i+1] == ':' && strings.IndexByte("OGDS", c) >= 0:
+			if start >= 0 {
+				return sddl[start:i], true
+			}
+			if c == component {
+				start = i + 2
+			}
+			i++
+		}
+	}
+	if start >= 0 {
+		return sddl[start:], true
+	}
+	return "", false
+}
+
+// GetFileACL returns the discretionary ACL of a file
+func (m *AnsibleModule) GetFileACL(path string) (*ACL, error) {
+	sddl, err := getFileDACL(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ACL of %s: %w", path, err)
+	}
+	return ParseACL(sddl)
+}
+
+// SetFileACL replaces the discretionary ACL of a file and reports whether it changed.
+// Inherited entries are managed by Windows and are ignored in the comparison.
+func (m *AnsibleModule) SetFileACL(path string, acl *ACL) (bool, error) {
+	current, err := m.GetFileACL(path)
+	if err != nil {
+		return false, err
+	}
+	desired, err := canonicalDACL(acl.String())
+	if err != nil {
+		return false, fmt.Errorf("invalid ACL %s: %w", acl, err)
+	}
+	desiredACL, err := ParseACL(desired)
+	if err != nil {
+		return false, err
+	}
+	if explicitACL(current) == explicitACL(desiredACL) {
+		return false, nil
+	}
+	if m.CheckMode {
+		return true, nil
+	}
+	if err := setFileDACL(path, desired); err != nil {
+		return false, fmt.Errorf("failed to set ACL of %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// explicitACL returns the SDDL of an ACL without its inherited entries
+func explicitACL(acl *ACL) string {
+	explicit := &ACL{Flags: strings.ReplaceAll(strings.ReplaceAll(acl.Flags, "AI", ""), "AR", "")}
+	for _, entry := range acl.Entries {
+		if !entry.Inherited() {
+			explicit.Entries = append(explicit.Entries, entry)
+		}
+	}
+	return explicit.String()
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package ansiblemodule
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// errWindowsOnly is returned by the Windows security helpers on other platforms
+var errWindowsOnly = fmt.Errorf("not supported on %s, use mode and owner instead", runtime.GOOS)
+
+// getFileAttributes is only available on Windows
+func getFileAttributes(path string) (FileAttribute, error) {
+	return 0, errWindowsOnly
+}
+
+// setFileAttributes is only available on Windows
+func setFileAttributes(path string, attrs FileAttribute) error {
+	return errWindowsOnly
+}
+
+// getFileDACL is only available on Windows
+func getFileDACL(path string) (string, error) {
+	return "", errWindowsOnly
+}
+
+// canonicalDACL is only available on Windows
+func canonicalDACL(sddl string) (string, error) {
+	return "", errWindowsOnly
+}
+
+// setFileDACL is only available on Windows
+func setFileDACL(path, sddl string) error {
+	return errWindowsOnly
+}
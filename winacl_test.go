@@ -0,0 +1,73 @@
+package ansiblemodule
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseFileAttributes(t *testing.T) {
+	attrs, err := ParseFileAttributes([]string{"Hidden", " readonly"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if attrs != AttributeHidden|AttributeReadOnly {
+		t.Errorf("Expected hidden and readonly, got %#x", attrs)
+	}
+	if names := attrs.Names(); !reflect.DeepEqual(names, []string{"hidden", "readonly"}) {
+		t.Errorf("Expected [hidden readonly], got %v", names)
+	}
+	if names := FileAttribute(0x80).Names(); len(names) != 0 {
+		t.Errorf("Expected no managed attribute names, got %v", names)
+	}
+
+	if _, err := ParseFileAttributes([]string{"compressed"}); err == nil {
+		t.Error("Expected error for unknown attribute")
+	}
+}
+
+func TestParseACL(t *testing.T) {
+	sddl := "O:BAG:SYD:PAI(A;OICI;FA;;;SY)(A;OICI;0x1200a9;;;BU)(D;ID;FW;;;S-1-5-21-1-2-3-1001)S:AI"
+	acl, err := ParseACL(sddl)
+	if err != nil {
+		t.Fatalf("Failed to parse ACL: %v", err)
+	}
+	if acl.Flags != "PAI" || !acl.Protected() {
+		t.Errorf("Expected protected flags PAI, got %q", acl.Flags)
+	}
+	if len(acl.Entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(acl.Entries))
+	}
+	expected := ACE{Type: "A", Flags: "OICI", Rights: "0x1200a9", SID: "BU"}
+	if acl.Entries[1] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, acl.Entries[1])
+	}
+	if acl.Entries[0].Inherited() || !acl.Entries[2].Inherited() {
+		t.Error("Expected only the last entry to be inherited")
+	}
+	if s := acl.String(); s != "D:PAI(A;OICI;FA;;;SY)(A;OICI;0x1200a9;;;BU)(D;ID;FW;;;S-1-5-21-1-2-3-1001)" {
+		t.Errorf("Unexpected SDDL: %s", s)
+	}
+	if s := explicitACL(acl); s != "D:P(A;OICI;FA;;;SY)(A;OICI;0x1200a9;;;BU)" {
+		t.Errorf("Unexpected explicit ACL: %s", s)
+	}
+
+	for _, invalid := range []string{"O:BA", "D:(A;;FA;;SY)", "D:(A;;FA;;;SY"} {
+		if _, err := ParseACL(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestWindowsSecurityUnsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows security is supported")
+	}
+	module := &AnsibleModule{}
+	if _, err := module.GetFileAttributes(t.TempDir()); err == nil {
+		t.Error("Expected error getting attributes")
+	}
+	if _, err := module.SetFileACL(t.TempDir(), &ACL{}); err == nil {
+		t.Error("Expected error setting ACL")
+	}
+}
@@ -0,0 +1,125 @@
+package ansiblemodule
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Security information flags and SDDL revision from the Windows API
+const (
+	daclSecurityInformation = 0x00000004
+	sddlRevision1           = 1
+)
+
+var (
+	advapi32            = syscall.NewLazyDLL("advapi32.dll")
+	procGetFileSecurity = advapi32.NewProc("GetFileSecurityW")
+	procSetFileSecurity = advapi32.NewProc("SetFileSecurityW")
+	procSDToStringSD    = advapi32.NewProc("ConvertSecurityDescriptorToStringSecurityDescriptorW")
+	procStringSDToSD    = advapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+)
+
+// getFileAttributes reads the attribute flags of a file
+func getFileAttributes(path string) (FileAttribute, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	attrs, err := syscall.GetFileAttributes(name)
+	if err != nil {
+		return 0, err
+	}
+	return FileAttribute(attrs), nil
+}
+
+// setFileAttributes replaces the attribute flags of a file
+func setFileAttributes(path string, attrs FileAttribute) error {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	return syscall.SetFileAttributes(name, uint32(attrs))
+}
+
+// getFileDACL returns the DACL of a file in SDDL notation
+func getFileDACL(path string) (string, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Ask for the required size first
+	var needed uint32
+	r, _, err := procGetFileSecurity.Call(uintptr(unsafe.Pointer(name)), daclSecurityInformation, 0, 0,
+		uintptr(unsafe.Pointer(&needed)))
+	if r == 0 && err != syscall.ERROR_INSUFFICIENT_BUFFER {
+		return "", err
+	}
+	descriptor := make([]byte, needed)
+	r, _, err = procGetFileSecurity.Call(uintptr(unsafe.Pointer(name)), daclSecurityInformation,
+		uintptr(unsafe.Pointer(&descriptor[0])), uintptr(needed), uintptr(unsafe.Pointer(&needed)))
+	if r == 0 {
+		return "", err
+	}
+	return descriptorToString(&descriptor[0])
+}
+
+// descriptorToString converts a security descriptor to its SDDL DACL
+func descriptorToString(descriptor *byte) (string, error) {
+	var str *uint16
+	var length uint32
+	r, _, err := procSDToStringSD.Call(uintptr(unsafe.Pointer(descriptor)), sddlRevision1, daclSecurityInformation,
+		uintptr(unsafe.Pointer(&str)), uintptr(unsafe.Pointer(&length)))
+	if r == 0 {
+		return "", err
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(str)))
+	return syscall.UTF16ToString(unsafe.Slice(str, length)), nil
+}
+
+// stringToDescriptor converts SDDL to a self-relative security descriptor,
+// which must be released with LocalFree
+func stringToDescriptor(sddl string) (*byte, error) {
+	str, err := syscall.UTF16PtrFromString(sddl)
+	if err != nil {
+		return nil, err
+	}
+	var descriptor *byte
+	r, _, err := procStringSDToSD.Call(uintptr(unsafe.Pointer(str)), sddlRevision1,
+		uintptr(unsafe.Pointer(&descriptor)), 0)
+	if r == 0 {
+		return nil, err
+	}
+	return descriptor, nil
+}
+
+// canonicalDACL round-trips SDDL through Windows so it compares equal to getFileDACL output
+func canonicalDACL(sddl string) (string, error) {
+	descriptor, err := stringToDescriptor(sddl)
+	if err != nil {
+		return "", err
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(descriptor)))
+	return descriptorToString(descriptor)
+}
+
+// setFileDACL replaces the DACL of a file. The P flag of the DACL sets the
+// protected control bit, blocking inheritance from the parent directory.
+func setFileDACL(path, sddl string) error {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	descriptor, err := stringToDescriptor(sddl)
+	if err != nil {
+		return err
+	}
+	defer syscall.LocalFree(syscall.Handle(unsafe.Pointer(descriptor)))
+
+	r, _, err := procSetFileSecurity.Call(uintptr(unsafe.Pointer(name)), daclSecurityInformation,
+		uintptr(unsafe.Pointer(descriptor)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package ansiblemodule
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// workspaceName is the set of names Workspace accepts
+var workspaceName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// Workspace is a scratch directory below the module temp directory for staging
+// several files, such as an archive being unpacked or a configuration being
+// assembled before it is validated and installed. It is removed on exit.
+type Workspace struct {
+	Name string
+	Dir  string
+
+	m       *AnsibleModule
+	mu      sync.Mutex
+	created []string // Staged paths relative to Dir, in creation order
+}
+
+// Workspace returns the scratch directory name, creating it below the module
+// temp directory on first use. Later calls with the same name return the same
+// workspace until it is removed.
+func (m *AnsibleModule) Workspace(name string) (*Workspace, error) {
+	if !workspaceName.MatchString(name) {
+		return nil, fmt.Errorf("invalid workspace name %q", name)
+	}
+	tmp, err := m.tmpDir()
+	if err != nil {
+		return nil, err
+	}
+
+	m.tmpMu.Lock()
+	defer m.tmpMu.Unlock()
+	if ws, ok := m.workspaces[name]; ok && filepath.Dir(ws.Dir) == tmp {
+		return ws, nil
+	}
+	dir := filepath.Join(tmp, "workspace-"+name)
+	if err := m.fs().MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create workspace %s: %w", name, err)
+	}
+	ws := &Workspace{Name: name, Dir: dir, m: m}
+	if m.workspaces == nil {
+		m.workspaces = make(map[string]*Workspace)
+	}
+	m.workspaces[name] = ws
+	return ws, nil
+}
+
+// removeWorkspaces removes every workspace, also when the temp directory was
+// supplied by the caller and is kept
+func (m *AnsibleModule) removeWorkspaces() {
+	m.tmpMu.Lock()
+	workspaces := m.workspaces
+	m.workspaces = nil
+	m.tmpMu.Unlock()
+	for _, ws := range workspaces {
+		m.removeTmpTree(ws.Dir)
+	}
+}
+
+// Path returns the absolute path of rel inside the workspace, rejecting paths
+// that would leave it
+func (w *Workspace) Path(rel string) (string, error) {
+	rel = filepath.Clean(filepath.FromSlash(rel))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside workspace %s", rel, w.Name)
+	}
+	return filepath.Join(w.Dir, rel), nil
+}
+
+// track records a staged path
+func (w *Workspace) track(rel string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	rel = filepath.ToSlash(filepath.Clean(filepath.FromSlash(rel)))
+	for _, existing := range w.created {
+		if existing == rel {
+			return
+		}
+	}
+	w.created = append(w.created, rel)
+}
+
+// WriteFile stages content at rel, creating parent directories, and returns
+// its absolute path
+func (w *Workspace) WriteFile(rel string, content []byte, mode os.FileMode) (string, error) {
+	path, err := w.Path(rel)
+	if err != nil {
+		return "", err
+	}
+	if err := w.m.fs().MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := w.writeFile(path, content, mode); err != nil {
+		return "", fmt.Errorf("failed to stage %s: %w", rel, err)
+	}
+	w.track(rel)
+	return path, nil
+}
+
+// CopyFile stages a copy of src at rel, keeping its permissions, and returns
+// its absolute path
+func (w *Workspace) CopyFile(src, rel string) (string, error) {
+	path, err := w.Path(rel)
+	if err != nil {
+		return "", err
+	}
+	in, err := w.m.fs().Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	info, err := w.m.fs().Stat(src)
+	if err != nil {
+		return "", err
+	}
+	if err := w.m.fs().MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	out, err := w.m.fs().OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return "", fmt.Errorf("failed to stage %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	w.track(rel)
+	return path, nil
+}
+
+// Mkdir creates the directory rel and its parents, returning its absolute path
+func (w *Workspace) Mkdir(rel string) (string, error) {
+	path, err := w.Path(rel)
+	if err != nil {
+		return "", err
+	}
+	if err := w.m.fs().MkdirAll(path, 0700); err != nil {
+		return "", err
+	}
+	w.track(rel)
+	return path, nil
+}
+
+// Files returns the paths staged with WriteFile, CopyFile and Mkdir, relative
+// to the workspace with forward slashes, in the order they were first created
+func (w *Workspace) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.created...)
+}
+
+// Remove deletes the workspace and everything in it. A later Workspace call
+// with the same name starts empty.
+func (w *Workspace) Remove() error {
+	w.m.tmpMu.Lock()
+	if w.m.workspaces[w.Name] == w {
+		delete(w.m.workspaces, w.Name)
+	}
+	w.m.tmpMu.Unlock()
+
+	w.mu.Lock()
+	w.created = nil
+	w.mu.Unlock()
+	return w.m.removeTmpTree(w.Dir)
+}
+
+// writeFile writes content to path in the module file system
+func (w *Workspace) writeFile(path string, content []byte, mode os.FileMode) error {
+	file, err := w.m.fs().OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
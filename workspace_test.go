@@ -0,0 +1,55 @@
+package ansiblemodule
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	tmp := t.TempDir()
+	module := &AnsibleModule{TmpDir: tmp}
+
+	ws, err := module.Workspace("render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := module.Workspace("render"); again != ws {
+		t.Error("Expected the same workspace for the same name")
+	}
+	if _, err := module.Workspace("../escape"); err == nil {
+		t.Error("Expected an invalid name to fail")
+	}
+
+	staged, err := ws.WriteFile("conf.d/app.conf", []byte("listen 80\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(staged); string(content) != "listen 80\n" {
+		t.Errorf("Unexpected staged content %q", content)
+	}
+	src := filepath.Join(t.TempDir(), "cert.pem")
+	os.WriteFile(src, []byte("certificate"), 0644)
+	if _, err := ws.CopyFile(src, "certs/cert.pem"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.WriteFile("../outside", nil, 0600); err == nil {
+		t.Error("Expected a path outside the workspace to fail")
+	}
+	if files := ws.Files(); !reflect.DeepEqual(files, []string{"conf.d/app.conf", "certs/cert.pem"}) {
+		t.Errorf("Unexpected staged files %v", files)
+	}
+
+	// Workspaces are removed on exit even below a caller supplied temp directory
+	var output bytes.Buffer
+	module.Output, module.ExitFunc = &output, func(int) {}
+	module.ExitJson(map[string]interface{}{})
+	if _, err := os.Stat(ws.Dir); !os.IsNotExist(err) {
+		t.Errorf("Expected workspace to be removed on exit, got %v", err)
+	}
+	if _, err := os.Stat(tmp); err != nil {
+		t.Errorf("Expected the supplied temp directory to be kept: %v", err)
+	}
+}
@@ -0,0 +1,427 @@
+package ansiblemodule
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlParser reads the block-style YAML subset used in Ansible module documentation:
+// mappings, sequences, flow collections of scalars, quoted, plain multi-line and
+// literal/folded block scalars. Anchors, tags and multiple documents are not supported.
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+// parseYAML parses a YAML document into maps, slices and scalars
+func parseYAML(text string) (interface{}, error) {
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n")}
+	value, err := p.parseBlock(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.skipBlank(); p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml line %d: unexpected content %q", p.pos+1, strings.TrimSpace(p.lines[p.pos]))
+	}
+	return value, nil
+}
+
+// lineIndent returns the number of leading spaces of a line
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// isBlankLine reports whether a line has no content besides a comment
+func isBlankLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" || trimmed == "..."
+}
+
+// skipBlank advances past blank and comment lines
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && isBlankLine(p.lines[p.pos]) {
+		p.pos++
+	}
+}
+
+// isSequenceItem reports whether trimmed line content starts a sequence entry
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// parseBlock parses the node starting at the next line indented at least minIndent
+func (p *yamlParser) parseBlock(minIndent int) (interface{}, error) {
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	line := p.lines[p.pos]
+	indent := lineIndent(line)
+	if indent < minIndent {
+		return nil, nil
+	}
+	if isSequenceItem(strings.TrimSpace(line)) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+// parseSequence parses block sequence entries at the given indent
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	items := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return items, nil
+		}
+		line := p.lines[p.pos]
+		content := strings.TrimSpace(line)
+		if lineIndent(line) != indent || !isSequenceItem(content) {
+			return items, nil
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(content, "-"))
+		switch {
+		case rest == "":
+			p.pos++
+			item, err := p.parseBlock(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		case yamlMappingKey(rest) != "" || isSequenceItem(rest):
+			// Nested collection starting on the entry line, re-read it at its own indent
+			itemIndent := indent + len(content) - len(rest)
+			p.lines[p.pos] = strings.Repeat(" ", itemIndent) + rest
+			item, err := p.parseBlock(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		default:
+			p.pos++
+			item, err := p.parseInlineValue(rest, indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+}
+
+// yamlMappingKey returns the key if content is a "key: value" or "key:" line
+func yamlMappingKey(content string) string {
+	if strings.HasPrefix(content, "\"") || strings.HasPrefix(content, "'") {
+		quote := content[:1]
+		end := strings.Index(content[1:], quote)
+		if end < 0 {
+			return ""
+		}
+		after := content[end+2:]
+		if after == ":" || strings.HasPrefix(after, ": ") {
+			return content[:end+2]
+		}
+		return ""
+	}
+	if strings.HasPrefix(content, "[") || strings.HasPrefix(content, "{") {
+		return ""
+	}
+	if strings.HasSuffix(content, ":") && !strings.Contains(content, ": ") {
+		return strings.TrimSuffix(content, ":")
+	}
+	if key, _, found := strings.Cut(content, ": "); found && !strings.Contains(key, " #") {
+		return key
+	}
+	return ""
+}
+
+// parseMapping parses block mapping entries at the given indent
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return values, nil
+		}
+		line := p.lines[p.pos]
+		content := strings.TrimSpace(line)
+		if lineIndent(line) != indent || isSequenceItem(content) {
+			if lineIndent(line) > indent {
+				return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.pos+1)
+			}
+			return values, nil
+		}
+
+		rawKey := yamlMappingKey(content)
+		if rawKey == "" {
+			return nil, fmt.Errorf("yaml line %d: expected key: value, got %q", p.pos+1, content)
+		}
+		key := rawKey
+		if unquoted, ok := parseYAMLScalar(rawKey).(string); ok {
+			key = unquoted
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(content[len(rawKey):], ":"))
+		rest = stripYAMLComment(rest)
+		p.pos++
+
+		var value interface{}
+		var err error
+		switch {
+		case rest == "":
+			// Nested block, sequences may start at the same indent as their key
+			p.skipBlank()
+			if p.pos < len(p.lines) && lineIndent(p.lines[p.pos]) == indent &&
+				isSequenceItem(strings.TrimSpace(p.lines[p.pos])) {
+				value, err = p.parseSequence(indent)
+			} else {
+				value, err = p.parseBlock(indent + 1)
+			}
+		case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+			value = p.parseBlockScalar(rest, indent)
+		default:
+			value, err = p.parseInlineValue(rest, indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+}
+
+// parseInlineValue parses a value on its line, joining plain scalars continued on
+// following lines indented beyond parentIndent
+func (p *yamlParser) parseInlineValue(text string, parentIndent int) (interface{}, error) {
+	text = stripYAMLComment(text)
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") ||
+		strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		// Quoted and flow values may also span lines
+		for !yamlBalanced(text) && p.pos < len(p.lines) {
+			text += " " + strings.TrimSpace(p.lines[p.pos])
+			p.pos++
+		}
+		return parseYAMLScalar(text), nil
+	}
+
+	parts := []string{text}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if isBlankLine(line) || lineIndent(line) <= parentIndent {
+			break
+		}
+		parts = append(parts, stripYAMLComment(strings.TrimSpace(line)))
+		p.pos++
+	}
+	if len(parts) > 1 {
+		return strings.Join(parts, " "), nil
+	}
+	return parseYAMLScalar(text), nil
+}
+
+// yamlBalanced reports whether a quoted or flow value is complete
+func yamlBalanced(text string) bool {
+	switch text[0] {
+	case '"':
+		for i := 1; i < len(text); i++ {
+			if text[i] == '\\' {
+				i++
+			} else if text[i] == '"' {
+				return true
+			}
+		}
+		return false
+	case '\'':
+		return len(text) > 1 && strings.Count(text, "'")%2 == 0
+	default:
+		depth := 0
+		inQuote := byte(0)
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+			switch {
+			case inQuote != 0:
+				if c == inQuote {
+					inQuote = 0
+				}
+			case c == '"' || c == '\'':
+				inQuote = c
+			case c == '[' || c == '{':
+				depth++
+			case c == ']' || c == '}':
+				depth--
+			}
+		}
+		return depth <= 0
+	}
+}
+
+// parseBlockScalar reads a literal (|) or folded (>) block scalar
+func (p *yamlParser) parseBlockScalar(header string, parentIndent int) string {
+	folded := strings.HasPrefix(header, ">")
+	chomp := ""
+	if strings.Contains(header, "-") {
+		chomp = "-"
+	} else if strings.Contains(header, "+") {
+		chomp = "+"
+	}
+
+	var lines []string
+	contentIndent := -1
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		indent := lineIndent(line)
+		if indent <= parentIndent {
+			break
+		}
+		if contentIndent < 0 {
+			contentIndent = indent
+		}
+		if indent < contentIndent {
+			break
+		}
+		lines = append(lines, line[contentIndent:])
+		p.pos++
+	}
+
+	// Trailing blank lines belong to the chomping, not the content
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var text string
+	if folded {
+		var b strings.Builder
+		for i, line := range lines {
+			switch {
+			case i == 0:
+			case line == "" || lines[i-1] == "":
+				b.WriteString("\n")
+			case strings.HasPrefix(line, " "):
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(line)
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+
+	switch chomp {
+	case "-":
+		return text
+	case "+":
+		return text + strings.Repeat("\n", trailing+1)
+	default:
+		if text == "" {
+			return ""
+		}
+		return text + "\n"
+	}
+}
+
+// stripYAMLComment removes a trailing comment from an unquoted value
+func stripYAMLComment(text string) string {
+	inQuote := byte(0)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || text[i-1] == ' ' || text[i-1] == '[' || text[i-1] == ','):
+			inQuote = c
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return strings.TrimSpace(text[:i])
+		}
+	}
+	return text
+}
+
+// splitFlowItems splits the content of a flow collection at top-level commas
+func splitFlowItems(text string) []string {
+	var items []string
+	depth := 0
+	inQuote := byte(0)
+	start := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case inQuote != 0:
+			if c == '\\' && inQuote == '"' {
+				i++
+			} else if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		items = append(items, last)
+	}
+	return items
+}
+
+// parseYAMLScalar converts a scalar or flow collection to a Go value
+func parseYAMLScalar(text string) interface{} {
+	text = strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(text, "\"") && strings.HasSuffix(text, "\"") && len(text) >= 2:
+		var s string
+		if err := json.Unmarshal([]byte(text), &s); err == nil {
+			return s
+		}
+		return text[1 : len(text)-1]
+	case strings.HasPrefix(text, "'") && strings.HasSuffix(text, "'") && len(text) >= 2:
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'")
+	case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+		items := []interface{}{}
+		for _, item := range splitFlowItems(text[1 : len(text)-1]) {
+			items = append(items, parseYAMLScalar(item))
+		}
+		return items
+	case strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}"):
+		values := make(map[string]interface{})
+		for _, item := range splitFlowItems(text[1 : len(text)-1]) {
+			key, value, _ := strings.Cut(item, ":")
+			if k, ok := parseYAMLScalar(key).(string); ok {
+				values[k] = parseYAMLScalar(value)
+			}
+		}
+		return values
+	}
+
+	switch strings.ToLower(text) {
+	case "", "~", "null":
+		return nil
+	case "true", "yes", "on":
+		return true
+	case "false", "no", "off":
+		return false
+	}
+	if i, err := strconv.Atoi(text); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && strings.ContainsAny(text, ".eE") &&
+		!strings.HasPrefix(text, ".") {
+		return f
+	}
+	return text
+}
@@ -0,0 +1,74 @@
+package ansiblemodule
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	text := `---
+# Comment line
+name: demo  # trailing comment
+count: 3
+ratio: 0.5
+enabled: yes
+empty:
+quoted: "a: b"
+single: 'it''s'
+flow: [a, "b, c", 1]
+map: {key: value, other: 2}
+list:
+- first
+- second item
+  continued
+nested:
+  - name: one
+    value: 1
+  -
+    name: two
+literal: |
+  line one
+  line two
+folded: >-
+  folded
+  text
+`
+	value, err := parseYAML(text)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"name":    "demo",
+		"count":   3,
+		"ratio":   0.5,
+		"enabled": true,
+		"empty":   nil,
+		"quoted":  "a: b",
+		"single":  "it's",
+		"flow":    []interface{}{"a", "b, c", 1},
+		"map":     map[string]interface{}{"key": "value", "other": 2},
+		"list":    []interface{}{"first", "second item continued"},
+		"nested": []interface{}{
+			map[string]interface{}{"name": "one", "value": 1},
+			map[string]interface{}{"name": "two"},
+		},
+		"literal": "line one\nline two\n",
+		"folded":  "folded text",
+	}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, value)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, text := range []string{
+		"key:\n    a: 1\n  b: 2",
+		"- item\nkey: value",
+		"just a scalar line\nkey: value",
+	} {
+		if _, err := parseYAML(text); err == nil {
+			t.Errorf("Expected error for %q", text)
+		}
+	}
+}